@@ -0,0 +1,10 @@
+CREATE TABLE position_states (
+  position_id BIGINT UNSIGNED NOT NULL PRIMARY KEY,
+  high_water_rate DECIMAL(15,4) NOT NULL DEFAULT 0,
+  exit_reason TEXT,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  CONSTRAINT fk_position_states_position_id
+    FOREIGN KEY (position_id)
+    REFERENCES positions(id)
+);
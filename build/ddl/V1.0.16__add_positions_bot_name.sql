@@ -0,0 +1,3 @@
+ALTER TABLE positions
+ADD bot_name VARCHAR(255) NOT NULL DEFAULT 'default' AFTER id,
+ADD INDEX idx_positions_bot_name (bot_name);
@@ -0,0 +1,11 @@
+CREATE TABLE strategy_states (
+  bot_name VARCHAR(255) NOT NULL,
+  strategy VARCHAR(50) NOT NULL,
+  pair VARCHAR(15) NOT NULL,
+  state_key VARCHAR(100) NOT NULL,
+  value TEXT NOT NULL,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (bot_name, strategy, pair, state_key)
+);
@@ -0,0 +1,4 @@
+ALTER TABLE events
+  MODIFY COLUMN event_type TINYINT UNSIGNED NOT NULL COMMENT '0:buy 1:sell 2:alert 3:rejected 255:other',
+  ADD COLUMN alert_id VARCHAR(255) NULL COMMENT '外部アラートのID（重複受信の判定用）' AFTER event_type,
+  ADD UNIQUE KEY uq_events_alert_id (alert_id);
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/infrastructure/memory"
+	"trading-bot/pkg/usecase/optimize"
+)
+
+// checkpoint 最適化の途中経過（中断した最適化を再開するために書き出す）
+// 同じ乱数の種から探索をやり直し、評価済みの値の組はメモから取り出して途中まで進める
+type checkpoint struct {
+	// 乱数の種
+	Seed int64 `json:"seed"`
+	// 最適化が終わった期間と探索アルゴリズムの設定ごとの最良の値の組
+	Completed map[string]*optimize.Trial `json:"completed"`
+	// 期間ごとの値の組ごとの評価値
+	Memo map[string]map[string]float64 `json:"memo"`
+}
+
+// loadCheckpoint 書き出したチェックポイントを読み込む（ファイルがなければ空）
+func loadCheckpoint(path string) (*checkpoint, error) {
+	c := &checkpoint{
+		Completed: map[string]*optimize.Trial{},
+		Memo:      map[string]map[string]float64{},
+	}
+	if path == "" {
+		return c, nil
+	}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, err
+	}
+	if c.Completed == nil {
+		c.Completed = map[string]*optimize.Trial{}
+	}
+	if c.Memo == nil {
+		c.Memo = map[string]map[string]float64{}
+	}
+	return c, nil
+}
+
+// save 書き出す（途中で止まっても壊れないように一時ファイルから置き換える）
+func (c *checkpoint) save(path string) error {
+	if path == "" {
+		return nil
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// datasetKey 評価値が同じになる条件（期間と評価方法と探索範囲と再生する取引と約定モデル）を表すキー
+func datasetKey(sConf *model.SimulatorConfig, rates []memory.Rate) string {
+	if len(rates) == 0 {
+		return ""
+	}
+	key := fmt.Sprintf("%s-%s/%d/%s/slippage:%g/jpy:%g/%s",
+		rates[0].Datetime, rates[len(rates)-1].Datetime, len(rates), sConf.Fitness, sConf.Slippage, sConf.InitialJPY, sConf.SearchSpaceFile)
+	if sConf.TickHistoryFile != "" {
+		// 取引を再生しないときは以前のチェックポイントと同じキーにする
+		key += "/ticks:" + sConf.TickHistoryFile
+	}
+	if fill := memory.NewFillModel(sConf); fill != (memory.FillModel{VolumeWindow: fill.VolumeWindow}) {
+		// 約定モデルを変えないときも以前のチェックポイントと同じキーにする
+		key += fmt.Sprintf("/fill:%+v", fill)
+	}
+	return key
+}
+
+// datasetSeed 期間ごとの乱数の種（再開しても期間ごとに同じ順で探索する）
+func datasetSeed(seed int64, dataset string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(dataset))
+	return seed ^ int64(h.Sum64())
+}
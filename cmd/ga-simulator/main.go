@@ -3,314 +3,293 @@ package main
 import (
 	"context"
 	"fmt"
-	"math/rand"
+	"io/ioutil"
 	"os"
-	"reflect"
-	"sort"
 	"time"
 	"trading-bot/pkg/domain"
 	"trading-bot/pkg/domain/model"
 	"trading-bot/pkg/infrastructure/memory"
+	"trading-bot/pkg/infrastructure/mysql"
 	"trading-bot/pkg/usecase"
-	"trading-bot/pkg/usecase/strategy"
+	"trading-bot/pkg/usecase/optimize"
+	"trading-bot/pkg/usecase/report"
 	"trading-bot/pkg/usecase/trade"
 
 	"github.com/kelseyhightower/envconfig"
 )
 
-const (
-	geneSize      = 6
-	population    = 1
-	maxGeneration = 1
-	maxErrorCount = 5
-	//maxConvergedCount = 3
-	selectionRate = 0.020
-	crossoverRate = 0.975
-	mutationRate  = 0.005
-
-	randomPopulationCount        = 4
-	randomPopulationBornInterval = 10
-)
-
-var (
-	// [197 440 268 886 994 391 176] => 10.254
-	goodGene = []int{30, 440, 268, 886, 994, 391}
-	//goodGene = []int{}
-)
-
-type Individual struct {
-	Profit *float64
-	Gene   Gene
-}
-
-// Gene 遺伝子
-// 値は0～999
-type Gene []int
-
-func (g *Gene) MakeConfig() *strategy.RangeConfig {
-	v := []int(*g)
-	//return &strategy.ScalpingConfig{
-	//	FundsRatio:             0.3,
-	//	ShortTermSize:          v[0],
-	//	LongTermSize:           v[0] + v[1],
-	//	LossCutLowerLimitPer:   float64(v[2]) / 1000.0,
-	//	FixProfitUpperLimitPer: 1.0 + float64(v[3])/1000.0,
-	//	BBandsNBDevUp:          float64(v[4]) / 300.0,
-	//	BBandsNBDevDown:        float64(v[5]) / 300.0,
-	//	RsiLower:               float64(v[6]) / 10.0,
-	//	RsiUpper:               float64(v[6])/10.0 + float64(v[7])/10.0,
-	//}
-	return &strategy.RangeConfig{
-		FundsRatio:             0.3,
-		TermSize:               v[0],
-		LossCutLowerLimitPer:   float64(v[1]) / 1000.0,
-		FixProfitUpperLimitPer: 1.0 + float64(v[2])/1000.0,
-		BBandsNBDevUp:          float64(v[3]) / 300.0,
-		BBandsNBDevDown:        float64(v[4]) / 300.0,
-		BBandsMaxWidthRate:     float64(v[5]) / 1000.0,
-	}
-}
-
-func (i *Individual) String() string {
-	return fmt.Sprintf("%v", i.Gene)
-}
+const maxErrorCount = 5
 
 func main() {
-	rand.Seed(time.Now().UnixNano())
-
 	logger := memory.Logger{Level: memory.Debug}
 
 	logger.Info("===== START GA SIMULATION ====================")
 	defer logger.Info("===== END GA SIMULATION ======================")
 
-	var individuals []*Individual
-	gi := 1
-	convergedCount := 0
-	for {
-		logger.Info("***** Generation %d *****", gi)
-
-		// 個体群を生成
-		if len(individuals) == 0 {
-			individuals = makeInitIndividuals(population, goodGene)
-			//} else if convergedCount >= maxConvergedCount {
-			//	individuals = makeInitIndividuals(population, individuals[0].Gene)
-		} else {
-			nextIndividual := []*Individual{}
-
-			// 一番成績のいい個体は続投
-			nextIndividual = append(nextIndividual, individuals[0])
-
-			// 多様性維持のため定期的にランダムな個体を追加する
-			if gi%randomPopulationBornInterval == 0 {
-				for n := 0; n < randomPopulationCount; n++ {
-					nextIndividual = append(nextIndividual, makeRandomIndividual())
-				}
-			}
-
-			for {
-				nextIndividual = append(nextIndividual, makeNextIndividual(individuals))
-				if len(nextIndividual) == population {
-					break
-				}
-			}
-			individuals = nextIndividual
-		}
+	var conf model.Config
+	if err := envconfig.Process("BOT", &conf); err != nil {
+		logger.Error(err.Error())
+		return
+	}
+	var sConf model.SimulatorConfig
+	if err := envconfig.Process("BOT", &sConf); err != nil {
+		logger.Error(err.Error())
+		return
+	}
+	if err := report.ValidFitness(sConf.Fitness); err != nil {
+		logger.Error(err.Error())
+		return
+	}
+	if err := sConf.ValidFillModel(); err != nil {
+		logger.Error(err.Error())
+		return
+	}
+	logger.Info("fitness: %s", sConf.Fitness)
 
-		// 個体群の成績を評価
-		for i, individual := range individuals {
-			logger.Info("running simulation [%d/%d] %s ...", i+1, len(individuals), individual.String())
-			errCount := 0
-			for {
-				p, err := simulation(&logger, &individual.Gene)
-				if err != nil {
-					logger.Error("error occured; %v", err)
-					errCount++
-					if errCount >= maxErrorCount {
-						logger.Error("terminate simulation [%d/%d]", errCount, maxErrorCount)
-						return
-					}
-				}
-				individual.Profit = &p
-				break
-			}
-		}
+	space, err := optimize.NewSearchSpace(sConf.SearchSpaceFile)
+	if err != nil {
+		logger.Error("failed to load search space, %v", err)
+		return
+	}
+	if !isRegistered(usecase.StrategyType(space.Strategy)) {
+		logger.Error("strategy is not registered; name = %s, registered = %v", space.Strategy, usecase.RegisteredStrategies())
+		return
+	}
+	logger.Info("strategy: %s, search space: %s (%d params)", space.Strategy, sConf.SearchSpaceFile, space.Dim())
 
-		// 利益順にソート
-		sort.SliceStable(individuals, func(i, j int) bool {
-			return *individuals[i].Profit > *individuals[j].Profit
-		})
+	rates, err := readRates(&conf, &sConf)
+	if err != nil {
+		logger.Error("failed to read rates, %v", err)
+		return
+	}
+	logger.Info("rates: %d", len(rates))
 
-		for _, individual := range individuals {
-			logger.Info("result %s => %.3f", individual.String(), *individual.Profit)
-		}
+	ticks, err := readTicks(&sConf)
+	if err != nil {
+		logger.Error("failed to read ticks, %v", err)
+		return
+	}
+	if ticks != nil {
+		logger.Info("ticks: %d", len(ticks))
+	}
 
-		bestInd := individuals[0]
-		logger.Info("best profit: %.3f", *bestInd.Profit)
-		logger.Info("gene: %v", bestInd.Gene)
-		logger.Info("params: %#v", *bestInd.Gene.MakeConfig())
+	o, err := newOptimizer(&logger, &conf, &sConf, space, ticks)
+	if err != nil {
+		logger.Error(err.Error())
+		return
+	}
+	logger.Info("optimizer: %s, seed: %d", sConf.Optimizer, o.checkpoint.Seed)
 
-		if isConverged(individuals) {
-			convergedCount++
-		} else {
-			convergedCount = 0
+	if sConf.WalkForwardInSampleHours > 0 && sConf.WalkForwardOutOfSampleHours > 0 {
+		if err := walkForward(&logger, &sConf, o, rates); err != nil {
+			logger.Error("error occured; %v", err)
+			return
 		}
-
-		if gi >= maxGeneration {
-			logger.Info("***** max generation *****")
-			break
+	} else {
+		best, err := o.optimize(rates)
+		if err != nil {
+			logger.Error("error occured; %v", err)
+			return
+		}
+		if err := writeBestConfig(&logger, &sConf, space, best); err != nil {
+			logger.Error("error occured; %v", err)
+			return
 		}
-		gi++
 	}
 
 	logger.Info("***** completed !!! *****")
 }
 
-func makeInitIndividuals(size int, goodGene []int) []*Individual {
-	individuals := []*Individual{}
-	if len(goodGene) == geneSize {
-		individuals = append(individuals, &Individual{Profit: nil, Gene: goodGene})
+// readRates シミュレーションに使うレートを読み込む（CSVかDBのmarketsテーブル）
+func readRates(conf *model.Config, sConf *model.SimulatorConfig) ([]memory.Rate, error) {
+	if err := sConf.ValidRateSource(); err != nil {
+		return nil, err
+	}
+	if sConf.RateSource == model.MarketsRateSource {
+		mysqlCli := mysql.NewClient(conf.DB.UserName, conf.DB.Password, conf.DB.Host, conf.DB.Port, conf.DB.Name)
+		return loadMarketRates(mysqlCli, conf.GetTargetPair(model.JPY), sConf)
 	}
 
-	for {
-		individuals = append(individuals, makeRandomIndividual())
-		if len(individuals) == size {
-			break
-		}
+	historical, err := os.Open(sConf.RateHistoryFile)
+	if err != nil {
+		return nil, err
 	}
-	return individuals
+	defer historical.Close()
+	return memory.ReadRates(historical)
 }
 
-func makeNextIndividual(current []*Individual) *Individual {
-	v := randValue()
-	if v <= int(selectionRate*1000) {
-		// 再生
-		return choose(current)
-	}
-	if v <= int((selectionRate+crossoverRate)*1000) {
-		// 交叉
-		var ind1, ind2 *Individual
-		for {
-			ind1 = choose(current)
-			ind2 = choose(current)
-			if !reflect.DeepEqual(ind1, ind2) {
-				break
-			}
+func isRegistered(t usecase.StrategyType) bool {
+	for _, r := range usecase.RegisteredStrategies() {
+		if r == t {
+			return true
 		}
-		return crossover(ind1, ind2)
 	}
+	return false
+}
 
-	// 突然変異
-	return mutate(choose(current))
+// optimizer 探索範囲の最適化（途中経過をチェックポイントに書き出す）
+type optimizer struct {
+	logger         domain.Logger
+	conf           *model.Config
+	sConf          *model.SimulatorConfig
+	space          *optimize.SearchSpace
+	checkpoint     *checkpoint
+	checkpointFile string
+	// 再生する取引（なければnil）
+	ticks []model.Trade
 }
 
-// choose 選択
-func choose(current []*Individual) *Individual {
-	// ランキング上位の個体ほど選択されやすいようにする
-	size := len(current)
-	list := []*Individual{}
-	for i, ind := range current {
-		count := size - i
-		for j := 0; j < count; j++ {
-			list = append(list, ind)
-		}
+// newOptimizer 生成（チェックポイントがあれば途中から再開する）
+func newOptimizer(logger domain.Logger, conf *model.Config, sConf *model.SimulatorConfig, space *optimize.SearchSpace, ticks []model.Trade) (*optimizer, error) {
+	c, err := loadCheckpoint(sConf.GaCheckpointFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load checkpoint, %v", err)
+	}
+	if len(c.Memo) > 0 || len(c.Completed) > 0 {
+		logger.Info("checkpoint: %s (%d datasets, %d completed)", sConf.GaCheckpointFile, len(c.Memo), len(c.Completed))
+	}
+	if sConf.OptimizerSeed != 0 {
+		c.Seed = sConf.OptimizerSeed
+	} else if c.Seed == 0 {
+		c.Seed = time.Now().UnixNano()
 	}
+	o := &optimizer{
+		logger:         logger,
+		conf:           conf,
+		sConf:          sConf,
+		space:          space,
+		checkpoint:     c,
+		checkpointFile: sConf.GaCheckpointFile,
+		ticks:          ticks,
+	}
+	// 探索する前に設定が正しいか確かめる
+	if _, err := o.algorithm(""); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
 
-	n := rand.Intn(len(list))
-	return list[n]
+func (o *optimizer) algorithm(dataset string) (optimize.Algorithm, error) {
+	return optimize.NewAlgorithm(optimize.AlgorithmType(o.sConf.Optimizer), o.space, optimize.Options{
+		Population:  o.sConf.OptimizerPopulation,
+		Generations: o.sConf.OptimizerGenerations,
+		Budget:      o.sConf.OptimizerBudget,
+		Seed:        datasetSeed(o.checkpoint.Seed, dataset),
+	})
 }
 
-func crossover(ind1 *Individual, ind2 *Individual) *Individual {
-	size := len(ind1.Gene)
-	var idx1, idx2 int
-	for {
-		idx1 = rand.Intn(size)
-		idx2 = rand.Intn(size)
-		if idx1 != idx2 {
-			break
-		}
+// optimize レートの期間で最も評価値の高い値の組を探す
+func (o *optimizer) optimize(rates []memory.Rate) (*optimize.Trial, error) {
+	logger := o.logger
+	dataset := datasetKey(o.sConf, rates)
+	run := o.runKey(dataset)
+	if best, ok := o.checkpoint.Completed[run]; ok {
+		best.Values = o.space.Normalize(best.Values)
+		logger.Info("***** already optimized (checkpoint) %s => %.3f *****", best.Values, best.Fitness)
+		return best, nil
 	}
 
-	var begin, end int
-	if idx1 < idx2 {
-		begin = idx1
-		end = idx2
-	} else {
-		begin = idx2
-		end = idx1
+	alg, err := o.algorithm(dataset)
+	if err != nil {
+		return nil, err
+	}
+	// 並列評価と評価値のメモはRunnerで行い、バッチごとにメモをチェックポイントに書き出す
+	runner := optimize.NewRunner(logger, o.space, o.sConf.GaWorkers, maxErrorCount, o.checkpoint.Memo[dataset])
+	runner.OnBatch = func(memo map[string]float64) error {
+		o.checkpoint.Memo[dataset] = memo
+		return o.save()
 	}
+	logger.Info("workers: %d", runner.Workers())
 
-	newGene := []int{}
-	for i := 0; i < size; i++ {
-		if i < begin || i > end {
-			newGene = append(newGene, ind1.Gene[i])
-		} else {
-			newGene = append(newGene, ind2.Gene[i])
-		}
+	result, err := runner.Run(alg, func(v optimize.Values) (float64, error) {
+		return fitness(logger, o.conf, o.sConf, o.space, v, rates, o.ticks)
+	})
+	if err != nil {
+		return nil, err
 	}
+	if result.Best == nil {
+		return nil, fmt.Errorf("no trial is evaluated")
+	}
+
+	best := result.Best
+	logger.Info("***** best fitness: %.3f *****", best.Fitness)
+	logger.Info("params: %s", best.Values)
 
-	return &Individual{Gene: newGene}
+	o.checkpoint.Completed[run] = best
+	o.checkpoint.Memo[dataset] = runner.Memo()
+	return best, o.save()
 }
 
-func mutate(org *Individual) *Individual {
-	size := len(org.Gene)
-	n := rand.Intn(size)
+// runKey 最適化の結果が同じになる条件（期間と探索アルゴリズムの設定）を表すキー
+// 評価値のメモは探索アルゴリズムによらないので期間ごとにする
+func (o *optimizer) runKey(dataset string) string {
+	return fmt.Sprintf("%s/%s/population:%d/generations:%d/budget:%d",
+		dataset, o.sConf.Optimizer, o.sConf.OptimizerPopulation, o.sConf.OptimizerGenerations, o.sConf.OptimizerBudget)
+}
 
-	newGene := []int{}
-	for i := 0; i < size; i++ {
-		if i == n {
-			newGene = append(newGene, randValue())
-		} else {
-			newGene = append(newGene, org.Gene[i])
-		}
+// save チェックポイントを書き出す
+func (o *optimizer) save() error {
+	if err := o.checkpoint.save(o.checkpointFile); err != nil {
+		return fmt.Errorf("failed to save checkpoint, %v", err)
 	}
-	return &Individual{Gene: newGene}
+	return nil
 }
 
-func makeRandomIndividual() *Individual {
-	gene := []int{}
-	for i := 0; i < geneSize; i++ {
-		gene = append(gene, randValue())
+// writeBestConfig 最良の値の組で上書きした戦略の設定ファイルを書き出す
+func writeBestConfig(logger domain.Logger, sConf *model.SimulatorConfig, space *optimize.SearchSpace, best *optimize.Trial) error {
+	if sConf.OptimizerBestConfigFile == "" {
+		return nil
 	}
-
-	return &Individual{
-		Profit: nil,
-		Gene:   gene,
+	b, err := space.Config(best.Values)
+	if err != nil {
+		return err
 	}
-}
-
-func simulation(logger domain.Logger, gene *Gene) (float64, error) {
-	var conf model.Config
-	if err := envconfig.Process("BOT", &conf); err != nil {
-		return 0, err
+	if err := ioutil.WriteFile(sConf.OptimizerBestConfigFile, b, 0644); err != nil {
+		return err
 	}
+	logger.Info("best config: %s", sConf.OptimizerBestConfigFile)
+	return nil
+}
 
-	var sConf model.SimulatorConfig
-	if err := envconfig.Process("BOT", &sConf); err != nil {
+// fitness 値の組の評価値
+func fitness(logger domain.Logger, conf *model.Config, sConf *model.SimulatorConfig, space *optimize.SearchSpace, v optimize.Values, rates []memory.Rate, ticks []model.Trade) (float64, error) {
+	r, err := simulation(logger, conf, sConf, space, v, rates, ticks)
+	if err != nil {
 		return 0, err
 	}
+	return r.Fitness(sConf.Fitness)
+}
 
-	historical, err := os.Open(sConf.RateHistoryFile)
+// simulation レートの期間で値の組を使ってシミュレーション（取引があれば期間内の取引を再生する）
+// 並列に呼ばれるため、ExchangeMockとDummyRDSは呼び出しごとに生成する（共有するのは読み込み済みのレートと取引だけ）
+func simulation(logger domain.Logger, conf *model.Config, sConf *model.SimulatorConfig, space *optimize.SearchSpace, v optimize.Values, rates []memory.Rate, ticks []model.Trade) (*report.Report, error) {
+	exCli, err := memory.NewExchangeMockWithRates(rates, sConf.Slippage)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	exCli, err := memory.NewExchangeMock(historical, sConf.Slippage)
-	if err != nil {
-		return 0, err
+	exCli.SetBalance(model.JPY, sConf.InitialJPY)
+	exCli.SetFillModel(memory.NewFillModel(sConf))
+	if ticks != nil {
+		exCli.SetTrades(ticks)
 	}
 
 	rdsCli := memory.NewDummyRDS(nil)
 
-	facade := trade.NewFacade(exCli, rdsCli, rdsCli, rdsCli, rdsCli, nil)
+	facade := trade.NewFacade(exCli, rdsCli, rdsCli, rdsCli, rdsCli, rdsCli, rdsCli, nil)
+	// 時刻はCSVの日時で進める
+	facade.SetClock(exCli)
 
-	currency := model.CurrencyType(conf.TargetCurrency)
-	//strategy, err := strategy.NewScalpingStrategy(facade, logger, gene.MakeConfig())
-	strategy, err := strategy.NewRangeStrategy(facade, logger, gene.MakeConfig())
+	strategyType := usecase.StrategyType(space.Strategy)
+	strategy, err := makeStrategy(logger, facade, space, strategyType, v)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
+	currency := model.CurrencyType(conf.TargetCurrency)
 	bot := usecase.NewBot(logger, facade, strategy, &usecase.BotConfig{
-		Currency: currency,
+		Strategy:         strategyType,
+		Currency:         currency,
+		PositionCountMax: conf.PositionCountMax,
 	})
 
 	pair := model.CurrencyPair{
@@ -318,6 +297,7 @@ func simulation(logger domain.Logger, gene *Gene) (float64, error) {
 		Settlement: model.JPY,
 	}
 	fetcher := usecase.NewFetcher(exCli, pair, rdsCli)
+	fetcher.SetClock(exCli)
 
 	simulator := usecase.Simulator{
 		Bot:          bot,
@@ -327,23 +307,26 @@ func simulation(logger domain.Logger, gene *Gene) (float64, error) {
 		Logger:       logger,
 	}
 
-	return simulator.Run(context.Background())
+	return simulator.RunWithReport(context.Background())
 }
 
-func isConverged(individuals []*Individual) bool {
-	isSame := true
-	for i := range individuals {
-		if i == 0 {
-			continue
-		}
-		if *individuals[i].Profit != *individuals[i-1].Profit {
-			isSame = false
-		}
+// makeStrategy 値の組で上書きした設定ファイルを一時ファイルに書き出して戦略を生成
+func makeStrategy(logger domain.Logger, facade *trade.Facade, space *optimize.SearchSpace, t usecase.StrategyType, v optimize.Values) (usecase.Strategy, error) {
+	b, err := space.Config(v)
+	if err != nil {
+		return nil, err
 	}
-
-	return isSame
-}
-
-func randValue() int {
-	return rand.Intn(1000)
+	tmp, err := ioutil.TempFile("", fmt.Sprintf("bot-%s.*.toml", t))
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+	return usecase.MakeStrategyWithConfig(t, tmp.Name(), facade, logger)
 }
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+	"trading-bot/pkg/domain"
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/infrastructure/memory"
+	"trading-bot/pkg/usecase/report"
+	"trading-bot/pkg/usecase/walkforward"
+)
+
+// walkForward 最適化期間ごとにパラメータを選び、続く検証期間で成績を確認する
+func walkForward(logger domain.Logger, sConf *model.SimulatorConfig, o *optimizer, rates []memory.Rate) error {
+	if len(rates) == 0 {
+		return fmt.Errorf("rates is empty")
+	}
+	windows, err := walkforward.Split(
+		rates[0].Time,
+		rates[len(rates)-1].Time.Add(time.Nanosecond),
+		time.Duration(sConf.WalkForwardInSampleHours)*time.Hour,
+		time.Duration(sConf.WalkForwardOutOfSampleHours)*time.Hour,
+	)
+	if err != nil {
+		return err
+	}
+
+	result := &walkforward.Result{Windows: []walkforward.WindowResult{}}
+	reports := []*report.Report{}
+	params := []map[string]float64{}
+	for i, w := range windows {
+		logger.Info("===== walk-forward [%d/%d] in-sample %s - %s, out-of-sample %s - %s =====", i+1, len(windows),
+			w.InSampleStart.Format(time.RFC3339), w.InSampleEnd.Format(time.RFC3339),
+			w.OutOfSampleStart.Format(time.RFC3339), w.OutOfSampleEnd.Format(time.RFC3339))
+
+		inSample := walkforward.Slice(rates, w.InSampleStart, w.InSampleEnd)
+		outOfSample := walkforward.Slice(rates, w.OutOfSampleStart, w.OutOfSampleEnd)
+		if len(inSample) == 0 || len(outOfSample) == 0 {
+			logger.Info("skip window (in-sample: %d rates, out-of-sample: %d rates)", len(inSample), len(outOfSample))
+			continue
+		}
+
+		best, err := o.optimize(inSample)
+		if err != nil {
+			return err
+		}
+		r, err := simulation(logger, o.conf, sConf, o.space, best.Values, outOfSample, o.ticks)
+		if err != nil {
+			return err
+		}
+		f, err := r.Fitness(sConf.Fitness)
+		if err != nil {
+			return err
+		}
+		logger.Info("out-of-sample %s => fitness: %.3f, profit: %.3f", best.Values, f, r.Metrics.Profit)
+
+		p := best.Values.Numeric()
+		params = append(params, p)
+		reports = append(reports, r)
+		result.Windows = append(result.Windows, walkforward.WindowResult{
+			Window:             w,
+			Params:             p,
+			InSampleFitness:    best.Fitness,
+			OutOfSampleFitness: f,
+			OutOfSample:        r.Metrics,
+		})
+	}
+	if len(reports) == 0 {
+		return fmt.Errorf("no window has rates")
+	}
+
+	result.Stitched = walkforward.Stitch(reports)
+	result.Stability = walkforward.Stability(params)
+
+	m := result.Stitched.Metrics
+	logger.Info("===== walk-forward result =====")
+	logger.Info("out-of-sample profit: %.3f (%.2f%%, buy and hold %.2f%%)", m.Profit, m.Return*100, m.BuyAndHoldReturn*100)
+	logger.Info("max drawdown: %.3f (%.2f%%), sharpe: %.3f", m.MaxDrawdown, m.MaxDrawdownRatio*100, m.Sharpe)
+	for _, s := range result.Stability {
+		logger.Info("param %s: mean %.3f, std %.3f, min %.3f, max %.3f, cv %.3f", s.Name, s.Mean, s.Std, s.Min, s.Max, s.CV)
+	}
+
+	return writeWalkForward(logger, sConf, result)
+}
+
+// writeWalkForward ウォークフォワードの結果と、つなげた検証期間の成績を書き出す
+func writeWalkForward(logger domain.Logger, sConf *model.SimulatorConfig, result *walkforward.Result) error {
+	if sConf.WalkForwardReportFile != "" {
+		file, err := os.Create(sConf.WalkForwardReportFile)
+		if err != nil {
+			return err
+		}
+		e := json.NewEncoder(file)
+		e.SetIndent("", "  ")
+		if err := e.Encode(result); err != nil {
+			file.Close()
+			return err
+		}
+		if err := file.Close(); err != nil {
+			return err
+		}
+		logger.Info("walk-forward report: %s", sConf.WalkForwardReportFile)
+	}
+	if sConf.ReportHtmlFile != "" {
+		file, err := os.Create(sConf.ReportHtmlFile)
+		if err != nil {
+			return err
+		}
+		if err := result.Stitched.WriteHTML(file); err != nil {
+			file.Close()
+			return err
+		}
+		if err := file.Close(); err != nil {
+			return err
+		}
+		logger.Info("out-of-sample report: %s", sConf.ReportHtmlFile)
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/infrastructure/memory"
+	"trading-bot/pkg/infrastructure/mysql"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+const (
+	location = "Asia/Tokyo"
+	// tradeHistoryVersion 書き出す約定履歴（CSV）の形式
+	tradeHistoryVersion = 1
+)
+
+func init() {
+	loc, err := time.LoadLocation(location)
+	if err != nil {
+		loc = time.FixedZone(location, 9*60*60)
+	}
+	time.Local = loc
+}
+
+func main() {
+	logger := memory.Logger{Level: memory.Debug}
+
+	logger.Info("===== START PROGRAM ====================")
+	defer logger.Info("===== END PROGRAM ======================")
+
+	var config Config
+	if err := envconfig.Process("", &config); err != nil {
+		logger.Error(err.Error())
+		return
+	}
+	pair, err := model.ParseToCurrencyPair(config.TargetPair)
+	if err != nil {
+		logger.Error(err.Error())
+		return
+	}
+	from, err := model.ParseDateTime(config.ExportFrom)
+	if err != nil {
+		logger.Error(err.Error())
+		return
+	}
+	to, err := model.ParseDateTime(config.ExportTo)
+	if err != nil {
+		logger.Error(err.Error())
+		return
+	}
+
+	logger.Info("pair: %s, period: %s - %s", pair.String(), config.ExportFrom, config.ExportTo)
+	mysqlCli := mysql.NewClient(config.DB.UserName, config.DB.Password, config.DB.Host, config.DB.Port, config.DB.Name)
+
+	if err := exportRates(mysqlCli, pair, from, to, config.OutputFile); err != nil {
+		logger.Error("failed to export rates, %v", err)
+		return
+	}
+	logger.Info("rates: %s", outputName(config.OutputFile))
+
+	if config.TradesFile != "" {
+		if err := exportTrades(mysqlCli, pair, from, to, config.TradesFile); err != nil {
+			logger.Error("failed to export trades, %v", err)
+			return
+		}
+		logger.Info("trades: %s", config.TradesFile)
+	}
+}
+
+type Config struct {
+	// 対象コインペア（例: btc_jpy）
+	TargetPair string `required:"true" split_words:"true"`
+	// 期間（RFC3339か2006-01-02、開始を含み終了を含まない、未指定なら最初/最後まで）
+	ExportFrom string `split_words:"true"`
+	ExportTo   string `split_words:"true"`
+	// レート履歴の書き出し先（未指定なら標準出力）
+	OutputFile string `split_words:"true"`
+	// 自分の約定履歴の書き出し先（未指定なら書き出さない）
+	TradesFile string `split_words:"true"`
+
+	// DB設定
+	DB model.DB `required:"true" split_words:"true"`
+}
+
+func outputName(path string) string {
+	if path == "" {
+		return "stdout"
+	}
+	return path
+}
+
+// exportRates marketsテーブルのレートと取引量をシミュレーター用のCSV（memory.HistoryVersionの形式）で書き出す
+func exportRates(mysqlCli *mysql.Client, pair *model.CurrencyPair, from, to time.Time, path string) error {
+	markets, err := mysqlCli.GetMarketsBetween(pair, from, to)
+	if err != nil {
+		return err
+	}
+	rates := []memory.Rate{}
+	for _, m := range markets {
+		rates = append(rates, memory.NewRateWithVolumes(m.RecordedAt, m.StoreRateAVG, m.ExRateBuy, m.ExRateSell, m.ExVolumeBuy, m.ExVolumeSell))
+	}
+	return writeFile(path, func(w io.Writer) error {
+		return memory.WriteRates(w, *pair, rates)
+	})
+}
+
+// exportTrades 期間内に注文した約定情報をCSVで書き出す
+func exportTrades(mysqlCli *mysql.Client, pair *model.CurrencyPair, from, to time.Time, path string) error {
+	trades, err := mysqlCli.GetTradesBetween(pair, from, to)
+	if err != nil {
+		return err
+	}
+	return writeFile(path, func(w io.Writer) error {
+		if _, err := fmt.Fprintf(w, "# trading-bot trade history; version=%d; pair=%s\n", tradeHistoryVersion, pair.String()); err != nil {
+			return err
+		}
+		writer := csv.NewWriter(w)
+		header := []string{
+			"ordered_at", "order_id", "order_type", "contract_id", "side", "rate",
+			"increase_currency", "increase_amount", "decrease_currency", "decrease_amount",
+			"fee_currency", "fee_amount", "liquidity",
+		}
+		if err := writer.Write(header); err != nil {
+			return err
+		}
+		for _, t := range trades {
+			side := "buy"
+			if model.OrderSide(t.Side) == model.SellSide {
+				side = "sell"
+			}
+			liquidity := "taker"
+			if model.LiquidityType(t.Liquidity) == model.Maker {
+				liquidity = "maker"
+			}
+			record := []string{
+				t.OrderedAt.Format(time.RFC3339),
+				strconv.FormatUint(t.OrderID, 10),
+				string(t.Type()),
+				strconv.FormatUint(t.ContractID, 10),
+				side,
+				formatFloat(t.Rate),
+				t.IncreaseCurrency,
+				formatFloat(t.IncreaseAmount),
+				t.DecreaseCurrency,
+				formatFloat(t.DecreaseAmount),
+				t.FeeCurrency,
+				formatFloat(t.FeeAmount),
+				liquidity,
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	})
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// writeFile ファイルに書き出す（pathが空なら標準出力）
+func writeFile(path string, write func(io.Writer) error) error {
+	if path == "" {
+		return write(os.Stdout)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"io"
+	"os"
+	"time"
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/infrastructure/memory"
+	"trading-bot/pkg/usecase/synthetic"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+const location = "Asia/Tokyo"
+
+func init() {
+	loc, err := time.LoadLocation(location)
+	if err != nil {
+		loc = time.FixedZone(location, 9*60*60)
+	}
+	time.Local = loc
+}
+
+func main() {
+	logger := memory.Logger{Level: memory.Debug}
+
+	logger.Info("===== START PROGRAM ====================")
+	defer logger.Info("===== END PROGRAM ======================")
+
+	var config Config
+	if err := envconfig.Process("", &config); err != nil {
+		logger.Error(err.Error())
+		return
+	}
+	pair, err := model.ParseToCurrencyPair(config.TargetPair)
+	if err != nil {
+		logger.Error(err.Error())
+		return
+	}
+	scenarios, err := loadScenarios(config.ScenarioFile)
+	if err != nil {
+		logger.Error(err.Error())
+		return
+	}
+	if config.ScenarioName == "" {
+		// シナリオを指定しなければ一覧を表示する
+		for _, s := range scenarios {
+			logger.Info("%s: %s (model: %s, steps: %d)", s.Name, s.Description, s.Model, s.Steps)
+		}
+		return
+	}
+	s, err := synthetic.Find(scenarios, config.ScenarioName)
+	if err != nil {
+		logger.Error(err.Error())
+		return
+	}
+
+	m, err := synthetic.Generate(s, *pair)
+	if err != nil {
+		logger.Error(err.Error())
+		return
+	}
+	logger.Info("scenario: %s, pair: %s, rates: %d, ticks: %d", s.Name, pair.String(), len(m.Rates), len(m.Ticks))
+
+	if err := writeFile(config.OutputFile, func(w io.Writer) error {
+		return memory.WriteRates(w, *pair, m.Rates)
+	}); err != nil {
+		logger.Error("failed to write rates, %v", err)
+		return
+	}
+	logger.Info("rates: %s", outputName(config.OutputFile))
+
+	if config.TicksFile != "" {
+		if err := writeFile(config.TicksFile, func(w io.Writer) error {
+			return memory.WriteTicks(w, *pair, m.Ticks)
+		}); err != nil {
+			logger.Error("failed to write ticks, %v", err)
+			return
+		}
+		logger.Info("ticks: %s", config.TicksFile)
+	}
+}
+
+type Config struct {
+	// 生成するシナリオの名前（未指定ならシナリオの一覧を表示）
+	ScenarioName string `split_words:"true"`
+	// シナリオの設定ファイル（[[scenario]]の配列、未指定なら組み込みのシナリオ）
+	ScenarioFile string `split_words:"true"`
+	// 対象コインペア
+	TargetPair string `default:"btc_jpy" split_words:"true"`
+	// レート履歴の書き出し先（未指定なら標準出力）
+	OutputFile string `split_words:"true"`
+	// 取引履歴の書き出し先（未指定なら書き出さない）
+	TicksFile string `split_words:"true"`
+}
+
+func loadScenarios(path string) ([]synthetic.Scenario, error) {
+	if path == "" {
+		return synthetic.Library(), nil
+	}
+	return synthetic.LoadScenarios(path)
+}
+
+func outputName(path string) string {
+	if path == "" {
+		return "stdout"
+	}
+	return path
+}
+
+// writeFile ファイルに書き出す（pathが空なら標準出力）
+func writeFile(path string, write func(io.Writer) error) error {
+	if path == "" {
+		return write(os.Stdout)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
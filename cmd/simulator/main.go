@@ -2,12 +2,15 @@ package main
 
 import (
 	"context"
+	"flag"
+	"io"
 	"os"
 	"trading-bot/pkg/domain"
 	"trading-bot/pkg/domain/model"
 	"trading-bot/pkg/infrastructure/memory"
 	"trading-bot/pkg/infrastructure/mysql"
 	"trading-bot/pkg/usecase"
+	"trading-bot/pkg/usecase/report"
 	"trading-bot/pkg/usecase/trade"
 
 	"github.com/kelseyhightower/envconfig"
@@ -19,6 +22,24 @@ func main() {
 	logger.Info("===== START PROGRAM ====================")
 	defer logger.Info("===== END PROGRAM ======================")
 
+	monteCarlo := flag.Bool("montecarlo", false, "run monte carlo simulation on paths generated from the rate history")
+	scenarios := flag.Bool("scenarios", false, "run the strategy on synthetic scenarios as a regression suite")
+	flag.Parse()
+	if *scenarios {
+		if err := runScenarios(&logger); err != nil {
+			logger.Error("error occured, %v\n", err)
+			// 回帰テストの失敗をCIで検知できるように終了コードで返す
+			os.Exit(1)
+		}
+		return
+	}
+	if *monteCarlo {
+		if err := runMonteCarlo(&logger); err != nil {
+			logger.Error("error occured, %v\n", err)
+		}
+		return
+	}
+
 	simulator, err := setup(&logger, "./configs/simulator.toml")
 	if err != nil {
 		logger.Error("error occured, %v\n", err)
@@ -27,15 +48,54 @@ func main() {
 
 	logger.Info("======================================")
 
-	profit, err := simulator.Run(context.Background())
+	r, err := simulator.RunWithReport(context.Background())
 	if err != nil {
 		logger.Error("error occured, %v\n", err)
 		return
-	} else {
-		logger.Info("profit: %.3f", profit)
+	}
+	m := r.Metrics
+	logger.Info("profit: %.3f (%.2f%%, buy and hold %.2f%%)", m.Profit, m.Return*100, m.BuyAndHoldReturn*100)
+	logger.Info("trades: %d, win rate: %.2f%%, profit factor: %.3f", m.TradeCount, m.WinRate*100, m.ProfitFactor)
+	logger.Info("max drawdown: %.3f (%.2f%%), sharpe: %.3f, sortino: %.3f", m.MaxDrawdown, m.MaxDrawdownRatio*100, m.Sharpe, m.Sortino)
+
+	if err := writeReport(&logger, r); err != nil {
+		logger.Error("failed to write report, %v\n", err)
 	}
 }
 
+// writeReport 成績をファイルに書き出す
+func writeReport(logger domain.Logger, r *report.Report) error {
+	var sConf model.SimulatorConfig
+	if err := envconfig.Process("BOT", &sConf); err != nil {
+		return err
+	}
+	files := []struct {
+		path  string
+		write func(io.Writer) error
+	}{
+		{path: sConf.ReportJsonFile, write: r.WriteJSON},
+		{path: sConf.ReportHtmlFile, write: r.WriteHTML},
+	}
+	for _, f := range files {
+		if f.path == "" {
+			continue
+		}
+		file, err := os.Create(f.path)
+		if err != nil {
+			return err
+		}
+		if err := f.write(file); err != nil {
+			file.Close()
+			return err
+		}
+		if err := file.Close(); err != nil {
+			return err
+		}
+		logger.Info("report: %s", f.path)
+	}
+	return nil
+}
+
 func setup(logger domain.Logger, configPath string) (*usecase.Simulator, error) {
 	var conf model.Config
 	if err := envconfig.Process("BOT", &conf); err != nil {
@@ -47,19 +107,55 @@ func setup(logger domain.Logger, configPath string) (*usecase.Simulator, error)
 		return nil, err
 	}
 
+	if err := sConf.ValidRateSource(); err != nil {
+		return nil, err
+	}
+	if err := sConf.ValidFillModel(); err != nil {
+		return nil, err
+	}
+
 	logger.Info("strategy: %s\n", sConf.StrategyName)
-	logger.Info("rate: %s\n", sConf.RateHistoryFile)
 
-	historical, err := os.Open(sConf.RateHistoryFile)
-	if err != nil {
-		return nil, err
+	pair := model.CurrencyPair{
+		Key:        model.CurrencyType(conf.TargetCurrency),
+		Settlement: model.JPY,
+	}
+	mysqlCli := mysql.NewClient(conf.DB.UserName, conf.DB.Password, conf.DB.Host, conf.DB.Port, conf.DB.Name)
+
+	var exCli *memory.ExchangeMock
+	if sConf.RateSource == model.MarketsRateSource {
+		logger.Info("rate: markets %s (%s - %s)\n", pair.String(), sConf.RateFrom, sConf.RateTo)
+		rates, err := loadMarketRates(mysqlCli, &pair, &sConf)
+		if err != nil {
+			return nil, err
+		}
+		if exCli, err = memory.NewExchangeMockWithRates(rates, sConf.Slippage); err != nil {
+			return nil, err
+		}
+	} else {
+		logger.Info("rate: %s\n", sConf.RateHistoryFile)
+		historical, err := os.Open(sConf.RateHistoryFile)
+		if err != nil {
+			return nil, err
+		}
+		if exCli, err = memory.NewExchangeMock(historical, sConf.Slippage); err != nil {
+			return nil, err
+		}
 	}
-	exCli, err := memory.NewExchangeMock(historical, sConf.Slippage)
+	exCli.SetBalance(model.JPY, sConf.InitialJPY)
+	exCli.SetFillModel(memory.NewFillModel(&sConf))
+
+	ticks, err := readTicks(&sConf)
 	if err != nil {
 		return nil, err
 	}
+	if ticks != nil {
+		logger.Info("tick: %s (%d trades)\n", sConf.TickHistoryFile, len(ticks))
+		exCli.SetTrades(ticks)
+	}
 
-	mysqlCli := mysql.NewClient(conf.DB.UserName, conf.DB.Password, conf.DB.Host, conf.DB.Port, conf.DB.Name)
+	// 時刻はCSVの日時で進める
+	mysqlCli.SetClock(exCli)
 
 	facade := trade.NewFacade(
 		exCli,
@@ -67,8 +163,11 @@ func setup(logger domain.Logger, configPath string) (*usecase.Simulator, error)
 		mysqlCli,
 		mysqlCli,
 		mysqlCli,
+		mysqlCli,
+		mysqlCli,
 		nil,
 	)
+	facade.SetClock(exCli)
 	strategy, err := usecase.MakeStrategy(
 		usecase.StrategyType(sConf.StrategyName),
 		facade,
@@ -80,15 +179,13 @@ func setup(logger domain.Logger, configPath string) (*usecase.Simulator, error)
 	}
 
 	bot := usecase.NewBot(logger, facade, strategy, &usecase.BotConfig{
+		Strategy:         usecase.StrategyType(sConf.StrategyName),
 		Currency:         model.CurrencyType(conf.TargetCurrency),
 		PositionCountMax: conf.PositionCountMax,
 	})
 
-	pair := model.CurrencyPair{
-		Key:        model.CurrencyType(conf.TargetCurrency),
-		Settlement: model.JPY,
-	}
 	fetcher := usecase.NewFetcher(exCli, pair, mysqlCli)
+	fetcher.SetClock(exCli)
 
 	return &usecase.Simulator{
 		Bot:          bot,
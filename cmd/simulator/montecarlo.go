@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"os"
+	"time"
+	"trading-bot/pkg/domain"
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/infrastructure/memory"
+	"trading-bot/pkg/infrastructure/mysql"
+	"trading-bot/pkg/usecase"
+	"trading-bot/pkg/usecase/montecarlo"
+	"trading-bot/pkg/usecase/report"
+	"trading-bot/pkg/usecase/trade"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+// runMonteCarlo 過去のレートから生成した経路で戦略をシミュレーションして成績の分布を集計
+func runMonteCarlo(logger domain.Logger) error {
+	var conf model.Config
+	if err := envconfig.Process("BOT", &conf); err != nil {
+		return err
+	}
+	var sConf model.SimulatorConfig
+	if err := envconfig.Process("BOT", &sConf); err != nil {
+		return err
+	}
+	if err := sConf.ValidRateSource(); err != nil {
+		return err
+	}
+	if err := sConf.ValidFillModel(); err != nil {
+		return err
+	}
+
+	pair := model.CurrencyPair{
+		Key:        model.CurrencyType(conf.TargetCurrency),
+		Settlement: model.JPY,
+	}
+	// 経路ごとのシミュレーションではDBを使わないので、DBにつなぐのはmarketsテーブルから読み込むときだけ
+	var mysqlCli *mysql.Client
+	if sConf.RateSource == model.MarketsRateSource {
+		mysqlCli = mysql.NewClient(conf.DB.UserName, conf.DB.Password, conf.DB.Host, conf.DB.Port, conf.DB.Name)
+	}
+	rates, err := readRates(mysqlCli, &pair, &sConf)
+	if err != nil {
+		return err
+	}
+	if sConf.TickHistoryFile != "" {
+		// 取引は過去のレートに対応するので生成した経路では再生しない
+		logger.Info("tick history is not replayed in monte carlo simulation, %s", sConf.TickHistoryFile)
+	}
+
+	o := montecarlo.Options{
+		Runs:        sConf.MonteCarloRuns,
+		BlockSize:   sConf.MonteCarloBlockSize,
+		SlippageMin: sConf.Slippage,
+		SlippageMax: sConf.MonteCarloSlippageMax,
+		FeeRateMin:  sConf.MonteCarloFeeRateMin,
+		FeeRateMax:  sConf.MonteCarloFeeRateMax,
+		RuinRatio:   sConf.MonteCarloRuinRatio,
+		Seed:        sConf.MonteCarloSeed,
+		Workers:     sConf.MonteCarloWorkers,
+	}
+	if o.Seed == 0 {
+		o.Seed = time.Now().UnixNano()
+	}
+	logger.Info("strategy: %s, rates: %d, runs: %d, block size: %d, seed: %d", sConf.StrategyName, len(rates), o.Runs, o.BlockSize, o.Seed)
+
+	paths, err := montecarlo.Paths(rates, o)
+	if err != nil {
+		return err
+	}
+	result, err := montecarlo.Run(logger, paths, o, func(p *montecarlo.Path) (*report.Report, error) {
+		return simulateInMemory(logger, &conf, &sConf, pair, p.Rates, nil, p.Slippage, p.FeeRate)
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Info("profit: mean %.3f, median %.3f, p5 %.3f, p95 %.3f", result.Profit.Mean, result.Profit.Median, result.Profit.P5, result.Profit.P95)
+	logger.Info("max drawdown: mean %.3f, median %.3f, p95 %.3f (shuffled trades p95 %.3f)", result.MaxDrawdown.Mean, result.MaxDrawdown.Median, result.MaxDrawdown.P95, result.ShuffledMaxDrawdown.P95)
+	logger.Info("ruin probability: %.2f%% (shuffled trades %.2f%%, equity <= %.0f%% of initial)", result.RuinProbability*100, result.ShuffledRuinProbability*100, result.RuinRatio*100)
+
+	if sConf.MonteCarloReportFile == "" {
+		return nil
+	}
+	file, err := os.Create(sConf.MonteCarloReportFile)
+	if err != nil {
+		return err
+	}
+	if err := result.WriteJSON(file); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	logger.Info("report: %s", sConf.MonteCarloReportFile)
+	return nil
+}
+
+// simulateInMemory 与えたレートと取引でシミュレーション（実行ごとに独立させるためDBの代わりにメモリを使う）
+func simulateInMemory(logger domain.Logger, conf *model.Config, sConf *model.SimulatorConfig, pair model.CurrencyPair, rates []memory.Rate, ticks []model.Trade, slippage, feeRate float64) (*report.Report, error) {
+	exCli, err := memory.NewExchangeMockWithRates(rates, slippage)
+	if err != nil {
+		return nil, err
+	}
+	exCli.SetBalance(model.JPY, sConf.InitialJPY)
+	exCli.SetFillModel(memory.NewFillModel(sConf))
+	exCli.SetFeeRate(feeRate)
+	if ticks != nil {
+		exCli.SetTrades(ticks)
+	}
+
+	rdsCli := memory.NewDummyRDS(nil)
+	facade := trade.NewFacade(exCli, rdsCli, rdsCli, rdsCli, rdsCli, rdsCli, rdsCli, nil)
+	// 時刻はレートの日時で進める
+	facade.SetClock(exCli)
+
+	strategyType := usecase.StrategyType(sConf.StrategyName)
+	strategy, err := usecase.MakeStrategy(strategyType, facade, logger)
+	if err != nil {
+		return nil, err
+	}
+	bot := usecase.NewBot(logger, facade, strategy, &usecase.BotConfig{
+		Strategy:         strategyType,
+		Currency:         pair.Key,
+		PositionCountMax: conf.PositionCountMax,
+	})
+	fetcher := usecase.NewFetcher(exCli, pair, rdsCli)
+	fetcher.SetClock(exCli)
+
+	simulator := usecase.Simulator{
+		Bot:          bot,
+		Fetcher:      fetcher,
+		TradeRepo:    rdsCli,
+		ExchangeMock: exCli,
+		Logger:       logger,
+	}
+	return simulator.RunWithReport(context.Background())
+}
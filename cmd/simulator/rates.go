@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/infrastructure/memory"
+	"trading-bot/pkg/infrastructure/mysql"
+)
+
+// loadMarketRates DBのmarketsテーブルから期間内のレートを読み込む
+func loadMarketRates(mysqlCli *mysql.Client, pair *model.CurrencyPair, sConf *model.SimulatorConfig) ([]memory.Rate, error) {
+	from, to, err := sConf.RatePeriod()
+	if err != nil {
+		return nil, err
+	}
+	markets, err := mysqlCli.GetMarketsBetween(pair, from, to)
+	if err != nil {
+		return nil, err
+	}
+	rates := []memory.Rate{}
+	for _, m := range markets {
+		rates = append(rates, memory.NewRateWithVolumes(m.RecordedAt, m.StoreRateAVG, m.ExRateBuy, m.ExRateSell, m.ExVolumeBuy, m.ExVolumeSell))
+	}
+	return rates, nil
+}
+
+// readTicks 再生する取引履歴を読み込む（TickHistoryFileが空ならnil）
+func readTicks(sConf *model.SimulatorConfig) ([]model.Trade, error) {
+	if sConf.TickHistoryFile == "" {
+		return nil, nil
+	}
+	f, err := os.Open(sConf.TickHistoryFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return memory.ReadTicks(f)
+}
+
+// readRates シミュレーションに使うレートを全て読み込む（CSVかDBのmarketsテーブル）
+func readRates(mysqlCli *mysql.Client, pair *model.CurrencyPair, sConf *model.SimulatorConfig) ([]memory.Rate, error) {
+	if sConf.RateSource == model.MarketsRateSource {
+		return loadMarketRates(mysqlCli, pair, sConf)
+	}
+	historical, err := os.Open(sConf.RateHistoryFile)
+	if err != nil {
+		return nil, err
+	}
+	defer historical.Close()
+	return memory.ReadRates(historical)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"trading-bot/pkg/domain"
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/usecase/report"
+	"trading-bot/pkg/usecase/synthetic"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+// ScenarioResult シナリオごとの回帰テストの結果
+type ScenarioResult struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Passed      bool             `json:"passed"`
+	Violations  []string         `json:"violations"`
+	Expect      synthetic.Expect `json:"expect"`
+	Metrics     *report.Metrics  `json:"metrics"`
+}
+
+// runScenarios 合成したシナリオで戦略をシミュレーションして成績の条件を満たすか確認（満たさないシナリオがあればエラー）
+func runScenarios(logger domain.Logger) error {
+	var conf model.Config
+	if err := envconfig.Process("BOT", &conf); err != nil {
+		return err
+	}
+	var sConf model.SimulatorConfig
+	if err := envconfig.Process("BOT", &sConf); err != nil {
+		return err
+	}
+	if err := sConf.ValidFillModel(); err != nil {
+		return err
+	}
+
+	pair := model.CurrencyPair{
+		Key:        model.CurrencyType(conf.TargetCurrency),
+		Settlement: model.JPY,
+	}
+	scenarios, err := selectScenarios(&sConf)
+	if err != nil {
+		return err
+	}
+	logger.Info("strategy: %s, scenarios: %d", sConf.StrategyName, len(scenarios))
+
+	results := []ScenarioResult{}
+	failed := []string{}
+	for i := range scenarios {
+		s := &scenarios[i]
+		m, err := synthetic.Generate(s, pair)
+		if err != nil {
+			return err
+		}
+		r, err := simulateInMemory(logger, &conf, &sConf, pair, m.Rates, m.Ticks, sConf.Slippage, 0)
+		if err != nil {
+			return fmt.Errorf("scenario %s, %w", s.Name, err)
+		}
+		violations := s.Violations(r.Metrics.MaxDrawdownRatio, r.Metrics.Return)
+		results = append(results, ScenarioResult{
+			Name:        s.Name,
+			Description: s.Description,
+			Passed:      len(violations) == 0,
+			Violations:  violations,
+			Expect:      s.Expect,
+			Metrics:     &r.Metrics,
+		})
+		if len(violations) == 0 {
+			logger.Info("PASS %s: return %.2f%%, max drawdown %.2f%%, trades %d", s.Name, r.Metrics.Return*100, r.Metrics.MaxDrawdownRatio*100, r.Metrics.TradeCount)
+		} else {
+			logger.Error("FAIL %s: %s", s.Name, strings.Join(violations, ", "))
+			failed = append(failed, s.Name)
+		}
+	}
+
+	if err := writeScenarioReport(logger, sConf.ScenarioReportFile, results); err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d scenarios failed, %v", len(failed), len(results), failed)
+	}
+	logger.Info("all %d scenarios passed", len(results))
+	return nil
+}
+
+// selectScenarios 実行するシナリオ（設定ファイルか組み込みのシナリオから名前で選ぶ）
+func selectScenarios(sConf *model.SimulatorConfig) ([]synthetic.Scenario, error) {
+	scenarios := synthetic.Library()
+	if sConf.ScenarioFile != "" {
+		var err error
+		if scenarios, err = synthetic.LoadScenarios(sConf.ScenarioFile); err != nil {
+			return nil, err
+		}
+	}
+	if len(sConf.ScenarioNames) == 0 {
+		return scenarios, nil
+	}
+	selected := []synthetic.Scenario{}
+	for _, name := range sConf.ScenarioNames {
+		s, err := synthetic.Find(scenarios, strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		selected = append(selected, *s)
+	}
+	return selected, nil
+}
+
+func writeScenarioReport(logger domain.Logger, path string, results []ScenarioResult) error {
+	if path == "" {
+		return nil
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	e := json.NewEncoder(file)
+	e.SetIndent("", "  ")
+	if err := e.Encode(results); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	logger.Info("report: %s", path)
+	return nil
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	"trading-bot/pkg/domain"
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/usecase"
+	"trading-bot/pkg/usecase/trade"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/sync/errgroup"
+)
+
+// controlSecretHeader 署名できない呼び出し元が共有シークレットをそのまま入れるヘッダ
+const controlSecretHeader = "X-Secret"
+
+// startReloaders 設定の再読み込みの契機（設定ファイルの更新、SIGHUP）を監視
+func startReloaders(ctx context.Context, errGroup *errgroup.Group, logger domain.Logger, config *model.Config, bots []*usecase.Bot) {
+	reloadAll := func() {
+		for _, b := range bots {
+			b.RequestReload()
+		}
+	}
+
+	errGroup.Go(func() error {
+		// SIGHUPで再読み込み
+		hup := make(chan os.Signal, 1)
+		defer close(hup)
+		signal.Notify(hup, syscall.SIGHUP)
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-hup:
+				logger.Info("[reload] received SIGHUP")
+				reloadAll()
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	})
+
+	if config.ConfigWatchSeconds > 0 {
+		watched := map[string][]*usecase.Bot{}
+		for _, b := range bots {
+			if b.Config.ConfigPath != "" {
+				watched[b.Config.ConfigPath] = append(watched[b.Config.ConfigPath], b)
+			}
+		}
+		for path, targets := range watched {
+			path, targets := path, targets
+			watcher, err := usecase.NewConfigWatcher(path, time.Duration(config.ConfigWatchSeconds)*time.Second)
+			if err != nil {
+				logger.Error("[reload] failed to watch %s, %v", path, err)
+				continue
+			}
+			errGroup.Go(func() error {
+				return watcher.Watch(ctx, func() {
+					logger.Info("[reload] detected update of %s", path)
+					for _, b := range targets {
+						b.RequestReload()
+					}
+				})
+			})
+		}
+	}
+}
+
+// startControl 操作用HTTPサーバーを起動
+func startControl(ctx context.Context, errGroup *errgroup.Group, logger domain.Logger, config *model.Config, bots []*usecase.Bot, risk *trade.RiskManager) {
+	if config.ControlAddr == "" {
+		return
+	}
+	if config.ControlSecret == "" {
+		logger.Error("control server is disabled (control secret is empty)")
+		return
+	}
+
+	r := mux.NewRouter()
+	r.HandleFunc("/reload", controlAuth(logger, config.ControlSecret, reloadHandler(logger, func() {
+		for _, b := range bots {
+			b.RequestReload()
+		}
+	}))).Methods(http.MethodPost)
+	r.HandleFunc("/flatten", controlAuth(logger, config.ControlSecret, flattenHandler(logger, bots))).Methods(http.MethodPost)
+	if risk != nil {
+		r.HandleFunc("/risk/reset", controlAuth(logger, config.ControlSecret, riskResetHandler(logger, risk))).Methods(http.MethodPost)
+	}
+	server := &http.Server{Addr: config.ControlAddr, Handler: r}
+
+	errGroup.Go(func() error {
+		logger.Info("control server listening on %s", config.ControlAddr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Error("control server stopped, %v", err)
+		}
+		return nil
+	})
+	errGroup.Go(func() error {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return server.Shutdown(shutdownCtx)
+	})
+}
+
+type controlResponse struct {
+	Message string `json:"message"`
+}
+
+// controlAuth 署名ヘッダ（リクエストURIと改行とボディのHMAC-SHA256）かX-Secretヘッダで認証してから処理する
+// URIも署名に含めるのは、flattenの対象のボットをクエリで指定するため
+func controlAuth(logger domain.Logger, secret string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, webhookBodyMax))
+		if err != nil {
+			writeControlResponse(w, logger, http.StatusBadRequest, fmt.Sprintf("failed to read body, %v", err))
+			return
+		}
+		message := append([]byte(r.URL.RequestURI()+"\n"), body...)
+		if !authenticate(secret, r.Header.Get(webhookSignatureHeader), message, r.Header.Get(controlSecretHeader)) {
+			logger.Error("[control] unauthorized request to %s from %s", r.URL.Path, r.RemoteAddr)
+			writeControlResponse(w, logger, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		next(w, r)
+	}
+}
+
+func writeControlResponse(w http.ResponseWriter, logger domain.Logger, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(controlResponse{Message: message}); err != nil {
+		logger.Error("failed to write response, %v", err)
+	}
+}
+
+func reloadHandler(logger domain.Logger, reload func()) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("[reload] requested via http from %s", r.RemoteAddr)
+		reload()
+
+		w.WriteHeader(http.StatusAccepted)
+		if err := json.NewEncoder(w).Encode(controlResponse{Message: "reload requested, applied before next trade"}); err != nil {
+			logger.Error("failed to write response, %v", err)
+		}
+	}
+}
+
+func riskResetHandler(logger domain.Logger, risk *trade.RiskManager) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("[risk] reset requested via http from %s", r.RemoteAddr)
+		status, message := http.StatusOK, "risk manager reset"
+		if err := risk.Reset(); err != nil {
+			status, message = http.StatusInternalServerError, err.Error()
+		}
+
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(controlResponse{Message: message}); err != nil {
+			logger.Error("failed to write response, %v", err)
+		}
+	}
+}
+
+// flattenHandler 全ボット（botパラメータがあればそのボットのみ）の注文を取り消してポジションを決済
+func flattenHandler(logger domain.Logger, bots []*usecase.Bot) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		target := r.URL.Query().Get("bot")
+		logger.Info("[flatten] requested via http from %s (bot:%s)", r.RemoteAddr, target)
+
+		results := []*usecase.FlattenResult{}
+		status := http.StatusOK
+		for _, b := range bots {
+			if target != "" && b.Name() != target {
+				continue
+			}
+			result := b.Flatten()
+			if len(result.Errors) > 0 {
+				status = http.StatusInternalServerError
+			}
+			results = append(results, result)
+		}
+		if len(results) == 0 {
+			status = http.StatusNotFound
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(results); err != nil {
+			logger.Error("failed to write response, %v", err)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"trading-bot/pkg/domain"
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/infrastructure/coincheck"
+	"trading-bot/pkg/infrastructure/mysql"
+	"trading-bot/pkg/infrastructure/slack"
+	"trading-bot/pkg/usecase"
+	"trading-bot/pkg/usecase/trade"
+)
+
+// flattenMode 全注文の取り消しと全ポジションの決済を行うコマンド
+// trading-bot flatten                     : BOT_TARGET_CURRENCY のボット
+// trading-bot flatten portfolio [設定ファイル] : ポートフォリオ内の全ボット
+const flattenMode = "flatten"
+
+// runFlatten 全注文を取り消して全ポジションを決済
+func runFlatten(logger domain.Logger, config *model.Config, exCli *coincheck.Client, args []string) error {
+	mysqlCli := mysql.NewClient(config.DB.UserName, config.DB.Password, config.DB.Host, config.DB.Port, config.DB.Name)
+	d := rateDuration
+
+	bots := []*usecase.Bot{}
+	if len(args) > 0 && usecase.StrategyType(args[0]) == portfolioMode {
+		configPath := defaultPortfolioConfigPath
+		if len(args) > 1 {
+			configPath = args[1]
+		}
+		pConfig, err := usecase.NewPortfolioConfig(configPath)
+		if err != nil {
+			return err
+		}
+		for _, c := range pConfig.Instances {
+			cli := mysqlCli.WithBotName(c.Name)
+			facade := trade.NewFacade(exCli, cli, cli, cli, cli, cli, cli, &d)
+			bots = append(bots, usecase.NewBot(logger, facade, nil, &usecase.BotConfig{
+				Name:     c.Name,
+				Strategy: c.Strategy,
+				Currency: c.Currency,
+			}))
+		}
+	} else {
+		facade := trade.NewFacade(exCli, mysqlCli, mysqlCli, mysqlCli, mysqlCli, mysqlCli, mysqlCli, &d)
+		bots = append(bots, usecase.NewBot(logger, facade, nil, &usecase.BotConfig{
+			Currency: model.CurrencyType(config.TargetCurrency),
+		}))
+	}
+
+	failed := 0
+	for _, b := range bots {
+		if config.SlackURL != "" {
+			b.Notifier = slack.NewClient(config.SlackURL)
+		}
+		result := b.Flatten()
+		logger.Info("[flatten] %s", result.Summary())
+		if len(result.Errors) > 0 {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("flatten failed on %d/%d bots", failed, len(bots))
+	}
+	return nil
+}
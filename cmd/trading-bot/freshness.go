@@ -0,0 +1,20 @@
+package main
+
+import (
+	"time"
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/usecase"
+)
+
+// newRateFreshnessConfig レートの鮮度の確認設定を生成（確認する項目がなければnil）
+func newRateFreshnessConfig(config *model.Config) *usecase.RateFreshnessConfig {
+	c := &usecase.RateFreshnessConfig{
+		MaxAge:        time.Duration(config.RateMaxAgeSeconds) * time.Second,
+		MaxGap:        time.Duration(config.RateMaxGapSeconds) * time.Second,
+		AlertInterval: time.Duration(config.RateStaleAlertSeconds) * time.Second,
+	}
+	if !c.Enabled() {
+		return nil
+	}
+	return c
+}
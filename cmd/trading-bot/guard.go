@@ -0,0 +1,25 @@
+package main
+
+import (
+	"time"
+	"trading-bot/pkg/domain"
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/domain/repository"
+	"trading-bot/pkg/infrastructure/coincheck"
+	"trading-bot/pkg/usecase/trade"
+)
+
+// newOrderGuard 誤発注防止の確認を生成（確認する項目がなければnil）
+func newOrderGuard(logger domain.Logger, config *model.Config, exCli *coincheck.Client, eventRepo repository.EventRepository) *trade.OrderGuard {
+	guardConfig := trade.GuardConfig{
+		MaxOrderJPY:      config.GuardMaxOrderJpy,
+		MaxRateDeviation: config.GuardMaxRateDeviation,
+		DisabledPairs:    config.GuardDisabledPairs,
+		MaxOrders:        config.GuardMaxOrders,
+		Window:           time.Duration(config.GuardWindowSeconds) * time.Second,
+	}
+	if !guardConfig.Enabled() {
+		return nil
+	}
+	return trade.NewOrderGuard(logger, exCli, eventRepo, guardConfig)
+}
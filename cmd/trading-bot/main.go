@@ -11,7 +11,9 @@ import (
 	"trading-bot/pkg/infrastructure/coincheck"
 	"trading-bot/pkg/infrastructure/memory"
 	"trading-bot/pkg/infrastructure/mysql"
+	"trading-bot/pkg/infrastructure/slack"
 	"trading-bot/pkg/usecase"
+	"trading-bot/pkg/usecase/schedule"
 	"trading-bot/pkg/usecase/trade"
 
 	"github.com/kelseyhightower/envconfig"
@@ -41,16 +43,51 @@ func main() {
 	logger.Info("======================================")
 
 	exCli := coincheck.NewClient(&logger, config.Exchange.AccessKey, config.Exchange.SecretKey)
-	bot, fetchers, err := setup(&logger, &config, strategyType, exCli)
+	if strategyType == flattenMode {
+		if err := runFlatten(&logger, &config, exCli, os.Args[2:]); err != nil {
+			logger.Error(err.Error())
+		}
+		return
+	}
+	if strategyType == riskResetMode {
+		if err := runRiskReset(&logger, &config, exCli); err != nil {
+			logger.Error(err.Error())
+		}
+		return
+	}
+	if strategyType == portfolioMode {
+		configPath := defaultPortfolioConfigPath
+		if len(os.Args) > 2 {
+			configPath = os.Args[2]
+		}
+		if err := runPortfolio(&logger, &config, exCli, configPath); err != nil {
+			logger.Error(err.Error())
+		}
+		return
+	}
+
+	mysqlCli := mysql.NewClient(config.DB.UserName, config.DB.Password, config.DB.Host, config.DB.Port, config.DB.Name)
+	risk := newRiskManager(&logger, &config, exCli, mysqlCli)
+	guard := newOrderGuard(&logger, &config, exCli, mysqlCli)
+	bot, fetchers, err := setup(&logger, &config, strategyType, exCli, mysqlCli, risk, guard)
 	if err != nil {
 		logger.Error(err.Error())
 		return
 	}
 
+	if config.SlackURL != "" {
+		bot.Notifier = slack.NewClient(config.SlackURL)
+	}
+	bot.RateFreshness = newRateFreshnessConfig(&config)
+
 	rootCtx, cancel := context.WithCancel(context.Background())
 	errGroup, ctx := errgroup.WithContext(rootCtx)
+	startReloaders(ctx, errGroup, &logger, &config, []*usecase.Bot{bot})
+	startControl(ctx, errGroup, &logger, &config, []*usecase.Bot{bot}, risk)
+	startRiskManager(ctx, errGroup, &logger, &config, risk, mysqlCli)
+	startWebhook(ctx, errGroup, &logger, &config, mysqlCli, []*usecase.Bot{bot})
 	errGroup.Go(func() error {
-		quit := make(chan os.Signal)
+		quit := make(chan os.Signal, 1)
 		defer close(quit)
 		signal.Notify(quit, os.Interrupt)
 		select {
@@ -109,6 +146,11 @@ func main() {
 				if err := bot.Trade(ctx); err != nil {
 					logger.Error("error occured in trade, %v", err)
 				}
+				if statuses := bot.Statuses(); len(statuses) > 0 {
+					if err := mysqlCli.UpsertBotStatuses(statuses); err != nil {
+						logger.Error("error occured in upsert statuses, %v", err)
+					}
+				}
 				if err := bot.Wait(ctx); err != nil {
 					logger.Error("error occured in wait, %v", err)
 				}
@@ -121,9 +163,7 @@ func main() {
 	}
 }
 
-func setup(logger domain.Logger, config *model.Config, strategyType usecase.StrategyType, exCli *coincheck.Client) (*usecase.Bot, []usecase.Fetcher, error) {
-	mysqlCli := mysql.NewClient(config.DB.UserName, config.DB.Password, config.DB.Host, config.DB.Port, config.DB.Name)
-
+func setup(logger domain.Logger, config *model.Config, strategyType usecase.StrategyType, exCli *coincheck.Client, mysqlCli *mysql.Client, risk *trade.RiskManager, guard *trade.OrderGuard) (*usecase.Bot, []usecase.Fetcher, error) {
 	d := rateDuration
 	facade := trade.NewFacade(
 		exCli,
@@ -131,8 +171,16 @@ func setup(logger domain.Logger, config *model.Config, strategyType usecase.Stra
 		mysqlCli,
 		mysqlCli,
 		mysqlCli,
+		mysqlCli,
+		mysqlCli,
 		&d,
 	)
+	if risk != nil {
+		risk.Attach(*config.GetTargetPair(model.JPY), facade)
+	}
+	if guard != nil {
+		guard.Attach(usecase.DefaultBotName, facade)
+	}
 
 	strategy, err := usecase.MakeStrategy(
 		strategyType,
@@ -144,9 +192,21 @@ func setup(logger domain.Logger, config *model.Config, strategyType usecase.Stra
 	}
 
 	bot := usecase.NewBot(logger, facade, strategy, &usecase.BotConfig{
+		Strategy:         strategyType,
+		ConfigPath:       usecase.StrategyConfigPath(strategyType),
 		Currency:         model.CurrencyType(config.TargetCurrency),
 		PositionCountMax: config.PositionCountMax,
 	})
+	if risk != nil {
+		risk.SetFlattener(facade, bot.RiskFlattener())
+	}
+	if config.ScheduleFile != "" {
+		s, err := schedule.NewScheduleWithFile(config.ScheduleFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		bot.Schedule = s
+	}
 
 	fetchers := []usecase.Fetcher{}
 	if config.RateLogIntervalSeconds != 0 {
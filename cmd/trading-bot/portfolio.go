@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+	"trading-bot/pkg/domain"
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/infrastructure/coincheck"
+	"trading-bot/pkg/infrastructure/mysql"
+	"trading-bot/pkg/infrastructure/slack"
+	"trading-bot/pkg/usecase"
+	"trading-bot/pkg/usecase/trade"
+
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// portfolioMode 複数戦略を稼働させるモード
+	portfolioMode usecase.StrategyType = "portfolio"
+
+	defaultPortfolioConfigPath = "./configs/portfolio.toml"
+)
+
+func runPortfolio(logger domain.Logger, config *model.Config, exCli *coincheck.Client, configPath string) error {
+	pConfig, err := usecase.NewPortfolioConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	mysqlCli := mysql.NewClient(config.DB.UserName, config.DB.Password, config.DB.Host, config.DB.Port, config.DB.Name)
+	risk := newRiskManager(logger, config, exCli, mysqlCli)
+	guard := newOrderGuard(logger, config, exCli, mysqlCli)
+	portfolio, err := usecase.NewPortfolio(logger, pConfig, exCli, func(botName string) *trade.Facade {
+		d := rateDuration
+		cli := mysqlCli.WithBotName(botName)
+		return trade.NewFacade(exCli, cli, cli, cli, cli, cli, cli, &d)
+	})
+	if err != nil {
+		return err
+	}
+	bots := []*usecase.Bot{}
+	for _, i := range portfolio.Instances {
+		logger.Info("instance: %s (%s)\n", i.Name, i.Pair.String())
+		if risk != nil {
+			risk.Attach(i.Pair, i.Facade)
+			risk.SetFlattener(i.Facade, i.Bot.RiskFlattener())
+		}
+		if guard != nil {
+			guard.Attach(i.Name, i.Facade)
+		}
+		if config.SlackURL != "" {
+			i.Bot.Notifier = slack.NewClient(config.SlackURL)
+		}
+		i.Bot.RateFreshness = newRateFreshnessConfig(config)
+		bots = append(bots, i.Bot)
+	}
+
+	fetchers := []usecase.Fetcher{}
+	if config.RateLogIntervalSeconds != 0 {
+		// ポートフォリオで取引する通貨ペアのレートを保存する
+		for _, pair := range portfolio.Pairs() {
+			fetchers = append(fetchers, *usecase.NewFetcher(exCli, pair, mysqlCli))
+		}
+	}
+
+	rootCtx, cancel := context.WithCancel(context.Background())
+	errGroup, ctx := errgroup.WithContext(rootCtx)
+	startReloaders(ctx, errGroup, logger, config, bots)
+	startControl(ctx, errGroup, logger, config, bots, risk)
+	startRiskManager(ctx, errGroup, logger, config, risk, mysqlCli)
+	startWebhook(ctx, errGroup, logger, config, mysqlCli, bots)
+	errGroup.Go(func() error {
+		quit := make(chan os.Signal, 1)
+		defer close(quit)
+		signal.Notify(quit, os.Interrupt)
+		select {
+		case <-quit:
+			logger.Info("terminating ...")
+			cancel()
+		case <-ctx.Done():
+		}
+		return nil
+	})
+
+	errGroup.Go(func() error {
+		// レートの定期保存
+		ticker := time.NewTicker(time.Duration(config.RateLogIntervalSeconds) * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				for _, f := range fetchers {
+					if err := f.Fetch(); err != nil {
+						logger.Error("failed to fetch, error: %w", err)
+					}
+				}
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	})
+
+	errGroup.Go(func() error {
+		// ポートフォリオ全体の状態を定期保存
+		ticker := time.NewTicker(time.Duration(pConfig.StatusIntervalSeconds) * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				statuses, err := portfolio.Statuses()
+				if err != nil {
+					logger.Error("error occured in get statuses, %v", err)
+					continue
+				}
+				if err := mysqlCli.UpsertBotStatuses(statuses); err != nil {
+					logger.Error("error occured in upsert statuses, %v", err)
+				}
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	})
+
+	for _, pair := range portfolio.Pairs() {
+		pair := pair
+		errGroup.Go(func() error {
+			// 取引履歴の監視
+			for {
+				select {
+				case <-ctx.Done():
+					return nil
+				default:
+					if err := exCli.SubscribeTradeHistory(ctx, &pair, portfolio.ReceiveTrade); err != nil {
+						if !strings.Contains(err.Error(), "i/o timeout") {
+							logger.Error("error occured, %v", err)
+						}
+					}
+				}
+			}
+		})
+	}
+
+	for _, i := range portfolio.Instances {
+		i := i
+		errGroup.Go(func() error {
+			for {
+				select {
+				case <-ctx.Done():
+					return nil
+				default:
+					if err := i.Bot.Trade(ctx); err != nil {
+						logger.Error("[%s] error occured in trade, %v", i.Name, err)
+					}
+					if err := i.Bot.Wait(ctx); err != nil {
+						logger.Error("[%s] error occured in wait, %v", i.Name, err)
+					}
+				}
+			}
+		})
+	}
+
+	return errGroup.Wait()
+}
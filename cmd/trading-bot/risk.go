@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"time"
+	"trading-bot/pkg/domain"
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/domain/repository"
+	"trading-bot/pkg/infrastructure/coincheck"
+	"trading-bot/pkg/infrastructure/mysql"
+	"trading-bot/pkg/infrastructure/slack"
+	"trading-bot/pkg/usecase/trade"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// riskResetMode リスク管理による停止を解除するコマンド
+const riskResetMode = "risk-reset"
+
+// newRiskManager リスク管理を生成（上限が未設定ならnil）
+func newRiskManager(logger domain.Logger, config *model.Config, exCli *coincheck.Client, stateRepo repository.StrategyStateRepository) *trade.RiskManager {
+	riskConfig := trade.RiskConfig{
+		DailyLossLimitJPY: config.RiskDailyLossLimitJpy,
+		MaxDrawdownRatio:  config.RiskMaxDrawdownRatio,
+		Flatten:           config.RiskFlatten,
+	}
+	if !riskConfig.Enabled() {
+		return nil
+	}
+	risk := trade.NewRiskManager(logger, exCli, stateRepo, riskConfig)
+	if config.SlackURL != "" {
+		risk.Notifier = slack.NewClient(config.SlackURL)
+	}
+	return risk
+}
+
+// startRiskManager 損益を定期的に集計
+func startRiskManager(ctx context.Context, errGroup *errgroup.Group, logger domain.Logger, config *model.Config, risk *trade.RiskManager, statusRepo repository.BotStatusRepository) {
+	if risk == nil {
+		return
+	}
+
+	update := func() {
+		state, err := risk.Update(time.Now())
+		if err != nil {
+			logger.Error("[risk] failed to update, %v", err)
+			return
+		}
+		logger.Debug("[risk] equity: %.3f, daily pnl: %.3f (realized %.3f, unrealized %.3f), drawdown: %.4f, halted: %v",
+			state.Equity, state.DailyPnL, state.RealizedPnL, state.UnrealizedPnL, state.Drawdown, state.Halted)
+		if err := statusRepo.UpsertBotStatuses(risk.Statuses()); err != nil {
+			logger.Error("[risk] failed to upsert statuses, %v", err)
+		}
+	}
+	// 停止中なら最初の注文前に反映する
+	update()
+
+	errGroup.Go(func() error {
+		ticker := time.NewTicker(time.Duration(config.RiskCheckSeconds) * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				update()
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	})
+}
+
+// runRiskReset リスク管理による停止を解除
+func runRiskReset(logger domain.Logger, config *model.Config, exCli *coincheck.Client) error {
+	mysqlCli := mysql.NewClient(config.DB.UserName, config.DB.Password, config.DB.Host, config.DB.Port, config.DB.Name)
+	risk := trade.NewRiskManager(logger, exCli, mysqlCli, trade.RiskConfig{})
+	if config.SlackURL != "" {
+		risk.Notifier = slack.NewClient(config.SlackURL)
+	}
+	return risk.Reset()
+}
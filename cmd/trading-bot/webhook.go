@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+	"trading-bot/pkg/domain"
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/domain/repository"
+	"trading-bot/pkg/usecase"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// webhookBodyMax リクエストボディの最大サイズ
+	webhookBodyMax = 64 * 1024
+	// webhookSignatureHeader HMAC-SHA256の署名（16進数、"sha256="は省略可）を入れるヘッダ
+	webhookSignatureHeader = "X-Signature"
+)
+
+// webhookPayload アラートのペイロード（ヘッダで署名できない送信元はsecretを含める）
+type webhookPayload struct {
+	usecase.Alert
+	Secret string `json:"secret"`
+}
+
+// startWebhook アラート受信用のHTTPサーバーを起動
+func startWebhook(ctx context.Context, errGroup *errgroup.Group, logger domain.Logger, config *model.Config, eventRepo repository.EventRepository, bots []*usecase.Bot) {
+	if config.WebhookAddr == "" {
+		return
+	}
+	if config.WebhookSecret == "" {
+		logger.Error("[alert] webhook is disabled (webhook secret is empty)")
+		return
+	}
+
+	trader := usecase.NewAlertTrader(logger, eventRepo, config.WebhookMaxOrderJpy)
+	for _, b := range bots {
+		trader.AddTarget(b)
+	}
+
+	r := mux.NewRouter()
+	r.HandleFunc("/webhook", webhookHandler(logger, config.WebhookSecret, trader)).Methods(http.MethodPost)
+	server := &http.Server{Addr: config.WebhookAddr, Handler: r}
+
+	errGroup.Go(func() error {
+		logger.Info("webhook server listening on %s", config.WebhookAddr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Error("webhook server stopped, %v", err)
+		}
+		return nil
+	})
+	errGroup.Go(func() error {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return server.Shutdown(shutdownCtx)
+	})
+}
+
+func webhookHandler(logger domain.Logger, secret string, trader *usecase.AlertTrader) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, webhookBodyMax))
+		if err != nil {
+			writeWebhookResponse(w, logger, http.StatusBadRequest, fmt.Sprintf("failed to read body, %v", err), nil)
+			return
+		}
+
+		var payload webhookPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			writeWebhookResponse(w, logger, http.StatusBadRequest, fmt.Sprintf("invalid json, %v", err), nil)
+			return
+		}
+		if !authenticate(secret, r.Header.Get(webhookSignatureHeader), body, payload.Secret) {
+			logger.Error("[alert] unauthorized request from %s", r.RemoteAddr)
+			writeWebhookResponse(w, logger, http.StatusUnauthorized, "unauthorized", nil)
+			return
+		}
+
+		logger.Info("[alert] received id:%s action:%s pair:%s bot:%s from %s", payload.ID, payload.Action, payload.Pair, payload.Bot, r.RemoteAddr)
+		result, err := trader.Handle(&payload.Alert)
+		if err != nil {
+			if result == nil {
+				writeWebhookResponse(w, logger, http.StatusBadRequest, err.Error(), nil)
+			} else {
+				// 記録済みのため再送されても二重には処理しない
+				writeWebhookResponse(w, logger, http.StatusInternalServerError, result.Message, result)
+			}
+			return
+		}
+		writeWebhookResponse(w, logger, http.StatusOK, result.Message, result)
+	}
+}
+
+// authenticate 署名ヘッダがあればHMAC-SHA256、なければペイロードのsecretで認証
+func authenticate(secret, signature string, body []byte, payloadSecret string) bool {
+	if signature != "" {
+		expected, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+		if err != nil {
+			return false
+		}
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		return hmac.Equal(mac.Sum(nil), expected)
+	}
+	if payloadSecret == "" {
+		return false
+	}
+	return hmac.Equal([]byte(payloadSecret), []byte(secret))
+}
+
+type webhookResponse struct {
+	Message string               `json:"message"`
+	Result  *usecase.AlertResult `json:"result,omitempty"`
+}
+
+func writeWebhookResponse(w http.ResponseWriter, logger domain.Logger, status int, message string, result *usecase.AlertResult) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(webhookResponse{Message: message, Result: result}); err != nil {
+		logger.Error("failed to write response, %v", err)
+	}
+}
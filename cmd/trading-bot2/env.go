@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// envconfigと同じ規則で項目名を単語に分ける
+	wordRegexp    = regexp.MustCompile("([^A-Z]+|[A-Z]+[^A-Z]+|[A-Z]+)")
+	acronymRegexp = regexp.MustCompile("([A-Z]+)([A-Z][^A-Z]+)")
+)
+
+// readEnvFile 設定ファイル（KEY=VALUE形式）を読み込む
+func readEnvFile(envFile string) (map[string]string, error) {
+	b, err := ioutil.ReadFile(envFile)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]string{}
+	for i, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("[%s:%d] invalid line, %s", envFile, i+1, line)
+		}
+		values[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return values, nil
+}
+
+// lookupEnvWith 設定ファイルの値を優先し、なければ環境変数の値を返す
+func lookupEnvWith(values map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		if v, ok := values[key]; ok {
+			return v, true
+		}
+		return os.LookupEnv(key)
+	}
+}
+
+// processEnv envconfig.Processと同じ規則（split_words, default, required）で設定を読み込む
+// envconfigは環境変数しか読めないため、環境変数を書き換えずに設定ファイルの値を使うときに使う
+func processEnv(prefix string, spec interface{}, lookup func(string) (string, bool)) error {
+	s := reflect.ValueOf(spec)
+	if s.Kind() != reflect.Ptr || s.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("spec must be a pointer to struct, %T", spec)
+	}
+	s = s.Elem()
+	t := s.Type()
+	for i := 0; i < s.NumField(); i++ {
+		f, ft := s.Field(i), t.Field(i)
+		if !f.CanSet() || ft.Tag.Get("ignored") == "true" {
+			continue
+		}
+		key := envKey(prefix, ft)
+		if f.Kind() == reflect.Struct {
+			if err := processEnv(key, f.Addr().Interface(), lookup); err != nil {
+				return err
+			}
+			continue
+		}
+
+		value, ok := lookup(key)
+		if !ok {
+			if def := ft.Tag.Get("default"); def != "" {
+				value, ok = def, true
+			}
+		}
+		if !ok {
+			if ft.Tag.Get("required") == "true" {
+				return fmt.Errorf("required key %s missing value", key)
+			}
+			continue
+		}
+		if err := setEnvField(f, value); err != nil {
+			return fmt.Errorf("envconfig.Process: assigning %s to %s: converting '%s' to type %s. details: %v", key, ft.Name, value, f.Type(), err)
+		}
+	}
+	return nil
+}
+
+func envKey(prefix string, ft reflect.StructField) string {
+	key := ft.Name
+	if ft.Tag.Get("split_words") == "true" {
+		words := []string{}
+		for _, w := range wordRegexp.FindAllString(ft.Name, -1) {
+			if m := acronymRegexp.FindStringSubmatch(w); len(m) == 3 {
+				words = append(words, m[1], m[2])
+			} else {
+				words = append(words, w)
+			}
+		}
+		key = strings.Join(words, "_")
+	}
+	if alt := ft.Tag.Get("envconfig"); alt != "" {
+		key = alt
+	}
+	if prefix != "" {
+		key = prefix + "_" + key
+	}
+	return strings.ToUpper(key)
+}
+
+func setEnvField(f reflect.Value, value string) error {
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(value)
+	case reflect.Bool:
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		f.SetBool(v)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err := strconv.ParseInt(value, 0, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetInt(v)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, err := strconv.ParseUint(value, 0, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetUint(v)
+	case reflect.Float32, reflect.Float64:
+		v, err := strconv.ParseFloat(value, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetFloat(v)
+	default:
+		return fmt.Errorf("unsupported type")
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+// leafFields 構造体の末端の項目を項目名（Exchange.AccessKeyなど）ごとに返す
+func leafFields(prefix string, v reflect.Value, fields map[string]reflect.Value) {
+	t := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		name := prefix + t.Field(i).Name
+		if v.Field(i).Kind() == reflect.Struct {
+			leafFields(name+".", v.Field(i), fields)
+			continue
+		}
+		fields[name] = v.Field(i)
+	}
+}
+
+func TestProcessEnv(t *testing.T) {
+	tests := map[string]struct {
+		env []string
+		// 全ての項目に値が入ることを確認する（BotConfigの項目の追加漏れ検出用）
+		all bool
+	}{
+		"all fields": {
+			env: append(replaceEnv("SOARED_WARNING_PERIOD_SECONDS", "300"), "ENV_FILE=configs/bot2.env", "CONFIG_WATCH_SECONDS=5"),
+			all: true,
+		},
+		"default value":      {env: validEnv},
+		"empty default":      {env: append(validEnv, "CONFIG_WATCH_SECONDS=")},
+		"hex int":            {env: replaceEnv("INTERVAL_SECONDS", "0x3c")},
+		"negative int":       {env: replaceEnv("TREND_LINE_OFFSET", "-1")},
+		"bool as number":     {env: replaceEnv("DEMO_MODE", "1")},
+		"bool upper case":    {env: replaceEnv("DEMO_MODE", "FALSE")},
+		"float exponent":     {env: replaceEnv("ENTRY_AREA_WIDTH", "5e-3")},
+		"float as int":       {env: replaceEnv("FUNDS_RATIO", "1")},
+		"empty string":       {env: replaceEnv("SLACK_URL", " ")},
+		"nested split words": {env: replaceEnv("DB_USER_NAME", "other")},
+		"missing required":   {env: replaceEnv("DB_PASSWORD", "")},
+		"missing nested":     {env: replaceEnv("EXCHANGE_ACCESS_KEY", "")},
+		"invalid int":        {env: replaceEnv("DB_PORT", "port")},
+		"invalid bool":       {env: replaceEnv("DEMO_MODE", "yes")},
+		"invalid float":      {env: replaceEnv("TARGET_PROFIT_PER", "0.5%")},
+		"int overflow":       {env: replaceEnv("VOLUME_CHECK_SECONDS", "99999999999999999999")},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			values := map[string]string{}
+			for _, l := range tt.env {
+				kv := strings.SplitN(l, "=", 2)
+				values[kv[0]] = kv[1]
+			}
+			var got BotConfig
+			gotErr := processEnv("", &got, func(key string) (string, bool) {
+				v, ok := values[key]
+				return v, ok
+			})
+
+			for k, v := range values {
+				t.Setenv(k, v)
+			}
+			var want BotConfig
+			wantErr := envconfig.Process("", &want)
+
+			if (gotErr != nil) != (wantErr != nil) {
+				t.Fatalf("processEnv() error = %v, envconfig.Process() error = %v", gotErr, wantErr)
+			}
+			if gotErr != nil {
+				return
+			}
+			gotFields, wantFields := map[string]reflect.Value{}, map[string]reflect.Value{}
+			leafFields("", reflect.ValueOf(got), gotFields)
+			leafFields("", reflect.ValueOf(want), wantFields)
+			for name, g := range gotFields {
+				w := wantFields[name]
+				if !reflect.DeepEqual(g.Interface(), w.Interface()) {
+					t.Errorf("%s = %v, want %v", name, g.Interface(), w.Interface())
+				}
+				if tt.all && g.IsZero() {
+					t.Errorf("%s is not set", name)
+				}
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"os/signal"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 	"trading-bot/pkg/domain"
 	"trading-bot/pkg/domain/model"
@@ -14,6 +15,7 @@ import (
 	"trading-bot/pkg/infrastructure/memory"
 	"trading-bot/pkg/infrastructure/mysql"
 	"trading-bot/pkg/infrastructure/slack"
+	"trading-bot/pkg/usecase"
 	"trading-bot/pkg/usecase/trade"
 
 	"github.com/kelseyhightower/envconfig"
@@ -23,6 +25,9 @@ import (
 const (
 	location  = "Asia/Tokyo"
 	volumeKey = "2006-01-02T15:04:00"
+
+	// stateStrategyName 状態の保存に使う戦略名
+	stateStrategyName = "trading-bot2"
 )
 
 var (
@@ -49,6 +54,10 @@ func main() {
 		logger.Error(err.Error())
 		return
 	}
+	if err := config.valid(); err != nil {
+		logger.Error("validation error: %v", err)
+		return
+	}
 
 	logger.Info("currency: %s\n", config.TargetCurrency)
 	logger.Info("interval: %d sec\n", config.IntervalSeconds)
@@ -59,6 +68,10 @@ func main() {
 	mysqlCli := mysql.NewClient(config.DB.UserName, config.DB.Password, config.DB.Host, config.DB.Port, config.DB.Name)
 	slackCli := slack.NewClient(config.SlackURL)
 	bot := NewBot(&config, coincheckCli, mysqlCli, &logger, slackCli)
+	if err := bot.LoadState(); err != nil {
+		logger.Error("failed to load state, %v", err)
+		return
+	}
 
 	rootCtx, cancel := context.WithCancel(context.Background())
 	errGroup, ctx := errgroup.WithContext(rootCtx)
@@ -71,6 +84,7 @@ func main() {
 		defer cancel()
 		return watchSignal(ctx, &logger)
 	})
+	errGroup.Go(bot.WatchReload(ctx))
 
 	if err := errGroup.Wait(); err != nil {
 		logger.Error("error occured, %v", err)
@@ -106,6 +120,10 @@ type BotConfig struct {
 	SlackURL string `required:"true" split_words:"true"`
 	// デモモード（注文やSlack通知を送信しない）
 	DemoMode bool `required:"true" split_words:"true"`
+	// 設定ファイル（KEY=VALUE形式、指定時は更新やSIGHUPで再読み込み）
+	EnvFile string `split_words:"true"`
+	// 設定ファイルの更新確認間隔（秒、0なら監視しない）
+	ConfigWatchSeconds int `default:"10" split_words:"true"`
 
 	// ===== エントリー判断関連 =====
 	// サポートライン/レジスタンスラインの判定範囲
@@ -140,6 +158,45 @@ type BotConfig struct {
 	TargetProfitPer float64 `required:"true" split_words:"true"`
 }
 
+func (c *BotConfig) valid() error {
+	if c.IntervalSeconds <= 0 {
+		return fmt.Errorf("IntervalSeconds is empty, %v", c.IntervalSeconds)
+	}
+	if c.TrendLinePeriod <= 0 {
+		return fmt.Errorf("TrendLinePeriod is empty, %v", c.TrendLinePeriod)
+	}
+	if c.EntryAreaWidth <= 0 {
+		return fmt.Errorf("EntryAreaWidth is empty, %v", c.EntryAreaWidth)
+	}
+	if c.FundsRatio <= 0 || c.FundsRatio > 1 {
+		return fmt.Errorf("FundsRatio is out of range(0, 1], %v", c.FundsRatio)
+	}
+	if c.FundsRatioPerOrder <= 0 || c.FundsRatioPerOrder > 1 {
+		return fmt.Errorf("FundsRatioPerOrder is out of range(0, 1], %v", c.FundsRatioPerOrder)
+	}
+	if c.TargetProfitPer <= 0 {
+		return fmt.Errorf("TargetProfitPer is empty, %v", c.TargetProfitPer)
+	}
+	return nil
+}
+
+// validReload 稼働中に変更できない項目が変わっていないか検証
+func (c *BotConfig) validReload(before *BotConfig) error {
+	if c.BotName != before.BotName {
+		return fmt.Errorf("BotName cannot be changed, %v -> %v", before.BotName, c.BotName)
+	}
+	if c.TargetCurrency != before.TargetCurrency {
+		return fmt.Errorf("TargetCurrency cannot be changed, %v -> %v", before.TargetCurrency, c.TargetCurrency)
+	}
+	if c.Exchange != before.Exchange {
+		return fmt.Errorf("Exchange cannot be changed")
+	}
+	if c.DB != before.DB {
+		return fmt.Errorf("DB cannot be changed")
+	}
+	return c.valid()
+}
+
 func (c *BotConfig) GetTargetPair(Settlement model.CurrencyType) *model.CurrencyPair {
 	return &model.CurrencyPair{
 		Key:        model.CurrencyType(c.TargetCurrency),
@@ -175,7 +232,9 @@ func (e *ExchangeInfo) HasPosition() bool {
 }
 
 type Bot struct {
-	Config       *BotConfig
+	// 設定（再読み込みで差し替えるため、Config()で参照する）
+	config       *BotConfig
+	configMu     sync.RWMutex
 	CoincheckCli *coincheck.Client
 	MysqlCli     *mysql.Client
 	Logger       *memory.Logger
@@ -183,31 +242,50 @@ type Bot struct {
 
 	buyStandby  bool
 	skipEndTime *time.Time
-	botStatuses []mysql.BotStatus
+	botStatuses []model.BotStatus
+	// 状態の復元と保存（取引と取引履歴の受信の両方から保存する）
+	stateMu sync.Mutex
 
 	sellVolumeCache sync.Map
 	buyVolumeCache  sync.Map
 	sellVolumeChan  chan *coincheck.TradeHistory
 	buyVolumeChan   chan *coincheck.TradeHistory
+
+	// 設定の再読み込み要求
+	reloadCh chan struct{}
 }
 
 func NewBot(config *BotConfig, coincheckCli *coincheck.Client, mysqlCli *mysql.Client, logger *memory.Logger, slackCli *slack.Client) *Bot {
 	return &Bot{
-		Config:          config,
+		config:          config,
 		CoincheckCli:    coincheckCli,
 		MysqlCli:        mysqlCli,
 		Logger:          logger,
 		SlackCli:        slackCli,
 		buyStandby:      false,
 		skipEndTime:     nil,
-		botStatuses:     []mysql.BotStatus{},
+		botStatuses:     []model.BotStatus{},
 		sellVolumeCache: sync.Map{},
 		buyVolumeCache:  sync.Map{},
 		sellVolumeChan:  make(chan *coincheck.TradeHistory),
 		buyVolumeChan:   make(chan *coincheck.TradeHistory),
+		reloadCh:        make(chan struct{}, 1),
 	}
 }
 
+// Config 現在の設定（再読み込みしても呼び出し元が持つ設定は書き換わらない）
+func (b *Bot) Config() *BotConfig {
+	b.configMu.RLock()
+	defer b.configMu.RUnlock()
+	return b.config
+}
+
+func (b *Bot) setConfig(config *BotConfig) {
+	b.configMu.Lock()
+	defer b.configMu.Unlock()
+	b.config = config
+}
+
 func (b *Bot) Trade(ctx context.Context) func() error {
 	return func() error {
 		for {
@@ -215,7 +293,13 @@ func (b *Bot) Trade(ctx context.Context) func() error {
 			case <-ctx.Done():
 				return nil
 			default:
-				b.botStatuses = []mysql.BotStatus{}
+				select {
+				case <-b.reloadCh:
+					b.reload()
+				default:
+				}
+
+				b.botStatuses = []model.BotStatus{}
 
 				err := b.trade(ctx)
 				if err != nil {
@@ -225,13 +309,17 @@ func (b *Bot) Trade(ctx context.Context) func() error {
 				if err := b.MysqlCli.UpsertBotStatuses(b.botStatuses); err != nil {
 					b.Logger.Error("error occured in upsertBotInfos, %v", err)
 				}
+
+				if err := b.SaveState(); err != nil {
+					b.Logger.Error("error occured in saveState, %v", err)
+				}
 			}
 		}
 	}
 }
 
 func (b *Bot) trade(ctx context.Context) error {
-	pair := b.Config.GetTargetPair(model.JPY)
+	pair := b.Config().GetTargetPair(model.JPY)
 
 	info, err := b.getExchangeInfo(pair)
 	if err != nil {
@@ -298,8 +386,8 @@ func (b *Bot) tradeForBuy(info *ExchangeInfo) error {
 }
 
 func (b *Bot) tradeForSell(info *ExchangeInfo) error {
-	botStatus := mysql.BotStatus{
-		BotName: b.Config.BotName, Type: "sell_rate", Value: -1, Memo: "約定待ちの売注文レート",
+	botStatus := model.BotStatus{
+		BotName: b.Config().BotName, Pair: b.Config().GetTargetPair(model.JPY).String(), Type: "sell_rate", Value: -1, Memo: "約定待ちの売注文レート",
 	}
 
 	if !info.HasPosition() {
@@ -373,12 +461,14 @@ func (b *Bot) tradeForSell(info *ExchangeInfo) error {
 	return nil
 }
 
+// losscut 指値売りが約定しないまま残っているとき、レジスタンスライン付近で反落したら注文を取り消して成行売りする
+// ポジションではなく残高と未約定の注文で判断するため、exit.Chain（Facadeのポジション単位の判定）は使わない
 func (b *Bot) losscut(info *ExchangeInfo, openOrders []model.Order) (bool, error) {
 	rates, err := b.MysqlCli.GetRates(info.Pair, &rateDuration)
 	if err != nil {
 		return false, err
 	}
-	required := b.Config.TrendLinePeriod + b.Config.TrendLineOffset
+	required := b.Config().TrendLinePeriod + b.Config().TrendLineOffset
 	if len(rates) < required {
 		b.Logger.Debug(
 			"skip losscut (rate len:%s < ResistanceLine required:%d)",
@@ -387,10 +477,10 @@ func (b *Bot) losscut(info *ExchangeInfo, openOrders []model.Order) (bool, error
 	}
 
 	l := len(rates)
-	slope, intercept := trade.ResistanceLine2(rates, l-b.Config.TrendLinePeriod-b.Config.TrendLineOffset, l-b.Config.TrendLineOffset)
+	slope, intercept := trade.ResistanceLine2(rates, l-b.Config().TrendLinePeriod-b.Config().TrendLineOffset, l-b.Config().TrendLineOffset)
 	resistanceLines := trade.MakeLine(slope, intercept, len(rates))
 	resistanceLine := resistanceLines[len(resistanceLines)-1]
-	width := resistanceLine * b.Config.EntryAreaWidth
+	width := resistanceLine * b.Config().EntryAreaWidth
 	upper := resistanceLine
 	lower := resistanceLine - width
 
@@ -401,13 +491,13 @@ func (b *Bot) losscut(info *ExchangeInfo, openOrders []model.Order) (bool, error
 			b.Logger.Debug(
 				"%s sellRate is not in losscut area (sellRate:%s <= lower:%s)(width=%.3f * %.3f)",
 				domain.Red("NG"), domain.Yellow("%.3f", info.SellRate), domain.Yellow("%.3f", lower),
-				resistanceLine, b.Config.EntryAreaWidth,
+				resistanceLine, b.Config().EntryAreaWidth,
 			)
 		} else {
 			b.Logger.Debug(
 				"%s sellRate is not in losscut area (sellRate:%s >= upper:%s)(width=%.3f * %.3f)",
 				domain.Red("NG"), domain.Yellow("%.3f", info.SellRate), domain.Yellow("%.3f", upper),
-				resistanceLine, b.Config.EntryAreaWidth,
+				resistanceLine, b.Config().EntryAreaWidth,
 			)
 		}
 		return false, nil
@@ -420,7 +510,7 @@ func (b *Bot) losscut(info *ExchangeInfo, openOrders []model.Order) (bool, error
 			domain.Red("NG"),
 			domain.Yellow("%.3f", before), domain.Yellow("%.3f", info.SellRate),
 			domain.Yellow("%.3f", lower), domain.Yellow("%.3f", info.SellRate), domain.Yellow("%.3f", upper),
-			resistanceLine, b.Config.EntryAreaWidth,
+			resistanceLine, b.Config().EntryAreaWidth,
 		)
 		return false, nil
 	}
@@ -430,7 +520,7 @@ func (b *Bot) losscut(info *ExchangeInfo, openOrders []model.Order) (bool, error
 		domain.Green("OK"),
 		domain.Yellow("%.3f", before), domain.Yellow("%.3f", info.SellRate),
 		domain.Yellow("%.3f", lower), domain.Yellow("%.3f", info.SellRate), domain.Yellow("%.3f", upper),
-		resistanceLine, b.Config.EntryAreaWidth,
+		resistanceLine, b.Config().EntryAreaWidth,
 	)
 
 	if err := b.cancel(openOrders); err != nil {
@@ -490,7 +580,7 @@ func (b *Bot) calcBuyAmount(info *ExchangeInfo) (float64, error) {
 		return 0, err
 	}
 
-	required := b.Config.TrendLinePeriod + b.Config.TrendLineOffset
+	required := b.Config().TrendLinePeriod + b.Config().TrendLineOffset
 	if len(rates) < required {
 		b.Logger.Debug("skip buy (rate len:%s < SupportLine required:%d)", domain.Yellow("%d", len(rates)), required)
 		b.buyStandby = false
@@ -517,7 +607,7 @@ func (b *Bot) calcBuyAmount(info *ExchangeInfo) (float64, error) {
 	var isBreakout bool
 	{
 		l := len(rates)
-		slope, intercept := trade.ResistanceLine2(rates, l-b.Config.TrendLinePeriod-b.Config.TrendLineOffset, l-b.Config.TrendLineOffset)
+		slope, intercept := trade.ResistanceLine2(rates, l-b.Config().TrendLinePeriod-b.Config().TrendLineOffset, l-b.Config().TrendLineOffset)
 		resistanceLines := trade.MakeLine(slope, intercept, len(rates))
 		resistanceLine := resistanceLines[len(resistanceLines)-1]
 		if slope < 0 {
@@ -528,12 +618,12 @@ func (b *Bot) calcBuyAmount(info *ExchangeInfo) (float64, error) {
 			)
 			isBreakout = false
 		} else {
-			width := resistanceLine * b.Config.EntryAreaWidth
+			width := resistanceLine * b.Config().EntryAreaWidth
 			upper := resistanceLine + width
 			lower := resistanceLine
 
 			diff := info.SellRate - rates[len(rates)-1]
-			diffBorder := info.SellRate * b.Config.BreakoutRatio
+			diffBorder := info.SellRate * b.Config().BreakoutRatio
 
 			isUpperEntryArea := (lower < info.SellRate && info.SellRate < resistanceLine+width)
 			isBreakout = isUpperEntryArea && diff > diffBorder
@@ -544,7 +634,7 @@ func (b *Bot) calcBuyAmount(info *ExchangeInfo) (float64, error) {
 						domain.Green("OK"),
 						domain.Yellow("%.3f", diff), domain.Yellow("%.3f", diffBorder),
 						domain.Yellow("%.3f", lower), domain.Yellow("%.3f", info.SellRate), domain.Yellow("%.3f", upper),
-						resistanceLine, b.Config.EntryAreaWidth,
+						resistanceLine, b.Config().EntryAreaWidth,
 					)
 				} else {
 					b.Logger.Debug(
@@ -552,7 +642,7 @@ func (b *Bot) calcBuyAmount(info *ExchangeInfo) (float64, error) {
 						domain.Red("NG"),
 						domain.Yellow("%.3f", diff), domain.Yellow("%.3f", diffBorder),
 						domain.Yellow("%.3f", lower), domain.Yellow("%.3f", info.SellRate), domain.Yellow("%.3f", upper),
-						resistanceLine, b.Config.EntryAreaWidth,
+						resistanceLine, b.Config().EntryAreaWidth,
 					)
 				}
 			} else {
@@ -560,29 +650,29 @@ func (b *Bot) calcBuyAmount(info *ExchangeInfo) (float64, error) {
 					b.Logger.Debug(
 						"%s sellRate is not in upper entry area (sellRate:%s <= lower:%s)(width=%.3f * %.3f)",
 						domain.Red("NG"), domain.Yellow("%.3f", info.SellRate), domain.Yellow("%.3f", lower),
-						resistanceLine, b.Config.EntryAreaWidth,
+						resistanceLine, b.Config().EntryAreaWidth,
 					)
 				} else {
 					b.Logger.Debug(
 						"%s sellRate is not in upper entry area (sellRate:%s >= upper:%s)(width=%.3f * %.3f)",
 						domain.Red("NG"), domain.Yellow("%.3f", info.SellRate), domain.Yellow("%.3f", upper),
-						resistanceLine, b.Config.EntryAreaWidth,
+						resistanceLine, b.Config().EntryAreaWidth,
 					)
 				}
 			}
 		}
-		b.botStatuses = append(b.botStatuses, mysql.BotStatus{BotName: b.Config.BotName, Type: "resistance_line_value", Value: resistanceLine, Memo: "レジスタンスラインの現在値"})
-		b.botStatuses = append(b.botStatuses, mysql.BotStatus{BotName: b.Config.BotName, Type: "resistance_line_slope", Value: slope, Memo: "レジスタンスラインの傾き"})
+		b.botStatuses = append(b.botStatuses, model.BotStatus{BotName: b.Config().BotName, Pair: b.Config().GetTargetPair(model.JPY).String(), Type: "resistance_line_value", Value: resistanceLine, Memo: "レジスタンスラインの現在値"})
+		b.botStatuses = append(b.botStatuses, model.BotStatus{BotName: b.Config().BotName, Pair: b.Config().GetTargetPair(model.JPY).String(), Type: "resistance_line_slope", Value: slope, Memo: "レジスタンスラインの傾き"})
 	}
 
 	// 前回のレートがエントリーエリア（サポートライン近く）？
 	var isLowerEntryArea bool
 	{
 		l := len(rates)
-		slope, intercept := trade.SupportLine2(rates, l-b.Config.TrendLinePeriod-b.Config.TrendLineOffset, l-b.Config.TrendLineOffset)
+		slope, intercept := trade.SupportLine2(rates, l-b.Config().TrendLinePeriod-b.Config().TrendLineOffset, l-b.Config().TrendLineOffset)
 		supportLines := trade.MakeLine(slope, intercept, len(rates))
 		supportLine := supportLines[len(supportLines)-1]
-		width := supportLine * b.Config.EntryAreaWidth
+		width := supportLine * b.Config().EntryAreaWidth
 		upper := supportLine + width
 		lower := supportLine - width
 		isLowerEntryArea = (lower < info.SellRate && info.SellRate < supportLine+width)
@@ -590,25 +680,25 @@ func (b *Bot) calcBuyAmount(info *ExchangeInfo) (float64, error) {
 			b.Logger.Debug(
 				"%s sellRate is in lower entry area (lower:%s < sellRate:%s < upper:%s)(width=%.3f * %.3f)",
 				domain.Green("OK"), domain.Yellow("%.3f", lower), domain.Yellow("%.3f", info.SellRate), domain.Yellow("%.3f", upper),
-				supportLine, b.Config.EntryAreaWidth,
+				supportLine, b.Config().EntryAreaWidth,
 			)
 		} else {
 			if info.SellRate <= supportLine {
 				b.Logger.Debug(
 					"%s sellRate is not in lower entry area (sellRate:%s <= lower:%s)(width=%.3f * %.3f)",
 					domain.Red("NG"), domain.Yellow("%.3f", info.SellRate), domain.Yellow("%.3f", lower),
-					supportLine, b.Config.EntryAreaWidth,
+					supportLine, b.Config().EntryAreaWidth,
 				)
 			} else {
 				b.Logger.Debug(
 					"%s sellRate is not in lower entry area (sellRate:%s >= upper:%s)(width=%.3f * %.3f)",
 					domain.Red("NG"), domain.Yellow("%.3f", info.SellRate), domain.Yellow("%.3f", upper),
-					supportLine, b.Config.EntryAreaWidth,
+					supportLine, b.Config().EntryAreaWidth,
 				)
 			}
 		}
-		b.botStatuses = append(b.botStatuses, mysql.BotStatus{BotName: b.Config.BotName, Type: "support_line_value", Value: supportLine, Memo: "サポートラインの現在値"})
-		b.botStatuses = append(b.botStatuses, mysql.BotStatus{BotName: b.Config.BotName, Type: "support_line_slope", Value: slope, Memo: "サポートラインの傾き"})
+		b.botStatuses = append(b.botStatuses, model.BotStatus{BotName: b.Config().BotName, Pair: b.Config().GetTargetPair(model.JPY).String(), Type: "support_line_value", Value: supportLine, Memo: "サポートラインの現在値"})
+		b.botStatuses = append(b.botStatuses, model.BotStatus{BotName: b.Config().BotName, Pair: b.Config().GetTargetPair(model.JPY).String(), Type: "support_line_slope", Value: slope, Memo: "サポートラインの傾き"})
 	}
 
 	entrySignal := (isLowerEntryArea || isBreakout) && isRising
@@ -631,7 +721,7 @@ func (b *Bot) calcBuyAmount(info *ExchangeInfo) (float64, error) {
 			averagingDown = true
 			averagingDownLittle = true
 		} else {
-			border := info.BuyOrderContractRate * b.Config.AveragingDownRatePer
+			border := info.BuyOrderContractRate * b.Config().AveragingDownRatePer
 			averagingDown = info.BuyRate < border
 			averagingDownLittle = info.BuyRate < info.BuyOrderContractRate
 			if averagingDown {
@@ -647,7 +737,7 @@ func (b *Bot) calcBuyAmount(info *ExchangeInfo) (float64, error) {
 	// 追加注文に使う金額(JPY)
 	newOrderJPY := domain.Round(info.BalanceCurrency.Reserved * info.BuyRate)
 	if newOrderJPY == 0.0 {
-		newOrderJPY = domain.Round(info.CalcTotalBalanceJPY() * b.Config.FundsRatioPerOrder)
+		newOrderJPY = domain.Round(info.CalcTotalBalanceJPY() * b.Config().FundsRatioPerOrder)
 	}
 	if newOrderJPY < buyJpyMin {
 		b.Logger.Debug("%s cannot sending buy order, jpy is too low (%.3f < min:%.3f)", domain.Red("NG"), newOrderJPY, buyJpyMin)
@@ -656,7 +746,7 @@ func (b *Bot) calcBuyAmount(info *ExchangeInfo) (float64, error) {
 	}
 
 	// 追加注文する余裕ある？
-	fundsTotalJPY := info.CalcTotalBalanceJPY() * b.Config.FundsRatio
+	fundsTotalJPY := info.CalcTotalBalanceJPY() * b.Config().FundsRatio
 	fundsBalanceJPY := fundsTotalJPY - info.BalanceCurrency.Total()*info.SellRate
 	canOrder := newOrderJPY <= fundsBalanceJPY
 	if canOrder {
@@ -710,7 +800,7 @@ func (b *Bot) buyAndWaitForContract(pair *model.CurrencyPair, amount float64) er
 	b.Logger.Debug("======================================")
 	defer b.Logger.Debug("======================================")
 
-	if b.Config.DemoMode {
+	if b.Config().DemoMode {
 		b.Logger.Debug(
 			"%s buy completed!!! (rate:%.3f, amount:%.3f)",
 			domain.Cyan("[DEMO]"), amount,
@@ -759,7 +849,7 @@ func (b *Bot) buyAndWaitForContract(pair *model.CurrencyPair, amount float64) er
 			if c.OrderID == order.ID {
 				b.Logger.Debug(domain.Green("contracted!!![id:%d]", order.ID))
 				b.buyStandby = false
-				b.setSkipEndTime(time.Now().Add(time.Duration(b.Config.BuyIntervalSeconds) * time.Second))
+				b.setSkipEndTime(time.Now().Add(time.Duration(b.Config().BuyIntervalSeconds) * time.Second))
 				return nil
 			}
 		}
@@ -768,7 +858,7 @@ func (b *Bot) buyAndWaitForContract(pair *model.CurrencyPair, amount float64) er
 }
 
 func (b *Bot) cancel(orders []model.Order) error {
-	if b.Config.DemoMode {
+	if b.Config().DemoMode {
 		return nil
 	}
 
@@ -816,7 +906,7 @@ func (b *Bot) calcSellRateAndAmount(info *ExchangeInfo) (rate float64, amount fl
 	}
 
 	usedJPY := totalJPY - info.BalanceJPY.Amount
-	profit := totalJPY * b.Config.FundsRatioPerOrder * b.Config.TargetProfitPer
+	profit := totalJPY * b.Config().FundsRatioPerOrder * b.Config().TargetProfitPer
 	rate = (usedJPY + profit) / amount
 
 	return
@@ -826,7 +916,7 @@ func (b *Bot) sell(info *ExchangeInfo, rate float64, amount float64) error {
 	b.Logger.Debug("======================================")
 	defer b.Logger.Debug("======================================")
 
-	if b.Config.DemoMode {
+	if b.Config().DemoMode {
 		b.Logger.Debug(
 			"%s sell completed!!! (rate:%.3f, amount:%.3f)",
 			domain.Cyan("[DEMO]"), rate, amount,
@@ -926,10 +1016,165 @@ func (b *Bot) setSkipEndTime(t time.Time) {
 	b.Logger.Debug("set skip end time => %s", domain.Yellow("%s", b.skipEndTime.Format(time.RFC3339)))
 }
 
+// LoadState 保存済みの状態を復元
+func (b *Bot) LoadState() error {
+	b.stateMu.Lock()
+	defer b.stateMu.Unlock()
+
+	s, err := b.MysqlCli.GetStrategyState(b.Config().BotName, stateStrategyName, b.Config().GetTargetPair(model.JPY).String())
+	if err != nil {
+		return err
+	}
+	if len(s.Values) == 0 {
+		return nil
+	}
+
+	if b.buyStandby, err = s.Bool("buy_standby", false); err != nil {
+		return err
+	}
+	if b.skipEndTime, err = s.Time("skip_end_time"); err != nil {
+		return err
+	}
+	for key, cache := range map[string]*sync.Map{"sell_volumes": &b.sellVolumeCache, "buy_volumes": &b.buyVolumeCache} {
+		volumes := map[string]float64{}
+		if _, err := s.JSON(key, &volumes); err != nil {
+			return err
+		}
+		for k, v := range volumes {
+			cache.Store(k, v)
+		}
+	}
+	b.Logger.Info("restored state %v", s.Values)
+	return nil
+}
+
+// SaveState 状態を保存
+func (b *Bot) SaveState() error {
+	b.stateMu.Lock()
+	defer b.stateMu.Unlock()
+
+	s := model.NewStrategyState(b.Config().BotName, stateStrategyName, b.Config().GetTargetPair(model.JPY).String())
+	s.SetBool("buy_standby", b.buyStandby)
+	s.SetTime("skip_end_time", b.skipEndTime)
+	for key, cache := range map[string]*sync.Map{"sell_volumes": &b.sellVolumeCache, "buy_volumes": &b.buyVolumeCache} {
+		volumes := map[string]float64{}
+		cache.Range(func(k, v interface{}) bool {
+			if kk, ok := k.(string); ok {
+				if vv, ok := v.(float64); ok {
+					volumes[kk] = vv
+				}
+			}
+			return true
+		})
+		if err := s.SetJSON(key, volumes); err != nil {
+			return err
+		}
+	}
+	return b.MysqlCli.UpsertStrategyState(s)
+}
+
+// WatchReload 設定ファイルの更新とSIGHUPを監視し、再読み込みを要求（次回の取引前に適用）
+func (b *Bot) WatchReload(ctx context.Context) func() error {
+	return func() error {
+		requestReload := func() {
+			select {
+			case b.reloadCh <- struct{}{}:
+			default:
+			}
+		}
+
+		errGroup, ctx := errgroup.WithContext(ctx)
+		errGroup.Go(func() error {
+			hup := make(chan os.Signal, 1)
+			defer close(hup)
+			signal.Notify(hup, syscall.SIGHUP)
+			defer signal.Stop(hup)
+			for {
+				select {
+				case <-hup:
+					b.Logger.Info("[reload] received SIGHUP")
+					requestReload()
+				case <-ctx.Done():
+					return nil
+				}
+			}
+		})
+		if b.Config().EnvFile != "" && b.Config().ConfigWatchSeconds > 0 {
+			watcher, err := usecase.NewConfigWatcher(b.Config().EnvFile, time.Duration(b.Config().ConfigWatchSeconds)*time.Second)
+			if err != nil {
+				b.Logger.Error("[reload] failed to watch %s, %v", b.Config().EnvFile, err)
+			} else {
+				errGroup.Go(func() error {
+					return watcher.Watch(ctx, func() {
+						b.Logger.Info("[reload] detected update of %s", b.Config().EnvFile)
+						requestReload()
+					})
+				})
+			}
+		}
+		return errGroup.Wait()
+	}
+}
+
+// reload 設定を再読み込み（失敗した場合は以前の設定のまま）
+func (b *Bot) reload() {
+	before := b.Config()
+	if before.EnvFile == "" {
+		b.Logger.Info("[reload] => skip reload (env file is empty)")
+		return
+	}
+
+	config, err := loadBotConfig(before.EnvFile)
+	if err == nil {
+		err = config.validReload(before)
+	}
+	if err != nil {
+		b.Logger.Error("[reload] => rejected, keep previous config (%s), %v", before.EnvFile, err)
+		b.notify(fmt.Sprintf("[%s] 設定の再読み込みを中止しました（以前の設定のまま稼働します）\nfile: %s\nerror: %v", before.BotName, before.EnvFile, err))
+		return
+	}
+
+	diffs := usecase.DiffConfig(before, config)
+	b.setConfig(config)
+	if len(diffs) == 0 {
+		b.Logger.Info("[reload] => reloaded (%s), no changes", config.EnvFile)
+		return
+	}
+	b.Logger.Info("[reload] => reloaded (%s), %s", config.EnvFile, strings.Join(diffs, ", "))
+	b.notify(fmt.Sprintf("[%s] 設定を再読み込みしました\nfile: %s\n%s", config.BotName, config.EnvFile, strings.Join(diffs, "\n")))
+}
+
+func (b *Bot) notify(message string) {
+	if b.Config().DemoMode {
+		return
+	}
+	if err := b.SlackCli.Notify(message); err != nil {
+		b.Logger.Error("failed to notify, %v", err)
+	}
+}
+
+// loadBotConfig 設定ファイル（KEY=VALUE形式）の値で設定を読み込む（環境変数は書き換えない）
+// 設定ファイルにない項目は起動時の環境変数の値となる
+func loadBotConfig(envFile string) (*BotConfig, error) {
+	values, err := readEnvFile(envFile)
+	if err != nil {
+		return nil, err
+	}
+	var config BotConfig
+	if err := processEnv("", &config, lookupEnvWith(values)); err != nil {
+		return nil, err
+	}
+	if config.EnvFile == "" {
+		// 次の再読み込みも同じファイルから読み込む
+		config.EnvFile = envFile
+	}
+	return &config, nil
+}
+
 func (b *Bot) WatchTrade(ctx context.Context) func() error {
 	return func() error {
 		// 取引履歴の監視
-		pair := b.Config.GetTargetPair(model.JPY)
+		pair := b.Config().GetTargetPair(model.JPY)
 		for {
 			select {
 			case <-ctx.Done():
@@ -956,22 +1201,25 @@ func (b *Bot) receiveTrade(h *coincheck.TradeHistory) error {
 
 func (b *Bot) ReceiveTradeHandler(ctx context.Context) func() error {
 	return func() error {
-		d := time.Duration(b.Config.VolumeCheckSeconds) * time.Second
+		d := time.Duration(b.Config().VolumeCheckSeconds) * time.Second
 		key := time.Now().Format(volumeKey)
 		for {
 			select {
 			case h := <-b.sellVolumeChan:
-				v, err := b.CoincheckCli.GetVolumes(b.Config.GetTargetPair(model.JPY), h.Side, d)
+				v, err := b.CoincheckCli.GetVolumes(b.Config().GetTargetPair(model.JPY), h.Side, d)
 				if err != nil {
 					return err
 				}
-				if v > b.Config.SellMaxVolume {
-					b.Logger.Debug("[receive] %s (sell volume:%.3f > max:%.3f)", domain.Green("set buyStandby"), v, b.Config.SellMaxVolume)
+				if v > b.Config().SellMaxVolume {
+					b.Logger.Debug("[receive] %s (sell volume:%.3f > max:%.3f)", domain.Green("set buyStandby"), v, b.Config().SellMaxVolume)
 					b.buyStandby = true
 					// 警戒期間をクリア
 					b.setSkipEndTime(time.Now())
+					if err := b.SaveState(); err != nil {
+						b.Logger.Error("[receive] error occured in saveState, %v", err)
+					}
 				} else {
-					b.Logger.Debug("[receive] skip set buyStandby (sell volume:%.3f <= max:%.3f)", v, b.Config.SellMaxVolume)
+					b.Logger.Debug("[receive] skip set buyStandby (sell volume:%.3f <= max:%.3f)", v, b.Config().SellMaxVolume)
 				}
 
 				total := 0.0
@@ -982,15 +1230,18 @@ func (b *Bot) ReceiveTradeHandler(ctx context.Context) func() error {
 				}
 				b.sellVolumeCache.Store(key, total+h.Amount)
 			case h := <-b.buyVolumeChan:
-				v, err := b.CoincheckCli.GetVolumes(b.Config.GetTargetPair(model.JPY), h.Side, d)
+				v, err := b.CoincheckCli.GetVolumes(b.Config().GetTargetPair(model.JPY), h.Side, d)
 				if err != nil {
 					return err
 				}
-				if v > b.Config.BuyMaxVolume {
-					b.Logger.Debug("[receive] %s (buy volume:%.3f > max:%.3f)", domain.Red("record soaredTime"), v, b.Config.BuyMaxVolume)
-					b.setSkipEndTime(time.Now().Add(time.Duration(b.Config.SoaredWarningPeriodSeconds) * time.Second))
+				if v > b.Config().BuyMaxVolume {
+					b.Logger.Debug("[receive] %s (buy volume:%.3f > max:%.3f)", domain.Red("record soaredTime"), v, b.Config().BuyMaxVolume)
+					b.setSkipEndTime(time.Now().Add(time.Duration(b.Config().SoaredWarningPeriodSeconds) * time.Second))
+					if err := b.SaveState(); err != nil {
+						b.Logger.Error("[receive] error occured in saveState, %v", err)
+					}
 				} else {
-					b.Logger.Debug("[receive] skip record soaredTime (buy volume:%.3f <= max:%.3f)", v, b.Config.BuyMaxVolume)
+					b.Logger.Debug("[receive] skip record soaredTime (buy volume:%.3f <= max:%.3f)", v, b.Config().BuyMaxVolume)
 				}
 
 				total := 0.0
@@ -1010,7 +1261,7 @@ func (b *Bot) ReceiveTradeHandler(ctx context.Context) func() error {
 }
 
 func (b *Bot) wait(ctx context.Context) error {
-	b.Logger.Debug("wait ... (%d sec)", b.Config.IntervalSeconds)
+	b.Logger.Debug("wait ... (%d sec)", b.Config().IntervalSeconds)
 
 	waitCount := 0
 	for {
@@ -1022,7 +1273,7 @@ func (b *Bot) wait(ctx context.Context) error {
 				b.Logger.Debug("stop wait (buy standby)")
 				return nil
 			}
-			if waitCount >= b.Config.IntervalSeconds {
+			if waitCount >= b.Config().IntervalSeconds {
 				return nil
 			}
 			time.Sleep(1 * time.Second)
@@ -1034,7 +1285,7 @@ func (b *Bot) wait(ctx context.Context) error {
 func (b *Bot) Fetch(ctx context.Context) func() error {
 	return func() error {
 		// レートの定期保存
-		ticker := time.NewTicker(time.Duration(b.Config.IntervalSeconds) * time.Second)
+		ticker := time.NewTicker(time.Duration(b.Config().IntervalSeconds) * time.Second)
 		defer ticker.Stop()
 		for {
 			select {
@@ -1050,7 +1301,7 @@ func (b *Bot) Fetch(ctx context.Context) func() error {
 }
 
 func (b *Bot) fetch(ctx context.Context) error {
-	pair := b.Config.GetTargetPair(model.JPY)
+	pair := b.Config().GetTargetPair(model.JPY)
 
 	storeRate, err := b.CoincheckCli.GetStoreRate(pair)
 	if err != nil {
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"trading-bot/pkg/infrastructure/memory"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+// validEnv 全ての必須項目を含む設定ファイル
+var validEnv = []string{
+	"BOT_NAME=test",
+	"TARGET_CURRENCY=mona",
+	"INTERVAL_SECONDS=60",
+	"EXCHANGE_ACCESS_KEY=access",
+	"EXCHANGE_SECRET_KEY=secret",
+	"DB_HOST=localhost",
+	"DB_PORT=3306",
+	"DB_NAME=bot",
+	"DB_USER_NAME=user",
+	"DB_PASSWORD=password",
+	"SLACK_URL=https://example.com",
+	"DEMO_MODE=true",
+	"TREND_LINE_PERIOD=150",
+	"TREND_LINE_OFFSET=1",
+	"ENTRY_AREA_WIDTH=0.005",
+	"BREAKOUT_RATIO=0.003",
+	"AVERAGING_DOWN_RATE_PER=0.98",
+	"SELL_MAX_VOLUME=2000.0",
+	"BUY_MAX_VOLUME=2000.0",
+	"VOLUME_CHECK_SECONDS=120",
+	"SOARED_WARNING_PERIOD_SECONDS=0",
+	"BUY_INTERVAL_SECONDS=600",
+	"FUNDS_RATIO=1.0",
+	"FUNDS_RATIO_PER_ORDER=0.2",
+	"TARGET_PROFIT_PER=0.005",
+}
+
+func writeEnv(t *testing.T, path string, lines []string) {
+	t.Helper()
+	if err := ioutil.WriteFile(path, []byte(strings.Join(lines, "\n")), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// replaceEnv 設定ファイルの項目を置き換える（値が空なら削除）
+func replaceEnv(key, value string) []string {
+	lines := []string{}
+	for _, l := range validEnv {
+		if strings.HasPrefix(l, key+"=") {
+			if value != "" {
+				lines = append(lines, key+"="+value)
+			}
+			continue
+		}
+		lines = append(lines, l)
+	}
+	return lines
+}
+
+func TestLoadBotConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bot.env")
+	writeEnv(t, path, validEnv)
+	got, err := loadBotConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 環境変数は書き換えない
+	if _, ok := os.LookupEnv("BOT_NAME"); ok {
+		t.Errorf("BOT_NAME is set in environment")
+	}
+
+	// envconfigで環境変数から読み込んだ場合と同じになる
+	for _, l := range validEnv {
+		kv := strings.SplitN(l, "=", 2)
+		t.Setenv(kv[0], kv[1])
+	}
+	var want BotConfig
+	if err := envconfig.Process("", &want); err != nil {
+		t.Fatal(err)
+	}
+	want.EnvFile = path
+	if !reflect.DeepEqual(got, &want) {
+		t.Errorf("loadBotConfig() = %+v, want %+v", got, want)
+	}
+}
+
+func TestBot_Reload(t *testing.T) {
+	tests := map[string]struct {
+		env []string
+		// 再読み込み後のIntervalSeconds（変わらなければ以前の設定のまま）
+		want int
+	}{
+		"applied":          {env: replaceEnv("INTERVAL_SECONDS", "30"), want: 30},
+		"bot name changed": {env: append(replaceEnv("BOT_NAME", "other"), "INTERVAL_SECONDS=30"), want: 60},
+		"exchange changed": {env: append(replaceEnv("EXCHANGE_SECRET_KEY", "other"), "INTERVAL_SECONDS=30"), want: 60},
+		"invalid value":    {env: append(replaceEnv("FUNDS_RATIO", "2.0"), "INTERVAL_SECONDS=30"), want: 60},
+		"not a number":     {env: replaceEnv("INTERVAL_SECONDS", "fast"), want: 60},
+		"missing required": {env: append(replaceEnv("TARGET_PROFIT_PER", ""), "INTERVAL_SECONDS=30"), want: 60},
+		"invalid line":     {env: append(replaceEnv("INTERVAL_SECONDS", "30"), "INVALID"), want: 60},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "bot.env")
+			writeEnv(t, path, validEnv)
+			config, err := loadBotConfig(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			bot := NewBot(config, nil, nil, &memory.Logger{Level: memory.Error}, nil)
+
+			writeEnv(t, path, tt.env)
+			bot.reload()
+
+			got := bot.Config()
+			if got.IntervalSeconds != tt.want {
+				t.Errorf("IntervalSeconds = %d, want %d", got.IntervalSeconds, tt.want)
+			}
+			if tt.want == 60 && got != config {
+				t.Errorf("config is replaced although reload is rejected")
+			}
+			// 再読み込み前の設定を参照している処理からは変わらない
+			if config.IntervalSeconds != 60 {
+				t.Errorf("previous config is modified, %d", config.IntervalSeconds)
+			}
+		})
+	}
+}
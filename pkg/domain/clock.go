@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Clock 現在日時と待機（シミュレーションでは履歴データの日時を使う）
+type Clock interface {
+	// Now 現在日時
+	Now() time.Time
+	// Sleep 指定時間待機（ctxが終了したら待機をやめる）
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+// SystemClock 実時間の時計
+type SystemClock struct{}
+
+// Now 現在日時
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// Sleep 指定時間待機
+func (SystemClock) Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	return nil
+}
@@ -1,5 +1,10 @@
 package model
 
+import (
+	"fmt"
+	"time"
+)
+
 // Config ボット用設定
 type Config struct {
 	TargetCurrency         string   `required:"true" split_words:"true"`
@@ -7,6 +12,51 @@ type Config struct {
 	PositionCountMax       int      `required:"true" split_words:"true"`
 	Exchange               Exchange `required:"true"`
 	DB                     DB       `required:"true"`
+
+	// SlackのIncomingWebhookのURL（未指定なら通知しない）
+	SlackURL string `split_words:"true"`
+	// 操作用HTTPサーバーの待受アドレス（例: :8081、未指定なら起動しない）
+	ControlAddr string `split_words:"true"`
+	// 操作用HTTPサーバーの認証に使う共有シークレット（HMAC-SHA256の鍵、またはX-Secretヘッダと照合、未指定なら起動しない）
+	ControlSecret string `split_words:"true"`
+	// 設定ファイルの更新確認間隔（秒、0なら監視しない）
+	ConfigWatchSeconds int `default:"10" split_words:"true"`
+	// 取引時間帯の設定ファイル（未指定なら常時取引）
+	ScheduleFile string `split_words:"true"`
+
+	// レートの最終記録からの経過時間の上限（秒、超えたら新規注文を止める、0なら確認しない）
+	RateMaxAgeSeconds int `split_words:"true"`
+	// レートの記録間隔の上限（秒、超える空きがあれば新規注文を止める、0なら確認しない）
+	RateMaxGapSeconds int `split_words:"true"`
+	// レートが古いままの間の再通知間隔（秒、0なら再通知しない）
+	RateStaleAlertSeconds int `default:"600" split_words:"true"`
+
+	// 1日の損失上限(JPY、0なら確認しない)
+	RiskDailyLossLimitJpy float64 `split_words:"true"`
+	// 総資産のピークからの下落率の上限（0〜1、0なら確認しない）
+	RiskMaxDrawdownRatio float64 `split_words:"true"`
+	// 上限に達したら全ポジションを決済するか
+	RiskFlatten bool `split_words:"true"`
+	// 損益の集計間隔（秒）
+	RiskCheckSeconds int `default:"60" split_words:"true"`
+
+	// 1注文の金額上限(JPY、0なら確認しない)
+	GuardMaxOrderJpy float64 `split_words:"true"`
+	// 指値と現在のレートの乖離率の上限（例: 0.05なら±5%、0なら確認しない）
+	GuardMaxRateDeviation float64 `split_words:"true"`
+	// 注文を禁止する通貨ペア（カンマ区切り、例: btc_jpy,mona_jpy）
+	GuardDisabledPairs []string `split_words:"true"`
+	// 期間内の注文数の上限（0なら確認しない）
+	GuardMaxOrders int `split_words:"true"`
+	// 注文数を数える期間（秒）
+	GuardWindowSeconds int `default:"60" split_words:"true"`
+
+	// アラート受信用HTTPサーバーの待受アドレス（例: :8082、未指定なら起動しない）
+	WebhookAddr string `split_words:"true"`
+	// アラートの認証に使う共有シークレット（HMAC-SHA256の鍵、またはペイロードのsecretと照合）
+	WebhookSecret string `split_words:"true"`
+	// アラートによる1回の買い注文の上限(JPY、0なら残高まで)
+	WebhookMaxOrderJpy float64 `split_words:"true"`
 }
 
 func (c *Config) GetTargetPair(Settlement CurrencyType) *CurrencyPair {
@@ -33,7 +83,145 @@ type DB struct {
 
 // SimulatorConfig シミュレーター用設定
 type SimulatorConfig struct {
-	StrategyName    string  `required:"true" split_words:"true"`
-	Slippage        float64 `required:"true" split_words:"true"`
-	RateHistoryFile string  `required:"true" split_words:"true"`
+	StrategyName string  `required:"true" split_words:"true"`
+	Slippage     float64 `required:"true" split_words:"true"`
+	// レートの読み込み元（csv: RateHistoryFile / markets: DBのmarketsテーブル）
+	RateSource string `default:"csv" split_words:"true"`
+	// レート履歴のCSV（RateSourceがcsvのとき）
+	RateHistoryFile string `split_words:"true"`
+	// 読み込む期間（RFC3339か2006-01-02、開始を含み終了を含まない、未指定なら最初/最後まで、RateSourceがmarketsのとき）
+	RateFrom   string  `split_words:"true"`
+	RateTo     string  `split_words:"true"`
+	InitialJPY float64 `default:"100000" split_words:"true"`
+	// 再生する取引履歴のCSV（空なら再生しない、形式はmemory.TickHistoryVersionを参照）
+	TickHistoryFile string `split_words:"true"`
+	// 約定モデル（memory.FillModelを参照、未指定なら指値に達したら全量を約定する）
+	FillPartial             bool    `default:"false" split_words:"true"`
+	FillQueueRatio          float64 `default:"0" split_words:"true"`
+	FillLatencySteps        int     `default:"0" split_words:"true"`
+	FillImpact              float64 `default:"0" split_words:"true"`
+	FillVolumeWindowMinutes int     `default:"60" split_words:"true"`
+	// 成績の書き出し先（JSON、空なら書き出さない）
+	ReportJsonFile string `default:"simulator-report.json" split_words:"true"`
+	// 成績の書き出し先（HTML、空なら書き出さない）
+	ReportHtmlFile string `default:"simulator-report.html" split_words:"true"`
+	// 最適化の評価値に使う指標（profit, sharpe, max_drawdown など）
+	Fitness string `default:"profit" split_words:"true"`
+	// ウォークフォワードの最適化期間（時間、0ならCSV全体で最適化）
+	WalkForwardInSampleHours int `split_words:"true"`
+	// ウォークフォワードの検証期間（時間、最適化期間の後ろに続けて検証期間ずつずらす）
+	WalkForwardOutOfSampleHours int `split_words:"true"`
+	// ウォークフォワードの結果の書き出し先（JSON）
+	WalkForwardReportFile string `default:"ga-walk-forward.json" split_words:"true"`
+	// 最適化で並列に評価する数（0ならCPU数）
+	GaWorkers int `split_words:"true"`
+	// 最適化の途中経過の書き出し先（あれば続きから再開する、空なら書き出さない）
+	GaCheckpointFile string `split_words:"true"`
+	// 最適化するパラメータの探索範囲（戦略と基準の設定ファイルもここで指定する）
+	SearchSpaceFile string `default:"./configs/search-range.toml" split_words:"true"`
+	// 探索アルゴリズム（ga / random / grid / cmaes）
+	Optimizer string `default:"ga" split_words:"true"`
+	// 1回に評価する数（GAとCMA-ESの個体数）
+	OptimizerPopulation int `default:"20" split_words:"true"`
+	// 世代数（GA, CMA-ES）
+	OptimizerGenerations int `default:"10" split_words:"true"`
+	// 評価する数の上限（ランダムサーチ、グリッドサーチ、0ならグリッドサーチは全件）
+	OptimizerBudget int `default:"100" split_words:"true"`
+	// 乱数の種（0ならチェックポイントの値か現在時刻）
+	OptimizerSeed int64 `split_words:"true"`
+	// 最良のパラメータで上書きした戦略の設定ファイルの書き出し先（空なら書き出さない）
+	OptimizerBestConfigFile string `default:"ga-best.toml" split_words:"true"`
+	// モンテカルロシミュレーション（simulator -montecarlo）の経路の数
+	MonteCarloRuns int `default:"100" split_words:"true"`
+	// 騰落率を復元抽出するブロックの長さ（レートの数）
+	MonteCarloBlockSize int `default:"24" split_words:"true"`
+	// 経路ごとのスリッページの上限（Slippageから上限までの一様乱数、上限がSlippage以下なら固定）
+	MonteCarloSlippageMax float64 `split_words:"true"`
+	// 経路ごとの手数料率の範囲（約定額に対する割合）
+	MonteCarloFeeRateMin float64 `split_words:"true"`
+	MonteCarloFeeRateMax float64 `split_words:"true"`
+	// 破産とみなす総資産（初期資産に対する割合）
+	MonteCarloRuinRatio float64 `default:"0.5" split_words:"true"`
+	// 乱数の種（0なら現在時刻）
+	MonteCarloSeed int64 `split_words:"true"`
+	// 並列に実行する数（0ならCPU数）
+	MonteCarloWorkers int `split_words:"true"`
+	// 結果の書き出し先（JSON、空なら書き出さない）
+	MonteCarloReportFile string `default:"simulator-montecarlo.json" split_words:"true"`
+	// 回帰テスト（simulator -scenarios）のシナリオの設定ファイル（空なら組み込みのシナリオ）
+	ScenarioFile string `split_words:"true"`
+	// 実行するシナリオの名前（カンマ区切り、空なら全て）
+	ScenarioNames []string `split_words:"true"`
+	// 結果の書き出し先（JSON、空なら書き出さない）
+	ScenarioReportFile string `default:"simulator-scenarios.json" split_words:"true"`
+}
+
+const (
+	// CsvRateSource レート履歴のCSVから読み込む
+	CsvRateSource = "csv"
+	// MarketsRateSource DBのmarketsテーブルから読み込む
+	MarketsRateSource = "markets"
+)
+
+// ValidRateSource レートの読み込み元の設定を確認
+func (c *SimulatorConfig) ValidRateSource() error {
+	switch c.RateSource {
+	case CsvRateSource:
+		if c.RateHistoryFile == "" {
+			return fmt.Errorf("RateHistoryFile is empty, %v", c.RateHistoryFile)
+		}
+	case MarketsRateSource:
+		if _, _, err := c.RatePeriod(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("RateSource is unknown, %v", c.RateSource)
+	}
+	return nil
+}
+
+// ValidFillModel 約定モデルの設定を確認
+func (c *SimulatorConfig) ValidFillModel() error {
+	if c.FillQueueRatio < 0 {
+		return fmt.Errorf("FillQueueRatio is negative, %v", c.FillQueueRatio)
+	}
+	if c.FillLatencySteps < 0 {
+		return fmt.Errorf("FillLatencySteps is negative, %v", c.FillLatencySteps)
+	}
+	if c.FillImpact < 0 {
+		return fmt.Errorf("FillImpact is negative, %v", c.FillImpact)
+	}
+	if c.FillVolumeWindowMinutes < 0 {
+		return fmt.Errorf("FillVolumeWindowMinutes is negative, %v", c.FillVolumeWindowMinutes)
+	}
+	return nil
+}
+
+// RatePeriod 読み込む期間（未指定ならゼロ値）
+func (c *SimulatorConfig) RatePeriod() (from, to time.Time, err error) {
+	if from, err = ParseDateTime(c.RateFrom); err != nil {
+		return
+	}
+	if to, err = ParseDateTime(c.RateTo); err != nil {
+		return
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		err = fmt.Errorf("RateFrom must be before RateTo, (from:%v, to:%v)", c.RateFrom, c.RateTo)
+	}
+	return
+}
+
+// ParseDateTime RFC3339か2006-01-02（ローカル時刻の0時）の日時を解析（空ならゼロ値）
+func ParseDateTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("datetime is invalid, %v", s)
+	}
+	return t, nil
 }
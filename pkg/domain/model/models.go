@@ -1,7 +1,9 @@
 package model
 
 import (
+	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -41,8 +43,47 @@ type LiquidityType int
 
 // StoreRate 販売所レート
 type StoreRate struct {
-	Pair CurrencyPair
-	Rate float64
+	Pair       CurrencyPair
+	Rate       float64
+	RecordedAt time.Time
+}
+
+// RateFreshness レートの鮮度（最終記録日時と記録間隔の空き）
+type RateFreshness struct {
+	// 期間内の記録数
+	Count int
+	// 最後の記録日時（記録がなければゼロ値）
+	LastRecordedAt time.Time
+	// 記録間隔の最大
+	MaxGap time.Duration
+	// 許容する間隔を超えた空きの数
+	GapCount int
+}
+
+// NewRateFreshness 記録日時（昇順）から鮮度を集計（gapを超える間隔を空きとして数える）
+func NewRateFreshness(recordedAt []time.Time, gap time.Duration) *RateFreshness {
+	f := &RateFreshness{Count: len(recordedAt)}
+	for i, t := range recordedAt {
+		if t.After(f.LastRecordedAt) {
+			f.LastRecordedAt = t
+		}
+		if i == 0 {
+			continue
+		}
+		d := t.Sub(recordedAt[i-1])
+		if d > f.MaxGap {
+			f.MaxGap = d
+		}
+		if gap > 0 && d > gap {
+			f.GapCount++
+		}
+	}
+	return f
+}
+
+// Age 最後の記録からの経過時間
+func (f *RateFreshness) Age(now time.Time) time.Duration {
+	return now.Sub(f.LastRecordedAt)
 }
 
 // OrderRate 注文レート
@@ -177,3 +218,137 @@ type Trade struct {
 	Side      OrderSide
 	CreatedAt time.Time
 }
+
+// PositionState ポジションの状態（決済判定用）
+type PositionState struct {
+	PositionID    uint64
+	HighWaterRate float64
+	ExitReason    string
+}
+
+// BotStatusPairAll 通貨ペアを問わないボット状態のペア名
+const BotStatusPairAll = "all"
+
+// BotStatus ボットの状態
+type BotStatus struct {
+	BotName string
+	// 通貨ペア（btc_jpy など、ペアを問わない場合は all）
+	Pair  string
+	Type  string
+	Value float64
+	Memo  string
+}
+
+// EventType イベント種別
+type EventType int
+
+const (
+	// BuyEvent 買い
+	BuyEvent EventType = 0
+	// SellEvent 売り
+	SellEvent EventType = 1
+	// AlertEvent 外部アラートの受信
+	AlertEvent EventType = 2
+	// RejectedEvent 注文の拒否
+	RejectedEvent EventType = 3
+	// OtherEvent その他
+	OtherEvent EventType = 255
+)
+
+// Event ダッシュボードに表示するイベント
+type Event struct {
+	ID   uint64
+	Pair string
+	Type EventType
+	// 外部アラートのID（アラート以外は空）
+	AlertID    string
+	Memo       string
+	RecordedAt time.Time
+}
+
+// StrategyState 戦略の状態（ボット・戦略・通貨ペア単位のキーと値の組）
+type StrategyState struct {
+	BotName  string
+	Strategy string
+	Pair     string
+	Values   map[string]string
+}
+
+// NewStrategyState 生成
+func NewStrategyState(botName, strategy, pair string) *StrategyState {
+	return &StrategyState{
+		BotName:  botName,
+		Strategy: strategy,
+		Pair:     pair,
+		Values:   map[string]string{},
+	}
+}
+
+// SetBool 真偽値を設定
+func (s *StrategyState) SetBool(key string, v bool) {
+	s.Values[key] = strconv.FormatBool(v)
+}
+
+// Bool 真偽値を取得（未設定ならdef）
+func (s *StrategyState) Bool(key string, def bool) (bool, error) {
+	v, ok := s.Values[key]
+	if !ok {
+		return def, nil
+	}
+	return strconv.ParseBool(v)
+}
+
+// SetFloat 数値を設定
+func (s *StrategyState) SetFloat(key string, v float64) {
+	s.Values[key] = strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// Float 数値を取得（未設定ならdef）
+func (s *StrategyState) Float(key string, def float64) (float64, error) {
+	v, ok := s.Values[key]
+	if !ok {
+		return def, nil
+	}
+	return strconv.ParseFloat(v, 64)
+}
+
+// SetTime 日時を設定（nilなら削除）
+func (s *StrategyState) SetTime(key string, v *time.Time) {
+	if v == nil {
+		delete(s.Values, key)
+		return
+	}
+	s.Values[key] = v.Format(time.RFC3339Nano)
+}
+
+// Time 日時を取得（未設定ならnil）
+func (s *StrategyState) Time(key string) (*time.Time, error) {
+	v, ok := s.Values[key]
+	if !ok {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// SetJSON 任意の値をJSONで設定
+func (s *StrategyState) SetJSON(key string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	s.Values[key] = string(b)
+	return nil
+}
+
+// JSON JSONで設定された値を取得（未設定ならfalse）
+func (s *StrategyState) JSON(key string, v interface{}) (bool, error) {
+	b, ok := s.Values[key]
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal([]byte(b), v)
+}
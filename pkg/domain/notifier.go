@@ -0,0 +1,6 @@
+package domain
+
+// Notifier 通知
+type Notifier interface {
+	Notify(message string) error
+}
@@ -10,6 +10,7 @@ type RateRepository interface {
 	AddRates(*model.CurrencyPair, float64, time.Time) error
 	GetRate(*model.CurrencyPair) (float64, error)
 	GetRates(*model.CurrencyPair, *time.Duration) ([]float64, error)
+	GetRateFreshness(*model.CurrencyPair, *time.Duration, time.Duration) (*model.RateFreshness, error)
 }
 
 // OrderRepository 注文用リポジトリ
@@ -31,6 +32,34 @@ type PositionRepository interface {
 	AddSettleOrder(uint64, *model.Order) (*model.Position, error)
 	CancelSettleOrder(uint64) (*model.Position, error)
 	GetOpenPositions() ([]model.Position, error)
+	GetClosedPositions() ([]model.Position, error)
+}
+
+// PositionStateRepository ポジション状態用リポジトリ
+type PositionStateRepository interface {
+	GetPositionState(positionID uint64) (*model.PositionState, error)
+	UpsertPositionState(*model.PositionState) error
+}
+
+// StrategyStateRepository 戦略の状態用リポジトリ
+type StrategyStateRepository interface {
+	GetStrategyState(botName, strategy, pair string) (*model.StrategyState, error)
+	UpsertStrategyState(*model.StrategyState) error
+}
+
+// EventRepository イベント用リポジトリ
+type EventRepository interface {
+	// RecordEvent イベントを記録
+	RecordEvent(*model.Event) error
+	// RecordAlertEvent アラートを記録（同じアラートIDが記録済みなら記録せずにfalseを返す）
+	RecordAlertEvent(*model.Event) (bool, error)
+	// UpdateEventMemo 記録済みのイベントの内容を更新
+	UpdateEventMemo(id uint64, memo string) error
+}
+
+// BotStatusRepository ボット状態用リポジトリ
+type BotStatusRepository interface {
+	UpsertBotStatuses([]model.BotStatus) error
 }
 
 type TradeRepository interface {
@@ -43,9 +72,15 @@ type TradeRepository interface {
 	AddSettleOrder(uint64, *model.Order) (*model.Position, error)
 	CancelSettleOrder(uint64) (*model.Position, error)
 	GetOpenPositions() ([]model.Position, error)
+	GetClosedPositions() ([]model.Position, error)
+	GetPositionState(positionID uint64) (*model.PositionState, error)
+	UpsertPositionState(*model.PositionState) error
+	GetStrategyState(botName, strategy, pair string) (*model.StrategyState, error)
+	UpsertStrategyState(*model.StrategyState) error
 	TruncateAll() error
 	GetProfit() (float64, error)
 	AddRates(*model.CurrencyPair, float64, time.Time) error
 	GetRate(*model.CurrencyPair) (float64, error)
 	GetRates(*model.CurrencyPair, *time.Duration) ([]float64, error)
+	GetRateFreshness(*model.CurrencyPair, *time.Duration, time.Duration) (*model.RateFreshness, error)
 }
@@ -9,7 +9,10 @@ import (
 type DummyRDS struct {
 	orders      map[uint64]*model.Order
 	positions   map[uint64]*model.Position
+	posStates   map[uint64]*model.PositionState
+	stStates    map[string]*model.StrategyState
 	contracts   map[uint64]*model.Contract
+	events      []model.Event
 	profit      float64
 	rates       []model.StoreRate
 	rateMaxSize *int
@@ -19,7 +22,10 @@ func NewDummyRDS(rateMaxSize *int) *DummyRDS {
 	return &DummyRDS{
 		orders:      map[uint64]*model.Order{},
 		positions:   map[uint64]*model.Position{},
+		posStates:   map[uint64]*model.PositionState{},
+		stStates:    map[string]*model.StrategyState{},
 		contracts:   map[uint64]*model.Contract{},
+		events:      []model.Event{},
 		profit:      0,
 		rates:       []model.StoreRate{},
 		rateMaxSize: rateMaxSize,
@@ -33,6 +39,9 @@ func (d *DummyRDS) GetOrder(id uint64) (*model.Order, error) {
 func (d *DummyRDS) GetOpenOrders() ([]model.Order, error) {
 	orders := []model.Order{}
 	for _, v := range d.orders {
+		if v.Status != model.Open {
+			continue
+		}
 		orders = append(orders, *v)
 	}
 	return orders, nil
@@ -45,7 +54,10 @@ func (d *DummyRDS) UpdateCloseOrderID(id, closerOrderID uint64) (*model.Position
 }
 
 func (d *DummyRDS) UpdateStatus(orderID uint64, status model.OrderStatus) error {
-	d.orders[orderID].Status = status
+	// DBにない注文は何もしない（MySQLのUPDATEと同じ）
+	if o, ok := d.orders[orderID]; ok {
+		o.Status = status
+	}
 	return nil
 }
 
@@ -119,6 +131,9 @@ func (d *DummyRDS) CancelSettleOrder(positionID uint64) (*model.Position, error)
 func (d *DummyRDS) GetOpenPositions() ([]model.Position, error) {
 	pp := []model.Position{}
 	for _, p := range d.positions {
+		if p.OpenerOrder.Status == model.Canceled {
+			continue
+		}
 		if p.CloserOrder == nil || p.CloserOrder.Status == model.Open {
 			pp = append(pp, *p)
 		}
@@ -126,9 +141,97 @@ func (d *DummyRDS) GetOpenPositions() ([]model.Position, error) {
 	return pp, nil
 }
 
+func (d *DummyRDS) GetClosedPositions() ([]model.Position, error) {
+	pp := []model.Position{}
+	for id := uint64(1); id <= uint64(len(d.positions)); id++ {
+		p, ok := d.positions[id]
+		if !ok {
+			continue
+		}
+		if p.CloserOrder != nil && p.CloserOrder.Status == model.Closed {
+			pp = append(pp, *p)
+		}
+	}
+	return pp, nil
+}
+
+func (d *DummyRDS) GetPositionState(positionID uint64) (*model.PositionState, error) {
+	s, ok := d.posStates[positionID]
+	if !ok {
+		return nil, nil
+	}
+	copied := *s
+	return &copied, nil
+}
+
+func (d *DummyRDS) UpsertPositionState(s *model.PositionState) error {
+	copied := *s
+	d.posStates[s.PositionID] = &copied
+	return nil
+}
+
+func (d *DummyRDS) GetStrategyState(botName, strategy, pair string) (*model.StrategyState, error) {
+	copied := model.NewStrategyState(botName, strategy, pair)
+	if s, ok := d.stStates[strategyStateKey(botName, strategy, pair)]; ok {
+		for k, v := range s.Values {
+			copied.Values[k] = v
+		}
+	}
+	return copied, nil
+}
+
+func (d *DummyRDS) UpsertStrategyState(s *model.StrategyState) error {
+	copied := model.NewStrategyState(s.BotName, s.Strategy, s.Pair)
+	for k, v := range s.Values {
+		copied.Values[k] = v
+	}
+	d.stStates[strategyStateKey(s.BotName, s.Strategy, s.Pair)] = copied
+	return nil
+}
+
+func strategyStateKey(botName, strategy, pair string) string {
+	return fmt.Sprintf("%s/%s/%s", botName, strategy, pair)
+}
+
+// RecordEvent イベントを記録
+func (d *DummyRDS) RecordEvent(e *model.Event) error {
+	e.ID = uint64(len(d.events) + 1)
+	d.events = append(d.events, *e)
+	return nil
+}
+
+// RecordAlertEvent アラートを記録（同じアラートIDが記録済みなら記録せずにfalseを返す）
+func (d *DummyRDS) RecordAlertEvent(e *model.Event) (bool, error) {
+	if e.AlertID == "" {
+		return false, fmt.Errorf("alert id is empty")
+	}
+	for _, v := range d.events {
+		if v.AlertID == e.AlertID {
+			return false, nil
+		}
+	}
+	return true, d.RecordEvent(e)
+}
+
+// UpdateEventMemo 記録済みのイベントの内容を更新
+func (d *DummyRDS) UpdateEventMemo(id uint64, memo string) error {
+	if id == 0 || int(id) > len(d.events) {
+		return fmt.Errorf("event is not found, id:%d", id)
+	}
+	d.events[id-1].Memo = memo
+	return nil
+}
+
+// Events 記録済みのイベント
+func (d *DummyRDS) Events() []model.Event {
+	return d.events
+}
+
 func (d *DummyRDS) TruncateAll() error {
 	d.orders = map[uint64]*model.Order{}
 	d.positions = map[uint64]*model.Position{}
+	d.posStates = map[uint64]*model.PositionState{}
+	d.stStates = map[string]*model.StrategyState{}
 	d.contracts = map[uint64]*model.Contract{}
 	d.profit = 0
 	return nil
@@ -141,8 +244,9 @@ func (d *DummyRDS) GetProfit() (float64, error) {
 // AddRates レート追加
 func (d *DummyRDS) AddRates(p *model.CurrencyPair, rate float64, t time.Time) error {
 	d.rates = append(d.rates, model.StoreRate{
-		Pair: *p,
-		Rate: rate,
+		Pair:       *p,
+		Rate:       rate,
+		RecordedAt: t,
 	})
 	if d.rateMaxSize != nil && len(d.rates) > *d.rateMaxSize {
 		d.rates = d.rates[1:]
@@ -169,3 +273,12 @@ func (d *DummyRDS) GetRates(p *model.CurrencyPair, duration *time.Duration) ([]f
 
 	return h, nil
 }
+
+// GetRateFreshness レートの鮮度を取得（GetRatesと同じく期間は考慮しない）
+func (d *DummyRDS) GetRateFreshness(p *model.CurrencyPair, duration *time.Duration, gap time.Duration) (*model.RateFreshness, error) {
+	recordedAt := []time.Time{}
+	for _, r := range d.rates {
+		recordedAt = append(recordedAt, r.RecordedAt)
+	}
+	return model.NewRateFreshness(recordedAt, gap), nil
+}
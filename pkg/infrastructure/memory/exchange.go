@@ -1,9 +1,11 @@
 package memory
 
 import (
-	"encoding/csv"
+	"context"
 	"fmt"
 	"io"
+	"math"
+	"sort"
 	"strconv"
 	"time"
 	"trading-bot/pkg/domain/model"
@@ -12,15 +14,22 @@ import (
 // Rate レート
 type Rate struct {
 	Datetime      string
+	Time          time.Time
 	StoreRate     float64
 	OrderBuyRate  float64
 	OrderSellRate float64
+	// 取引量（前のレートからの間の取引量、履歴になければ0）
+	BuyVolume  float64
+	SellVolume float64
 }
 
-// NewRate レートを生成
+// NewRate レートを生成（CSVの形式はHistoryVersionを参照）
 func NewRate(v []string) (*Rate, error) {
+	if len(v) == len(historyHeader) {
+		return newRateWithVolumes(v)
+	}
 	if len(v) != 3 {
-		return nil, fmt.Errorf("csv is not 3 columns, [%d columns]", len(v))
+		return nil, fmt.Errorf("csv is not 3 or %d columns, [%d columns]", len(historyHeader), len(v))
 	}
 	buyRate, err := strconv.ParseFloat(v[1], 32)
 	if err != nil {
@@ -30,50 +39,187 @@ func NewRate(v []string) (*Rate, error) {
 	if err != nil {
 		return nil, err
 	}
+	t, err := time.Parse(time.RFC3339, v[0])
+	if err != nil {
+		return nil, err
+	}
 
 	return &Rate{
 		Datetime:      v[0],
+		Time:          t,
 		StoreRate:     sellRate,
 		OrderBuyRate:  buyRate,
 		OrderSellRate: sellRate,
 	}, nil
 }
 
+func newRateWithVolumes(v []string) (*Rate, error) {
+	t, err := time.Parse(time.RFC3339, v[0])
+	if err != nil {
+		return nil, err
+	}
+	values := []float64{}
+	for _, s := range v[1:] {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, f)
+	}
+	rate := NewRateWithVolumes(t, values[0], values[1], values[2], values[3], values[4])
+	return &rate, nil
+}
+
+const (
+	// defaultBalanceJPY 初期残高(JPY)
+	defaultBalanceJPY = 100000
+	// volumeHistory GetVolumesのために残しておくレートの期間
+	volumeHistory = 24 * time.Hour
+	// filledTolerance 全量を約定したとみなす誤差（数量に対する割合）
+	filledTolerance = 1e-9
+)
+
 // ExchangeMock 取引所モック
 type ExchangeMock struct {
-	rateReader *csv.Reader
-	slippage   float64
-	Rate       Rate
-	orders     []model.Order
-	contracts  []model.Contract
+	// 次のレートを読む（なければnil）
+	nextRate  func() (*Rate, error)
+	slippage  float64
+	Rate      Rate
+	orders    []model.Order
+	contracts []model.Contract
+	balances  map[model.CurrencyType]float64
+	// 注文の約定日時
+	closedAt map[uint64]time.Time
+	// 直近のレート（取引量の集計用、古い順）
+	recent []Rate
+	// 先読みした次のレート（取引の再生で次のレートの日時を知るため）
+	peeked *Rate
+	// 再生する取引（日時順）と次に再生する取引の位置
+	trades     []model.Trade
+	tradeIndex int
+	// シミュレーション上の現在日時（レートか再生中の取引の日時）
+	now time.Time
+	// 約定モデル
+	fillModel FillModel
+	// 手数料率（約定額に対する割合、決済通貨で払う）
+	feeRate float64
+	// NextStepの回数と注文が取引所に届くステップ
+	step     int
+	activeAt map[uint64]int
+	// 注文ごとの約定済みの数量と先に並んでいる数量の見積もり
+	filled map[uint64]float64
+	queue  map[uint64]float64
 }
 
 // NewExchangeMock 生成
 func NewExchangeMock(r io.Reader, slippage float64) (*ExchangeMock, error) {
-	reader := csv.NewReader(r)
-
-	// ヘッダを読み飛ばす
-	_, err := reader.Read()
+	next, err := newRateReader(r)
 	if err != nil {
 		return nil, err
 	}
-
-	record, err := reader.Read()
+	rate, err := next()
 	if err != nil {
 		return nil, err
 	}
-	rate, err := NewRate(record)
-	if err != nil {
-		return nil, err
+	return newExchangeMock(next, *rate, slippage), nil
+}
+
+// NewExchangeMockWithRates 読み込み済みのレートで生成（期間を区切ったシミュレーション用）
+func NewExchangeMockWithRates(rates []Rate, slippage float64) (*ExchangeMock, error) {
+	if len(rates) == 0 {
+		return nil, fmt.Errorf("rates is empty")
 	}
+	i := 0
+	return newExchangeMock(func() (*Rate, error) {
+		i++
+		if i >= len(rates) {
+			return nil, io.EOF
+		}
+		return &rates[i], nil
+	}, rates[0], slippage), nil
+}
 
+func newExchangeMock(nextRate func() (*Rate, error), rate Rate, slippage float64) *ExchangeMock {
 	return &ExchangeMock{
-		rateReader: reader,
-		slippage:   slippage,
-		Rate:       *rate,
-		orders:     []model.Order{},
-		contracts:  []model.Contract{},
-	}, nil
+		nextRate:  nextRate,
+		slippage:  slippage,
+		Rate:      rate,
+		orders:    []model.Order{},
+		contracts: []model.Contract{},
+		balances:  map[model.CurrencyType]float64{model.JPY: defaultBalanceJPY},
+		closedAt:  map[uint64]time.Time{},
+		recent:    []Rate{rate},
+		now:       rate.Time,
+		activeAt:  map[uint64]int{},
+		filled:    map[uint64]float64{},
+		queue:     map[uint64]float64{},
+	}
+}
+
+// SetFeeRate 手数料率を設定（約定額に対する割合、決済通貨で払う）
+func (e *ExchangeMock) SetFeeRate(rate float64) {
+	e.feeRate = rate
+}
+
+// SetFillModel 約定モデルを設定（注文する前に設定する）
+func (e *ExchangeMock) SetFillModel(m FillModel) {
+	e.fillModel = m
+}
+
+// SetTrades 再生する取引を設定（日時順でなければ複製して並べ替える）
+// 設定するとGetVolumesは取引量を取引から集計する
+func (e *ExchangeMock) SetTrades(trades []model.Trade) {
+	less := func(t []model.Trade) func(i, j int) bool {
+		return func(i, j int) bool { return t[i].CreatedAt.Before(t[j].CreatedAt) }
+	}
+	e.trades = trades
+	if !sort.SliceIsSorted(trades, less(trades)) {
+		e.trades = append([]model.Trade{}, trades...)
+		sort.SliceStable(e.trades, less(e.trades))
+	}
+	e.tradeIndex = 0
+}
+
+// NextTrade 現在のレートから次のレートの前までの取引を日時順に1件ずつ返す（なければfalse、最後のレートの後は再生しない）
+// 現在日時は返した取引の日時に進め、未約定の注文は取引のレートで約定を確認する
+func (e *ExchangeMock) NextTrade() (*model.Trade, bool) {
+	// 現在のレートより前の取引は読み飛ばす
+	for e.tradeIndex < len(e.trades) && e.trades[e.tradeIndex].CreatedAt.Before(e.Rate.Time) {
+		e.tradeIndex++
+	}
+	if e.tradeIndex >= len(e.trades) {
+		return nil, false
+	}
+	t := e.trades[e.tradeIndex]
+	if next := e.peek(); next == nil || !t.CreatedAt.Before(next.Time) {
+		return nil, false
+	}
+	e.tradeIndex++
+	if t.CreatedAt.After(e.now) {
+		e.now = t.CreatedAt
+	}
+
+	for _, o := range e.orders {
+		e.closeOrderByTrade(o.ID, &t)
+	}
+	return &t, true
+}
+
+// peek 次のレートを先読み（なければnil）
+func (e *ExchangeMock) peek() *Rate {
+	if e.peeked == nil {
+		rate, err := e.nextRate()
+		if err != nil {
+			return nil
+		}
+		e.peeked = rate
+	}
+	return e.peeked
+}
+
+// SetBalance 残高を設定
+func (e *ExchangeMock) SetBalance(currency model.CurrencyType, amount float64) {
+	e.balances[currency] = amount
 }
 
 // GetStoreRate 販売所のレートを取得
@@ -104,15 +250,15 @@ func (e *ExchangeMock) GetOrderRate(p *model.CurrencyPair, side model.OrderSide)
 func (e *ExchangeMock) GetBalance(currency model.CurrencyType) (*model.Balance, error) {
 	return &model.Balance{
 		Currency: currency,
-		Amount:   100000,
+		Amount:   e.balances[currency],
 	}, nil
 }
 
 // GetOpenOrders 未決済の注文を取得
-func (e *ExchangeMock) GetOpenOrders(*model.CurrencyPair) ([]model.Order, error) {
+func (e *ExchangeMock) GetOpenOrders(pair *model.CurrencyPair) ([]model.Order, error) {
 	oo := []model.Order{}
 	for _, o := range e.orders {
-		if o.Status == model.Open {
+		if o.Status == model.Open && (pair == nil || o.Pair == *pair) {
 			oo = append(oo, o)
 		}
 	}
@@ -133,11 +279,6 @@ func (e *ExchangeMock) PostOrder(o *model.NewOrder) (*model.Order, error) {
 		amount = *o.Amount
 	}
 
-	orderedAt, err := time.Parse(time.RFC3339, e.Rate.Datetime)
-	if err != nil {
-		return nil, err
-	}
-
 	order := model.Order{
 		ID:           uint64(len(e.orders) + 1),
 		Type:         o.Type,
@@ -146,9 +287,11 @@ func (e *ExchangeMock) PostOrder(o *model.NewOrder) (*model.Order, error) {
 		Rate:         o.Rate,
 		StopLossRate: o.StopLossRate,
 		Status:       model.Open,
-		OrderedAt:    orderedAt,
+		OrderedAt:    e.Now(),
 	}
 	e.orders = append(e.orders, order)
+	e.activeAt[order.ID] = e.step + e.fillModel.Latency
+	e.queue[order.ID] = e.estimateQueue(&order)
 
 	if order.Type == model.MarketBuy || order.Type == model.MarketSell {
 		e.closeOrder(order.ID)
@@ -163,17 +306,31 @@ func (e *ExchangeMock) DeleteOrder(id uint64) error {
 	return nil
 }
 
+// Now シミュレーション上の現在日時（現在のレートか再生中の取引の日時）
+func (e *ExchangeMock) Now() time.Time {
+	return e.now
+}
+
+// Sleep 待機しない（時刻はNextStepで進める）
+func (e *ExchangeMock) Sleep(ctx context.Context, d time.Duration) error {
+	return nil
+}
+
 // NextStep 次のステップに進める
 func (e *ExchangeMock) NextStep() bool {
-	record, err := e.rateReader.Read()
-	if err != nil {
-		return false
-	}
-	rate, err := NewRate(record)
-	if err != nil {
+	rate := e.peek()
+	if rate == nil {
 		return false
 	}
+	e.peeked = nil
+	e.step++
 	e.Rate = *rate
+	e.now = rate.Time
+	e.recent = append(e.recent, *rate)
+	border := rate.Time.Add(-volumeHistory)
+	for len(e.recent) > 0 && e.recent[0].Time.Before(border) {
+		e.recent = e.recent[1:]
+	}
 
 	for _, o := range e.orders {
 		e.closeOrder(o.ID)
@@ -183,37 +340,33 @@ func (e *ExchangeMock) NextStep() bool {
 }
 
 func (e *ExchangeMock) closeOrder(orderID uint64) {
+	e.closeOrderAt(orderID, e.barQuote())
+}
+
+// closeOrderByTrade 取引のレートで未約定の注文の約定を確認
+func (e *ExchangeMock) closeOrderByTrade(orderID uint64, t *model.Trade) {
+	e.closeOrderAt(orderID, tradeQuote(t))
+}
+
+// closeOrderAt レートと取引量で未約定の注文の約定を確認（約定モデルはFillModelを参照）
+func (e *ExchangeMock) closeOrderAt(orderID uint64, q quote) {
 	o := &e.orders[orderID-1]
-	if o.Status != model.Open {
+	if o.Status != model.Open || e.step < e.activeAt[o.ID] {
 		return
 	}
 
 	var contract *model.Contract
 	switch o.Type {
 	case model.Buy:
-		if o.Rate != nil && (*o.Rate) >= e.Rate.OrderBuyRate {
-			o.Status = model.Closed
-		} else if o.StopLossRate != nil && (*o.StopLossRate) <= e.Rate.OrderBuyRate {
-			o.Status = model.Closed
-		}
-		if o.Status == model.Closed {
-			contract = &model.Contract{
-				ID:               uint64(len(e.contracts) + 1),
-				OrderID:          o.ID,
-				Rate:             e.Rate.OrderBuyRate,
-				IncreaseCurrency: o.Pair.Key,
-				IncreaseAmount:   o.Amount / e.Rate.OrderBuyRate,
-				DecreaseCurrency: o.Pair.Settlement,
-				DecreaseAmount:   -o.Amount,
-				FeeCurrency:      "",
-				Fee:              0,
-				Liquidity:        model.Taker,
-				Side:             model.BuySide,
-			}
+		// 指値に達したら指値で約定（部分約定あり）、逆指値に達したら現在レートで残りを約定
+		if o.Rate != nil && (*o.Rate) >= q.buyRate {
+			amount := e.limitFillAmount(o, (*o.Rate) > q.buyRate, q, q.buyLimitVolume)
+			contract = e.buyContract(o, *o.Rate, amount, model.Maker)
+		} else if o.StopLossRate != nil && (*o.StopLossRate) <= q.buyRate {
+			contract = e.buyContract(o, q.buyRate, o.Amount-e.filled[o.ID], model.Taker)
 		}
 	case model.MarketBuy:
-		o.Status = model.Closed
-		rate := e.Rate.OrderBuyRate * (1.00 + e.slippage)
+		rate := q.buyRate * (1.00 + e.marketSlippage(&o.Pair, o.Amount/q.buyRate))
 		contract = &model.Contract{
 			ID:               uint64(len(e.contracts) + 1),
 			OrderID:          o.ID,
@@ -227,51 +380,110 @@ func (e *ExchangeMock) closeOrder(orderID uint64) {
 			Liquidity:        model.Taker,
 			Side:             model.BuySide,
 		}
+		e.filled[o.ID] = o.Amount
 	case model.Sell:
-		if o.Rate != nil && (*o.Rate) <= e.Rate.OrderSellRate {
-			o.Status = model.Closed
-		} else if o.StopLossRate != nil && (*o.StopLossRate) >= e.Rate.OrderSellRate {
-			o.Status = model.Closed
-		}
-		if o.Status == model.Closed {
-			contract = &model.Contract{
-				ID:               uint64(len(e.contracts) + 1),
-				OrderID:          o.ID,
-				Rate:             e.Rate.OrderSellRate,
-				IncreaseCurrency: o.Pair.Settlement,
-				IncreaseAmount:   o.Amount * e.Rate.OrderBuyRate,
-				DecreaseCurrency: o.Pair.Key,
-				DecreaseAmount:   -o.Amount,
-				FeeCurrency:      "",
-				Fee:              0,
-				Liquidity:        model.Taker,
-				Side:             model.SellSide,
-			}
+		// 指値に達したら指値で約定（部分約定あり）、逆指値に達したら現在レートで残りを約定
+		if o.Rate != nil && (*o.Rate) <= q.sellRate {
+			amount := e.limitFillAmount(o, (*o.Rate) < q.sellRate, q, q.sellLimitVolume)
+			contract = e.sellContract(o, *o.Rate, amount, model.Maker)
+		} else if o.StopLossRate != nil && (*o.StopLossRate) >= q.sellRate {
+			contract = e.sellContract(o, q.sellRate, o.Amount-e.filled[o.ID], model.Taker)
 		}
 	case model.MarketSell:
-		o.Status = model.Closed
-		rate := e.Rate.OrderSellRate * (1.00 - e.slippage)
-		contract = &model.Contract{
-			ID:               uint64(len(e.contracts) + 1),
-			OrderID:          o.ID,
-			Rate:             rate,
-			IncreaseCurrency: o.Pair.Settlement,
-			IncreaseAmount:   o.Amount * rate,
-			DecreaseCurrency: o.Pair.Key,
-			DecreaseAmount:   -o.Amount,
-			FeeCurrency:      "",
-			Fee:              0,
-			Liquidity:        model.Taker,
-			Side:             model.SellSide,
+		rate := q.sellRate * (1.00 - e.marketSlippage(&o.Pair, o.Amount))
+		contract = e.sellContract(o, rate, o.Amount, model.Taker)
+	}
+
+	if contract == nil {
+		return
+	}
+	if e.feeRate > 0 {
+		contract.FeeCurrency = o.Pair.Settlement
+		contract.Fee = math.Abs(contract.IncreaseAmount) * e.feeRate
+		if contract.DecreaseCurrency == o.Pair.Settlement {
+			contract.Fee = math.Abs(contract.DecreaseAmount) * e.feeRate
 		}
+		e.balances[contract.FeeCurrency] -= contract.Fee
+	}
+	e.contracts = append(e.contracts, *contract)
+	e.balances[contract.IncreaseCurrency] += contract.IncreaseAmount
+	e.balances[contract.DecreaseCurrency] += contract.DecreaseAmount
+	if e.filled[o.ID] >= o.Amount*(1-filledTolerance) {
+		o.Status = model.Closed
+		e.closedAt[o.ID] = e.Now()
+	}
+}
+
+// buyContract 買いの約定（数量が0ならnil）
+func (e *ExchangeMock) buyContract(o *model.Order, rate, amount float64, liquidity model.LiquidityType) *model.Contract {
+	if amount <= 0 {
+		return nil
+	}
+	e.filled[o.ID] += amount
+	return &model.Contract{
+		ID:               uint64(len(e.contracts) + 1),
+		OrderID:          o.ID,
+		Rate:             rate,
+		IncreaseCurrency: o.Pair.Key,
+		IncreaseAmount:   amount,
+		DecreaseCurrency: o.Pair.Settlement,
+		DecreaseAmount:   -amount * rate,
+		FeeCurrency:      "",
+		Fee:              0,
+		Liquidity:        liquidity,
+		Side:             model.BuySide,
 	}
+}
 
-	if contract != nil {
-		e.contracts = append(e.contracts, *contract)
+// sellContract 売りの約定（数量が0ならnil）
+func (e *ExchangeMock) sellContract(o *model.Order, rate, amount float64, liquidity model.LiquidityType) *model.Contract {
+	if amount <= 0 {
+		return nil
 	}
+	e.filled[o.ID] += amount
+	return &model.Contract{
+		ID:               uint64(len(e.contracts) + 1),
+		OrderID:          o.ID,
+		Rate:             rate,
+		IncreaseCurrency: o.Pair.Settlement,
+		IncreaseAmount:   amount * rate,
+		DecreaseCurrency: o.Pair.Key,
+		DecreaseAmount:   -amount,
+		FeeCurrency:      "",
+		Fee:              0,
+		Liquidity:        liquidity,
+		Side:             model.SellSide,
+	}
+}
+
+// ClosedAt 注文の約定日時（未約定ならfalse）
+func (e *ExchangeMock) ClosedAt(orderID uint64) (time.Time, bool) {
+	t, ok := e.closedAt[orderID]
+	return t, ok
 }
 
-// GetVolumes 取引量を取得
-func (e *ExchangeMock) GetVolumes(*model.CurrencyPair, model.OrderSide, time.Duration) (float64, error) {
-	return 0, nil
+// GetVolumes 取引量を取得（現在日時から期間内の取引量の合計）
+// 再生する取引があれば再生済みの取引から、なければレートの取引量から集計する（最大でvolumeHistoryまで）
+func (e *ExchangeMock) GetVolumes(_ *model.CurrencyPair, side model.OrderSide, d time.Duration) (float64, error) {
+	border := e.Now().Add(-d)
+	volume := 0.0
+	if len(e.trades) > 0 {
+		for i := e.tradeIndex - 1; i >= 0 && e.trades[i].CreatedAt.After(border); i-- {
+			if e.trades[i].Side == side && !e.trades[i].CreatedAt.After(e.Now()) {
+				volume += e.trades[i].Amount
+			}
+		}
+		return volume, nil
+	}
+	for _, r := range e.recent {
+		if !r.Time.After(border) {
+			continue
+		}
+		if side == model.BuySide {
+			volume += r.BuyVolume
+		} else {
+			volume += r.SellVolume
+		}
+	}
+	return volume, nil
 }
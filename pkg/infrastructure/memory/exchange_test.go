@@ -1,8 +1,10 @@
 package memory_test
 
 import (
+	"context"
 	"strings"
 	"testing"
+	"time"
 	"trading-bot/pkg/domain/model"
 	"trading-bot/pkg/infrastructure/memory"
 )
@@ -283,3 +285,131 @@ func TestExchangeMock_CloseSellOrder_2step(t *testing.T) {
 		t.Errorf("Contract is not contains order\ncontracts: %#v", contracts)
 	}
 }
+
+func TestExchangeMock_Clock(t *testing.T) {
+	rates := []string{
+		"日付, 販売所買い価格, 販売所売り価格",
+		"2021-02-23T19:27:01Z,200.0,199.0",
+		"2021-02-23T20:27:01Z,200.0,199.0",
+	}
+	mock, err := memory.NewExchangeMock(strings.NewReader(strings.Join(rates, "\n")), 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	want := time.Date(2021, 2, 23, 19, 27, 1, 0, time.UTC)
+	if got := mock.Now(); !got.Equal(want) {
+		t.Errorf("Now() = %v, want %v", got, want)
+	}
+
+	// 実時間では待機しない
+	started := time.Now()
+	if err := mock.Sleep(context.Background(), time.Hour); err != nil {
+		t.Fatal(err.Error())
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("Sleep() took %v, want no wait", elapsed)
+	}
+
+	mock.NextStep()
+	want = want.Add(time.Hour)
+	if got := mock.Now(); !got.Equal(want) {
+		t.Errorf("Now() after NextStep = %v, want %v", got, want)
+	}
+
+	amount := 1000.0
+	order, err := mock.PostOrder(&model.NewOrder{Type: model.MarketBuy, Pair: model.BtcJpy, MarketBuyAmount: &amount})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if !order.OrderedAt.Equal(want) {
+		t.Errorf("OrderedAt = %v, want %v", order.OrderedAt, want)
+	}
+}
+
+func TestExchangeMock_FeeRate(t *testing.T) {
+	rates := []string{
+		"日付, 販売所買い価格, 販売所売り価格",
+		"2021-02-23T19:27:01Z,1000.0,1000.0",
+	}
+	mock, err := memory.NewExchangeMock(strings.NewReader(strings.Join(rates, "\n")), 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	mock.SetBalance(model.JPY, 10000)
+	mock.SetFeeRate(0.01)
+
+	jpy := 5000.0
+	if _, err := mock.PostOrder(&model.NewOrder{Type: model.MarketBuy, Pair: model.BtcJpy, MarketBuyAmount: &jpy}); err != nil {
+		t.Fatal(err.Error())
+	}
+	amount := 5.0
+	if _, err := mock.PostOrder(&model.NewOrder{Type: model.MarketSell, Pair: model.BtcJpy, Amount: &amount}); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// 約定額の1%を円で払う
+	contracts, _ := mock.GetContracts()
+	if len(contracts) != 2 || contracts[0].Fee != 50 || contracts[1].Fee != 50 || contracts[0].FeeCurrency != model.JPY {
+		t.Errorf("contracts = %+v, want fee 50 JPY each", contracts)
+	}
+	if b, _ := mock.GetBalance(model.JPY); b.Amount != 10000-100 {
+		t.Errorf("JPY balance = %v, want %v", b.Amount, 10000-100)
+	}
+}
+
+func TestExchangeMock_LimitContract(t *testing.T) {
+	rates := []string{
+		"日付, 販売所買い価格, 販売所売り価格",
+		"2021-02-23T19:27:01Z,1000.0,990.0",
+		"2021-02-23T19:27:02Z,900.0,890.0",
+		"2021-02-23T19:27:03Z,1100.0,1090.0",
+	}
+	tests := map[string]struct {
+		order model.NewOrder
+		want  model.Contract
+	}{
+		// 指値に達したら指値で数量分を約定
+		"buy limit": {
+			order: model.NewOrder{Type: model.Buy, Rate: floatPtr(950), Amount: floatPtr(2)},
+			want:  model.Contract{Rate: 950, IncreaseAmount: 2, DecreaseAmount: -1900, Liquidity: model.Maker, Side: model.BuySide},
+		},
+		"sell limit": {
+			order: model.NewOrder{Type: model.Sell, Rate: floatPtr(1050), Amount: floatPtr(2)},
+			want:  model.Contract{Rate: 1050, IncreaseAmount: 2100, DecreaseAmount: -2, Liquidity: model.Maker, Side: model.SellSide},
+		},
+		// 逆指値に達したら売りのレートで評価する
+		"sell stop loss": {
+			order: model.NewOrder{Type: model.Sell, StopLossRate: floatPtr(950), Amount: floatPtr(2)},
+			want:  model.Contract{Rate: 890, IncreaseAmount: 1780, DecreaseAmount: -2, Liquidity: model.Taker, Side: model.SellSide},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			mock, err := memory.NewExchangeMock(strings.NewReader(strings.Join(rates, "\n")), 0)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			o := tt.order
+			o.Pair = model.BtcJpy
+			if _, err := mock.PostOrder(&o); err != nil {
+				t.Fatal(err.Error())
+			}
+			for mock.NextStep() {
+			}
+			contracts, _ := mock.GetContracts()
+			if len(contracts) != 1 {
+				t.Fatalf("contracts = %+v, want 1 contract", contracts)
+			}
+			c := contracts[0]
+			if c.Rate != tt.want.Rate || c.IncreaseAmount != tt.want.IncreaseAmount || c.DecreaseAmount != tt.want.DecreaseAmount ||
+				c.Liquidity != tt.want.Liquidity || c.Side != tt.want.Side {
+				t.Errorf("contract = %+v, want %+v", c, tt.want)
+			}
+		})
+	}
+}
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
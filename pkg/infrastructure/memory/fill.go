@@ -0,0 +1,122 @@
+package memory
+
+import (
+	"math"
+	"time"
+	"trading-bot/pkg/domain/model"
+)
+
+// FillModel 注文の約定モデル（ゼロ値は指値に達したら全量を約定し、成行は固定のスリッページで約定する）
+type FillModel struct {
+	// 指値に達した取引量だけ部分的に約定する（取引量が分からないときは全量）
+	Partial bool
+	// 注文時に同じ価格で先に並んでいる数量の見積もり（VolumeWindowの約定側の取引量に対する割合、Partialのとき）
+	QueueRatio float64
+	// 注文が取引所に届くまでのステップ数（NextStepの回数）
+	Latency int
+	// 成行の数量が取引量に占める割合あたりに加えるスリッページ
+	Impact float64
+	// 待ち行列とスリッページの見積もりに使う直近の取引量の期間
+	VolumeWindow time.Duration
+}
+
+// NewFillModel シミュレーターの設定から生成
+func NewFillModel(c *model.SimulatorConfig) FillModel {
+	return FillModel{
+		Partial:      c.FillPartial,
+		QueueRatio:   c.FillQueueRatio,
+		Latency:      c.FillLatencySteps,
+		Impact:       c.FillImpact,
+		VolumeWindow: time.Duration(c.FillVolumeWindowMinutes) * time.Minute,
+	}
+}
+
+// quote 注文の約定を確認するレートと取引量
+type quote struct {
+	buyRate  float64
+	sellRate float64
+	// 買いの指値/売りの指値に達した取引量（負なら不明）
+	buyLimitVolume  float64
+	sellLimitVolume float64
+	// 取引の再生（価格を超えた取引では全量を約定し、価格ちょうどの取引は取引量で約定する）
+	replay bool
+}
+
+// barQuote 現在のレートの約定確認
+// 取引を再生するときは価格を超えた分だけ約定し（価格ちょうどは取引で約定する）、
+// そうでなければレートの取引量で約定する
+func (e *ExchangeMock) barQuote() quote {
+	q := quote{
+		buyRate:         e.Rate.OrderBuyRate,
+		sellRate:        e.Rate.OrderSellRate,
+		buyLimitVolume:  e.Rate.SellVolume,
+		sellLimitVolume: e.Rate.BuyVolume,
+		replay:          len(e.trades) > 0,
+	}
+	if q.replay {
+		q.buyLimitVolume, q.sellLimitVolume = 0, 0
+	} else if e.Rate.BuyVolume == 0 && e.Rate.SellVolume == 0 {
+		q.buyLimitVolume, q.sellLimitVolume = -1, -1
+	}
+	return q
+}
+
+// tradeQuote 再生する取引の約定確認
+// 買いの指値は売りの取引の数量で、売りの指値は買いの取引の数量で約定する
+func tradeQuote(t *model.Trade) quote {
+	q := quote{
+		buyRate:  t.Rate,
+		sellRate: t.Rate,
+		replay:   true,
+	}
+	switch t.Side {
+	case model.SellSide:
+		q.buyLimitVolume = t.Amount
+	case model.BuySide:
+		q.sellLimitVolume = t.Amount
+	}
+	return q
+}
+
+// limitFillAmount 指値の注文が約定する数量
+func (e *ExchangeMock) limitFillAmount(o *model.Order, through bool, q quote, volume float64) float64 {
+	remaining := o.Amount - e.filled[o.ID]
+	if !e.fillModel.Partial || volume < 0 || (q.replay && through) {
+		return remaining
+	}
+	queue := e.queue[o.ID]
+	if volume <= queue {
+		e.queue[o.ID] = queue - volume
+		return 0
+	}
+	e.queue[o.ID] = 0
+	return math.Min(remaining, volume-queue)
+}
+
+// estimateQueue 注文時に先に並んでいる数量を見積もる
+func (e *ExchangeMock) estimateQueue(o *model.Order) float64 {
+	if !e.fillModel.Partial || e.fillModel.QueueRatio <= 0 || (o.Type != model.Buy && o.Type != model.Sell) {
+		return 0
+	}
+	// 買いの指値は売りの取引で、売りの指値は買いの取引で約定する
+	side := model.SellSide
+	if o.Type == model.Sell {
+		side = model.BuySide
+	}
+	volume, _ := e.GetVolumes(&o.Pair, side, e.fillModel.VolumeWindow)
+	return volume * e.fillModel.QueueRatio
+}
+
+// marketSlippage 成行のスリッページ（数量が直近の取引量に占める割合に応じて増やす）
+func (e *ExchangeMock) marketSlippage(p *model.CurrencyPair, amount float64) float64 {
+	slippage := e.slippage
+	if e.fillModel.Impact <= 0 {
+		return slippage
+	}
+	buy, _ := e.GetVolumes(p, model.BuySide, e.fillModel.VolumeWindow)
+	sell, _ := e.GetVolumes(p, model.SellSide, e.fillModel.VolumeWindow)
+	if volume := buy + sell; volume > 0 {
+		slippage += e.fillModel.Impact * amount / volume
+	}
+	return slippage
+}
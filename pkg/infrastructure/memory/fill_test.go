@@ -0,0 +1,162 @@
+package memory_test
+
+import (
+	"math"
+	"testing"
+	"time"
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/infrastructure/memory"
+)
+
+func TestExchangeMock_FillModel(t *testing.T) {
+	start := time.Date(2021, 2, 23, 0, 0, 0, 0, time.UTC)
+	// 1分毎のレート（直前の1分の売りの取引量は2）
+	rates := []memory.Rate{}
+	for i, rate := range []float64{1010, 1010, 1000, 990, 990} {
+		rates = append(rates, memory.NewRateWithVolumes(start.Add(time.Duration(i)*time.Minute), rate, rate, rate-1, 2, 2))
+	}
+	trade := func(sec int, rate, amount float64) model.Trade {
+		return model.Trade{Pair: model.BtcJpy, Rate: rate, Amount: amount, Side: model.SellSide, CreatedAt: start.Add(time.Duration(sec) * time.Second)}
+	}
+	buyTrade := func(sec int, rate, amount float64) model.Trade {
+		t := trade(sec, rate, amount)
+		t.Side = model.BuySide
+		return t
+	}
+
+	tests := map[string]struct {
+		fill   memory.FillModel
+		trades []model.Trade
+		// ステップ毎の約定済みの数量（指値1000で3の買い）
+		want []float64
+	}{
+		"touch": {
+			want: []float64{0, 0, 3, 3, 3},
+		},
+		"partial by rate volumes": {
+			fill: memory.FillModel{Partial: true},
+			want: []float64{0, 0, 2, 3, 3},
+		},
+		"partial with queue": {
+			fill: memory.FillModel{Partial: true, QueueRatio: 0.5, VolumeWindow: time.Minute},
+			// 先に並んでいる1を約定してから
+			want: []float64{0, 0, 1, 3, 3},
+		},
+		"latency": {
+			fill: memory.FillModel{Latency: 3},
+			want: []float64{0, 0, 0, 3, 3},
+		},
+		"partial by trades": {
+			fill: memory.FillModel{Partial: true},
+			trades: []model.Trade{
+				trade(30, 1005, 5),
+				trade(90, 1000, 0.5),
+				trade(100, 1000, 1),
+				// 指値を超えた取引があれば残りを全て約定
+				trade(150, 995, 0.1),
+			},
+			want: []float64{0, 1.5, 3, 3, 3},
+		},
+		"partial by sell trades": {
+			fill: memory.FillModel{Partial: true},
+			trades: []model.Trade{
+				// 買いの取引では買いの指値は約定しない
+				buyTrade(30, 1000, 5),
+				trade(90, 1000, 0.5),
+				buyTrade(95, 1000, 5),
+				trade(100, 1000, 1),
+			},
+			// 残りは指値を超えたレートで約定
+			want: []float64{0, 1.5, 1.5, 3, 3},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			mock, err := memory.NewExchangeMockWithRates(rates, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			mock.SetFillModel(tt.fill)
+			if tt.trades != nil {
+				mock.SetTrades(tt.trades)
+			}
+			rate, amount := 1000.0, 3.0
+			order, err := mock.PostOrder(&model.NewOrder{Type: model.Buy, Pair: model.BtcJpy, Rate: &rate, Amount: &amount})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []float64{}
+			for {
+				for {
+					if _, ok := mock.NextTrade(); !ok {
+						break
+					}
+				}
+				got = append(got, filled(t, mock, order.ID))
+				if !mock.NextStep() {
+					break
+				}
+			}
+			for i := range tt.want {
+				if i >= len(got) || math.Abs(got[i]-tt.want[i]) > 1e-9 {
+					t.Fatalf("filled = %v, want %v", got, tt.want)
+				}
+			}
+			oo, _ := mock.GetOpenOrders(&model.BtcJpy)
+			if len(oo) != 0 {
+				t.Errorf("open orders = %+v, want all closed", oo)
+			}
+		})
+	}
+}
+
+func TestExchangeMock_MarketImpact(t *testing.T) {
+	start := time.Date(2021, 2, 23, 0, 0, 0, 0, time.UTC)
+	rates := []memory.Rate{
+		memory.NewRateWithVolumes(start, 1000, 1000, 1000, 5, 5),
+		memory.NewRateWithVolumes(start.Add(time.Minute), 1000, 1000, 1000, 5, 5),
+	}
+	tests := map[string]struct {
+		fill   memory.FillModel
+		amount float64
+		want   float64
+	}{
+		"fixed slippage":        {amount: 1, want: 1000 * (1 - 0.01)},
+		"impact by volume":      {fill: memory.FillModel{Impact: 0.1, VolumeWindow: time.Hour}, amount: 2, want: 1000 * (1 - 0.01 - 0.1*2/20)},
+		"impact without window": {fill: memory.FillModel{Impact: 0.1}, amount: 2, want: 1000 * (1 - 0.01)},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			mock, err := memory.NewExchangeMockWithRates(rates, 0.01)
+			if err != nil {
+				t.Fatal(err)
+			}
+			mock.SetFillModel(tt.fill)
+			mock.NextStep()
+			if _, err := mock.PostOrder(&model.NewOrder{Type: model.MarketSell, Pair: model.BtcJpy, Amount: &tt.amount}); err != nil {
+				t.Fatal(err)
+			}
+			contracts, _ := mock.GetContracts()
+			if len(contracts) != 1 || math.Abs(contracts[0].Rate-tt.want) > 1e-9 {
+				t.Errorf("contracts = %+v, want rate %v", contracts, tt.want)
+			}
+		})
+	}
+}
+
+// filled 注文の約定済みの数量
+func filled(t *testing.T, mock *memory.ExchangeMock, orderID uint64) float64 {
+	t.Helper()
+	contracts, err := mock.GetContracts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	amount := 0.0
+	for _, c := range contracts {
+		if c.OrderID == orderID {
+			amount += c.IncreaseAmount
+		}
+	}
+	return amount
+}
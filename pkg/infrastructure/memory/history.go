@@ -0,0 +1,148 @@
+package memory
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"trading-bot/pkg/domain/model"
+)
+
+// HistoryVersion 書き出すレート履歴（CSV）の形式
+// 1は日時, 取引所買いレート, 取引所売りレートの3列
+// 2は日時, 販売所レート, 取引所買いレート, 取引所売りレート, 買い取引量, 売り取引量の6列で、
+// 先頭に "# trading-bot rate history; version=2; pair=btc_jpy" のような行を付ける（取引量は前の行からの間の取引量）
+const HistoryVersion = 2
+
+var historyHeader = []string{"datetime", "store_rate", "order_buy_rate", "order_sell_rate", "buy_volume", "sell_volume"}
+
+// NewRateWithVolumes 取引量付きのレートを生成
+func NewRateWithVolumes(t time.Time, storeRate, buyRate, sellRate, buyVolume, sellVolume float64) Rate {
+	return Rate{
+		Datetime:      t.Format(time.RFC3339),
+		Time:          t,
+		StoreRate:     storeRate,
+		OrderBuyRate:  buyRate,
+		OrderSellRate: sellRate,
+		BuyVolume:     buyVolume,
+		SellVolume:    sellVolume,
+	}
+}
+
+// ReadRates CSVのレートを全て読み込む
+func ReadRates(r io.Reader) ([]Rate, error) {
+	next, err := newRateReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	rates := []Rate{}
+	for {
+		rate, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		rates = append(rates, *rate)
+	}
+	return rates, nil
+}
+
+// WriteRates レートをCSV（HistoryVersionの形式）で書き出す
+func WriteRates(w io.Writer, pair model.CurrencyPair, rates []Rate) error {
+	if _, err := fmt.Fprintf(w, "# trading-bot rate history; version=%d; pair=%s\n", HistoryVersion, pair.String()); err != nil {
+		return err
+	}
+	writer := csv.NewWriter(w)
+	if err := writer.Write(historyHeader); err != nil {
+		return err
+	}
+	for _, r := range rates {
+		record := []string{r.Time.Format(time.RFC3339)}
+		for _, v := range []float64{r.StoreRate, r.OrderBuyRate, r.OrderSellRate, r.BuyVolume, r.SellVolume} {
+			record = append(record, strconv.FormatFloat(v, 'f', -1, 64))
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// newRateReader CSVのレートを1行ずつ読む関数を生成（なくなったらio.EOF）
+func newRateReader(r io.Reader) (func() (*Rate, error), error) {
+	br := bufio.NewReader(r)
+	version, err := readHistoryVersion(br)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(br)
+	reader.Comment = '#'
+	// ヘッダを読み飛ばす
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	if version >= 2 && len(header) != len(historyHeader) {
+		return nil, fmt.Errorf("csv header is not %d columns, [%d columns]", len(historyHeader), len(header))
+	}
+
+	return func() (*Rate, error) {
+		record, err := reader.Read()
+		if err != nil {
+			return nil, err
+		}
+		return NewRate(record)
+	}, nil
+}
+
+// readHistoryVersion 先頭のコメント行から形式を読み取る（コメント行がなければ1）
+func readHistoryVersion(br *bufio.Reader) (int, error) {
+	fields, line, err := readHistoryComment(br)
+	if err != nil {
+		return 0, err
+	}
+	if fields == nil {
+		return 1, nil
+	}
+	v, ok := fields["version"]
+	if !ok {
+		return 0, fmt.Errorf("history version is not found, %v", line)
+	}
+	version, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("history version is invalid, %v", v)
+	}
+	if version > HistoryVersion {
+		return 0, fmt.Errorf("history version is not supported, (version:%d, supported:%d)", version, HistoryVersion)
+	}
+	return version, nil
+}
+
+// readHistoryComment 先頭のコメント行（"# ...; key=value; ..."）を読み取る（コメント行がなければnil）
+func readHistoryComment(br *bufio.Reader) (map[string]string, string, error) {
+	b, err := br.Peek(1)
+	if err != nil || b[0] != '#' {
+		return nil, "", nil
+	}
+	line, err := br.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return nil, "", err
+	}
+	line = strings.TrimSpace(line)
+	fields := map[string]string{}
+	for _, field := range strings.Split(strings.TrimPrefix(line, "#"), ";") {
+		kv := strings.SplitN(strings.TrimSpace(field), "=", 2)
+		if len(kv) == 2 {
+			fields[kv[0]] = kv[1]
+		}
+	}
+	return fields, line, nil
+}
@@ -0,0 +1,117 @@
+package memory_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/infrastructure/memory"
+)
+
+func TestReadRates(t *testing.T) {
+	at := time.Date(2021, 2, 23, 19, 27, 0, 0, time.UTC)
+	tests := map[string]struct {
+		csv     []string
+		want    []memory.Rate
+		wantErr bool
+	}{
+		"version 1": {
+			csv: []string{
+				"日時,取引所買い価格,取引所売り価格",
+				"2021-02-23T19:27:00Z,201,200",
+			},
+			want: []memory.Rate{{Datetime: "2021-02-23T19:27:00Z", Time: at, StoreRate: 200, OrderBuyRate: 201, OrderSellRate: 200}},
+		},
+		"version 2": {
+			csv: []string{
+				"# trading-bot rate history; version=2; pair=btc_jpy",
+				"datetime,store_rate,order_buy_rate,order_sell_rate,buy_volume,sell_volume",
+				"2021-02-23T19:27:00Z,200.5,201,200,0.25,0.125",
+			},
+			want: []memory.Rate{memory.NewRateWithVolumes(at, 200.5, 201, 200, 0.25, 0.125)},
+		},
+		"unsupported version": {
+			csv: []string{
+				"# trading-bot rate history; version=99; pair=btc_jpy",
+				"datetime,store_rate,order_buy_rate,order_sell_rate,buy_volume,sell_volume",
+			},
+			wantErr: true,
+		},
+		"version 2 with 3 columns": {
+			csv: []string{
+				"# trading-bot rate history; version=2; pair=btc_jpy",
+				"datetime,order_buy_rate,order_sell_rate",
+				"2021-02-23T19:27:00Z,201,200",
+			},
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := memory.ReadRates(strings.NewReader(strings.Join(tt.csv, "\n")))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadRates() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadRates() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteRates(t *testing.T) {
+	at := time.Date(2021, 2, 23, 19, 27, 0, 0, time.UTC)
+	rates := []memory.Rate{
+		memory.NewRateWithVolumes(at, 4930000.5, 4930962, 4929962, 0.1, 0.2),
+		memory.NewRateWithVolumes(at.Add(time.Minute), 4931000, 4931962, 4930962, 0, 1.5),
+	}
+	var buf bytes.Buffer
+	if err := memory.WriteRates(&buf, model.BtcJpy, rates); err != nil {
+		t.Fatal(err)
+	}
+	got, err := memory.ReadRates(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, rates) {
+		t.Errorf("ReadRates(WriteRates()) = %+v, want %+v", got, rates)
+	}
+}
+
+func TestExchangeMock_GetVolumes(t *testing.T) {
+	start := time.Date(2021, 2, 23, 0, 0, 0, 0, time.UTC)
+	rates := []memory.Rate{}
+	for i := 0; i < 4; i++ {
+		rates = append(rates, memory.NewRateWithVolumes(start.Add(time.Duration(i)*time.Minute), 100, 101, 99, float64(i+1), 10))
+	}
+	mock, err := memory.NewExchangeMockWithRates(rates, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for mock.NextStep() {
+	}
+
+	tests := map[string]struct {
+		side model.OrderSide
+		d    time.Duration
+		want float64
+	}{
+		"buy 2 minutes":  {side: model.BuySide, d: 2 * time.Minute, want: 3 + 4},
+		"buy all":        {side: model.BuySide, d: time.Hour, want: 1 + 2 + 3 + 4},
+		"sell 1 minute":  {side: model.SellSide, d: time.Minute, want: 10},
+		"sell no period": {side: model.SellSide, d: 0, want: 0},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := mock.GetVolumes(&model.BtcJpy, tt.side, tt.d)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("GetVolumes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package memory
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+	"trading-bot/pkg/domain/model"
+)
+
+// TickHistoryVersion 取引履歴（CSV）の形式
+// 先頭に "# trading-bot tick history; version=1; pair=btc_jpy" のような行を付け、
+// ID, 日時, レート, 数量, サイド（buy/sell）の5列
+const TickHistoryVersion = 1
+
+var tickHeader = []string{"id", "datetime", "rate", "amount", "side"}
+
+// ReadTicks CSVの取引履歴を全て読み込む（ExchangeMock.SetTradesで再生する）
+func ReadTicks(r io.Reader) ([]model.Trade, error) {
+	br := bufio.NewReader(r)
+	fields, line, err := readHistoryComment(br)
+	if err != nil {
+		return nil, err
+	}
+	if fields == nil {
+		return nil, fmt.Errorf("tick history header is not found")
+	}
+	version, err := strconv.Atoi(fields["version"])
+	if err != nil {
+		return nil, fmt.Errorf("tick history version is invalid, %v", line)
+	}
+	if version > TickHistoryVersion {
+		return nil, fmt.Errorf("tick history version is not supported, (version:%d, supported:%d)", version, TickHistoryVersion)
+	}
+	pair, err := model.ParseToCurrencyPair(fields["pair"])
+	if err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(br)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = len(tickHeader)
+	// ヘッダを読み飛ばす
+	if _, err := reader.Read(); err != nil {
+		return nil, err
+	}
+
+	trades := []model.Trade{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		t, err := newTick(*pair, record)
+		if err != nil {
+			return nil, err
+		}
+		trades = append(trades, *t)
+	}
+	return trades, nil
+}
+
+// WriteTicks 取引履歴をCSV（TickHistoryVersionの形式）で書き出す
+func WriteTicks(w io.Writer, pair model.CurrencyPair, trades []model.Trade) error {
+	if _, err := fmt.Fprintf(w, "# trading-bot tick history; version=%d; pair=%s\n", TickHistoryVersion, pair.String()); err != nil {
+		return err
+	}
+	writer := csv.NewWriter(w)
+	if err := writer.Write(tickHeader); err != nil {
+		return err
+	}
+	for _, t := range trades {
+		side := "buy"
+		if t.Side == model.SellSide {
+			side = "sell"
+		}
+		record := []string{
+			strconv.FormatUint(t.ID, 10),
+			t.CreatedAt.Format(time.RFC3339Nano),
+			strconv.FormatFloat(t.Rate, 'f', -1, 64),
+			strconv.FormatFloat(t.Amount, 'f', -1, 64),
+			side,
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func newTick(pair model.CurrencyPair, v []string) (*model.Trade, error) {
+	id, err := strconv.ParseUint(v[0], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	t, err := time.Parse(time.RFC3339Nano, v[1])
+	if err != nil {
+		return nil, err
+	}
+	rate, err := strconv.ParseFloat(v[2], 64)
+	if err != nil {
+		return nil, err
+	}
+	amount, err := strconv.ParseFloat(v[3], 64)
+	if err != nil {
+		return nil, err
+	}
+	var side model.OrderSide
+	switch v[4] {
+	case "buy":
+		side = model.BuySide
+	case "sell":
+		side = model.SellSide
+	default:
+		return nil, fmt.Errorf("side is invalid, %v", v[4])
+	}
+	return &model.Trade{
+		ID:        id,
+		Pair:      pair,
+		Rate:      rate,
+		Amount:    amount,
+		Side:      side,
+		CreatedAt: t,
+	}, nil
+}
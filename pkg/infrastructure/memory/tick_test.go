@@ -0,0 +1,143 @@
+package memory_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/infrastructure/memory"
+)
+
+func TestReadTicks(t *testing.T) {
+	at := time.Date(2021, 2, 23, 19, 27, 0, 500000000, time.UTC)
+	tests := map[string]struct {
+		csv     []string
+		want    []model.Trade
+		wantErr bool
+	}{
+		"version 1": {
+			csv: []string{
+				"# trading-bot tick history; version=1; pair=btc_jpy",
+				"id,datetime,rate,amount,side",
+				"10,2021-02-23T19:27:00.5Z,5000000,0.01,sell",
+			},
+			want: []model.Trade{{ID: 10, Pair: model.BtcJpy, Rate: 5000000, Amount: 0.01, Side: model.SellSide, CreatedAt: at}},
+		},
+		"no header comment": {
+			csv: []string{
+				"id,datetime,rate,amount,side",
+				"10,2021-02-23T19:27:00.5Z,5000000,0.01,sell",
+			},
+			wantErr: true,
+		},
+		"invalid side": {
+			csv: []string{
+				"# trading-bot tick history; version=1; pair=btc_jpy",
+				"id,datetime,rate,amount,side",
+				"10,2021-02-23T19:27:00.5Z,5000000,0.01,hold",
+			},
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := memory.ReadTicks(strings.NewReader(strings.Join(tt.csv, "\n")))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadTicks() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadTicks() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteTicks(t *testing.T) {
+	at := time.Date(2021, 2, 23, 19, 27, 0, 0, time.UTC)
+	trades := []model.Trade{
+		{ID: 1, Pair: model.BtcJpy, Rate: 5000000.5, Amount: 0.1, Side: model.BuySide, CreatedAt: at},
+		{ID: 2, Pair: model.BtcJpy, Rate: 4999000, Amount: 1.5, Side: model.SellSide, CreatedAt: at.Add(time.Second)},
+	}
+	var buf bytes.Buffer
+	if err := memory.WriteTicks(&buf, model.BtcJpy, trades); err != nil {
+		t.Fatal(err)
+	}
+	got, err := memory.ReadTicks(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, trades) {
+		t.Errorf("ReadTicks(WriteTicks()) = %+v, want %+v", got, trades)
+	}
+}
+
+func TestExchangeMock_NextTrade(t *testing.T) {
+	start := time.Date(2021, 2, 23, 0, 0, 0, 0, time.UTC)
+	rates := []memory.Rate{
+		memory.NewRateWithVolumes(start, 1000, 1001, 999, 0, 0),
+		memory.NewRateWithVolumes(start.Add(time.Minute), 1000, 1001, 999, 0, 0),
+		memory.NewRateWithVolumes(start.Add(2*time.Minute), 1000, 1001, 999, 0, 0),
+	}
+	trade := func(id uint64, sec int, rate, amount float64, side model.OrderSide) model.Trade {
+		return model.Trade{ID: id, Pair: model.BtcJpy, Rate: rate, Amount: amount, Side: side, CreatedAt: start.Add(time.Duration(sec) * time.Second)}
+	}
+	mock, err := memory.NewExchangeMockWithRates(rates, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 日時順でなくても日時順に再生し、最初のレートより前と最後のレートより後は再生しない
+	mock.SetTrades([]model.Trade{
+		trade(4, 70, 990, 0.5, model.SellSide),
+		trade(2, 20, 995, 2, model.SellSide),
+		trade(1, 10, 1000, 1, model.BuySide),
+		trade(3, 60, 1000, 4, model.BuySide),
+		trade(0, -10, 1000, 8, model.BuySide),
+		trade(5, 130, 1000, 16, model.BuySide),
+	})
+	rate := 995.0
+	amount := 1.0
+	order, err := mock.PostOrder(&model.NewOrder{Type: model.Buy, Pair: model.BtcJpy, Rate: &rate, Amount: &amount})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantSteps := [][]uint64{{1, 2}, {3, 4}, {}}
+	for step, want := range wantSteps {
+		got := []uint64{}
+		for {
+			tr, ok := mock.NextTrade()
+			if !ok {
+				break
+			}
+			if !mock.Now().Equal(tr.CreatedAt) {
+				t.Errorf("Now() = %v, want %v", mock.Now(), tr.CreatedAt)
+			}
+			got = append(got, tr.ID)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("step %d trades = %v, want %v", step, got, want)
+		}
+
+		if step == 0 {
+			// 売りの取引が指値に達したので取引の日時に指値で約定
+			at, ok := mock.ClosedAt(order.ID)
+			if !ok || !at.Equal(start.Add(20*time.Second)) {
+				t.Errorf("ClosedAt() = %v, %v, want %v", at, ok, start.Add(20*time.Second))
+			}
+			contracts, _ := mock.GetContracts()
+			if len(contracts) != 1 || contracts[0].Rate != rate || contracts[0].Liquidity != model.Maker {
+				t.Errorf("contracts = %+v, want maker at %v", contracts, rate)
+			}
+		}
+		if step == 1 {
+			buy, _ := mock.GetVolumes(&model.BtcJpy, model.BuySide, time.Minute)
+			sell, _ := mock.GetVolumes(&model.BtcJpy, model.SellSide, time.Minute)
+			if buy != 4 || sell != 2+0.5 {
+				t.Errorf("GetVolumes() = buy %v, sell %v, want buy 4, sell 2.5", buy, sell)
+			}
+		}
+		mock.NextStep()
+	}
+}
@@ -1,20 +1,33 @@
 package mysql
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"time"
+	"trading-bot/pkg/domain"
 	"trading-bot/pkg/domain/model"
 
+	driver "github.com/go-sql-driver/mysql"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 )
 
+// mysqlErrDuplicateEntry 一意制約違反のエラー番号
+const mysqlErrDuplicateEntry = 1062
+
+// DefaultBotName ボット名の初期値
+const DefaultBotName = "default"
+
 // Client MySQL用クライアント
 type Client struct {
 	db *gorm.DB
+	// ポジションの管理対象とするボット名
+	botName string
+	// 期間指定の取得に使う時計
+	clock domain.Clock
 }
 
 // NewClient MySQL用クライアントの生成
@@ -28,7 +41,23 @@ func NewClient(userName, password, dbHost string, dbPort int, dbName string) *Cl
 	}
 
 	return &Client{
-		db: db,
+		db:      db,
+		botName: DefaultBotName,
+		clock:   domain.SystemClock{},
+	}
+}
+
+// SetClock 時計を設定（シミュレーションでは履歴データの日時で期間を決める）
+func (c *Client) SetClock(clock domain.Clock) {
+	c.clock = clock
+}
+
+// WithBotName ポジションの管理対象を指定ボットに限定したクライアントを生成
+func (c *Client) WithBotName(botName string) *Client {
+	return &Client{
+		db:      c.db,
+		botName: botName,
+		clock:   c.clock,
 	}
 }
 
@@ -163,6 +192,7 @@ func (c *Client) AddNewOrder(o *model.Order) (*model.Position, error) {
 	}
 
 	pRecord := Position{
+		BotName:       c.botName,
 		OpenerOrderID: oRecord.ID,
 		CloserOrderID: nil,
 	}
@@ -216,15 +246,17 @@ func (c *Client) AddSettleOrder(positionID uint64, o *model.Order) (*model.Posit
 	}, nil
 }
 
-// CancelSettleOrder 決済注文をキャンセル
+// CancelSettleOrder 決済注文をキャンセル（決済注文を取消済みにしてポジションから外す、新規注文はそのまま）
 func (c *Client) CancelSettleOrder(positionID uint64) (*model.Position, error) {
 	var pos Position
 	if err := c.db.First(&pos, positionID).Error; err != nil {
 		return nil, err
 	}
 
-	if err := c.db.Model(&Order{}).Where("id = ?", pos.OpenerOrderID).Update("status", 2).Error; err != nil {
-		return nil, err
+	if pos.CloserOrderID != nil {
+		if err := c.db.Model(&Order{}).Where("id = ?", *pos.CloserOrderID).Update("status", int(model.Canceled)).Error; err != nil {
+			return nil, err
+		}
 	}
 
 	if err := c.db.Model(&Position{}).Where("id = ?", pos.ID).Update("closer_order_id", nil).Error; err != nil {
@@ -247,15 +279,44 @@ func (c *Client) CancelSettleOrder(positionID uint64) (*model.Position, error) {
 	}, nil
 }
 
-// GetOpenPositions ポジションを取得
+// GetOpenPositions ポジションを取得（決済注文がないか未約定のもの、新規注文を取り消したものは除く）
 func (c *Client) GetOpenPositions() ([]model.Position, error) {
 	var records []struct {
 		ID uint64
 	}
 	err := c.db.Table("positions").
 		Select("positions.id").
-		Joins("LEFT JOIN orders ON positions.closer_order_id = orders.id").
-		Where("positions.closer_order_id IS NULL OR orders.status = 0").
+		Joins("INNER JOIN orders AS opener ON positions.opener_order_id = opener.id").
+		Joins("LEFT JOIN orders AS closer ON positions.closer_order_id = closer.id").
+		Where("positions.bot_name = ?", c.botName).
+		Where("opener.status <> ? AND (positions.closer_order_id IS NULL OR closer.status = ?)", model.Canceled, model.Open).
+		Scan(&records).Error
+	if err != nil {
+		return nil, err
+	}
+
+	pp := []model.Position{}
+	for _, r := range records {
+		p, err := c.getPosition(r.ID)
+		if err != nil {
+			return nil, err
+		}
+		pp = append(pp, *p)
+	}
+
+	return pp, nil
+}
+
+// GetClosedPositions 決済済みのポジションを取得
+func (c *Client) GetClosedPositions() ([]model.Position, error) {
+	var records []struct {
+		ID uint64
+	}
+	err := c.db.Table("positions").
+		Select("positions.id").
+		Joins("INNER JOIN orders ON positions.closer_order_id = orders.id").
+		Where("positions.bot_name = ? AND orders.status = ?", c.botName, model.Closed).
+		Order("positions.id").
 		Scan(&records).Error
 	if err != nil {
 		return nil, err
@@ -273,11 +334,70 @@ func (c *Client) GetOpenPositions() ([]model.Position, error) {
 	return pp, nil
 }
 
+// GetPositionState ポジションの状態を取得
+func (c *Client) GetPositionState(positionID uint64) (*model.PositionState, error) {
+	records := []PositionState{}
+	if err := c.db.Where("position_id = ?", positionID).Limit(1).Find(&records).Error; err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	return records[0].ToDomainModel(), nil
+}
+
+// UpsertPositionState ポジションの状態を更新
+func (c *Client) UpsertPositionState(s *model.PositionState) error {
+	return c.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(NewPositionState(s)).Error
+}
+
+// GetStrategyState 戦略の状態を取得（未保存なら値が空の状態を返す）
+func (c *Client) GetStrategyState(botName, strategy, pair string) (*model.StrategyState, error) {
+	records := []StrategyState{}
+	err := c.db.Where("bot_name = ? AND strategy = ? AND pair = ?", botName, strategy, pair).Find(&records).Error
+	if err != nil {
+		return nil, err
+	}
+
+	s := model.NewStrategyState(botName, strategy, pair)
+	for _, r := range records {
+		s.Values[r.StateKey] = r.Value
+	}
+	return s, nil
+}
+
+// UpsertStrategyState 戦略の状態を置き換え
+func (c *Client) UpsertStrategyState(s *model.StrategyState) error {
+	return c.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("bot_name = ? AND strategy = ? AND pair = ?", s.BotName, s.Strategy, s.Pair).Delete(&StrategyState{}).Error
+		if err != nil {
+			return err
+		}
+		if len(s.Values) == 0 {
+			return nil
+		}
+
+		records := []StrategyState{}
+		for k, v := range s.Values {
+			records = append(records, StrategyState{
+				BotName:  s.BotName,
+				Strategy: s.Strategy,
+				Pair:     s.Pair,
+				StateKey: k,
+				Value:    v,
+			})
+		}
+		return tx.Create(&records).Error
+	})
+}
+
 // TruncateAll 全テーブルから全レコードを削除
 func (c *Client) TruncateAll() error {
 	qq := []string{
 		"SET FOREIGN_KEY_CHECKS = 0;",
 		"TRUNCATE TABLE profits;",
+		"TRUNCATE TABLE position_states;",
+		"TRUNCATE TABLE strategy_states;",
 		"TRUNCATE TABLE positions;",
 		"TRUNCATE TABLE contracts;",
 		"TRUNCATE TABLE orders;",
@@ -330,7 +450,7 @@ func (c *Client) GetRates(p *model.CurrencyPair, d *time.Duration) (rates []floa
 			Order("recorded_at").Find(&rr).
 			Error
 	} else {
-		begin := time.Now().Add(-1 * *d)
+		begin := c.clock.Now().Add(-1 * *d)
 		err = c.db.
 			Where("recorded_at > ? AND pair = ?", begin, p.String()).
 			Order("recorded_at").Find(&rr).
@@ -348,6 +468,34 @@ func (c *Client) GetRates(p *model.CurrencyPair, d *time.Duration) (rates []floa
 	return rates, nil
 }
 
+// GetRateFreshness GetRatesで取得するレート（markets）の鮮度を取得
+func (c *Client) GetRateFreshness(p *model.CurrencyPair, d *time.Duration, gap time.Duration) (*model.RateFreshness, error) {
+	var recordedAt []time.Time
+	q := c.db.Model(&Market{}).Where("pair = ?", p.String())
+	if d != nil {
+		q = q.Where("recorded_at > ?", c.clock.Now().Add(-1**d))
+	}
+	if err := q.Order("recorded_at").Pluck("recorded_at", &recordedAt).Error; err != nil {
+		return nil, err
+	}
+	f := model.NewRateFreshness(recordedAt, gap)
+	if f.Count > 0 || d == nil {
+		return f, nil
+	}
+
+	// 期間内に記録がない場合も最後の記録日時は返す
+	var last Market
+	err := c.db.Where("pair = ?", p.String()).Order("recorded_at DESC").First(&last).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	f.LastRecordedAt = last.RecordedAt
+	return f, nil
+}
+
 // AddMarket 市場情報を追加
 func (c *Client) AddMarket(info *Market) error {
 	return c.db.Create(&info).Error
@@ -361,7 +509,7 @@ func (c *Client) GetMarkets(p *model.CurrencyPair, d *time.Duration) (markets []
 			Order("recorded_at").Find(&markets).
 			Error
 	} else {
-		begin := time.Now().Add(-1 * *d)
+		begin := c.clock.Now().Add(-1 * *d)
 		err = c.db.
 			Where("recorded_at > ? AND pair = ?", begin, p.String()).
 			Order("recorded_at").Find(&markets).
@@ -370,9 +518,34 @@ func (c *Client) GetMarkets(p *model.CurrencyPair, d *time.Duration) (markets []
 	return
 }
 
+// GetMarketsBetween 期間内の市場情報を取得（開始を含み終了を含まない、終了がゼロ値なら最後まで）
+func (c *Client) GetMarketsBetween(p *model.CurrencyPair, from, to time.Time) (markets []Market, err error) {
+	q := c.db.Where("pair = ? AND recorded_at >= ?", p.String(), from)
+	if !to.IsZero() {
+		q = q.Where("recorded_at < ?", to)
+	}
+	err = q.Order("recorded_at").Find(&markets).Error
+	return
+}
+
+// GetTradesBetween 期間内に注文した約定情報を取得（開始を含み終了を含まない、終了がゼロ値なら最後まで）
+func (c *Client) GetTradesBetween(p *model.CurrencyPair, from, to time.Time) (trades []Trade, err error) {
+	q := c.db.Table("contracts").
+		Select("orders.ordered_at, orders.id AS order_id, orders.order_type, contracts.id AS contract_id, contracts.rate, contracts.side, "+
+			"contracts.increase_currency, contracts.increase_amount, contracts.decrease_currency, contracts.decrease_amount, "+
+			"contracts.fee_currency, contracts.fee_amount, contracts.liquidity").
+		Joins("JOIN orders ON orders.id = contracts.order_id").
+		Where("orders.pair = ? AND orders.ordered_at >= ?", p.String(), from)
+	if !to.IsZero() {
+		q = q.Where("orders.ordered_at < ?", to)
+	}
+	err = q.Order("orders.ordered_at, contracts.id").Scan(&trades).Error
+	return
+}
+
 // DeleteMarkets
 func (c *Client) DeleteMarkets(p *model.CurrencyPair, expire time.Duration) error {
-	border := time.Now().Add(-1 * expire)
+	border := c.clock.Now().Add(-1 * expire)
 	return c.db.Where("pair = ? AND recorded_at < ?", p.String(), border).Delete(&Market{}).Error
 }
 
@@ -381,6 +554,38 @@ func (c *Client) AddEvent(e *Event) error {
 	return c.db.Create(&e).Error
 }
 
+// RecordEvent イベントを記録
+func (c *Client) RecordEvent(e *model.Event) error {
+	r := NewEvent(e)
+	if err := c.db.Create(r).Error; err != nil {
+		return err
+	}
+	e.ID = r.ID
+	return nil
+}
+
+// RecordAlertEvent アラートを記録（同じアラートIDが記録済みなら記録せずにfalseを返す）
+func (c *Client) RecordAlertEvent(e *model.Event) (bool, error) {
+	if e.AlertID == "" {
+		return false, fmt.Errorf("alert id is empty")
+	}
+	r := NewEvent(e)
+	if err := c.db.Create(r).Error; err != nil {
+		var mysqlErr *driver.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry {
+			return false, nil
+		}
+		return false, err
+	}
+	e.ID = r.ID
+	return true, nil
+}
+
+// UpdateEventMemo 記録済みのイベントの内容を更新
+func (c *Client) UpdateEventMemo(id uint64, memo string) error {
+	return c.db.Model(&Event{}).Where("id = ?", id).Update("memo", memo).Error
+}
+
 // GetEvents
 func (c *Client) GetEvents(p *model.CurrencyPair, d *time.Duration) (events []Event, err error) {
 	if d == nil {
@@ -389,7 +594,7 @@ func (c *Client) GetEvents(p *model.CurrencyPair, d *time.Duration) (events []Ev
 			Order("recorded_at").Find(&events).
 			Error
 	} else {
-		begin := time.Now().Add(-1 * *d)
+		begin := c.clock.Now().Add(-1 * *d)
 		err = c.db.
 			Where("recorded_at > ? AND pair = ?", begin, p.String()).
 			Order("recorded_at").Find(&events).
@@ -434,10 +639,14 @@ func (c *Client) GetBotStatusAll(botName string, p *model.CurrencyPair) (map[str
 	return m, nil
 }
 
-// UpsertBotInfo
-func (c *Client) UpsertBotStatuses(statuses []BotStatus) error {
+// UpsertBotStatuses ボットの状態を更新
+func (c *Client) UpsertBotStatuses(statuses []model.BotStatus) error {
 	if len(statuses) == 0 {
 		return nil
 	}
-	return c.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&statuses).Error
+	records := []BotStatus{}
+	for _, s := range statuses {
+		records = append(records, *NewBotStatus(&s))
+	}
+	return c.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&records).Error
 }
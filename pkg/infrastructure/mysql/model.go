@@ -50,21 +50,9 @@ func (o *Order) ToDomainModel() (*model.Order, error) {
 		return nil, err
 	}
 
-	var orderType model.OrderType
-	switch o.OrderType {
-	case 0:
-		orderType = model.Buy
-	case 1:
-		orderType = model.Sell
-	case 2:
-		orderType = model.MarketBuy
-	case 3:
-		orderType = model.MarketSell
-	}
-
 	return &model.Order{
 		ID:           o.ID,
-		Type:         orderType,
+		Type:         toOrderType(o.OrderType),
 		Pair:         *pair,
 		Amount:       o.Amount,
 		Rate:         o.Rate,
@@ -74,6 +62,20 @@ func (o *Order) ToDomainModel() (*model.Order, error) {
 	}, nil
 }
 
+func toOrderType(t int) model.OrderType {
+	switch t {
+	case 0:
+		return model.Buy
+	case 1:
+		return model.Sell
+	case 2:
+		return model.MarketBuy
+	case 3:
+		return model.MarketSell
+	}
+	return ""
+}
+
 // Contract 約定情報
 type Contract struct {
 	ID               uint64
@@ -109,10 +111,36 @@ func NewContract(org *model.Contract) *Contract {
 // Position ポジション
 type Position struct {
 	ID            uint64
+	BotName       string
 	OpenerOrderID uint64
 	CloserOrderID *uint64
 }
 
+// PositionState ポジションの状態
+type PositionState struct {
+	PositionID    uint64 `gorm:"primaryKey"`
+	HighWaterRate float64
+	ExitReason    string
+}
+
+// NewPositionState 生成
+func NewPositionState(org *model.PositionState) *PositionState {
+	return &PositionState{
+		PositionID:    org.PositionID,
+		HighWaterRate: org.HighWaterRate,
+		ExitReason:    org.ExitReason,
+	}
+}
+
+// ToDomainModel ドメインモデルに変換
+func (s *PositionState) ToDomainModel() *model.PositionState {
+	return &model.PositionState{
+		PositionID:    s.PositionID,
+		HighWaterRate: s.HighWaterRate,
+		ExitReason:    s.ExitReason,
+	}
+}
+
 // Profit 利益
 type Profit struct {
 	Amount float64
@@ -140,20 +168,63 @@ type Market struct {
 	RecordedAt   time.Time
 }
 
+// Trade 約定情報と注文日時の組（履歴の書き出し用）
+type Trade struct {
+	OrderedAt        time.Time
+	OrderID          uint64
+	OrderType        int
+	ContractID       uint64
+	Rate             float64
+	Side             int
+	IncreaseCurrency string
+	IncreaseAmount   float64
+	DecreaseCurrency string
+	DecreaseAmount   float64
+	FeeCurrency      string
+	FeeAmount        float64
+	Liquidity        int
+}
+
+// Type 注文種別
+func (t *Trade) Type() model.OrderType {
+	return toOrderType(t.OrderType)
+}
+
 // Event イベント
 type Event struct {
 	ID         uint64
 	Pair       string
 	EventType  int
+	AlertID    *string
 	Memo       string
 	RecordedAt time.Time
 }
 
 const (
-	BuyEvent  = 0
-	SellEvent = 1
+	BuyEvent  = int(model.BuyEvent)
+	SellEvent = int(model.SellEvent)
 )
 
+func NewEvent(e *model.Event) *Event {
+	var alertID *string
+	if e.AlertID != "" {
+		id := e.AlertID
+		alertID = &id
+	}
+	recordedAt := e.RecordedAt
+	if recordedAt.IsZero() {
+		recordedAt = time.Now()
+	}
+	return &Event{
+		ID:         e.ID,
+		Pair:       e.Pair,
+		EventType:  int(e.Type),
+		AlertID:    alertID,
+		Memo:       e.Memo,
+		RecordedAt: recordedAt,
+	}
+}
+
 // AccountInfo アカウント情報
 type AccountInfo struct {
 	Type  string
@@ -170,9 +241,19 @@ const (
 	AccountInfoTypeTotalJPY AccocuntInfoType = "total_jpy"
 )
 
+// StrategyState 戦略の状態（1キー1レコード）
+type StrategyState struct {
+	BotName  string `gorm:"primaryKey"`
+	Strategy string `gorm:"primaryKey"`
+	Pair     string `gorm:"primaryKey"`
+	StateKey string `gorm:"primaryKey"`
+	Value    string
+}
+
 // BotInfo ボット情報
 type BotStatus struct {
 	BotName string
+	Pair    string
 	Type    string
 	Value   float64
 	Memo    string
@@ -181,3 +262,13 @@ type BotStatus struct {
 func (BotStatus) TableName() string {
 	return "bot_statuses"
 }
+
+func NewBotStatus(s *model.BotStatus) *BotStatus {
+	return &BotStatus{
+		BotName: s.BotName,
+		Pair:    s.Pair,
+		Type:    s.Type,
+		Value:   s.Value,
+		Memo:    s.Memo,
+	}
+}
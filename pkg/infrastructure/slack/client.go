@@ -45,3 +45,8 @@ func (c *Client) PostMessage(messageObj interface{}) error {
 
 	return nil
 }
+
+// Notify テキストメッセージを通知
+func (c *Client) Notify(message string) error {
+	return c.PostMessage(&TextMessage{Text: message})
+}
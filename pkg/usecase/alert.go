@@ -0,0 +1,273 @@
+package usecase
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"trading-bot/pkg/domain"
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/domain/repository"
+	"trading-bot/pkg/usecase/trade"
+)
+
+// AlertAction アラートで指示する操作
+type AlertAction string
+
+const (
+	// AlertOpen ポジションがなければ新規に買う
+	AlertOpen AlertAction = "open"
+	// AlertClose ポジションを全て決済
+	AlertClose AlertAction = "close"
+	// AlertScale amount_jpyが指定されていれば買い増し、ratioが指定されていればその割合のポジションを古い順に決済
+	AlertScale AlertAction = "scale"
+)
+
+// Alert 外部からのアラート（TradingView等のWebhook）
+type Alert struct {
+	// アラートID（同じIDのアラートは1回だけ処理する）
+	ID string `json:"id"`
+	// 操作対象のボット名（未指定なら通貨ペアで判断）
+	Bot string `json:"bot"`
+	// 通貨ペア（例: btc_jpy）
+	Pair string `json:"pair"`
+	// 操作
+	Action AlertAction `json:"action"`
+	// 買い付け金額(JPY) [open, scale]
+	AmountJPY float64 `json:"amount_jpy"`
+	// 決済するポジションの割合（0〜1） [scale]
+	Ratio float64 `json:"ratio"`
+	// メッセージ（記録用）
+	Message string `json:"message"`
+}
+
+func (a *Alert) valid() error {
+	if a.ID == "" {
+		return fmt.Errorf("id is empty")
+	}
+	if len(a.ID) > 255 {
+		return fmt.Errorf("id is too long, %d", len(a.ID))
+	}
+	switch a.Action {
+	case AlertOpen:
+		if a.AmountJPY <= 0 {
+			return fmt.Errorf("amount_jpy is empty, %v", a.AmountJPY)
+		}
+	case AlertClose:
+	case AlertScale:
+		if (a.AmountJPY > 0) == (a.Ratio > 0) {
+			return fmt.Errorf("either amount_jpy or ratio is required, amount_jpy:%v ratio:%v", a.AmountJPY, a.Ratio)
+		}
+		if a.AmountJPY < 0 || a.Ratio < 0 || a.Ratio > 1 {
+			return fmt.Errorf("amount_jpy or ratio is out of range, amount_jpy:%v ratio:%v", a.AmountJPY, a.Ratio)
+		}
+	default:
+		return fmt.Errorf("action is unknown, %v", a.Action)
+	}
+	return nil
+}
+
+// AlertResult アラートの処理結果
+type AlertResult struct {
+	// 処理済みのアラートだったか
+	Duplicated bool `json:"duplicated"`
+	// 操作したボット名
+	Bot string `json:"bot,omitempty"`
+	// 送信した注文
+	Orders []string `json:"orders"`
+	// 処理内容
+	Message string `json:"message"`
+}
+
+// alertTarget アラートで操作するボット
+type alertTarget struct {
+	name   string
+	pair   model.CurrencyPair
+	facade *trade.Facade
+	bot    *Bot
+}
+
+// AlertTrader アラートに従って注文する
+type AlertTrader struct {
+	logger    domain.Logger
+	eventRepo repository.EventRepository
+	targets   []alertTarget
+	// 1回の買い注文の上限(JPY、0なら残高まで)
+	maxOrderJPY float64
+}
+
+// NewAlertTrader 生成
+func NewAlertTrader(logger domain.Logger, eventRepo repository.EventRepository, maxOrderJPY float64) *AlertTrader {
+	return &AlertTrader{
+		logger:      logger,
+		eventRepo:   eventRepo,
+		targets:     []alertTarget{},
+		maxOrderJPY: maxOrderJPY,
+	}
+}
+
+// AddTarget アラートで操作するボットを追加
+func (t *AlertTrader) AddTarget(b *Bot) {
+	t.targets = append(t.targets, alertTarget{name: b.Name(), pair: b.pair, facade: b.facade, bot: b})
+}
+
+// target アラートで操作するボットを選択
+func (t *AlertTrader) target(a *Alert) (*alertTarget, error) {
+	var pair *model.CurrencyPair
+	if a.Pair != "" {
+		p, err := model.ParseToCurrencyPair(strings.ToLower(a.Pair))
+		if err != nil {
+			return nil, err
+		}
+		pair = p
+	}
+
+	found := []*alertTarget{}
+	for i := range t.targets {
+		target := &t.targets[i]
+		if a.Bot != "" && target.name != a.Bot {
+			continue
+		}
+		if pair != nil && target.pair != *pair {
+			continue
+		}
+		found = append(found, target)
+	}
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("target bot is not found, bot:%s pair:%s", a.Bot, a.Pair)
+	case 1:
+		return found[0], nil
+	default:
+		return nil, fmt.Errorf("target bot is ambiguous (specify bot), pair:%s", a.Pair)
+	}
+}
+
+// Handle アラートを処理して記録
+func (t *AlertTrader) Handle(a *Alert) (*AlertResult, error) {
+	if err := a.valid(); err != nil {
+		return nil, err
+	}
+	target, err := t.target(a)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+	event := &model.Event{
+		Pair:       target.pair.String(),
+		Type:       model.AlertEvent,
+		AlertID:    a.ID,
+		Memo:       fmt.Sprintf("[alert] received %s", string(payload)),
+		RecordedAt: target.facade.Now(),
+	}
+	recorded, err := t.eventRepo.RecordAlertEvent(event)
+	if err != nil {
+		return nil, err
+	}
+	if !recorded {
+		t.logger.Info("[alert] => skip (duplicated alert id:%s)", a.ID)
+		return &AlertResult{Duplicated: true, Bot: target.name, Orders: []string{}, Message: "duplicated"}, nil
+	}
+
+	result := &AlertResult{Bot: target.name, Orders: []string{}}
+	actErr := target.bot.actAlert(t, target, a, result)
+	if actErr != nil {
+		result.Message = fmt.Sprintf("failed, %v", actErr)
+		t.logger.Error("[alert][%s] %s => %s", a.ID, a.Action, result.Message)
+	} else {
+		t.logger.Info("[alert][%s] %s => %s %v", a.ID, a.Action, result.Message, result.Orders)
+	}
+
+	memo := fmt.Sprintf("[alert] %s %s (bot:%s) => %s\n%s\norders:\n%s",
+		a.Action, a.Message, target.name, result.Message, string(payload), strings.Join(result.Orders, "\n"))
+	if err := t.eventRepo.UpdateEventMemo(event.ID, memo); err != nil {
+		t.logger.Error("[alert][%s] failed to update event, %v", a.ID, err)
+	}
+	return result, actErr
+}
+
+// actAlert アラートの操作を実行
+// 戦略の処理や全決済と同時に注文しないように止めて実行し、買いはポジション数の上限までとする
+func (b *Bot) actAlert(t *AlertTrader, target *alertTarget, a *Alert, result *AlertResult) error {
+	b.strategyMu.Lock()
+	defer b.strategyMu.Unlock()
+
+	positions, err := b.facade.GetOpenPositions()
+	if err != nil {
+		return err
+	}
+	buy := func() error {
+		if len(positions) >= b.Config.PositionCountMax {
+			return fmt.Errorf("open position count reached max, %d >= %d", len(positions), b.Config.PositionCountMax)
+		}
+		return t.buy(target, a.AmountJPY, result)
+	}
+
+	switch a.Action {
+	case AlertOpen:
+		if len(positions) > 0 {
+			result.Message = fmt.Sprintf("skip open (already has %d positions)", len(positions))
+			return nil
+		}
+		return buy()
+	case AlertScale:
+		if a.AmountJPY > 0 {
+			return buy()
+		}
+		// 古いポジションから決済
+		sort.Slice(positions, func(i, j int) bool { return positions[i].ID < positions[j].ID })
+		count := int(math.Ceil(float64(len(positions)) * a.Ratio))
+		return t.close(target, positions[:count], result)
+	case AlertClose:
+		return t.close(target, positions, result)
+	}
+	return nil
+}
+
+func (t *AlertTrader) buy(target *alertTarget, amountJPY float64, result *AlertResult) error {
+	if t.maxOrderJPY > 0 && amountJPY > t.maxOrderJPY {
+		return fmt.Errorf("amount %.3f JPY exceeds max %.3f JPY", amountJPY, t.maxOrderJPY)
+	}
+	balance, err := target.facade.GetJpyBalance()
+	if err != nil {
+		return err
+	}
+	if amountJPY > balance.Amount {
+		return fmt.Errorf("amount %.3f JPY exceeds balance %.3f JPY", amountJPY, balance.Amount)
+	}
+
+	pos, err := target.facade.SendMarketBuyOrder(&target.pair, amountJPY, nil)
+	if err != nil {
+		return err
+	}
+	result.Orders = append(result.Orders, pos.OpenerOrder.String())
+	result.Message = fmt.Sprintf("bought %.3f JPY", amountJPY)
+	return nil
+}
+
+func (t *AlertTrader) close(target *alertTarget, positions []model.Position, result *AlertResult) error {
+	closed := 0
+	for _, p := range positions {
+		p := p
+		order, err := target.facade.ClosePosition(&p)
+		if err != nil {
+			return err
+		}
+		if order == nil {
+			continue
+		}
+		if order.Status == model.Canceled {
+			result.Orders = append(result.Orders, fmt.Sprintf("canceled %s", order.String()))
+		} else {
+			result.Orders = append(result.Orders, order.String())
+		}
+		closed++
+	}
+	result.Message = fmt.Sprintf("closed %d/%d positions", closed, len(positions))
+	return nil
+}
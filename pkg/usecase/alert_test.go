@@ -0,0 +1,162 @@
+package usecase_test
+
+import (
+	"strings"
+	"testing"
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/infrastructure/memory"
+	"trading-bot/pkg/usecase"
+	"trading-bot/pkg/usecase/trade"
+)
+
+func TestAlertTrader_Handle(t *testing.T) {
+	tests := map[string]struct {
+		alerts []usecase.Alert
+		// 最後のアラートの結果
+		wantErr        bool
+		wantDuplicated bool
+		wantOrders     int
+		// 全アラート処理後の状態
+		wantPositions int
+		wantEvents    int
+	}{
+		"open": {
+			alerts: []usecase.Alert{
+				{ID: "a1", Pair: "btc_jpy", Action: usecase.AlertOpen, AmountJPY: 1000},
+			},
+			wantOrders:    1,
+			wantPositions: 1,
+			wantEvents:    1,
+		},
+		"duplicated id": {
+			alerts: []usecase.Alert{
+				{ID: "a1", Pair: "btc_jpy", Action: usecase.AlertOpen, AmountJPY: 1000},
+				{ID: "a1", Pair: "btc_jpy", Action: usecase.AlertScale, AmountJPY: 1000},
+			},
+			wantDuplicated: true,
+			wantOrders:     0,
+			wantPositions:  1,
+			wantEvents:     1,
+		},
+		"open twice": {
+			alerts: []usecase.Alert{
+				{ID: "a1", Pair: "btc_jpy", Action: usecase.AlertOpen, AmountJPY: 1000},
+				{ID: "a2", Pair: "btc_jpy", Action: usecase.AlertOpen, AmountJPY: 1000},
+			},
+			wantOrders:    0,
+			wantPositions: 1,
+			wantEvents:    2,
+		},
+		"close": {
+			alerts: []usecase.Alert{
+				{ID: "a1", Pair: "btc_jpy", Action: usecase.AlertOpen, AmountJPY: 1000},
+				{ID: "a2", Pair: "btc_jpy", Action: usecase.AlertScale, AmountJPY: 1000},
+				{ID: "a3", Pair: "btc_jpy", Action: usecase.AlertClose},
+			},
+			wantOrders:    2,
+			wantPositions: 0,
+			wantEvents:    3,
+		},
+		"scale ratio": {
+			alerts: []usecase.Alert{
+				{ID: "a1", Pair: "btc_jpy", Action: usecase.AlertOpen, AmountJPY: 1000},
+				{ID: "a2", Pair: "btc_jpy", Action: usecase.AlertScale, AmountJPY: 1000},
+				{ID: "a3", Pair: "btc_jpy", Action: usecase.AlertScale, AmountJPY: 1000},
+				{ID: "a4", Pair: "btc_jpy", Action: usecase.AlertScale, Ratio: 0.5},
+			},
+			wantOrders:    2,
+			wantPositions: 1,
+			wantEvents:    4,
+		},
+		"over position count max": {
+			alerts: []usecase.Alert{
+				{ID: "a1", Pair: "btc_jpy", Action: usecase.AlertOpen, AmountJPY: 1000},
+				{ID: "a2", Pair: "btc_jpy", Action: usecase.AlertScale, AmountJPY: 1000},
+				{ID: "a3", Pair: "btc_jpy", Action: usecase.AlertScale, AmountJPY: 1000},
+				{ID: "a4", Pair: "btc_jpy", Action: usecase.AlertScale, AmountJPY: 1000},
+			},
+			wantErr:       true,
+			wantOrders:    0,
+			wantPositions: 3,
+			wantEvents:    4,
+		},
+		"over max order": {
+			alerts: []usecase.Alert{
+				{ID: "a1", Pair: "btc_jpy", Action: usecase.AlertOpen, AmountJPY: 20000},
+			},
+			wantErr:       true,
+			wantOrders:    0,
+			wantPositions: 0,
+			wantEvents:    1,
+		},
+		"invalid action": {
+			alerts: []usecase.Alert{
+				{ID: "a1", Pair: "btc_jpy", Action: "hold"},
+			},
+			wantErr:    true,
+			wantEvents: 0,
+		},
+		"unknown pair": {
+			alerts: []usecase.Alert{
+				{ID: "a1", Pair: "eth_jpy", Action: usecase.AlertClose},
+			},
+			wantErr:    true,
+			wantEvents: 0,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			rates := []string{
+				"日付, 販売所買い価格, 販売所売り価格",
+				"2021-02-23T19:27:01Z,1000.0,1000.0",
+			}
+			exCli, err := memory.NewExchangeMock(strings.NewReader(strings.Join(rates, "\n")), 0)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			rds := memory.NewDummyRDS(nil)
+			facade := trade.NewFacade(exCli, rds, rds, rds, rds, rds, rds, nil)
+			logger := &memory.Logger{Level: memory.Error}
+			fetcher := usecase.NewFetcher(exCli, model.BtcJpy, rds)
+
+			trader := usecase.NewAlertTrader(logger, rds, 10000)
+			trader.AddTarget(usecase.NewBot(logger, facade, nil, &usecase.BotConfig{
+				Currency:         model.BTC,
+				PositionCountMax: 3,
+			}))
+
+			var result *usecase.AlertResult
+			for i := range tt.alerts {
+				result, err = trader.Handle(&tt.alerts[i])
+				// 約定を反映
+				if err := fetcher.Fetch(); err != nil {
+					t.Fatal(err.Error())
+				}
+			}
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Handle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if result != nil {
+				if result.Duplicated != tt.wantDuplicated {
+					t.Errorf("Duplicated = %v, want %v", result.Duplicated, tt.wantDuplicated)
+				}
+				if len(result.Orders) != tt.wantOrders {
+					t.Errorf("Orders = %v, want %d orders", result.Orders, tt.wantOrders)
+				}
+			}
+
+			positions, err := facade.GetOpenPositions()
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			if len(positions) != tt.wantPositions {
+				t.Errorf("open positions count = %d, want %d; %v", len(positions), tt.wantPositions, positions)
+			}
+			if events := rds.Events(); len(events) != tt.wantEvents {
+				t.Errorf("events count = %d, want %d; %v", len(events), tt.wantEvents, events)
+			}
+		})
+	}
+}
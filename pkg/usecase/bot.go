@@ -2,9 +2,14 @@ package usecase
 
 import (
 	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
 	"trading-bot/pkg/domain"
 	"trading-bot/pkg/domain/model"
 	"trading-bot/pkg/infrastructure/coincheck"
+	"trading-bot/pkg/usecase/schedule"
 	"trading-bot/pkg/usecase/trade"
 )
 
@@ -14,10 +19,44 @@ type Bot struct {
 	strategy Strategy
 	pair     model.CurrencyPair
 
+	// 状態の復元済みか
+	restored bool
+	// 最後に保存した状態
+	savedState map[string]string
+	stateMu    sync.Mutex
+
+	// 設定の再読み込み要求
+	reloadCh chan struct{}
+	// 設定の再読み込み中は取引検知時の処理を止める
+	strategyMu sync.RWMutex
+
+	// 適用中の取引時間帯の状態
+	session *schedule.Session
+	// レートが古い状態
+	stale   rateStaleness
+	staleMu sync.Mutex
+	// ポジション数が上限に達して新規注文を止めているか（状態が変わったときだけ通知する）
+	positionCapped bool
+
 	Config *BotConfig
+	// 通知先（未設定なら通知しない）
+	Notifier domain.Notifier
+	// 取引時間帯（未設定なら常時取引）
+	Schedule *schedule.Schedule
+	// レートの鮮度の確認（未設定なら確認しない）
+	RateFreshness *RateFreshnessConfig
 }
 
+// DefaultBotName ボット名の初期値
+const DefaultBotName = "default"
+
 type BotConfig struct {
+	// ボット名（戦略の状態の保存単位、未指定なら default）
+	Name string
+	// 戦略種別（戦略の状態の保存単位）
+	Strategy StrategyType
+	// 戦略の設定ファイル（再読み込み用）
+	ConfigPath       string
 	Currency         model.CurrencyType
 	PositionCountMax int
 }
@@ -31,7 +70,8 @@ func NewBot(l domain.Logger, f *trade.Facade, s Strategy, config *BotConfig) *Bo
 			Key:        config.Currency,
 			Settlement: model.JPY,
 		},
-		Config: config,
+		reloadCh: make(chan struct{}, 1),
+		Config:   config,
 	}
 }
 
@@ -40,15 +80,53 @@ func (b *Bot) Trade(ctx context.Context) error {
 		return nil
 	}
 
+	select {
+	case <-b.reloadCh:
+		b.reload()
+	default:
+	}
+
+	if !b.restored {
+		if err := b.RestoreState(); err != nil {
+			return err
+		}
+	}
+	defer func() {
+		if err := b.SaveState(); err != nil {
+			b.logger.Error("failed to save strategy state, %v", err)
+		}
+	}()
+
+	session := b.updateSession()
+	stale := b.updateRateFreshness()
+
+	// 全決済中は待つ
+	b.strategyMu.RLock()
+	defer b.strategyMu.RUnlock()
+
 	pp, err := b.facade.GetOpenPositions()
 	if err != nil {
 		return err
 	}
 
 	cnt := len(pp)
-	if cnt >= b.Config.PositionCountMax {
+	if !session.CanEntry() {
+		b.logger.Debug("[buy] => skip buy (session: %s)", session.String())
+	} else if stale != "" {
+		b.logger.Debug("[buy] => skip buy (stale rate: %s)", stale)
+	} else if cnt >= b.Config.PositionCountMax {
 		b.logger.Debug("[buy] => skip buy (open pos count: %d >= max(%d))", cnt, b.Config.PositionCountMax)
+		if !b.positionCapped {
+			// 積立のように決済しない戦略は上限に達すると以降は買わなくなるため、気付けるように知らせる
+			b.positionCapped = true
+			b.logger.Info("[%s] open position count reached max, stop buying (count:%d, max:%d)", b.Name(), cnt, b.Config.PositionCountMax)
+			b.notify(fmt.Sprintf("[%s] ポジション数が上限に達したため新規注文を止めます（%d / %d）", b.Name(), cnt, b.Config.PositionCountMax))
+		}
 	} else {
+		if b.positionCapped {
+			b.positionCapped = false
+			b.logger.Info("[%s] open position count is below max, resume buying (count:%d, max:%d)", b.Name(), cnt, b.Config.PositionCountMax)
+		}
 		if err := b.strategy.Buy(b.pair, pp); err != nil {
 			return err
 		}
@@ -70,8 +148,29 @@ func (b *Bot) ReceiveTrade(h *coincheck.TradeHistory) error {
 	if b.strategy == nil {
 		return nil
 	}
+	b.strategyMu.RLock()
+	defer b.strategyMu.RUnlock()
+	if !b.restored {
+		if err := b.RestoreState(); err != nil {
+			return err
+		}
+	}
+	defer func() {
+		if err := b.SaveState(); err != nil {
+			b.logger.Error("failed to save strategy state, %v", err)
+		}
+	}()
 
 	if h.Side == model.BuySide {
+		if session := b.currentSession(); !session.CanEntry() {
+			b.logger.Debug("[buy] => skip buy (session: %s)", session.String())
+			return nil
+		}
+		if stale := b.staleReason(); stale != "" {
+			b.logger.Debug("[buy] => skip buy (stale rate: %s)", stale)
+			return nil
+		}
+
 		pp, err := b.facade.GetOpenPositions()
 		if err != nil {
 			return err
@@ -88,3 +187,172 @@ func (b *Bot) ReceiveTrade(h *coincheck.TradeHistory) error {
 		return b.strategy.SellTradeCallback(b.pair, h.Rate)
 	}
 }
+
+// Name ボット名
+func (b *Bot) Name() string {
+	if b.Config.Name == "" {
+		return DefaultBotName
+	}
+	return b.Config.Name
+}
+
+// RestoreState 保存済みの戦略の状態を復元
+func (b *Bot) RestoreState() error {
+	b.stateMu.Lock()
+	defer b.stateMu.Unlock()
+
+	if b.restored {
+		return nil
+	}
+	st, ok := b.strategy.(StatefulStrategy)
+	if !ok {
+		b.restored = true
+		return nil
+	}
+
+	s, err := b.facade.GetStrategyState(b.Name(), string(b.Config.Strategy), &b.pair)
+	if err != nil {
+		return err
+	}
+	if len(s.Values) > 0 {
+		if err := st.LoadState(s); err != nil {
+			return err
+		}
+		b.logger.Info("restored strategy state %v", s.Values)
+	}
+	b.savedState = s.Values
+	b.restored = true
+	return nil
+}
+
+// SaveState 戦略の状態を保存（前回から変化がなければ何もしない）
+func (b *Bot) SaveState() error {
+	b.stateMu.Lock()
+	defer b.stateMu.Unlock()
+
+	st, ok := b.strategy.(StatefulStrategy)
+	if !ok || !b.restored {
+		// 復元前に保存すると保存済みの状態を上書きしてしまう
+		return nil
+	}
+
+	s := model.NewStrategyState(b.Name(), string(b.Config.Strategy), b.pair.String())
+	if err := st.SaveState(s); err != nil {
+		return err
+	}
+	if reflect.DeepEqual(s.Values, b.savedState) {
+		return nil
+	}
+	if err := b.facade.UpsertStrategyState(s); err != nil {
+		return err
+	}
+	b.savedState = s.Values
+	return nil
+}
+
+// Statuses 戦略が公開する状態を取得
+func (b *Bot) Statuses() []model.BotStatus {
+	st, ok := b.strategy.(StatusStrategy)
+	if !ok {
+		return []model.BotStatus{}
+	}
+	b.strategyMu.RLock()
+	defer b.strategyMu.RUnlock()
+	return st.Statuses(b.Name(), b.pair)
+}
+
+// RequestReload 設定の再読み込みを要求（次回のTrade開始時に適用）
+func (b *Bot) RequestReload() {
+	select {
+	case b.reloadCh <- struct{}{}:
+	default:
+		// 要求済み
+	}
+}
+
+// reload 設定を再読み込み（失敗した場合は以前の設定のまま）
+func (b *Bot) reload() {
+	st, ok := b.strategy.(ReloadableStrategy)
+	if !ok {
+		b.logger.Info("[reload] => skip reload (strategy %s is not reloadable)", b.Config.Strategy)
+		return
+	}
+	path := b.configPath()
+	if path == "" {
+		b.logger.Info("[reload] => skip reload (config path is empty)")
+		return
+	}
+
+	b.strategyMu.Lock()
+	before, after, err := st.ReloadConfig(path)
+	b.strategyMu.Unlock()
+
+	if err != nil {
+		b.logger.Error("[reload] => rejected, keep previous config (%s), %v", path, err)
+		b.notify(fmt.Sprintf("[%s] 設定の再読み込みを中止しました（以前の設定のまま稼働します）\nfile: %s\nerror: %v", b.Name(), path, err))
+		return
+	}
+
+	diffs := DiffConfig(before, after)
+	if len(diffs) == 0 {
+		b.logger.Info("[reload] => reloaded (%s), no changes", path)
+		return
+	}
+	b.logger.Info("[reload] => reloaded (%s), %s", path, strings.Join(diffs, ", "))
+	b.notify(fmt.Sprintf("[%s] 設定を再読み込みしました\nfile: %s\n%s", b.Name(), path, strings.Join(diffs, "\n")))
+}
+
+// configPath 適用する戦略の設定ファイル（取引時間帯のプロファイルがあればそちらを優先）
+func (b *Bot) configPath() string {
+	if b.Schedule != nil && b.session != nil && b.session.Profile != "" {
+		return b.Schedule.ProfileConfigPath(b.session.Profile)
+	}
+	return b.Config.ConfigPath
+}
+
+// currentSession 現在の取引時間帯の状態
+func (b *Bot) currentSession() *schedule.Session {
+	if b.Schedule == nil {
+		return &schedule.Session{InWindow: true}
+	}
+	return b.Schedule.At(b.facade.Now())
+}
+
+// updateSession 取引時間帯の状態を更新（プロファイルが変わった場合は戦略の設定を切り替える）
+func (b *Bot) updateSession() *schedule.Session {
+	session := b.currentSession()
+	before := b.session
+	b.session = session
+	if before != nil && *before == *session {
+		return session
+	}
+	if before == nil && session.CanEntry() && session.Profile == "" {
+		return session
+	}
+
+	b.logger.Info("[schedule] session changed => %s", session.String())
+	canEntryBefore := before == nil || before.CanEntry()
+	if canEntryBefore && !session.CanEntry() {
+		b.notify(fmt.Sprintf("[%s] 新規注文を停止しました（%s）", b.Name(), session.String()))
+	} else if !canEntryBefore && session.CanEntry() {
+		b.notify(fmt.Sprintf("[%s] 新規注文を再開しました（%s）", b.Name(), session.String()))
+	}
+
+	profileBefore := ""
+	if before != nil {
+		profileBefore = before.Profile
+	}
+	if profileBefore != session.Profile {
+		b.reload()
+	}
+	return session
+}
+
+func (b *Bot) notify(message string) {
+	if b.Notifier == nil {
+		return
+	}
+	if err := b.Notifier.Notify(message); err != nil {
+		b.logger.Error("failed to notify, %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package usecase_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/infrastructure/memory"
+	"trading-bot/pkg/usecase"
+	"trading-bot/pkg/usecase/trade"
+)
+
+// statefulStrategy 状態として値を持つだけの戦略
+type statefulStrategy struct {
+	values map[string]string
+	loaded int
+}
+
+func (s *statefulStrategy) Buy(pair model.CurrencyPair, positions []model.Position) error { return nil }
+func (s *statefulStrategy) Sell(pair model.CurrencyPair, positions []model.Position) error {
+	return nil
+}
+func (s *statefulStrategy) BuyTradeCallback(pair model.CurrencyPair, rate float64) error  { return nil }
+func (s *statefulStrategy) SellTradeCallback(pair model.CurrencyPair, rate float64) error { return nil }
+func (s *statefulStrategy) Wait(ctx context.Context) error                                { return nil }
+
+func (s *statefulStrategy) SaveState(st *model.StrategyState) error {
+	for k, v := range s.values {
+		st.Values[k] = v
+	}
+	return nil
+}
+
+func (s *statefulStrategy) LoadState(st *model.StrategyState) error {
+	s.values = map[string]string{}
+	for k, v := range st.Values {
+		s.values[k] = v
+	}
+	s.loaded++
+	return nil
+}
+
+func TestBot_SaveState(t *testing.T) {
+	exCli, err := memory.NewExchangeMock(strings.NewReader("日付, 販売所買い価格, 販売所売り価格\n2021-02-23T19:27:01Z,1000.0,1000.0"), 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	logger := &memory.Logger{Level: memory.Error}
+	newBot := func(rds *memory.DummyRDS, name string, s *statefulStrategy) *usecase.Bot {
+		facade := trade.NewFacade(exCli, rds, rds, rds, rds, rds, rds, nil)
+		return usecase.NewBot(logger, facade, s, &usecase.BotConfig{Name: name, Strategy: usecase.Grid, Currency: model.BTC})
+	}
+	get := func(rds *memory.DummyRDS, name string) map[string]string {
+		st, err := rds.GetStrategyState(name, string(usecase.Grid), model.BtcJpy.String())
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		return st.Values
+	}
+
+	tests := map[string]struct {
+		// 保存済みの状態
+		saved map[string]string
+		// 復元してから保存するか
+		restore bool
+		// 保存後の状態と、再起動したボットが復元する状態
+		want map[string]string
+	}{
+		"round trip": {
+			saved:   map[string]string{},
+			restore: true,
+			want:    map[string]string{"level": "3"},
+		},
+		"restore and overwrite": {
+			saved:   map[string]string{"level": "1", "base": "1000"},
+			restore: true,
+			want:    map[string]string{"level": "3"},
+		},
+		// 復元前に保存すると保存済みの状態を上書きしてしまうため保存しない
+		"save before restore": {
+			saved:   map[string]string{"level": "1"},
+			restore: false,
+			want:    map[string]string{"level": "1"},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			rds := memory.NewDummyRDS(nil)
+			if len(tt.saved) > 0 {
+				st := model.NewStrategyState("test", string(usecase.Grid), model.BtcJpy.String())
+				for k, v := range tt.saved {
+					st.Values[k] = v
+				}
+				if err := rds.UpsertStrategyState(st); err != nil {
+					t.Fatal(err.Error())
+				}
+			}
+
+			s := &statefulStrategy{values: map[string]string{}}
+			bot := newBot(rds, "test", s)
+			if tt.restore {
+				if err := bot.RestoreState(); err != nil {
+					t.Fatal(err.Error())
+				}
+				if len(tt.saved) > 0 && s.loaded != 1 {
+					t.Errorf("loaded = %d, want 1", s.loaded)
+				}
+				// 2回目は復元しない
+				if err := bot.RestoreState(); err != nil {
+					t.Fatal(err.Error())
+				}
+				if s.loaded > 1 {
+					t.Errorf("loaded = %d, want restored once", s.loaded)
+				}
+			}
+			s.values = map[string]string{"level": "3"}
+			if err := bot.SaveState(); err != nil {
+				t.Fatal(err.Error())
+			}
+			if got := get(rds, "test"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("saved state = %v, want %v", got, tt.want)
+			}
+			// 他のボットの状態とは分ける
+			if got := get(rds, "other"); len(got) != 0 {
+				t.Errorf("other bot state = %v, want empty", got)
+			}
+
+			// 再起動したボットが保存した状態を復元する
+			restarted := &statefulStrategy{values: map[string]string{}}
+			if err := newBot(rds, "test", restarted).RestoreState(); err != nil {
+				t.Fatal(err.Error())
+			}
+			if !reflect.DeepEqual(restarted.values, tt.want) {
+				t.Errorf("restored state = %v, want %v", restarted.values, tt.want)
+			}
+		})
+	}
+}
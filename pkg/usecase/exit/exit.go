@@ -0,0 +1,246 @@
+package exit
+
+import (
+	"math"
+	"time"
+	"trading-bot/pkg/domain"
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/usecase/trade"
+)
+
+// Config 決済ポリシー用設定（0の項目は無効）
+type Config struct {
+	// 利確ライン（買値に対する割合）
+	TakeProfitPer float64 `toml:"take_profit_per"`
+	// 損切ライン（買値に対する割合）
+	StopLossPer float64 `toml:"stop_loss_per"`
+
+	// ATRの算出期間
+	ATRPeriod int `toml:"atr_period"`
+	// 損切幅（ATRの何倍か）
+	ATRMultiplier float64 `toml:"atr_multiplier"`
+
+	// トレーリングストップ（最高値に対する割合）
+	TrailingStopPer float64 `toml:"trailing_stop_per"`
+
+	// 損切ラインを建値に移動する含み益（買値に対する割合）
+	BreakEvenTriggerPer float64 `toml:"break_even_trigger_per"`
+
+	// 最大保有時間（秒）
+	MaxHoldingSeconds int `toml:"max_holding_seconds"`
+}
+
+// Target 判定対象
+type Target struct {
+	PositionIDs []uint64
+	// 購入金額(JPY)
+	BuyJPY float64
+	// 保有量
+	Amount float64
+	// 現在の売レート
+	Rate float64
+	// レート履歴
+	Rates []float64
+	// 保有開始後の最高レート
+	HighWaterRate float64
+	// 保有開始日時
+	OpenedAt time.Time
+	// 現在日時
+	Now time.Time
+}
+
+// EntryRate 平均取得レート
+func (t *Target) EntryRate() float64 {
+	if t.Amount == 0 {
+		return 0
+	}
+	return t.BuyJPY / t.Amount
+}
+
+// SellJPY 現レートでの評価額(JPY)
+func (t *Target) SellJPY() float64 {
+	return t.Rate * t.Amount
+}
+
+// Policy 決済ポリシー
+type Policy interface {
+	// Name ポリシー名
+	Name() string
+	// ShouldExit 決済すべきか判定し、理由を返す
+	ShouldExit(t *Target) (bool, string)
+}
+
+// HighWaterPolicy 保有開始後の最高値を使う決済ポリシー
+// 最高値は再起動後も使えるようにポジションの状態として保存する
+type HighWaterPolicy interface {
+	Policy
+	// UsesHighWater 最高値を使うか
+	UsesHighWater() bool
+}
+
+// Result 判定結果
+type Result struct {
+	Policy string
+	Reason string
+}
+
+// Chain 決済ポリシーの連鎖
+type Chain struct {
+	logger   domain.Logger
+	facade   *trade.Facade
+	policies []Policy
+	// 最高値を使うポリシーがあるか（なければ毎回の状態の読み書きを省く）
+	highWater bool
+}
+
+// NewChain 生成
+func NewChain(facade *trade.Facade, logger domain.Logger, policies ...Policy) *Chain {
+	c := &Chain{
+		logger:   logger,
+		facade:   facade,
+		policies: policies,
+	}
+	for _, p := range policies {
+		if hp, ok := p.(HighWaterPolicy); ok && hp.UsesHighWater() {
+			c.highWater = true
+		}
+	}
+	return c
+}
+
+// NewChainFromConfig 設定から生成
+func NewChainFromConfig(facade *trade.Facade, logger domain.Logger, c *Config) *Chain {
+	policies := []Policy{}
+	if c.TakeProfitPer > 0 {
+		policies = append(policies, &TakeProfit{Per: c.TakeProfitPer})
+	}
+	if c.StopLossPer > 0 {
+		policies = append(policies, &StopLoss{Per: c.StopLossPer})
+	}
+	if c.ATRPeriod > 0 && c.ATRMultiplier > 0 {
+		policies = append(policies, &ATRStop{Period: c.ATRPeriod, Multiplier: c.ATRMultiplier})
+	}
+	if c.TrailingStopPer > 0 {
+		policies = append(policies, &TrailingStop{Per: c.TrailingStopPer})
+	}
+	if c.BreakEvenTriggerPer > 0 {
+		policies = append(policies, &BreakEven{TriggerPer: c.BreakEvenTriggerPer})
+	}
+	if c.MaxHoldingSeconds > 0 {
+		policies = append(policies, &MaxHolding{Duration: time.Duration(c.MaxHoldingSeconds) * time.Second})
+	}
+	return NewChain(facade, logger, policies...)
+}
+
+// Check ポジション単位で決済すべきか判定
+func (c *Chain) Check(pos *model.Position, rate float64, rates []float64) (*Result, error) {
+	return c.CheckGroup([]model.Position{*pos}, rate, rates)
+}
+
+// CheckGroup 複数ポジションをまとめて決済すべきか判定
+func (c *Chain) CheckGroup(positions []model.Position, rate float64, rates []float64) (*Result, error) {
+	if len(positions) == 0 {
+		return nil, nil
+	}
+
+	t := &Target{
+		PositionIDs:   []uint64{},
+		Rate:          rate,
+		Rates:         rates,
+		HighWaterRate: math.MaxFloat64,
+		Now:           c.facade.Now(),
+	}
+	if !c.highWater {
+		t.HighWaterRate = 0
+	}
+	states := []*model.PositionState{}
+	for i, pos := range positions {
+		cc, err := c.facade.GetContracts(pos.OpenerOrder.ID)
+		if err != nil {
+			return nil, err
+		}
+		buyJPY, amount := 0.0, 0.0
+		for _, contract := range cc {
+			buyJPY += -contract.DecreaseAmount
+			amount += contract.IncreaseAmount
+		}
+
+		if c.highWater {
+			s, err := c.updateHighWater(&pos, buyJPY, amount, rate)
+			if err != nil {
+				return nil, err
+			}
+			states = append(states, s)
+			// 最も新しいポジションの保有開始後の最高値を採用
+			t.HighWaterRate = math.Min(t.HighWaterRate, s.HighWaterRate)
+		}
+
+		t.PositionIDs = append(t.PositionIDs, pos.ID)
+		t.BuyJPY += buyJPY
+		t.Amount += amount
+		if i == 0 || pos.OpenerOrder.OrderedAt.Before(t.OpenedAt) {
+			t.OpenedAt = pos.OpenerOrder.OrderedAt
+		}
+	}
+
+	if t.Amount == 0 {
+		c.logger.Debug("[pos:%v][exit] => skip exit check (not contracted)", t.PositionIDs)
+		return nil, nil
+	}
+
+	for _, p := range c.policies {
+		exit, reason := p.ShouldExit(t)
+		if !exit {
+			c.logger.Debug("[pos:%v][%s] => skip exit (%s)", t.PositionIDs, p.Name(), reason)
+			continue
+		}
+
+		c.logger.Debug("[pos:%v][%s] => should exit (%s)", t.PositionIDs, p.Name(), reason)
+		if !c.highWater {
+			// 決済理由を記録するときのみ状態を読み込む
+			for _, pos := range positions {
+				s, err := c.facade.GetPositionState(pos.ID)
+				if err != nil {
+					return nil, err
+				}
+				if s == nil {
+					s = &model.PositionState{PositionID: pos.ID}
+				}
+				states = append(states, s)
+			}
+		}
+		for _, s := range states {
+			s.ExitReason = p.Name() + ": " + reason
+			if err := c.facade.UpsertPositionState(s); err != nil {
+				return nil, err
+			}
+		}
+		return &Result{Policy: p.Name(), Reason: reason}, nil
+	}
+
+	return nil, nil
+}
+
+// updateHighWater 保有開始後の最高値を更新
+func (c *Chain) updateHighWater(pos *model.Position, buyJPY, amount, rate float64) (*model.PositionState, error) {
+	s, err := c.facade.GetPositionState(pos.ID)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		s = &model.PositionState{PositionID: pos.ID}
+		if amount > 0 {
+			s.HighWaterRate = buyJPY / amount
+		}
+	}
+
+	if rate <= s.HighWaterRate {
+		return s, nil
+	}
+
+	s.HighWaterRate = rate
+	if err := c.facade.UpsertPositionState(s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
@@ -0,0 +1,230 @@
+package exit_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/infrastructure/memory"
+	"trading-bot/pkg/usecase/exit"
+	"trading-bot/pkg/usecase/trade"
+)
+
+func TestPolicies(t *testing.T) {
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	base := exit.Target{
+		BuyJPY:        1000,
+		Amount:        10,
+		Rate:          100,
+		Rates:         []float64{100, 100, 100, 100, 100},
+		HighWaterRate: 100,
+		OpenedAt:      now.Add(-1 * time.Hour),
+		Now:           now,
+	}
+
+	tests := map[string]struct {
+		policy exit.Policy
+		modify func(t *exit.Target)
+		want   bool
+	}{
+		"take profit not reached": {
+			policy: &exit.TakeProfit{Per: 1.1},
+			modify: func(t *exit.Target) { t.Rate = 110 },
+			want:   false,
+		},
+		"take profit reached": {
+			policy: &exit.TakeProfit{Per: 1.1},
+			modify: func(t *exit.Target) { t.Rate = 111 },
+			want:   true,
+		},
+		"stop loss not reached": {
+			policy: &exit.StopLoss{Per: 0.9},
+			modify: func(t *exit.Target) { t.Rate = 90 },
+			want:   false,
+		},
+		"stop loss reached": {
+			policy: &exit.StopLoss{Per: 0.9},
+			modify: func(t *exit.Target) { t.Rate = 89 },
+			want:   true,
+		},
+		"atr stop without enough rates": {
+			policy: &exit.ATRStop{Period: 10, Multiplier: 1},
+			modify: func(t *exit.Target) { t.Rate = 1 },
+			want:   false,
+		},
+		"atr stop reached": {
+			policy: &exit.ATRStop{Period: 2, Multiplier: 2},
+			modify: func(t *exit.Target) {
+				t.Rates = []float64{100, 102, 100, 102, 100}
+				t.Rate = 95
+			},
+			want: true,
+		},
+		"atr stop within range": {
+			policy: &exit.ATRStop{Period: 2, Multiplier: 2},
+			modify: func(t *exit.Target) {
+				t.Rates = []float64{100, 102, 100, 102, 100}
+				t.Rate = 97
+			},
+			want: false,
+		},
+		"trailing stop tracks high water": {
+			policy: &exit.TrailingStop{Per: 0.95},
+			modify: func(t *exit.Target) {
+				t.HighWaterRate = 120
+				t.Rate = 113
+			},
+			want: true,
+		},
+		"trailing stop not reached": {
+			policy: &exit.TrailingStop{Per: 0.95},
+			modify: func(t *exit.Target) {
+				t.HighWaterRate = 120
+				t.Rate = 115
+			},
+			want: false,
+		},
+		"break even not armed": {
+			policy: &exit.BreakEven{TriggerPer: 1.05},
+			modify: func(t *exit.Target) {
+				t.HighWaterRate = 104
+				t.Rate = 99
+			},
+			want: false,
+		},
+		"break even armed and returned to entry": {
+			policy: &exit.BreakEven{TriggerPer: 1.05},
+			modify: func(t *exit.Target) {
+				t.HighWaterRate = 106
+				t.Rate = 100
+			},
+			want: true,
+		},
+		"max holding not reached": {
+			policy: &exit.MaxHolding{Duration: 2 * time.Hour},
+			modify: func(t *exit.Target) {},
+			want:   false,
+		},
+		"max holding reached": {
+			policy: &exit.MaxHolding{Duration: 30 * time.Minute},
+			modify: func(t *exit.Target) {},
+			want:   true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			target := base
+			tt.modify(&target)
+			got, reason := tt.policy.ShouldExit(&target)
+			if got != tt.want {
+				t.Errorf("ShouldExit() = %v, want %v (reason: %s)", got, tt.want, reason)
+			}
+			if reason == "" {
+				t.Errorf("ShouldExit() reason is empty")
+			}
+		})
+	}
+}
+
+func TestChain_HighWaterPersists(t *testing.T) {
+	rates := []string{
+		"日付, 販売所買い価格, 販売所売り価格",
+		"2021-02-23T19:27:01Z,100.0,100.0",
+	}
+	exCli, err := memory.NewExchangeMock(strings.NewReader(strings.Join(rates, "\n")), 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	rds := memory.NewDummyRDS(nil)
+	facade := trade.NewFacade(exCli, rds, rds, rds, rds, rds, rds, nil)
+	logger := &memory.Logger{Level: memory.Error}
+
+	pos, err := facade.SendMarketBuyOrder(&model.BtcJpy, 1000, nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	cc, err := exCli.GetContracts()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := rds.UpsertContracts(cc); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	config := &exit.Config{TrailingStopPer: 0.9}
+	chain := exit.NewChainFromConfig(facade, logger, config)
+	if r, err := chain.Check(pos, 150, nil); err != nil || r != nil {
+		t.Fatalf("Check() = %v, %v; want nil, nil", r, err)
+	}
+
+	// 再起動を想定して新しいChainで判定
+	restarted := exit.NewChainFromConfig(facade, logger, config)
+	r, err := restarted.Check(pos, 130, nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if r == nil || r.Policy != "trailing_stop" {
+		t.Fatalf("Check() = %v; want trailing_stop", r)
+	}
+
+	s, err := facade.GetPositionState(pos.ID)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if s.HighWaterRate != 150 {
+		t.Errorf("HighWaterRate = %v, want 150", s.HighWaterRate)
+	}
+	if !strings.HasPrefix(s.ExitReason, "trailing_stop") {
+		t.Errorf("ExitReason = %q, want trailing_stop prefix", s.ExitReason)
+	}
+}
+
+func TestChain_StatelessPolicies(t *testing.T) {
+	rates := []string{
+		"日付, 販売所買い価格, 販売所売り価格",
+		"2021-02-23T19:27:01Z,100.0,100.0",
+	}
+	exCli, err := memory.NewExchangeMock(strings.NewReader(strings.Join(rates, "\n")), 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	rds := memory.NewDummyRDS(nil)
+	facade := trade.NewFacade(exCli, rds, rds, rds, rds, rds, rds, nil)
+	logger := &memory.Logger{Level: memory.Error}
+
+	pos, err := facade.SendMarketBuyOrder(&model.BtcJpy, 1000, nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	cc, err := exCli.GetContracts()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := rds.UpsertContracts(cc); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// 最高値を使わないポリシーのみなら、決済しない間は状態を保存しない
+	chain := exit.NewChain(facade, logger, &exit.TakeProfit{Per: 1.2}, &exit.StopLoss{Per: 0.9})
+	if r, err := chain.Check(pos, 110, nil); err != nil || r != nil {
+		t.Fatalf("Check() = %v, %v; want nil, nil", r, err)
+	}
+	if s, err := facade.GetPositionState(pos.ID); err != nil || s != nil {
+		t.Fatalf("GetPositionState() = %+v, %v; want nil, nil", s, err)
+	}
+
+	// 決済するときは決済理由を保存する
+	if r, err := chain.Check(pos, 150, nil); err != nil || r == nil || r.Policy != "take_profit" {
+		t.Fatalf("Check() = %v, %v; want take_profit", r, err)
+	}
+	s, err := facade.GetPositionState(pos.ID)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if s == nil || !strings.HasPrefix(s.ExitReason, "take_profit") {
+		t.Fatalf("state = %+v, want take_profit exit reason", s)
+	}
+	if s.HighWaterRate != 0 {
+		t.Errorf("HighWaterRate = %v, want 0 (not tracked)", s.HighWaterRate)
+	}
+}
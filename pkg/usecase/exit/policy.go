@@ -0,0 +1,146 @@
+package exit
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/markcheno/go-talib"
+)
+
+// TakeProfit 固定利確
+type TakeProfit struct {
+	Per float64
+}
+
+// Name ポリシー名
+func (p *TakeProfit) Name() string {
+	return "take_profit"
+}
+
+// ShouldExit 決済すべきか判定
+func (p *TakeProfit) ShouldExit(t *Target) (bool, string) {
+	limit := t.BuyJPY * p.Per
+	sellJPY := t.SellJPY()
+	if sellJPY <= limit {
+		return false, fmt.Sprintf("sell[jpy:%.3f] <= upper limit[jpy:%.3f] = buy[jpy:%.3f] * %.3f", sellJPY, limit, t.BuyJPY, p.Per)
+	}
+	return true, fmt.Sprintf("sell[jpy:%.3f] > upper limit[jpy:%.3f] = buy[jpy:%.3f] * %.3f", sellJPY, limit, t.BuyJPY, p.Per)
+}
+
+// StopLoss 固定損切
+type StopLoss struct {
+	Per float64
+}
+
+// Name ポリシー名
+func (p *StopLoss) Name() string {
+	return "stop_loss"
+}
+
+// ShouldExit 決済すべきか判定
+func (p *StopLoss) ShouldExit(t *Target) (bool, string) {
+	limit := t.BuyJPY * p.Per
+	sellJPY := t.SellJPY()
+	if sellJPY >= limit {
+		return false, fmt.Sprintf("sell[jpy:%.3f] >= lower limit[jpy:%.3f] = buy[jpy:%.3f] * %.3f", sellJPY, limit, t.BuyJPY, p.Per)
+	}
+	return true, fmt.Sprintf("sell[jpy:%.3f] < lower limit[jpy:%.3f] = buy[jpy:%.3f] * %.3f", sellJPY, limit, t.BuyJPY, p.Per)
+}
+
+// ATRStop ATRを基準にした損切
+type ATRStop struct {
+	Period     int
+	Multiplier float64
+}
+
+// Name ポリシー名
+func (p *ATRStop) Name() string {
+	return "atr_stop"
+}
+
+// ShouldExit 決済すべきか判定
+func (p *ATRStop) ShouldExit(t *Target) (bool, string) {
+	if len(t.Rates) <= p.Period {
+		return false, fmt.Sprintf("rate count:%d <= required:%d", len(t.Rates), p.Period)
+	}
+
+	// 終値しかないため高値・安値にも同じ値を使う
+	atrs := talib.Atr(t.Rates, t.Rates, t.Rates, p.Period)
+	atr := atrs[len(atrs)-1]
+	stopRate := t.EntryRate() - atr*p.Multiplier
+	if t.Rate >= stopRate {
+		return false, fmt.Sprintf("rate:%.3f >= stop:%.3f = entry:%.3f - atr:%.3f * %.3f", t.Rate, stopRate, t.EntryRate(), atr, p.Multiplier)
+	}
+	return true, fmt.Sprintf("rate:%.3f < stop:%.3f = entry:%.3f - atr:%.3f * %.3f", t.Rate, stopRate, t.EntryRate(), atr, p.Multiplier)
+}
+
+// TrailingStop 最高値に追従する損切
+type TrailingStop struct {
+	Per float64
+}
+
+// Name ポリシー名
+func (p *TrailingStop) Name() string {
+	return "trailing_stop"
+}
+
+// UsesHighWater 最高値を使うか
+func (p *TrailingStop) UsesHighWater() bool {
+	return true
+}
+
+// ShouldExit 決済すべきか判定
+func (p *TrailingStop) ShouldExit(t *Target) (bool, string) {
+	stopRate := t.HighWaterRate * p.Per
+	if t.Rate >= stopRate {
+		return false, fmt.Sprintf("rate:%.3f >= stop:%.3f = high:%.3f * %.3f", t.Rate, stopRate, t.HighWaterRate, p.Per)
+	}
+	return true, fmt.Sprintf("rate:%.3f < stop:%.3f = high:%.3f * %.3f", t.Rate, stopRate, t.HighWaterRate, p.Per)
+}
+
+// BreakEven 一定の含み益が出たら損切ラインを建値に移動
+type BreakEven struct {
+	TriggerPer float64
+}
+
+// Name ポリシー名
+func (p *BreakEven) Name() string {
+	return "break_even"
+}
+
+// UsesHighWater 最高値を使うか
+func (p *BreakEven) UsesHighWater() bool {
+	return true
+}
+
+// ShouldExit 決済すべきか判定
+func (p *BreakEven) ShouldExit(t *Target) (bool, string) {
+	entry := t.EntryRate()
+	trigger := entry * p.TriggerPer
+	if t.HighWaterRate < trigger {
+		return false, fmt.Sprintf("not armed (high:%.3f < trigger:%.3f = entry:%.3f * %.3f)", t.HighWaterRate, trigger, entry, p.TriggerPer)
+	}
+	if t.Rate > entry {
+		return false, fmt.Sprintf("rate:%.3f > entry:%.3f (high:%.3f >= trigger:%.3f)", t.Rate, entry, t.HighWaterRate, trigger)
+	}
+	return true, fmt.Sprintf("rate:%.3f <= entry:%.3f (high:%.3f >= trigger:%.3f)", t.Rate, entry, t.HighWaterRate, trigger)
+}
+
+// MaxHolding 最大保有時間
+type MaxHolding struct {
+	Duration time.Duration
+}
+
+// Name ポリシー名
+func (p *MaxHolding) Name() string {
+	return "max_holding"
+}
+
+// ShouldExit 決済すべきか判定
+func (p *MaxHolding) ShouldExit(t *Target) (bool, string) {
+	held := t.Now.Sub(t.OpenedAt)
+	if held < p.Duration {
+		return false, fmt.Sprintf("held:%v < max:%v", held, p.Duration)
+	}
+	return true, fmt.Sprintf("held:%v >= max:%v", held, p.Duration)
+}
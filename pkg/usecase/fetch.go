@@ -1,10 +1,11 @@
 package usecase
 
 import (
-	"time"
+	"trading-bot/pkg/domain"
 	"trading-bot/pkg/domain/exchange"
 	"trading-bot/pkg/domain/model"
 	"trading-bot/pkg/domain/repository"
+	"trading-bot/pkg/usecase/trade"
 )
 
 // Fetcher 情報取得
@@ -12,6 +13,7 @@ type Fetcher struct {
 	pair   model.CurrencyPair
 	exCli  exchange.Client
 	rdsCli repository.TradeRepository
+	clock  domain.Clock
 }
 
 // NewFetcher 生成
@@ -20,9 +22,15 @@ func NewFetcher(exCli exchange.Client, pair model.CurrencyPair, rdsCli repositor
 		exCli:  exCli,
 		pair:   pair,
 		rdsCli: rdsCli,
+		clock:  domain.SystemClock{},
 	}
 }
 
+// SetClock 時計を設定（レートの記録日時に使う）
+func (f *Fetcher) SetClock(c domain.Clock) {
+	f.clock = c
+}
+
 // Fetch 各種情報を取得
 func (f *Fetcher) Fetch() error {
 	r, err := f.exCli.GetOrderRate(&f.pair, model.SellSide)
@@ -30,82 +38,10 @@ func (f *Fetcher) Fetch() error {
 		return err
 	}
 
-	now := time.Now()
+	now := f.clock.Now()
 	if err := f.rdsCli.AddRates(&f.pair, float64(r.Rate), now); err != nil {
 		return err
 	}
 
-	if err := f.fetchContracts(); err != nil {
-		return err
-	}
-
-	if err := f.fetchOrders(); err != nil {
-		return err
-	}
-
-	return nil
-}
-
-//fetchContracts 約定情報を更新
-func (f *Fetcher) fetchContracts() error {
-	oo, err := f.rdsCli.GetOpenOrders()
-	if err != nil {
-		return err
-	}
-	if len(oo) == 0 {
-		return nil
-	}
-
-	cc, err := f.exCli.GetContracts()
-	if err != nil {
-		return err
-	}
-
-	targets := []model.Contract{}
-	for _, c := range cc {
-		for _, o := range oo {
-			if c.OrderID == o.ID {
-				targets = append(targets, c)
-				break
-			}
-		}
-	}
-
-	if err := f.rdsCli.UpsertContracts(targets); err != nil {
-		return err
-	}
-
-	return nil
-}
-
-// FetchOrders 注文情報を更新
-func (f *Fetcher) fetchOrders() error {
-	openOrders, err := f.exCli.GetOpenOrders(&f.pair)
-	if err != nil {
-		return err
-	}
-
-	registeredOrders, err := f.rdsCli.GetOpenOrders()
-	if err != nil {
-		return err
-	}
-
-	for _, o := range registeredOrders {
-		opened := false
-		for _, openOrder := range openOrders {
-			if openOrder.ID == o.ID {
-				opened = true
-				break
-			}
-		}
-
-		if opened {
-			continue
-		}
-		if err := f.rdsCli.UpdateStatus(o.ID, model.Closed); err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return trade.SyncOrders(f.exCli, &f.pair, f.rdsCli, f.rdsCli)
 }
@@ -0,0 +1,98 @@
+package usecase
+
+import (
+	"fmt"
+	"strings"
+	"trading-bot/pkg/usecase/trade"
+)
+
+// FlattenResult 全決済の結果
+type FlattenResult struct {
+	// ボット名
+	Bot string `json:"bot"`
+	// 通貨ペア
+	Pair string `json:"pair"`
+	// 取り消した注文
+	CanceledOrders []string `json:"canceled_orders"`
+	// 決済したポジション
+	ClosedPositions []string `json:"closed_positions"`
+	// 発生したエラー
+	Errors []string `json:"errors"`
+}
+
+// Summary 通知用の要約
+func (r *FlattenResult) Summary() string {
+	s := fmt.Sprintf("[%s] %s 注文取消 %d件 / ポジション決済 %d件", r.Bot, r.Pair, len(r.CanceledOrders), len(r.ClosedPositions))
+	if len(r.Errors) > 0 {
+		s += fmt.Sprintf(" / エラー %d件\n%s", len(r.Errors), strings.Join(r.Errors, "\n"))
+	}
+	return s
+}
+
+// Flatten 対象通貨ペアの未約定の注文を全て取り消し、全ポジションを成行で決済してDBを取引所に合わせる
+// 実行中は戦略の処理を止める
+func (b *Bot) Flatten() *FlattenResult {
+	b.strategyMu.Lock()
+	defer b.strategyMu.Unlock()
+
+	result := &FlattenResult{
+		Bot:             b.Name(),
+		Pair:            b.pair.String(),
+		CanceledOrders:  []string{},
+		ClosedPositions: []string{},
+		Errors:          []string{},
+	}
+	addError := func(format string, args ...interface{}) {
+		message := fmt.Sprintf(format, args...)
+		b.logger.Error("[flatten][%s] %s", result.Bot, message)
+		result.Errors = append(result.Errors, message)
+	}
+
+	canceled, err := b.facade.CancelOpenOrders(&b.pair)
+	for _, o := range canceled {
+		b.logger.Info("[flatten][%s] canceled %s", result.Bot, o.String())
+		result.CanceledOrders = append(result.CanceledOrders, o.String())
+	}
+	if err != nil {
+		addError("failed to cancel orders, %v", err)
+	}
+
+	positions, err := b.facade.GetOpenPositions()
+	if err != nil {
+		addError("failed to get positions, %v", err)
+	}
+	for _, p := range positions {
+		p := p
+		if p.OpenerOrder.Pair != b.pair {
+			continue
+		}
+		order, err := b.facade.ClosePosition(&p)
+		if err != nil {
+			addError("failed to close position %d, %v", p.ID, err)
+			continue
+		}
+		if order == nil {
+			continue
+		}
+		b.logger.Info("[flatten][%s] closed position %d => %s", result.Bot, p.ID, order.String())
+		result.ClosedPositions = append(result.ClosedPositions, fmt.Sprintf("position %d: %s", p.ID, order.String()))
+	}
+
+	if err := b.facade.SyncOrders(&b.pair); err != nil {
+		addError("failed to sync orders, %v", err)
+	}
+
+	b.notify(fmt.Sprintf("全決済を実行しました\n%s", result.Summary()))
+	return result
+}
+
+// RiskFlattener リスク管理の全決済で使う全決済（戦略の処理を止めて決済する）
+func (b *Bot) RiskFlattener() trade.Flattener {
+	return func() (int, error) {
+		result := b.Flatten()
+		if len(result.Errors) > 0 {
+			return len(result.ClosedPositions), fmt.Errorf("%s", strings.Join(result.Errors, ", "))
+		}
+		return len(result.ClosedPositions), nil
+	}
+}
@@ -0,0 +1,236 @@
+package usecase_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/infrastructure/memory"
+	"trading-bot/pkg/usecase"
+	"trading-bot/pkg/usecase/trade"
+)
+
+func TestBot_Flatten(t *testing.T) {
+	rates := []string{
+		"日付, 販売所買い価格, 販売所売り価格",
+		"2021-02-23T19:27:01Z,1000.0,1000.0",
+	}
+	exCli, err := memory.NewExchangeMock(strings.NewReader(strings.Join(rates, "\n")), 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	rds := memory.NewDummyRDS(nil)
+	facade := trade.NewFacade(exCli, rds, rds, rds, rds, rds, rds, nil)
+	logger := &memory.Logger{Level: memory.Error}
+	bot := usecase.NewBot(logger, facade, nil, &usecase.BotConfig{Currency: model.BTC})
+
+	// 約定済みで決済注文が未約定のポジション
+	filled, err := facade.SendMarketBuyOrder(&model.BtcJpy, 1000, nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := facade.SyncOrders(&model.BtcJpy); err != nil {
+		t.Fatal(err.Error())
+	}
+	if _, err := facade.SendSellOrder(&model.BtcJpy, 1, 2000, filled); err != nil {
+		t.Fatal(err.Error())
+	}
+	// 買い注文が未約定のポジション
+	if _, err := facade.SendBuyOrder(&model.BtcJpy, 1, 500, nil); err != nil {
+		t.Fatal(err.Error())
+	}
+	// 他の通貨ペアの未約定の注文（ポートフォリオの別のボットの注文）
+	other, err := facade.SendBuyOrder(&model.MonaJpy, 1, 1, nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	result := bot.Flatten()
+	if len(result.Errors) > 0 {
+		t.Fatalf("Flatten() errors = %v", result.Errors)
+	}
+	if len(result.CanceledOrders) != 2 {
+		t.Errorf("canceled orders = %v, want 2 orders", result.CanceledOrders)
+	}
+	if len(result.ClosedPositions) != 1 {
+		t.Errorf("closed positions = %v, want 1 position", result.ClosedPositions)
+	}
+
+	orders, err := exCli.GetOpenOrders(&model.BtcJpy)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(orders) != 0 {
+		t.Errorf("open orders on exchange = %v, want none", orders)
+	}
+	positions, err := facade.GetOpenPositions()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(positions) != 1 || positions[0].ID != other.ID {
+		t.Errorf("open positions = %v, want only the other pair's position", positions)
+	}
+	// 他の通貨ペアの注文は取り消さず、約定済みにもしない
+	registered, err := rds.GetOpenOrders()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(registered) != 1 || registered[0].ID != other.OpenerOrder.ID {
+		t.Errorf("open orders in db = %v, want only the other pair's order", registered)
+	}
+	if others, _ := exCli.GetOpenOrders(&model.MonaJpy); len(others) != 1 {
+		t.Errorf("open orders of the other pair on exchange = %v, want 1 order", others)
+	}
+	profit, err := rds.GetProfit()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	// 1000で買って1000で売る
+	if profit != 0 {
+		t.Errorf("profit = %v, want 0", profit)
+	}
+}
+
+func TestBot_RiskFlattener(t *testing.T) {
+	rates := []string{
+		"日付, 販売所買い価格, 販売所売り価格",
+		"2021-02-23T19:27:01Z,1000.0,1000.0",
+		"2021-02-23T19:27:02Z,900.0,900.0",
+	}
+	exCli, err := memory.NewExchangeMock(strings.NewReader(strings.Join(rates, "\n")), 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	exCli.SetBalance(model.JPY, 100000)
+	rds := memory.NewDummyRDS(nil)
+	facade := trade.NewFacade(exCli, rds, rds, rds, rds, rds, rds, nil)
+	logger := &memory.Logger{Level: memory.Error}
+	bot := usecase.NewBot(logger, facade, nil, &usecase.BotConfig{Currency: model.BTC})
+
+	risk := trade.NewRiskManager(logger, exCli, rds, trade.RiskConfig{DailyLossLimitJPY: 3000, Flatten: true})
+	risk.Attach(model.BtcJpy, facade)
+	risk.SetFlattener(facade, bot.RiskFlattener())
+
+	now := time.Date(2021, 2, 23, 10, 0, 0, 0, time.UTC)
+	if _, err := risk.Update(now); err != nil {
+		t.Fatal(err.Error())
+	}
+	filled, err := facade.SendMarketBuyOrder(&model.BtcJpy, 50000, nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := facade.SyncOrders(&model.BtcJpy); err != nil {
+		t.Fatal(err.Error())
+	}
+	// 戦略が出した未約定の決済注文
+	if _, err := facade.SendSellOrder(&model.BtcJpy, 50, 2000, filled); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// 1000 => 900 で損失の上限を超え、ボットの全決済で決済する
+	exCli.NextStep()
+	state, err := risk.Update(now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if !state.Halted {
+		t.Fatalf("Halted = false, want true")
+	}
+	orders, err := exCli.GetOpenOrders(&model.BtcJpy)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(orders) != 0 {
+		t.Errorf("open orders on exchange = %v, want none", orders)
+	}
+	positions, err := facade.GetOpenPositions()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(positions) != 0 {
+		t.Errorf("open positions = %v, want none", positions)
+	}
+}
+
+func TestBot_Flatten_Guard(t *testing.T) {
+	rates := []string{
+		"日付, 販売所買い価格, 販売所売り価格",
+		"2021-02-23T19:27:01Z,1000.0,1000.0",
+	}
+	exCli, err := memory.NewExchangeMock(strings.NewReader(strings.Join(rates, "\n")), 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	rds := memory.NewDummyRDS(nil)
+	facade := trade.NewFacade(exCli, rds, rds, rds, rds, rds, rds, nil)
+	logger := &memory.Logger{Level: memory.Error}
+	bot := usecase.NewBot(logger, facade, nil, &usecase.BotConfig{Currency: model.BTC})
+
+	// 注文数の上限まで新規注文した後
+	trade.NewOrderGuard(logger, exCli, rds, trade.GuardConfig{MaxOrders: 1, Window: time.Minute}).Attach("test", facade)
+	if _, err := facade.SendMarketBuyOrder(&model.BtcJpy, 1000, nil); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := facade.SyncOrders(&model.BtcJpy); err != nil {
+		t.Fatal(err.Error())
+	}
+	// 新規注文は全て拒否する設定でも決済注文は通す
+	config := trade.GuardConfig{MaxOrderJPY: 100, DisabledPairs: []string{"btc_jpy"}}
+	trade.NewOrderGuard(logger, exCli, rds, config).Attach("test", facade)
+
+	result := bot.Flatten()
+	if len(result.Errors) > 0 {
+		t.Fatalf("Flatten() errors = %v", result.Errors)
+	}
+	if len(result.ClosedPositions) != 1 {
+		t.Errorf("closed positions = %v, want 1 position", result.ClosedPositions)
+	}
+	if events := rds.Events(); len(events) != 0 {
+		t.Errorf("rejected events = %v, want none", events)
+	}
+	if _, err := facade.SendMarketBuyOrder(&model.BtcJpy, 10, nil); err == nil {
+		t.Errorf("SendMarketBuyOrder() error is nil; disabled pair")
+	}
+}
+
+// failingOrderRepo 注文の取得に失敗するDB
+type failingOrderRepo struct {
+	*memory.DummyRDS
+}
+
+func (r *failingOrderRepo) GetOrder(uint64) (*model.Order, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestBot_Flatten_OrderRepoError(t *testing.T) {
+	rates := []string{
+		"日付, 販売所買い価格, 販売所売り価格",
+		"2021-02-23T19:27:01Z,1000.0,1000.0",
+	}
+	exCli, err := memory.NewExchangeMock(strings.NewReader(strings.Join(rates, "\n")), 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	rds := memory.NewDummyRDS(nil)
+	facade := trade.NewFacade(exCli, rds, &failingOrderRepo{rds}, rds, rds, rds, rds, nil)
+	logger := &memory.Logger{Level: memory.Error}
+	bot := usecase.NewBot(logger, facade, nil, &usecase.BotConfig{Currency: model.BTC})
+
+	// 自身のポジションにない注文（DBの障害で他のボットの注文か判断できない）
+	rate, amount := 500.0, 1.0
+	if _, err := exCli.PostOrder(&model.NewOrder{Type: model.Buy, Pair: model.BtcJpy, Rate: &rate, Amount: &amount}); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	result := bot.Flatten()
+	if len(result.Errors) == 0 {
+		t.Errorf("Flatten() errors = none, want an error")
+	}
+	if len(result.CanceledOrders) != 0 {
+		t.Errorf("canceled orders = %v, want none", result.CanceledOrders)
+	}
+	if orders, _ := exCli.GetOpenOrders(&model.BtcJpy); len(orders) != 1 {
+		t.Errorf("open orders on exchange = %v, want 1 order", orders)
+	}
+}
@@ -0,0 +1,89 @@
+package usecase
+
+import (
+	"fmt"
+	"time"
+)
+
+// RateFreshnessConfig レートの鮮度の確認設定
+type RateFreshnessConfig struct {
+	// 最後の記録からの経過時間の上限
+	MaxAge time.Duration
+	// 記録間隔の上限（0なら空きを確認しない）
+	MaxGap time.Duration
+	// 古いままの間の再通知間隔（0なら再通知しない）
+	AlertInterval time.Duration
+}
+
+// Enabled 確認する項目があるか
+func (c *RateFreshnessConfig) Enabled() bool {
+	return c.MaxAge > 0 || c.MaxGap > 0
+}
+
+// rateStaleness レートが古い状態
+type rateStaleness struct {
+	// 古い理由（空なら問題なし）
+	reason string
+	// 古くなった日時
+	since time.Time
+	// 最後に通知した日時
+	notifiedAt time.Time
+}
+
+// staleReason 直近の確認でレートが古いと判定した理由（問題なければ空）
+func (b *Bot) staleReason() string {
+	b.staleMu.Lock()
+	defer b.staleMu.Unlock()
+	return b.stale.reason
+}
+
+// checkRateFreshness レートの鮮度を確認して古い理由を返す（問題なければ空）
+func (b *Bot) checkRateFreshness(now time.Time) string {
+	c := b.RateFreshness
+	f, err := b.facade.GetRateFreshness(&b.pair, c.MaxGap)
+	if err != nil {
+		return fmt.Sprintf("failed to get rate freshness, %v", err)
+	}
+	if f.Count == 0 && f.LastRecordedAt.IsZero() {
+		return "no rate is recorded"
+	}
+	if age := f.Age(now); c.MaxAge > 0 && age > c.MaxAge {
+		return fmt.Sprintf("last rate is %v old (recorded at %s, max %v)",
+			age.Truncate(time.Second), f.LastRecordedAt.Format(time.RFC3339), c.MaxAge)
+	}
+	if c.MaxGap > 0 && f.GapCount > 0 {
+		return fmt.Sprintf("rates have %d gaps (max gap %v > %v)", f.GapCount, f.MaxGap.Truncate(time.Second), c.MaxGap)
+	}
+	return ""
+}
+
+// updateRateFreshness レートの鮮度を確認し、古くなった・古いままの・復旧した場合に通知する
+func (b *Bot) updateRateFreshness() string {
+	if b.RateFreshness == nil || !b.RateFreshness.Enabled() {
+		return ""
+	}
+	now := b.facade.Now()
+	reason := b.checkRateFreshness(now)
+
+	b.staleMu.Lock()
+	defer b.staleMu.Unlock()
+	before := b.stale
+	switch {
+	case before.reason == "" && reason != "":
+		b.logger.Error("[freshness] rate is stale, %s", reason)
+		b.stale = rateStaleness{reason: reason, since: now, notifiedAt: now}
+		b.notify(fmt.Sprintf("[%s] レートが古いため新規注文を停止しました（%s）", b.Name(), reason))
+	case before.reason != "" && reason == "":
+		b.logger.Info("[freshness] rate is recovered")
+		b.stale = rateStaleness{}
+		b.notify(fmt.Sprintf("[%s] レートが復旧したため新規注文を再開しました（停止期間: %v）", b.Name(), now.Sub(before.since).Truncate(time.Second)))
+	case reason != "":
+		b.stale.reason = reason
+		interval := b.RateFreshness.AlertInterval
+		if interval > 0 && now.Sub(before.notifiedAt) >= interval {
+			b.stale.notifiedAt = now
+			b.notify(fmt.Sprintf("[%s] レートが古いため新規注文の停止が続いています（%v経過、%s）", b.Name(), now.Sub(before.since).Truncate(time.Second), reason))
+		}
+	}
+	return reason
+}
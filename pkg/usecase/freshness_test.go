@@ -0,0 +1,145 @@
+package usecase_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/infrastructure/memory"
+	"trading-bot/pkg/usecase"
+	"trading-bot/pkg/usecase/trade"
+)
+
+type buyCountStrategy struct {
+	buys int
+}
+
+func (s *buyCountStrategy) Buy(pair model.CurrencyPair, positions []model.Position) error {
+	s.buys++
+	return nil
+}
+
+func (s *buyCountStrategy) Sell(pair model.CurrencyPair, positions []model.Position) error {
+	return nil
+}
+
+func (s *buyCountStrategy) BuyTradeCallback(pair model.CurrencyPair, rate float64) error {
+	s.buys++
+	return nil
+}
+
+func (s *buyCountStrategy) SellTradeCallback(pair model.CurrencyPair, rate float64) error {
+	return nil
+}
+
+func (s *buyCountStrategy) Wait(ctx context.Context) error {
+	return nil
+}
+
+type messageNotifier struct {
+	messages []string
+}
+
+func (n *messageNotifier) Notify(message string) error {
+	n.messages = append(n.messages, message)
+	return nil
+}
+
+func TestBot_RateFreshness(t *testing.T) {
+	tests := map[string]struct {
+		config usecase.RateFreshnessConfig
+		// Trade毎に追加するレートの記録日時（現在からの経過時間）
+		steps        [][]time.Duration
+		wantBuys     []bool
+		wantNotifies []string
+	}{
+		"fresh rates": {
+			config:       usecase.RateFreshnessConfig{MaxAge: 5 * time.Minute, MaxGap: 5 * time.Minute},
+			steps:        [][]time.Duration{{2 * time.Minute, time.Minute}},
+			wantBuys:     []bool{true},
+			wantNotifies: []string{},
+		},
+		"no rates": {
+			config:       usecase.RateFreshnessConfig{MaxAge: 5 * time.Minute},
+			steps:        [][]time.Duration{{}},
+			wantBuys:     []bool{false},
+			wantNotifies: []string{"停止しました"},
+		},
+		"old rates": {
+			config:       usecase.RateFreshnessConfig{MaxAge: 5 * time.Minute},
+			steps:        [][]time.Duration{{10 * time.Minute}},
+			wantBuys:     []bool{false},
+			wantNotifies: []string{"停止しました"},
+		},
+		"gap in rates": {
+			config:       usecase.RateFreshnessConfig{MaxAge: 5 * time.Minute, MaxGap: 5 * time.Minute},
+			steps:        [][]time.Duration{{10 * time.Minute, time.Minute}},
+			wantBuys:     []bool{false},
+			wantNotifies: []string{"停止しました"},
+		},
+		"alert while stale": {
+			config:       usecase.RateFreshnessConfig{MaxAge: 5 * time.Minute, AlertInterval: time.Nanosecond},
+			steps:        [][]time.Duration{{10 * time.Minute}, {}},
+			wantBuys:     []bool{false, false},
+			wantNotifies: []string{"停止しました", "停止が続いています"},
+		},
+		"recovered": {
+			config:       usecase.RateFreshnessConfig{MaxAge: 5 * time.Minute},
+			steps:        [][]time.Duration{{10 * time.Minute}, {0}},
+			wantBuys:     []bool{false, true},
+			wantNotifies: []string{"停止しました", "再開しました"},
+		},
+		"disabled": {
+			config:       usecase.RateFreshnessConfig{},
+			steps:        [][]time.Duration{{}},
+			wantBuys:     []bool{true},
+			wantNotifies: []string{},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			rates := []string{
+				"日付, 販売所買い価格, 販売所売り価格",
+				"2021-02-23T19:27:01Z,1000.0,1000.0",
+			}
+			exCli, err := memory.NewExchangeMock(strings.NewReader(strings.Join(rates, "\n")), 0)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			rds := memory.NewDummyRDS(nil)
+			facade := trade.NewFacade(exCli, rds, rds, rds, rds, rds, rds, nil)
+			logger := &memory.Logger{Level: memory.Error}
+			strategy := &buyCountStrategy{}
+			notifier := &messageNotifier{}
+			bot := usecase.NewBot(logger, facade, strategy, &usecase.BotConfig{Currency: model.BTC, PositionCountMax: 1})
+			bot.Notifier = notifier
+			bot.RateFreshness = &tt.config
+
+			for i, step := range tt.steps {
+				for _, ago := range step {
+					if err := rds.AddRates(&model.BtcJpy, 1000, time.Now().Add(-ago)); err != nil {
+						t.Fatal(err.Error())
+					}
+				}
+				buys := strategy.buys
+				if err := bot.Trade(context.Background()); err != nil {
+					t.Fatal(err.Error())
+				}
+				if got := strategy.buys > buys; got != tt.wantBuys[i] {
+					t.Errorf("steps[%d] bought = %v, want %v", i, got, tt.wantBuys[i])
+				}
+			}
+
+			if len(notifier.messages) != len(tt.wantNotifies) {
+				t.Fatalf("notifies = %v, want %v", notifier.messages, tt.wantNotifies)
+			}
+			for i, want := range tt.wantNotifies {
+				if !strings.Contains(notifier.messages[i], want) {
+					t.Errorf("notifies[%d] = %s, want contains %s", i, notifier.messages[i], want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,331 @@
+package montecarlo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"runtime"
+	"sort"
+	"sync"
+	"trading-bot/pkg/domain"
+	"trading-bot/pkg/infrastructure/memory"
+	"trading-bot/pkg/usecase/report"
+)
+
+// Options 経路の生成と集計の設定
+type Options struct {
+	// 生成する経路の数
+	Runs int
+	// ブロックブートストラップのブロックの長さ（レートの数）
+	BlockSize int
+	// スリッページの範囲（経路ごとに一様乱数で選ぶ、上限が下限より小さければ下限で固定）
+	SlippageMin float64
+	SlippageMax float64
+	// 手数料率の範囲（約定額に対する割合、経路ごとに一様乱数で選ぶ）
+	FeeRateMin float64
+	FeeRateMax float64
+	// 破産とみなす総資産（初期資産に対する割合）
+	RuinRatio float64
+	// 乱数の種
+	Seed int64
+	// 並列に実行する数（0ならCPU数）
+	Workers int
+}
+
+func (o *Options) valid() error {
+	if o.Runs <= 0 {
+		return fmt.Errorf("runs must be positive, %v", o.Runs)
+	}
+	if o.BlockSize <= 0 {
+		return fmt.Errorf("block size must be positive, %v", o.BlockSize)
+	}
+	if o.SlippageMin < 0 || o.FeeRateMin < 0 || o.FeeRateMax < 0 {
+		return fmt.Errorf("slippage and fee rate must not be negative, (slippage:%v, fee:%v-%v)", o.SlippageMin, o.FeeRateMin, o.FeeRateMax)
+	}
+	if o.RuinRatio < 0 || o.RuinRatio >= 1 {
+		return fmt.Errorf("ruin ratio must be in [0, 1), %v", o.RuinRatio)
+	}
+	return nil
+}
+
+// Path シミュレーションする1本の経路
+type Path struct {
+	Index    int
+	Rates    []memory.Rate
+	Slippage float64
+	FeeRate  float64
+	// 取引の順番を入れ替えるための乱数の種
+	seed int64
+}
+
+// Paths 過去のレートから経路を生成（1本目は過去のレートのまま、乱数の種が同じなら同じ経路）
+func Paths(rates []memory.Rate, o Options) ([]Path, error) {
+	if err := o.valid(); err != nil {
+		return nil, err
+	}
+	if len(rates) < 2 {
+		return nil, fmt.Errorf("rates are too short, %d", len(rates))
+	}
+	rnd := rand.New(rand.NewSource(o.Seed))
+	paths := []Path{}
+	for i := 0; i < o.Runs; i++ {
+		p := Path{
+			Index:    i,
+			Rates:    rates,
+			Slippage: uniform(rnd, o.SlippageMin, o.SlippageMax),
+			FeeRate:  uniform(rnd, o.FeeRateMin, o.FeeRateMax),
+			seed:     rnd.Int63(),
+		}
+		if i > 0 {
+			p.Rates = Bootstrap(rates, o.BlockSize, rnd)
+		}
+		paths = append(paths, p)
+	}
+	return paths, nil
+}
+
+func uniform(rnd *rand.Rand, min, max float64) float64 {
+	if max <= min {
+		return min
+	}
+	return min + rnd.Float64()*(max-min)
+}
+
+// Bootstrap 騰落率をブロック単位で復元抽出して並べ直したレート
+// 日時は元のまま、売りレートを騰落率で動かし、買いレートと販売所レートは抽出した時点の売りレートとの比を保つ
+func Bootstrap(rates []memory.Rate, blockSize int, rnd *rand.Rand) []memory.Rate {
+	if len(rates) < 2 {
+		return append([]memory.Rate{}, rates...)
+	}
+	if blockSize > len(rates)-1 {
+		blockSize = len(rates) - 1
+	}
+	out := make([]memory.Rate, 0, len(rates))
+	out = append(out, rates[0])
+	sell := rates[0].OrderSellRate
+	for len(out) < len(rates) {
+		// 騰落率はrates[j-1]からrates[j]（j >= 1）
+		start := 1 + rnd.Intn(len(rates)-blockSize)
+		for j := start; j < start+blockSize && len(out) < len(rates); j++ {
+			src := rates[j]
+			if prev := rates[j-1].OrderSellRate; prev > 0 {
+				sell *= src.OrderSellRate / prev
+			}
+			buy, store := sell, sell
+			if src.OrderSellRate > 0 {
+				buy = sell * src.OrderBuyRate / src.OrderSellRate
+				store = sell * src.StoreRate / src.OrderSellRate
+			}
+			t := rates[len(out)].Time
+			out = append(out, memory.NewRateWithVolumes(t, store, buy, sell, src.BuyVolume, src.SellVolume))
+		}
+	}
+	return out
+}
+
+// PathResult 1本の経路の成績
+type PathResult struct {
+	Path     int            `json:"path"`
+	Slippage float64        `json:"slippage"`
+	FeeRate  float64        `json:"fee_rate"`
+	Metrics  report.Metrics `json:"metrics"`
+	// 総資産の最小値
+	MinEquity float64 `json:"min_equity"`
+	// 決済済みの取引の順番を入れ替えた場合の最大ドローダウン(JPY)と総資産の最小値
+	ShuffledMaxDrawdown float64 `json:"shuffled_max_drawdown"`
+	ShuffledMinEquity   float64 `json:"shuffled_min_equity"`
+	// 総資産が破産とみなす額を下回ったか
+	Ruined bool `json:"ruined"`
+}
+
+// Distribution 経路ごとの値の分布
+type Distribution struct {
+	Mean   float64 `json:"mean"`
+	Std    float64 `json:"std"`
+	Min    float64 `json:"min"`
+	P5     float64 `json:"p5"`
+	P25    float64 `json:"p25"`
+	Median float64 `json:"median"`
+	P75    float64 `json:"p75"`
+	P95    float64 `json:"p95"`
+	Max    float64 `json:"max"`
+}
+
+// NewDistribution 値の分布を集計（分位点は線形補間）
+func NewDistribution(values []float64) Distribution {
+	if len(values) == 0 {
+		return Distribution{}
+	}
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+	d := Distribution{
+		Min:    sorted[0],
+		P5:     quantile(sorted, 0.05),
+		P25:    quantile(sorted, 0.25),
+		Median: quantile(sorted, 0.5),
+		P75:    quantile(sorted, 0.75),
+		P95:    quantile(sorted, 0.95),
+		Max:    sorted[len(sorted)-1],
+	}
+	for _, v := range sorted {
+		d.Mean += v
+	}
+	d.Mean /= float64(len(sorted))
+	for _, v := range sorted {
+		d.Std += (v - d.Mean) * (v - d.Mean)
+	}
+	d.Std = math.Sqrt(d.Std / float64(len(sorted)))
+	return d
+}
+
+func quantile(sorted []float64, q float64) float64 {
+	pos := q * float64(len(sorted)-1)
+	i := int(pos)
+	if i+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[i] + (sorted[i+1]-sorted[i])*(pos-float64(i))
+}
+
+// Result モンテカルロシミュレーションの結果
+type Result struct {
+	Runs int `json:"runs"`
+	// 破産とみなす総資産（初期資産に対する割合）
+	RuinRatio           float64      `json:"ruin_ratio"`
+	Profit              Distribution `json:"profit"`
+	Return              Distribution `json:"return"`
+	MaxDrawdown         Distribution `json:"max_drawdown"`
+	MaxDrawdownRatio    Distribution `json:"max_drawdown_ratio"`
+	ShuffledMaxDrawdown Distribution `json:"shuffled_max_drawdown"`
+	// 破産した経路の割合
+	RuinProbability float64 `json:"ruin_probability"`
+	// 取引の順番を入れ替えて破産した経路の割合
+	ShuffledRuinProbability float64      `json:"shuffled_ruin_probability"`
+	Paths                   []PathResult `json:"paths"`
+}
+
+// Simulate 経路をシミュレーションして成績を返す
+type Simulate func(p *Path) (*report.Report, error)
+
+// Run 経路を並列にシミュレーションして集計（1本でも失敗したらエラー）
+func Run(logger domain.Logger, paths []Path, o Options, simulate Simulate) (*Result, error) {
+	workers := o.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	results := make([]PathResult, len(paths))
+	errs := make([]error, len(paths))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				p := &paths[i]
+				logger.Info("running simulation [%d/%d] slippage: %.5f, fee rate: %.5f ...", i+1, len(paths), p.Slippage, p.FeeRate)
+				r, err := simulate(p)
+				if err != nil {
+					errs[i] = err
+					continue
+				}
+				results[i] = evaluate(p, r, o.RuinRatio)
+				logger.Info("result [%d/%d] profit: %.3f, max drawdown: %.3f", i+1, len(paths), r.Metrics.Profit, r.Metrics.MaxDrawdown)
+			}
+		}()
+	}
+	for i := range paths {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("failed to simulate path %d, %v", i, err)
+		}
+	}
+	return Summarize(results, o.RuinRatio), nil
+}
+
+// evaluate 経路の成績から破産の判定と取引の順番を入れ替えた場合のドローダウンを求める
+func evaluate(p *Path, r *report.Report, ruinRatio float64) PathResult {
+	m := r.Metrics
+	result := PathResult{
+		Path:      p.Index,
+		Slippage:  p.Slippage,
+		FeeRate:   p.FeeRate,
+		Metrics:   m,
+		MinEquity: m.InitialEquity,
+	}
+	for _, e := range r.EquityCurve {
+		result.MinEquity = math.Min(result.MinEquity, e.Equity)
+	}
+	result.ShuffledMaxDrawdown, result.ShuffledMinEquity = ShuffleTrades(r.Trades, m.InitialEquity, rand.New(rand.NewSource(p.seed)))
+	result.Ruined = result.MinEquity <= m.InitialEquity*ruinRatio
+	return result
+}
+
+// ShuffleTrades 決済済みの取引の損益の順番を入れ替えて初期資産から積み上げた場合の最大ドローダウンと総資産の最小値
+func ShuffleTrades(trades []report.Trade, initial float64, rnd *rand.Rand) (maxDrawdown, minEquity float64) {
+	profits := []float64{}
+	for _, t := range trades {
+		if !t.Open {
+			profits = append(profits, t.Profit)
+		}
+	}
+	rnd.Shuffle(len(profits), func(i, j int) { profits[i], profits[j] = profits[j], profits[i] })
+
+	equity, peak := initial, initial
+	minEquity = initial
+	for _, p := range profits {
+		equity += p
+		peak = math.Max(peak, equity)
+		maxDrawdown = math.Max(maxDrawdown, peak-equity)
+		minEquity = math.Min(minEquity, equity)
+	}
+	return maxDrawdown, minEquity
+}
+
+// Summarize 経路ごとの成績から分布と破産確率を集計
+func Summarize(results []PathResult, ruinRatio float64) *Result {
+	profit, ret, dd, ddRatio, shuffled := []float64{}, []float64{}, []float64{}, []float64{}, []float64{}
+	ruined, shuffledRuined := 0, 0
+	for _, r := range results {
+		profit = append(profit, r.Metrics.Profit)
+		ret = append(ret, r.Metrics.Return)
+		dd = append(dd, r.Metrics.MaxDrawdown)
+		ddRatio = append(ddRatio, r.Metrics.MaxDrawdownRatio)
+		shuffled = append(shuffled, r.ShuffledMaxDrawdown)
+		if r.Ruined {
+			ruined++
+		}
+		if r.ShuffledMinEquity <= r.Metrics.InitialEquity*ruinRatio {
+			shuffledRuined++
+		}
+	}
+	result := &Result{
+		Runs:                len(results),
+		RuinRatio:           ruinRatio,
+		Profit:              NewDistribution(profit),
+		Return:              NewDistribution(ret),
+		MaxDrawdown:         NewDistribution(dd),
+		MaxDrawdownRatio:    NewDistribution(ddRatio),
+		ShuffledMaxDrawdown: NewDistribution(shuffled),
+		Paths:               results,
+	}
+	if len(results) > 0 {
+		result.RuinProbability = float64(ruined) / float64(len(results))
+		result.ShuffledRuinProbability = float64(shuffledRuined) / float64(len(results))
+	}
+	return result
+}
+
+// WriteJSON JSON形式で書き出す
+func (r *Result) WriteJSON(w io.Writer) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(r)
+}
@@ -0,0 +1,132 @@
+package montecarlo_test
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+	"trading-bot/pkg/infrastructure/memory"
+	"trading-bot/pkg/usecase/montecarlo"
+	"trading-bot/pkg/usecase/report"
+)
+
+var start = time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC)
+
+func makeRates(sells ...float64) []memory.Rate {
+	rates := []memory.Rate{}
+	for i, sell := range sells {
+		rates = append(rates, memory.NewRateWithVolumes(start.Add(time.Duration(i)*time.Hour), sell, sell*1.01, sell, 1, 2))
+	}
+	return rates
+}
+
+func TestBootstrap(t *testing.T) {
+	rates := makeRates(100, 110, 99, 120, 90, 100)
+	tests := map[string]struct {
+		blockSize int
+		// ブロックが全体なら元と同じ
+		same bool
+	}{
+		"block of 2":  {blockSize: 2},
+		"whole block": {blockSize: 10, same: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := montecarlo.Bootstrap(rates, tt.blockSize, rand.New(rand.NewSource(1)))
+			if len(got) != len(rates) {
+				t.Fatalf("len = %d, want %d", len(got), len(rates))
+			}
+			for i := range got {
+				if !got[i].Time.Equal(rates[i].Time) {
+					t.Errorf("time[%d] = %v, want %v", i, got[i].Time, rates[i].Time)
+				}
+				if math.Abs(got[i].OrderBuyRate/got[i].OrderSellRate-1.01) > 1e-9 {
+					t.Errorf("spread[%d] = %v / %v, want 1.01", i, got[i].OrderBuyRate, got[i].OrderSellRate)
+				}
+			}
+			if tt.same {
+				for i := range got {
+					if math.Abs(got[i].OrderSellRate-rates[i].OrderSellRate) > 1e-9 {
+						t.Errorf("sell[%d] = %v, want %v", i, got[i].OrderSellRate, rates[i].OrderSellRate)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestPaths(t *testing.T) {
+	rates := makeRates(100, 110, 99, 120, 90, 100)
+	o := montecarlo.Options{Runs: 3, BlockSize: 2, SlippageMin: 0.001, SlippageMax: 0.002, FeeRateMax: 0.001, RuinRatio: 0.5, Seed: 42}
+	paths, err := montecarlo.Paths(rates, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 3 || !reflect.DeepEqual(paths[0].Rates, rates) {
+		t.Fatalf("paths = %d, first path must be the history", len(paths))
+	}
+	for _, p := range paths {
+		if p.Slippage < 0.001 || p.Slippage > 0.002 || p.FeeRate < 0 || p.FeeRate > 0.001 {
+			t.Errorf("path %d slippage = %v, fee rate = %v", p.Index, p.Slippage, p.FeeRate)
+		}
+	}
+	again, _ := montecarlo.Paths(rates, o)
+	if !reflect.DeepEqual(paths, again) {
+		t.Errorf("paths with the same seed are different")
+	}
+
+	o.RuinRatio = 1
+	if _, err := montecarlo.Paths(rates, o); err == nil {
+		t.Errorf("Paths() with ruin ratio 1 must fail")
+	}
+}
+
+func TestNewDistribution(t *testing.T) {
+	got := montecarlo.NewDistribution([]float64{5, 1, 3, 2, 4})
+	want := montecarlo.Distribution{Mean: 3, Std: math.Sqrt(2), Min: 1, P5: 1.2, P25: 2, Median: 3, P75: 4, P95: 4.8, Max: 5}
+	if math.Abs(got.P5-want.P5) > 1e-9 || math.Abs(got.P95-want.P95) > 1e-9 || math.Abs(got.Std-want.Std) > 1e-9 {
+		t.Fatalf("NewDistribution() = %+v, want %+v", got, want)
+	}
+	got.P5, got.P95, got.Std = want.P5, want.P95, want.Std
+	if got != want {
+		t.Errorf("NewDistribution() = %+v, want %+v", got, want)
+	}
+}
+
+func TestShuffleTrades(t *testing.T) {
+	trades := []report.Trade{{Profit: 100}, {Profit: -300}, {Profit: 50}, {Profit: -200}, {Profit: 1000, Open: true}}
+	for seed := int64(0); seed < 10; seed++ {
+		dd, min := montecarlo.ShuffleTrades(trades, 1000, rand.New(rand.NewSource(seed)))
+		// 順番によらずドローダウンは最大の損失から損失の合計まで、未決済の取引は含めない
+		if dd < 300 || dd > 500 || min < 1000-500 || min > 1000-300+150 {
+			t.Errorf("seed %d: ShuffleTrades() = %v, %v", seed, dd, min)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	rates := makeRates(100, 110, 99, 120)
+	o := montecarlo.Options{Runs: 4, BlockSize: 1, RuinRatio: 0.5, Seed: 1, Workers: 2}
+	paths, err := montecarlo.Paths(rates, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := &memory.Logger{Level: memory.Error}
+	r, err := montecarlo.Run(logger, paths, o, func(p *montecarlo.Path) (*report.Report, error) {
+		// 偶数番目の経路は総資産が4割まで落ちる
+		recorder := report.NewRecorder()
+		recorder.AddEquity(start, 1000, 100)
+		if p.Index%2 == 0 {
+			recorder.AddEquity(start.Add(time.Hour), 400, 100)
+		}
+		recorder.AddEquity(start.Add(2*time.Hour), 1100, 100)
+		return recorder.Build("test", "btc_jpy", []report.Trade{}), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Runs != 4 || r.RuinProbability != 0.5 || r.Profit.Mean != 100 || r.MaxDrawdown.Max != 600 {
+		t.Errorf("Run() = runs %d, ruin %v, profit %+v, max drawdown %+v", r.Runs, r.RuinProbability, r.Profit, r.MaxDrawdown)
+	}
+}
@@ -0,0 +1,152 @@
+package optimize
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// AlgorithmType 探索アルゴリズム
+type AlgorithmType string
+
+const (
+	// GA 遺伝的アルゴリズム
+	GA AlgorithmType = "ga"
+	// Random ランダムサーチ
+	Random AlgorithmType = "random"
+	// Grid グリッドサーチ
+	Grid AlgorithmType = "grid"
+	// CMAES 対角共分散のCMA-ES
+	CMAES AlgorithmType = "cmaes"
+)
+
+// Algorithm 探索アルゴリズム
+// パラメータは探索範囲の各項目を[0, 1)に正規化した値の組で扱う
+type Algorithm interface {
+	// Ask 次に評価する値の組（空なら探索終了）
+	Ask() [][]float64
+
+	// Tell Askで返した値の組の評価値（大きいほど良い、値の組はAskで返した順のまま渡す）
+	Tell(points [][]float64, fitness []float64)
+}
+
+// Options 探索の設定
+type Options struct {
+	// 1回のAskで返す数（GAとCMA-ESの個体数）
+	Population int
+	// 世代数（GA, CMA-ES）
+	Generations int
+	// 評価する数の上限（ランダムサーチ、グリッドサーチ、0ならグリッドサーチは全件）
+	Budget int
+	// 乱数の種（同じ種なら同じ順に同じ値の組を返すので、チェックポイントから再開できる）
+	Seed int64
+}
+
+func (o *Options) valid() error {
+	if o.Population <= 0 {
+		return fmt.Errorf("Population is empty, %+v", o)
+	}
+	return nil
+}
+
+// NewAlgorithm 探索アルゴリズムを生成
+func NewAlgorithm(t AlgorithmType, space *SearchSpace, opts Options) (Algorithm, error) {
+	if err := opts.valid(); err != nil {
+		return nil, err
+	}
+	rng := rand.New(rand.NewSource(opts.Seed))
+	switch t {
+	case GA:
+		return newGeneticAlgorithm(space, opts, rng), nil
+	case Random:
+		return newRandomSearch(space, opts, rng), nil
+	case Grid:
+		return newGridSearch(space, opts), nil
+	case CMAES:
+		return newCMAES(space, opts, rng), nil
+	default:
+		return nil, fmt.Errorf("algorithm is unknown; name = %s", t)
+	}
+}
+
+// AlgorithmTypes 選択できる探索アルゴリズム
+func AlgorithmTypes() []AlgorithmType {
+	return []AlgorithmType{GA, Random, Grid, CMAES}
+}
+
+func randomPoint(dim int, rng *rand.Rand) []float64 {
+	point := make([]float64, dim)
+	for i := range point {
+		point[i] = rng.Float64()
+	}
+	return point
+}
+
+// randomSearch ランダムサーチ
+type randomSearch struct {
+	dim   int
+	opts  Options
+	rng   *rand.Rand
+	asked int
+}
+
+func newRandomSearch(space *SearchSpace, opts Options, rng *rand.Rand) *randomSearch {
+	return &randomSearch{dim: space.Dim(), opts: opts, rng: rng}
+}
+
+func (a *randomSearch) Ask() [][]float64 {
+	points := [][]float64{}
+	for len(points) < a.opts.Population && a.asked < a.opts.Budget {
+		points = append(points, randomPoint(a.dim, a.rng))
+		a.asked++
+	}
+	return points
+}
+
+func (a *randomSearch) Tell(points [][]float64, fitness []float64) {}
+
+// gridSearch グリッドサーチ（全組み合わせを順に評価する）
+type gridSearch struct {
+	axes  [][]float64
+	opts  Options
+	index []int
+	asked int
+	done  bool
+}
+
+func newGridSearch(space *SearchSpace, opts Options) *gridSearch {
+	axes := [][]float64{}
+	for i := range space.Params {
+		axes = append(axes, space.Params[i].grid())
+	}
+	return &gridSearch{axes: axes, opts: opts, index: make([]int, len(axes))}
+}
+
+func (a *gridSearch) Ask() [][]float64 {
+	points := [][]float64{}
+	for len(points) < a.opts.Population && !a.done {
+		if a.opts.Budget > 0 && a.asked >= a.opts.Budget {
+			break
+		}
+		point := make([]float64, len(a.axes))
+		for i, axis := range a.axes {
+			point[i] = axis[a.index[i]]
+		}
+		points = append(points, point)
+		a.asked++
+		a.next()
+	}
+	return points
+}
+
+func (a *gridSearch) next() {
+	for i := len(a.index) - 1; i >= 0; i-- {
+		a.index[i]++
+		if a.index[i] < len(a.axes[i]) {
+			return
+		}
+		a.index[i] = 0
+	}
+	a.done = true
+}
+
+func (a *gridSearch) Tell(points [][]float64, fitness []float64) {}
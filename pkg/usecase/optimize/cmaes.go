@@ -0,0 +1,162 @@
+package optimize
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+const initialSigma = 0.3
+
+// cmaes 対角共分散のCMA-ES（sep-CMA-ES）
+// 平均・各軸の分散・ステップ幅を評価値の上位の個体から更新し、探索の中心と広がりを絞り込む
+type cmaes struct {
+	space      *SearchSpace
+	opts       Options
+	rng        *rand.Rand
+	generation int
+
+	mu      int
+	weights []float64
+	muEff   float64
+	cSigma  float64
+	dSigma  float64
+	cc      float64
+	c1      float64
+	cMu     float64
+	chiN    float64
+
+	mean  []float64
+	sigma float64
+	c     []float64
+	pSig  []float64
+	pc    []float64
+	// 直近にAskで返した値の組ごとの (x - mean) / sigma
+	steps [][]float64
+}
+
+func newCMAES(space *SearchSpace, opts Options, rng *rand.Rand) *cmaes {
+	n := float64(space.Dim())
+	lambda := opts.Population
+	mu := lambda / 2
+	if mu < 1 {
+		mu = 1
+	}
+	weights := make([]float64, mu)
+	sum := 0.0
+	for i := range weights {
+		weights[i] = math.Log(float64(mu)+0.5) - math.Log(float64(i+1))
+		sum += weights[i]
+	}
+	sq := 0.0
+	for i := range weights {
+		weights[i] /= sum
+		sq += weights[i] * weights[i]
+	}
+	muEff := 1 / sq
+
+	a := &cmaes{
+		space:   space,
+		opts:    opts,
+		rng:     rng,
+		mu:      mu,
+		weights: weights,
+		muEff:   muEff,
+		cSigma:  (muEff + 2) / (n + muEff + 5),
+		cc:      (4 + muEff/n) / (n + 4 + 2*muEff/n),
+		chiN:    math.Sqrt(n) * (1 - 1/(4*n) + 1/(21*n*n)),
+		sigma:   initialSigma,
+	}
+	a.dSigma = 1 + 2*math.Max(0, math.Sqrt((muEff-1)/(n+1))-1) + a.cSigma
+	// 対角のみ更新するので学習率を(n+2)/3倍する
+	a.c1 = math.Min(1, 2/((n+1.3)*(n+1.3)+muEff)*(n+2)/3)
+	a.cMu = math.Min(1-a.c1, 2*(muEff-2+1/muEff)/((n+2)*(n+2)+muEff)*(n+2)/3)
+
+	a.mean = space.Initial()
+	if a.mean == nil {
+		a.mean = make([]float64, space.Dim())
+		for i := range a.mean {
+			a.mean[i] = 0.5
+		}
+	}
+	a.c = make([]float64, space.Dim())
+	for i := range a.c {
+		a.c[i] = 1
+	}
+	a.pSig = make([]float64, space.Dim())
+	a.pc = make([]float64, space.Dim())
+	return a
+}
+
+func (a *cmaes) Ask() [][]float64 {
+	if a.generation >= a.opts.Generations {
+		return [][]float64{}
+	}
+	a.generation++
+
+	a.steps = [][]float64{}
+	points := [][]float64{}
+	for len(points) < a.opts.Population {
+		point := make([]float64, len(a.mean))
+		step := make([]float64, len(a.mean))
+		for i := range point {
+			x := a.mean[i] + a.sigma*math.Sqrt(a.c[i])*a.rng.NormFloat64()
+			// 範囲外は境界に寄せ、更新にも寄せた値を使う
+			point[i] = math.Min(math.Max(x, 0), math.Nextafter(1, 0))
+			step[i] = (point[i] - a.mean[i]) / a.sigma
+		}
+		a.steps = append(a.steps, step)
+		points = append(points, point)
+	}
+	return points
+}
+
+func (a *cmaes) Tell(points [][]float64, fitness []float64) {
+	n := len(a.mean)
+	if n == 0 || len(points) != len(a.steps) {
+		return
+	}
+	order := make([]int, len(points))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return fitness[order[i]] > fitness[order[j]] })
+
+	selected := [][]float64{}
+	for _, i := range order {
+		selected = append(selected, a.steps[i])
+		if len(selected) == a.mu {
+			break
+		}
+	}
+	weights := a.weights[:len(selected)]
+	total := 0.0
+	for _, w := range weights {
+		total += w
+	}
+
+	yw := make([]float64, n)
+	for k, step := range selected {
+		for i := range yw {
+			yw[i] += weights[k] / total * step[i]
+		}
+	}
+
+	norm := 0.0
+	for i := range a.mean {
+		a.mean[i] = math.Min(math.Max(a.mean[i]+a.sigma*yw[i], 0), math.Nextafter(1, 0))
+		a.pSig[i] = (1-a.cSigma)*a.pSig[i] + math.Sqrt(a.cSigma*(2-a.cSigma)*a.muEff)*yw[i]/math.Sqrt(a.c[i])
+		a.pc[i] = (1-a.cc)*a.pc[i] + math.Sqrt(a.cc*(2-a.cc)*a.muEff)*yw[i]
+		norm += a.pSig[i] * a.pSig[i]
+	}
+	for i := range a.c {
+		rankMu := 0.0
+		for k, step := range selected {
+			rankMu += weights[k] / total * step[i] * step[i]
+		}
+		a.c[i] = (1-a.c1-a.cMu)*a.c[i] + a.c1*a.pc[i]*a.pc[i] + a.cMu*rankMu
+		a.c[i] = math.Max(a.c[i], 1e-12)
+	}
+	a.sigma *= math.Exp(a.cSigma / a.dSigma * (math.Sqrt(norm)/a.chiN - 1))
+	a.sigma = math.Min(math.Max(a.sigma, 1e-8), 1)
+}
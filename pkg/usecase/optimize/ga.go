@@ -0,0 +1,136 @@
+package optimize
+
+import (
+	"math/rand"
+	"sort"
+)
+
+const (
+	selectionRate = 0.020
+	crossoverRate = 0.975
+
+	randomPopulationCount        = 4
+	randomPopulationBornInterval = 10
+)
+
+type individual struct {
+	point   []float64
+	fitness float64
+}
+
+// geneticAlgorithm 遺伝的アルゴリズム
+// 一番成績のいい個体は続投し、残りは再生・交叉・突然変異で生成する
+type geneticAlgorithm struct {
+	space      *SearchSpace
+	opts       Options
+	rng        *rand.Rand
+	generation int
+	current    []individual
+}
+
+func newGeneticAlgorithm(space *SearchSpace, opts Options, rng *rand.Rand) *geneticAlgorithm {
+	return &geneticAlgorithm{space: space, opts: opts, rng: rng}
+}
+
+func (a *geneticAlgorithm) Ask() [][]float64 {
+	if a.generation >= a.opts.Generations {
+		return [][]float64{}
+	}
+	a.generation++
+
+	points := [][]float64{}
+	if len(a.current) == 0 {
+		// 初期値として基準の設定ファイルの値を入れる
+		if initial := a.space.Initial(); initial != nil {
+			points = append(points, initial)
+		}
+		for len(points) < a.opts.Population {
+			points = append(points, randomPoint(a.space.Dim(), a.rng))
+		}
+		return points
+	}
+
+	points = append(points, a.current[0].point)
+	// 多様性維持のため定期的にランダムな個体を追加する
+	if a.generation%randomPopulationBornInterval == 0 {
+		for n := 0; n < randomPopulationCount && len(points) < a.opts.Population; n++ {
+			points = append(points, randomPoint(a.space.Dim(), a.rng))
+		}
+	}
+	for len(points) < a.opts.Population {
+		points = append(points, a.next())
+	}
+	return points
+}
+
+func (a *geneticAlgorithm) Tell(points [][]float64, fitness []float64) {
+	a.current = []individual{}
+	for i := range points {
+		a.current = append(a.current, individual{point: points[i], fitness: fitness[i]})
+	}
+	sort.SliceStable(a.current, func(i, j int) bool {
+		return a.current[i].fitness > a.current[j].fitness
+	})
+}
+
+func (a *geneticAlgorithm) next() []float64 {
+	v := a.rng.Float64()
+	if v < selectionRate {
+		// 再生
+		return a.choose()
+	}
+	if v < selectionRate+crossoverRate && len(a.current) > 1 && a.space.Dim() > 1 {
+		// 交叉（子の値も確率1/次元数で突然変異させ、連続値の探索が止まらないようにする）
+		point := a.crossover(a.choose(), a.choose())
+		for i := range point {
+			if a.rng.Float64() < 1/float64(len(point)) {
+				point[i] = a.rng.Float64()
+			}
+		}
+		return point
+	}
+	// 突然変異
+	return a.mutate(a.choose())
+}
+
+// choose 選択（ランキング上位の個体ほど選択されやすいようにする）
+func (a *geneticAlgorithm) choose() []float64 {
+	size := len(a.current)
+	n := a.rng.Intn(size * (size + 1) / 2)
+	for i := range a.current {
+		n -= size - i
+		if n < 0 {
+			return a.current[i].point
+		}
+	}
+	return a.current[size-1].point
+}
+
+// crossover 二点交叉
+func (a *geneticAlgorithm) crossover(p1, p2 []float64) []float64 {
+	size := len(p1)
+	begin := a.rng.Intn(size)
+	end := a.rng.Intn(size)
+	for begin == end {
+		end = a.rng.Intn(size)
+	}
+	if begin > end {
+		begin, end = end, begin
+	}
+	point := make([]float64, size)
+	for i := range point {
+		if i < begin || i > end {
+			point[i] = p1[i]
+		} else {
+			point[i] = p2[i]
+		}
+	}
+	return point
+}
+
+// mutate 1つの値をランダムに置き換える
+func (a *geneticAlgorithm) mutate(org []float64) []float64 {
+	point := append([]float64{}, org...)
+	point[a.rng.Intn(len(point))] = a.rng.Float64()
+	return point
+}
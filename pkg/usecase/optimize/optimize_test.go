@@ -0,0 +1,303 @@
+package optimize_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"trading-bot/pkg/infrastructure/memory"
+	"trading-bot/pkg/usecase/optimize"
+
+	"github.com/BurntSushi/toml"
+)
+
+const baseConfig = `
+interval_seconds = 10
+term_size = 100
+loss_cut_lower_limit_per = 0.95
+
+[exit]
+trailing_stop_per = 0.98
+`
+
+const searchSpace = `
+strategy = "range"
+base_config = "base.toml"
+
+[[params]]
+key = "term_size"
+type = "int"
+min = 10
+max = 200
+step = 10
+
+[[params]]
+key = "loss_cut_lower_limit_per"
+type = "float"
+min = 0.9
+max = 0.99
+step = 0.01
+
+[[params]]
+key = "exit.trailing_stop_per"
+type = "float"
+min = 0.9
+max = 1.0
+
+[[params]]
+key = "sizing.model"
+type = "choice"
+values = ["fixed_jpy", "kelly"]
+`
+
+type config struct {
+	Interval             int     `toml:"interval_seconds"`
+	TermSize             int     `toml:"term_size"`
+	LossCutLowerLimitPer float64 `toml:"loss_cut_lower_limit_per"`
+	Exit                 struct {
+		TrailingStopPer float64 `toml:"trailing_stop_per"`
+	} `toml:"exit"`
+	Sizing struct {
+		Model string `toml:"model"`
+	} `toml:"sizing"`
+}
+
+func newSpace(t *testing.T, space string) *optimize.SearchSpace {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "base.toml"), []byte(baseConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f := filepath.Join(dir, "search.toml")
+	if err := ioutil.WriteFile(f, []byte(space), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := optimize.NewSearchSpace(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestSearchSpace(t *testing.T) {
+	s := newSpace(t, searchSpace)
+
+	v := s.Decode([]float64{0, 0.999, 0.5, 0.6})
+	want := optimize.Values{
+		"term_size":                int64(10),
+		"loss_cut_lower_limit_per": 0.99,
+		"exit.trailing_stop_per":   0.95,
+		"sizing.model":             "kelly",
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Fatalf("Decode() = %v, want %v", v, want)
+	}
+
+	b, err := s.Config(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c config
+	if _, err := toml.Decode(string(b), &c); err != nil {
+		t.Fatalf("Config() = %s, %v", b, err)
+	}
+	if c.Interval != 10 || c.TermSize != 10 || c.LossCutLowerLimitPer != 0.99 ||
+		c.Exit.TrailingStopPer != 0.95 || c.Sizing.Model != "kelly" {
+		t.Errorf("Config() = %+v", c)
+	}
+
+	// 基準の設定ファイルに選択肢の項目がないので初期値はない
+	if initial := s.Initial(); initial != nil {
+		t.Errorf("Initial() = %v, want nil", initial)
+	}
+}
+
+func TestSearchSpace_Initial(t *testing.T) {
+	s := newSpace(t, `
+strategy = "range"
+base_config = "base.toml"
+
+[[params]]
+key = "term_size"
+type = "int"
+min = 10
+max = 200
+step = 10
+
+[[params]]
+key = "loss_cut_lower_limit_per"
+type = "float"
+min = 0.9
+max = 0.99
+step = 0.01
+`)
+	got := s.Decode(s.Initial())
+	want := optimize.Values{"term_size": int64(100), "loss_cut_lower_limit_per": 0.95}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode(Initial()) = %v, want %v", got, want)
+	}
+}
+
+func TestNewSearchSpace_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"no strategy": `
+[[params]]
+key = "term_size"
+type = "int"
+max = 10
+`,
+		"unknown type": `
+strategy = "range"
+[[params]]
+key = "term_size"
+type = "bool"
+`,
+		"no choices": `
+strategy = "range"
+[[params]]
+key = "spacing"
+type = "choice"
+`,
+		"duplicated key": `
+strategy = "range"
+[[params]]
+key = "term_size"
+type = "int"
+max = 10
+[[params]]
+key = "term_size"
+type = "int"
+max = 20
+`,
+	}
+	for name, space := range tests {
+		t.Run(name, func(t *testing.T) {
+			f := filepath.Join(t.TempDir(), "search.toml")
+			if err := ioutil.WriteFile(f, []byte(space), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := optimize.NewSearchSpace(f); err == nil {
+				t.Error("NewSearchSpace() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestGridSearch(t *testing.T) {
+	s := newSpace(t, searchSpace)
+	alg, err := optimize.NewAlgorithm(optimize.Grid, s, optimize.Options{Population: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]bool{}
+	for {
+		points := alg.Ask()
+		if len(points) == 0 {
+			break
+		}
+		for _, p := range points {
+			seen[s.Decode(p).Key()] = true
+		}
+	}
+	// 20 * 10 * 5（刻みのない小数） * 2
+	if len(seen) != 2000 {
+		t.Errorf("grid size = %d, want 2000", len(seen))
+	}
+}
+
+func TestRunner_Run(t *testing.T) {
+	s := newSpace(t, `
+strategy = "range"
+
+[[params]]
+key = "x"
+type = "float"
+min = -5
+max = 5
+
+[[params]]
+key = "n"
+type = "int"
+min = 0
+max = 20
+`)
+	// x = 1, n = 7 が最良
+	evaluate := func(v optimize.Values) (float64, error) {
+		x := v["x"].(float64)
+		n := float64(v["n"].(int64))
+		return -((x-1)*(x-1) + (n-7)*(n-7)), nil
+	}
+
+	tests := map[optimize.AlgorithmType]optimize.Options{
+		optimize.GA:     {Population: 20, Generations: 30},
+		optimize.Random: {Population: 20, Budget: 600},
+		optimize.Grid:   {Population: 20},
+		optimize.CMAES:  {Population: 12, Generations: 50},
+	}
+	for name, opts := range tests {
+		t.Run(string(name), func(t *testing.T) {
+			opts.Seed = 1
+			alg, err := optimize.NewAlgorithm(name, s, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := optimize.NewRunner(&memory.Logger{Level: memory.Error}, s, 4, 5, nil)
+			result, err := r.Run(alg, evaluate)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Best == nil || result.Best.Fitness < -1.1 {
+				t.Errorf("best = %+v, want near x=1, n=7", result.Best)
+			}
+		})
+	}
+}
+
+func TestRunner_Memo(t *testing.T) {
+	s := newSpace(t, `
+strategy = "range"
+
+[[params]]
+key = "n"
+type = "int"
+min = 0
+max = 3
+`)
+	var mu sync.Mutex
+	count := 0
+	evaluate := func(v optimize.Values) (float64, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		count++
+		if v["n"].(int64) == 3 {
+			return 0, fmt.Errorf("failed")
+		}
+		return float64(v["n"].(int64)), nil
+	}
+
+	alg, _ := optimize.NewAlgorithm(optimize.Grid, s, optimize.Options{Population: 4})
+	r := optimize.NewRunner(&memory.Logger{Level: memory.Error}, s, 2, 5, map[string]float64{"n=0": 10})
+	result, err := r.Run(alg, evaluate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// n=0はメモから取り出し、失敗したn=3はメモしない
+	if count != 3 {
+		t.Errorf("evaluated %d times, want 3", count)
+	}
+	if result.Best.Values.Key() != "n=0" || result.Best.Fitness != 10 {
+		t.Errorf("best = %+v", result.Best)
+	}
+	memo := r.Memo()
+	if _, ok := memo["n=3"]; ok || len(memo) != 3 {
+		t.Errorf("memo = %v", memo)
+	}
+
+	// 失敗が上限に達したら打ち切る
+	alg, _ = optimize.NewAlgorithm(optimize.Grid, s, optimize.Options{Population: 4})
+	r = optimize.NewRunner(&memory.Logger{Level: memory.Error}, s, 2, 1, nil)
+	if _, err := r.Run(alg, evaluate); err == nil {
+		t.Error("Run() error = nil, want error")
+	}
+}
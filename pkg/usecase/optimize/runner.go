@@ -0,0 +1,172 @@
+package optimize
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+	"trading-bot/pkg/domain"
+)
+
+// failedFitness 評価に失敗した値の組の評価値（最良には選ばれない）
+const failedFitness = -math.MaxFloat64
+
+// Evaluate パラメータの値で戦略を評価する（大きいほど良い）
+type Evaluate func(v Values) (float64, error)
+
+// Trial 評価した値の組
+type Trial struct {
+	Values  Values  `json:"values"`
+	Fitness float64 `json:"fitness"`
+}
+
+// Result 探索の結果
+type Result struct {
+	// 最良の値の組（評価できた組がなければnil）
+	Best *Trial `json:"best"`
+	// 評価した値の組（メモから取り出したものも含む、評価順）
+	Trials []Trial `json:"trials"`
+}
+
+// Runner 探索アルゴリズムが返す値の組をワーカーで並列に評価し、評価値を値の組ごとにメモする
+// ga-simulatorの並列評価（GaWorkers）と評価値のメモ（チェックポイント）もこれで行う
+type Runner struct {
+	logger domain.Logger
+	space  *SearchSpace
+	// 並列に評価する数
+	workers int
+	// 失敗がこの数に達したら探索を打ち切る
+	maxErrorCount int
+
+	mu   sync.Mutex
+	memo map[string]float64
+
+	// OnBatch Askで返した値の組を評価するたびに呼ぶ（チェックポイントの書き出し用）
+	OnBatch func(memo map[string]float64) error
+}
+
+// NewRunner 生成（workersが0以下ならCPU数、memoは評価済みの値の組の評価値）
+func NewRunner(logger domain.Logger, space *SearchSpace, workers, maxErrorCount int, memo map[string]float64) *Runner {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if memo == nil {
+		memo = map[string]float64{}
+	}
+	return &Runner{
+		logger:        logger,
+		space:         space,
+		workers:       workers,
+		maxErrorCount: maxErrorCount,
+		memo:          memo,
+	}
+}
+
+// Workers 並列に評価する数
+func (r *Runner) Workers() int {
+	return r.workers
+}
+
+// Run アルゴリズムが空を返すまで評価を繰り返す
+// 失敗した値の組は最低の評価値としてアルゴリズムに渡し、メモしない
+func (r *Runner) Run(alg Algorithm, evaluate Evaluate) (*Result, error) {
+	result := &Result{Trials: []Trial{}}
+	errCount := 0
+	var lastErr error
+	for batch := 1; ; batch++ {
+		points := alg.Ask()
+		if len(points) == 0 {
+			return result, nil
+		}
+		r.logger.Info("***** batch %d (%d trials) *****", batch, len(points))
+
+		trials, errs := r.evaluate(points, evaluate)
+		fitness := []float64{}
+		for i := range trials {
+			fitness = append(fitness, trials[i].Fitness)
+			if errs[i] != nil {
+				errCount++
+				lastErr = errs[i]
+				continue
+			}
+			result.Trials = append(result.Trials, trials[i])
+			if result.Best == nil || trials[i].Fitness > result.Best.Fitness {
+				best := trials[i]
+				result.Best = &best
+			}
+		}
+		if r.maxErrorCount > 0 && errCount >= r.maxErrorCount {
+			return nil, fmt.Errorf("terminate optimization [%d/%d], %v", errCount, r.maxErrorCount, lastErr)
+		}
+		alg.Tell(points, fitness)
+
+		if result.Best != nil {
+			r.logger.Info("best fitness: %.3f, params: %s", result.Best.Fitness, result.Best.Values)
+		}
+		if r.OnBatch != nil {
+			if err := r.OnBatch(r.Memo()); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func (r *Runner) evaluate(points [][]float64, evaluate Evaluate) ([]Trial, []error) {
+	trials := make([]Trial, len(points))
+	errs := make([]error, len(points))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < r.workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				v := trials[i].Values
+				r.logger.Info("running simulation [%d/%d] %s ...", i+1, len(points), v)
+				f, err := evaluate(v)
+				if err != nil {
+					r.logger.Error("error occured; %v", err)
+					errs[i] = err
+					trials[i].Fitness = failedFitness
+					continue
+				}
+				trials[i].Fitness = f
+				r.mu.Lock()
+				r.memo[v.Key()] = f
+				r.mu.Unlock()
+				r.logger.Info("result %s => %.3f", v, f)
+			}
+		}()
+	}
+
+	for i, point := range points {
+		v := r.space.Decode(point)
+		trials[i].Values = v
+		if f, ok := r.lookup(v); ok {
+			trials[i].Fitness = f
+			continue
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return trials, errs
+}
+
+func (r *Runner) lookup(v Values) (float64, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f, ok := r.memo[v.Key()]
+	return f, ok
+}
+
+// Memo メモの複製
+func (r *Runner) Memo() map[string]float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	memo := make(map[string]float64, len(r.memo))
+	for k, v := range r.memo {
+		memo[k] = v
+	}
+	return memo
+}
@@ -0,0 +1,397 @@
+package optimize
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ParamType パラメータの型
+type ParamType string
+
+const (
+	// Int 整数
+	Int ParamType = "int"
+	// Float 小数
+	Float ParamType = "float"
+	// Choice 選択肢から選ぶ文字列
+	Choice ParamType = "choice"
+)
+
+// gridPoints 刻みのない小数をグリッドサーチするときの分割数
+const gridPoints = 5
+
+// Number 整数でも小数でも書ける数値
+type Number float64
+
+// UnmarshalTOML min = 10 のように整数で書いても読み込めるようにする
+func (n *Number) UnmarshalTOML(v interface{}) error {
+	switch x := v.(type) {
+	case int64:
+		*n = Number(x)
+	case float64:
+		*n = Number(x)
+	default:
+		return fmt.Errorf("number is expected, %v", v)
+	}
+	return nil
+}
+
+// Param 探索するパラメータ
+type Param struct {
+	// 戦略の設定ファイルの項目名（ネストした項目は exit.trailing_stop_per のようにドットでつなぐ）
+	Key  string    `toml:"key"`
+	Type ParamType `toml:"type"`
+	// 範囲（int, float、両端を含む）
+	Min Number `toml:"min"`
+	Max Number `toml:"max"`
+	// 刻み（intで0なら1、floatで0なら連続値）
+	Step Number `toml:"step"`
+	// 選択肢（choice）
+	Values []string `toml:"values"`
+}
+
+func (p *Param) valid() error {
+	if p.Key == "" {
+		return fmt.Errorf("Key is empty, %+v", p)
+	}
+	switch p.Type {
+	case Int, Float:
+		if p.lower() > p.upper() {
+			return fmt.Errorf("Min is greater than Max, %+v", p)
+		}
+		if p.Step < 0 {
+			return fmt.Errorf("Step is negative, %+v", p)
+		}
+	case Choice:
+		if len(p.Values) == 0 {
+			return fmt.Errorf("Values is empty, %+v", p)
+		}
+	default:
+		return fmt.Errorf("Type is unknown, %+v", p)
+	}
+	return nil
+}
+
+func (p *Param) lower() float64 {
+	return float64(p.Min)
+}
+
+func (p *Param) upper() float64 {
+	return float64(p.Max)
+}
+
+// step 刻み（連続値なら0）
+func (p *Param) step() float64 {
+	if p.Type == Int && p.Step == 0 {
+		return 1
+	}
+	return float64(p.Step)
+}
+
+// count 取りうる値の数（連続値なら0）
+func (p *Param) count() int {
+	if p.Type == Choice {
+		return len(p.Values)
+	}
+	step := p.step()
+	if step == 0 {
+		return 0
+	}
+	return int(math.Floor((p.upper()-p.lower())/step+1e-9)) + 1
+}
+
+// decode [0, 1)の値をパラメータの値にする
+func (p *Param) decode(u float64) interface{} {
+	u = math.Min(math.Max(u, 0), math.Nextafter(1, 0))
+	if p.Type == Choice {
+		return p.Values[int(u*float64(len(p.Values)))]
+	}
+	v := p.lower() + u*(p.upper()-p.lower())
+	if n := p.count(); n > 0 {
+		v = p.lower() + float64(int(u*float64(n)))*p.step()
+	}
+	if p.Type == Int {
+		return int64(math.Round(v))
+	}
+	return p.round(v)
+}
+
+// encode パラメータの値を[0, 1)の値にする（離散値なら区間の中央）
+func (p *Param) encode(v interface{}) (float64, bool) {
+	if p.Type == Choice {
+		s, ok := v.(string)
+		if !ok {
+			return 0, false
+		}
+		for i, c := range p.Values {
+			if c == s {
+				return (float64(i) + 0.5) / float64(len(p.Values)), true
+			}
+		}
+		return 0, false
+	}
+	var f float64
+	switch n := v.(type) {
+	case int64:
+		f = float64(n)
+	case float64:
+		f = n
+	default:
+		return 0, false
+	}
+	if f < p.lower() || f > p.upper() {
+		return 0, false
+	}
+	if n := p.count(); n > 0 {
+		i := math.Min(math.Round((f-p.lower())/p.step()), float64(n-1))
+		return (i + 0.5) / float64(n), true
+	}
+	if p.upper() == p.lower() {
+		return 0.5, true
+	}
+	return math.Min((f-p.lower())/(p.upper()-p.lower()), math.Nextafter(1, 0)), true
+}
+
+// round 刻みの桁数で丸める（0.1+0.2のような誤差を設定ファイルに出さない）
+func (p *Param) round(v float64) float64 {
+	if p.Step == 0 {
+		return v
+	}
+	s := strconv.FormatFloat(float64(p.Step), 'f', -1, 64)
+	decimals := 0
+	if i := strings.Index(s, "."); i >= 0 {
+		decimals = len(s) - i - 1
+	}
+	r, err := strconv.ParseFloat(strconv.FormatFloat(v, 'f', decimals, 64), 64)
+	if err != nil {
+		return v
+	}
+	return r
+}
+
+// grid グリッドサーチで試す値（[0, 1)の値、刻みのない小数はgridPoints等分）
+func (p *Param) grid() []float64 {
+	n := p.count()
+	if n == 0 {
+		n = gridPoints
+		if p.upper() == p.lower() {
+			n = 1
+		}
+		points := []float64{}
+		for i := 0; i < n; i++ {
+			if n == 1 {
+				points = append(points, 0)
+				continue
+			}
+			// 両端を含めるため最後は1の直前にする
+			points = append(points, math.Min(float64(i)/float64(n-1), math.Nextafter(1, 0)))
+		}
+		return points
+	}
+	points := []float64{}
+	for i := 0; i < n; i++ {
+		points = append(points, (float64(i)+0.5)/float64(n))
+	}
+	return points
+}
+
+// Values パラメータの値（項目名ごと、intはint64、floatはfloat64、choiceはstring）
+type Values map[string]interface{}
+
+// Key 値の組を表す文字列（項目名順、メモのキーに使う）
+func (v Values) Key() string {
+	keys := []string{}
+	for k := range v {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	s := []string{}
+	for _, k := range keys {
+		s = append(s, fmt.Sprintf("%s=%v", k, v[k]))
+	}
+	return strings.Join(s, ",")
+}
+
+// String Keyと同じ
+func (v Values) String() string {
+	return v.Key()
+}
+
+// Numeric 数値の項目（パラメータのばらつきの集計用）
+func (v Values) Numeric() map[string]float64 {
+	params := map[string]float64{}
+	for k, x := range v {
+		switch n := x.(type) {
+		case int64:
+			params[k] = float64(n)
+		case float64:
+			params[k] = n
+		}
+	}
+	return params
+}
+
+// SearchSpace 戦略ごとの探索範囲
+type SearchSpace struct {
+	// 戦略種別（usecase.StrategyType）
+	Strategy string `toml:"strategy"`
+	// 探索しない項目に使う設定ファイル（相対パスなら探索範囲のファイルからのパス）
+	BaseConfig string  `toml:"base_config"`
+	Params     []Param `toml:"params"`
+
+	base map[string]interface{}
+}
+
+// NewSearchSpace 探索範囲のファイルを読み込む
+func NewSearchSpace(f string) (*SearchSpace, error) {
+	var s SearchSpace
+	if _, err := toml.DecodeFile(f, &s); err != nil {
+		return nil, err
+	}
+	if err := s.valid(); err != nil {
+		return nil, err
+	}
+	if s.BaseConfig != "" {
+		p := s.BaseConfig
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(filepath.Dir(f), p)
+		}
+		if _, err := toml.DecodeFile(p, &s.base); err != nil {
+			return nil, fmt.Errorf("failed to load base config, %v", err)
+		}
+	}
+	return &s, nil
+}
+
+func (s *SearchSpace) valid() error {
+	if s.Strategy == "" {
+		return fmt.Errorf("Strategy is empty, %+v", s)
+	}
+	if len(s.Params) == 0 {
+		return fmt.Errorf("Params is empty, %+v", s)
+	}
+	keys := map[string]bool{}
+	for i := range s.Params {
+		if err := s.Params[i].valid(); err != nil {
+			return err
+		}
+		if keys[s.Params[i].Key] {
+			return fmt.Errorf("Key is duplicated, %v", s.Params[i].Key)
+		}
+		keys[s.Params[i].Key] = true
+	}
+	return nil
+}
+
+// Dim 探索するパラメータの数
+func (s *SearchSpace) Dim() int {
+	return len(s.Params)
+}
+
+// Decode [0, 1)の値の組をパラメータの値にする
+func (s *SearchSpace) Decode(point []float64) Values {
+	v := Values{}
+	for i := range s.Params {
+		v[s.Params[i].Key] = s.Params[i].decode(point[i])
+	}
+	return v
+}
+
+// Normalize 値を探索範囲の型にそろえる（JSONから読み込むとintの値がfloat64になるため）
+func (s *SearchSpace) Normalize(v Values) Values {
+	n := Values{}
+	for k, x := range v {
+		n[k] = x
+	}
+	for i := range s.Params {
+		p := &s.Params[i]
+		if f, ok := n[p.Key].(float64); ok && p.Type == Int {
+			n[p.Key] = int64(math.Round(f))
+		}
+	}
+	return n
+}
+
+// Initial 基準の設定ファイルの値（範囲外や未指定の項目があればnil、GAなどの初期値に使う）
+func (s *SearchSpace) Initial() []float64 {
+	if s.base == nil {
+		return nil
+	}
+	point := []float64{}
+	for i := range s.Params {
+		v, ok := lookup(s.base, s.Params[i].Key)
+		if !ok {
+			return nil
+		}
+		u, ok := s.Params[i].encode(v)
+		if !ok {
+			return nil
+		}
+		point = append(point, u)
+	}
+	return point
+}
+
+// Config 基準の設定ファイルをパラメータの値で上書きした設定ファイルの内容
+func (s *SearchSpace) Config(v Values) ([]byte, error) {
+	config := copyMap(s.base)
+	for k, x := range v {
+		if err := set(config, k, x); err != nil {
+			return nil, err
+		}
+	}
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(config); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func lookup(m map[string]interface{}, key string) (interface{}, bool) {
+	keys := strings.Split(key, ".")
+	for _, k := range keys[:len(keys)-1] {
+		child, ok := m[k].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		m = child
+	}
+	v, ok := m[keys[len(keys)-1]]
+	return v, ok
+}
+
+func set(m map[string]interface{}, key string, v interface{}) error {
+	keys := strings.Split(key, ".")
+	for _, k := range keys[:len(keys)-1] {
+		child, ok := m[k]
+		if !ok {
+			child = map[string]interface{}{}
+			m[k] = child
+		}
+		c, ok := child.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("key is not a table, %v", key)
+		}
+		m = c
+	}
+	m[keys[len(keys)-1]] = v
+	return nil
+}
+
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	c := map[string]interface{}{}
+	for k, v := range m {
+		if child, ok := v.(map[string]interface{}); ok {
+			v = copyMap(child)
+		}
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,241 @@
+package usecase
+
+import (
+	"fmt"
+	"trading-bot/pkg/domain"
+	"trading-bot/pkg/domain/exchange"
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/infrastructure/coincheck"
+	"trading-bot/pkg/usecase/schedule"
+	"trading-bot/pkg/usecase/trade"
+
+	"github.com/BurntSushi/toml"
+)
+
+// PortfolioBotName ポートフォリオ全体の状態を記録する際のボット名
+const PortfolioBotName = "portfolio"
+
+// PortfolioConfig ポートフォリオ用設定
+type PortfolioConfig struct {
+	// 状態の記録間隔（秒）
+	StatusIntervalSeconds int `toml:"status_interval_seconds"`
+	// 稼働させる戦略
+	Instances []PortfolioInstanceConfig `toml:"instances"`
+}
+
+// PortfolioInstanceConfig ポートフォリオ内の戦略ごとの設定
+type PortfolioInstanceConfig struct {
+	// ボット名（ポジションの管理単位）
+	Name string `toml:"name"`
+	// 戦略
+	Strategy StrategyType `toml:"strategy"`
+	// 購入対象コイン
+	Currency model.CurrencyType `toml:"currency"`
+	// 戦略の設定ファイル（未指定なら ./configs/bot-<strategy>.toml）
+	ConfigFile string `toml:"config_file"`
+	// 取引時間帯の設定ファイル（未指定なら常時取引）
+	ScheduleFile string `toml:"schedule_file"`
+	// 最大ポジション数
+	PositionCountMax int `toml:"position_count_max"`
+	// 資金枠（JPY）
+	BudgetJPY float64 `toml:"budget_jpy"`
+	// 資金枠（総資産に対する割合）
+	BudgetRatio float64 `toml:"budget_ratio"`
+}
+
+// ConfigPath 戦略の設定ファイルのパス
+func (c *PortfolioInstanceConfig) ConfigPath() string {
+	if c.ConfigFile != "" {
+		return c.ConfigFile
+	}
+	return StrategyConfigPath(c.Strategy)
+}
+
+func (c *PortfolioConfig) valid() error {
+	if c.StatusIntervalSeconds == 0 {
+		return fmt.Errorf("StatusIntervalSeconds is empty, %v", c.StatusIntervalSeconds)
+	}
+	if len(c.Instances) == 0 {
+		return fmt.Errorf("Instances is empty")
+	}
+	names := map[string]bool{}
+	for _, i := range c.Instances {
+		if i.Name == "" {
+			return fmt.Errorf("Name is empty, %v", i)
+		}
+		if i.Name == PortfolioBotName {
+			return fmt.Errorf("Name is reserved, %s", i.Name)
+		}
+		if names[i.Name] {
+			return fmt.Errorf("Name is duplicated, %s", i.Name)
+		}
+		names[i.Name] = true
+		if i.Strategy == "" {
+			return fmt.Errorf("[%s] Strategy is empty", i.Name)
+		}
+		if i.Currency == "" {
+			return fmt.Errorf("[%s] Currency is empty", i.Name)
+		}
+		if i.PositionCountMax == 0 {
+			return fmt.Errorf("[%s] PositionCountMax is empty, %v", i.Name, i.PositionCountMax)
+		}
+	}
+	return nil
+}
+
+// NewPortfolioConfig 設定ファイルから生成
+func NewPortfolioConfig(f string) (*PortfolioConfig, error) {
+	var conf PortfolioConfig
+	if _, err := toml.DecodeFile(f, &conf); err != nil {
+		return nil, err
+	}
+	if err := conf.valid(); err != nil {
+		return nil, fmt.Errorf("[%s] validation error: %w", f, err)
+	}
+	return &conf, nil
+}
+
+// PortfolioInstance ポートフォリオ内で稼働する戦略
+type PortfolioInstance struct {
+	Name       string
+	Pair       model.CurrencyPair
+	Bot        *Bot
+	Facade     *trade.Facade
+	Allocation *trade.Allocation
+}
+
+// Portfolio 複数の戦略を1プロセスで稼働させる
+type Portfolio struct {
+	logger    domain.Logger
+	allocator *trade.Allocator
+
+	Config    *PortfolioConfig
+	Instances []*PortfolioInstance
+}
+
+// NewPortfolio 生成
+// makeFacade はボット名ごとにポジションを分けたFacadeを生成する
+func NewPortfolio(logger domain.Logger, config *PortfolioConfig, exCli exchange.Client, makeFacade func(botName string) *trade.Facade) (*Portfolio, error) {
+	allocator := trade.NewAllocator(exCli)
+	instances := []*PortfolioInstance{}
+	for _, c := range config.Instances {
+		pair := model.CurrencyPair{Key: c.Currency, Settlement: model.JPY}
+		facade := makeFacade(c.Name)
+
+		allocation, err := allocator.Allocate(c.Name, pair, trade.Budget{AmountJPY: c.BudgetJPY, EquityRatio: c.BudgetRatio}, facade)
+		if err != nil {
+			return nil, err
+		}
+
+		strategy, err := MakeStrategyWithConfig(c.Strategy, c.ConfigPath(), facade, logger)
+		if err != nil {
+			return nil, fmt.Errorf("[%s] %w", c.Name, err)
+		}
+
+		bot := NewBot(logger, facade, strategy, &BotConfig{
+			Name:             c.Name,
+			Strategy:         c.Strategy,
+			ConfigPath:       c.ConfigPath(),
+			Currency:         c.Currency,
+			PositionCountMax: c.PositionCountMax,
+		})
+		if c.ScheduleFile != "" {
+			s, err := schedule.NewScheduleWithFile(c.ScheduleFile)
+			if err != nil {
+				return nil, fmt.Errorf("[%s] %w", c.Name, err)
+			}
+			bot.Schedule = s
+		}
+
+		instances = append(instances, &PortfolioInstance{
+			Name:       c.Name,
+			Pair:       pair,
+			Bot:        bot,
+			Facade:     facade,
+			Allocation: allocation,
+		})
+	}
+
+	return &Portfolio{
+		logger:    logger,
+		allocator: allocator,
+		Config:    config,
+		Instances: instances,
+	}, nil
+}
+
+// Pairs 稼働中の戦略が対象とする通貨ペア
+func (p *Portfolio) Pairs() []model.CurrencyPair {
+	pairs := []model.CurrencyPair{}
+	for _, i := range p.Instances {
+		found := false
+		for _, pair := range pairs {
+			if pair == i.Pair {
+				found = true
+				break
+			}
+		}
+		if !found {
+			pairs = append(pairs, i.Pair)
+		}
+	}
+	return pairs
+}
+
+// ReceiveTrade 取引履歴を対象通貨ペアの戦略に通知
+func (p *Portfolio) ReceiveTrade(h *coincheck.TradeHistory) error {
+	for _, i := range p.Instances {
+		if i.Pair.String() != h.Pair {
+			continue
+		}
+		if err := i.Bot.ReceiveTrade(h); err != nil {
+			p.logger.Error("[%s] error occured in receive trade, %v", i.Name, err)
+		}
+	}
+	return nil
+}
+
+// Statuses 戦略ごと及びポートフォリオ全体の状態を取得
+func (p *Portfolio) Statuses() ([]model.BotStatus, error) {
+	statuses := []model.BotStatus{}
+	var totalLimit, totalUsed float64
+	var totalCount int
+	for _, i := range p.Instances {
+		limit, err := i.Allocation.LimitJPY()
+		if err != nil {
+			return nil, err
+		}
+		used, err := i.Allocation.UsedJPY()
+		if err != nil {
+			return nil, err
+		}
+		pp, err := i.Facade.GetOpenPositions()
+		if err != nil {
+			return nil, err
+		}
+
+		pair := i.Pair.String()
+		statuses = append(statuses,
+			model.BotStatus{BotName: i.Name, Pair: pair, Type: "budget_jpy", Value: limit, Memo: "資金枠"},
+			model.BotStatus{BotName: i.Name, Pair: pair, Type: "used_jpy", Value: used, Memo: "使用中の資金"},
+			model.BotStatus{BotName: i.Name, Pair: pair, Type: "position_count", Value: float64(len(pp)), Memo: "未決済ポジション数"},
+			model.BotStatus{BotName: i.Name, Pair: pair, Type: "position_count_max", Value: float64(i.Bot.Config.PositionCountMax), Memo: "最大ポジション数"},
+		)
+		statuses = append(statuses, i.Bot.Statuses()...)
+		totalLimit += limit
+		totalUsed += used
+		totalCount += len(pp)
+	}
+
+	equity, err := p.allocator.GetEquityJPY()
+	if err != nil {
+		return nil, err
+	}
+	statuses = append(statuses,
+		model.BotStatus{BotName: PortfolioBotName, Pair: model.BotStatusPairAll, Type: "equity_jpy", Value: equity, Memo: "総資産（JPY換算）"},
+		model.BotStatus{BotName: PortfolioBotName, Pair: model.BotStatusPairAll, Type: "budget_jpy", Value: totalLimit, Memo: "資金枠の合計"},
+		model.BotStatus{BotName: PortfolioBotName, Pair: model.BotStatusPairAll, Type: "used_jpy", Value: totalUsed, Memo: "使用中の資金の合計"},
+		model.BotStatus{BotName: PortfolioBotName, Pair: model.BotStatusPairAll, Type: "position_count", Value: float64(totalCount), Memo: "未決済ポジション数の合計"},
+	)
+	return statuses, nil
+}
@@ -0,0 +1,64 @@
+package regime
+
+// Detector 判定結果の揺れを抑えて相場状態を確定させる
+// 同じ判定がconfirmCount回続くまで相場状態を切り替えない（判定不能の場合は現在の状態を維持）
+type Detector struct {
+	confirmCount int
+
+	current   Regime
+	candidate Regime
+	count     int
+}
+
+// NewDetector 生成
+func NewDetector(confirmCount int) *Detector {
+	if confirmCount < 1 {
+		confirmCount = 1
+	}
+	return &Detector{
+		confirmCount: confirmCount,
+		current:      Unknown,
+		candidate:    Unknown,
+	}
+}
+
+// Current 確定済みの相場状態
+func (d *Detector) Current() Regime {
+	return d.current
+}
+
+// Candidate 切り替え候補の相場状態と連続回数
+func (d *Detector) Candidate() (Regime, int) {
+	return d.candidate, d.count
+}
+
+// Restore 確定済みの相場状態と切り替え候補を復元
+func (d *Detector) Restore(current, candidate Regime, count int) {
+	d.current = current
+	d.candidate = candidate
+	d.count = count
+}
+
+// Update 判定結果を反映し、相場状態が切り替わったかを返す
+func (d *Detector) Update(r Regime) bool {
+	if r == Unknown || r == d.current {
+		d.candidate = Unknown
+		d.count = 0
+		return false
+	}
+
+	if r == d.candidate {
+		d.count++
+	} else {
+		d.candidate = r
+		d.count = 1
+	}
+	if d.count < d.confirmCount {
+		return false
+	}
+
+	d.current = r
+	d.candidate = Unknown
+	d.count = 0
+	return true
+}
@@ -0,0 +1,233 @@
+package regime
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/markcheno/go-talib"
+)
+
+// Regime 相場の状態
+type Regime string
+
+const (
+	// Unknown 判定不能（指標が割れている、レート履歴が不足している）
+	Unknown Regime = "unknown"
+	// Trend トレンド相場
+	Trend Regime = "trend"
+	// Range レンジ相場
+	Range Regime = "range"
+)
+
+// Value ボット状態として記録する数値（unknown:0, range:1, trend:2）
+func (r Regime) Value() float64 {
+	switch r {
+	case Range:
+		return 1
+	case Trend:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// Config 相場状態の判定用設定
+type Config struct {
+	// ADXの算出期間
+	ADXPeriod int `toml:"adx_period"`
+	// ADXがこれ以上ならトレンド
+	ADXTrendThreshold float64 `toml:"adx_trend_threshold"`
+	// ADXがこれ以下ならレンジ
+	ADXRangeThreshold float64 `toml:"adx_range_threshold"`
+
+	// ボリンジャーバンドの算出期間
+	BBandsPeriod int `toml:"bbands_period"`
+	// バンド幅のパーセンタイルを求める期間
+	BBandsWidthLookback int `toml:"bbands_width_lookback"`
+	// バンド幅のパーセンタイルがこれ以上ならトレンド（0〜1）
+	BBandsWidthTrendPercentile float64 `toml:"bbands_width_trend_percentile"`
+	// バンド幅のパーセンタイルがこれ以下ならレンジ（0〜1）
+	BBandsWidthRangePercentile float64 `toml:"bbands_width_range_percentile"`
+
+	// 分散比の比較期間（何期間分の変化率と比べるか）
+	VarianceRatioLag int `toml:"variance_ratio_lag"`
+	// 分散比の算出に使うレート数
+	VarianceRatioPeriod int `toml:"variance_ratio_period"`
+	// 分散比がこれ以上ならトレンド
+	VarianceRatioTrendThreshold float64 `toml:"variance_ratio_trend_threshold"`
+	// 分散比がこれ以下ならレンジ
+	VarianceRatioRangeThreshold float64 `toml:"variance_ratio_range_threshold"`
+
+	// 判定に必要な票数（3指標のうちいくつが一致したら判定するか）
+	Votes int `toml:"votes"`
+}
+
+// Valid 設定値を検証
+func (c *Config) Valid() error {
+	if c.ADXPeriod <= 0 {
+		return fmt.Errorf("ADXPeriod is empty, %v", c.ADXPeriod)
+	}
+	if c.ADXRangeThreshold >= c.ADXTrendThreshold {
+		return fmt.Errorf("ADXRangeThreshold must be less than ADXTrendThreshold, %v >= %v", c.ADXRangeThreshold, c.ADXTrendThreshold)
+	}
+	if c.BBandsPeriod <= 0 {
+		return fmt.Errorf("BBandsPeriod is empty, %v", c.BBandsPeriod)
+	}
+	if c.BBandsWidthLookback <= 0 {
+		return fmt.Errorf("BBandsWidthLookback is empty, %v", c.BBandsWidthLookback)
+	}
+	if c.BBandsWidthRangePercentile >= c.BBandsWidthTrendPercentile {
+		return fmt.Errorf("BBandsWidthRangePercentile must be less than BBandsWidthTrendPercentile, %v >= %v", c.BBandsWidthRangePercentile, c.BBandsWidthTrendPercentile)
+	}
+	if c.VarianceRatioLag < 2 {
+		return fmt.Errorf("VarianceRatioLag must be 2 or more, %v", c.VarianceRatioLag)
+	}
+	if c.VarianceRatioPeriod <= c.VarianceRatioLag {
+		return fmt.Errorf("VarianceRatioPeriod must be greater than VarianceRatioLag, %v <= %v", c.VarianceRatioPeriod, c.VarianceRatioLag)
+	}
+	if c.VarianceRatioRangeThreshold >= c.VarianceRatioTrendThreshold {
+		return fmt.Errorf("VarianceRatioRangeThreshold must be less than VarianceRatioTrendThreshold, %v >= %v", c.VarianceRatioRangeThreshold, c.VarianceRatioTrendThreshold)
+	}
+	if c.Votes < 1 || c.Votes > 3 {
+		return fmt.Errorf("Votes is out of range[1, 3], %v", c.Votes)
+	}
+	return nil
+}
+
+// RequiredRates 判定に必要なレート数
+func (c *Config) RequiredRates() int {
+	n := c.ADXPeriod*2 + 1
+	if m := c.BBandsPeriod + c.BBandsWidthLookback; m > n {
+		n = m
+	}
+	if c.VarianceRatioPeriod+1 > n {
+		n = c.VarianceRatioPeriod + 1
+	}
+	return n
+}
+
+// Result 判定結果
+type Result struct {
+	Regime Regime
+	// ADX（終値のみで算出）
+	ADX float64
+	// 現在のバンド幅の過去に対するパーセンタイル（0〜1）
+	BBandsWidthPercentile float64
+	// 分散比（1より大きければトレンド、小さければ平均回帰の傾向）
+	VarianceRatio float64
+	// トレンド・レンジと判定した指標の数
+	TrendVotes int
+	RangeVotes int
+}
+
+func (r *Result) String() string {
+	return fmt.Sprintf("%s (adx:%.3f, bbands width percentile:%.3f, variance ratio:%.3f, votes trend:%d range:%d)",
+		r.Regime, r.ADX, r.BBandsWidthPercentile, r.VarianceRatio, r.TrendVotes, r.RangeVotes)
+}
+
+// Classify レート履歴から相場状態を判定
+func Classify(c *Config, rates []float64) *Result {
+	if len(rates) < c.RequiredRates() {
+		return &Result{Regime: Unknown}
+	}
+
+	r := &Result{
+		ADX:                   adx(rates, c.ADXPeriod),
+		BBandsWidthPercentile: bbandsWidthPercentile(rates, c.BBandsPeriod, c.BBandsWidthLookback),
+		VarianceRatio:         varianceRatio(rates[len(rates)-c.VarianceRatioPeriod-1:], c.VarianceRatioLag),
+	}
+
+	vote := func(v, trend, rng float64) {
+		if v >= trend {
+			r.TrendVotes++
+		} else if v <= rng {
+			r.RangeVotes++
+		}
+	}
+	vote(r.ADX, c.ADXTrendThreshold, c.ADXRangeThreshold)
+	vote(r.BBandsWidthPercentile, c.BBandsWidthTrendPercentile, c.BBandsWidthRangePercentile)
+	vote(r.VarianceRatio, c.VarianceRatioTrendThreshold, c.VarianceRatioRangeThreshold)
+
+	switch {
+	case r.TrendVotes >= c.Votes && r.TrendVotes > r.RangeVotes:
+		r.Regime = Trend
+	case r.RangeVotes >= c.Votes && r.RangeVotes > r.TrendVotes:
+		r.Regime = Range
+	default:
+		r.Regime = Unknown
+	}
+	return r
+}
+
+// adx 終値のみからADXを算出（高値・安値として終値を使う）
+func adx(rates []float64, period int) float64 {
+	values := talib.Adx(rates, rates, rates, period)
+	return values[len(values)-1]
+}
+
+// bbandsWidthPercentile 直近のバンド幅が過去lookback期間の中で何パーセンタイルか
+func bbandsWidthPercentile(rates []float64, period, lookback int) float64 {
+	upper, middle, lower := talib.BBands(rates, period, 2.0, 2.0, talib.SMA)
+	widths := []float64{}
+	for i := len(rates) - lookback; i < len(rates); i++ {
+		if middle[i] == 0 {
+			continue
+		}
+		widths = append(widths, (upper[i]-lower[i])/middle[i])
+	}
+	if len(widths) == 0 {
+		return 0
+	}
+
+	current := widths[len(widths)-1]
+	sort.Float64s(widths)
+	below := sort.SearchFloat64s(widths, current)
+	if len(widths) == 1 {
+		return 1
+	}
+	return float64(below) / float64(len(widths)-1)
+}
+
+// varianceRatio 分散比（lag期間の対数収益率の分散 / (lag × 1期間の対数収益率の分散)）
+func varianceRatio(rates []float64, lag int) float64 {
+	returns := make([]float64, 0, len(rates)-1)
+	for i := 1; i < len(rates); i++ {
+		if rates[i-1] <= 0 || rates[i] <= 0 {
+			continue
+		}
+		returns = append(returns, math.Log(rates[i]/rates[i-1]))
+	}
+	if len(returns) <= lag {
+		return 1
+	}
+
+	lagReturns := []float64{}
+	for i := lag; i <= len(returns); i++ {
+		var sum float64
+		for _, r := range returns[i-lag : i] {
+			sum += r
+		}
+		lagReturns = append(lagReturns, sum)
+	}
+
+	v1 := variance(returns)
+	if v1 == 0 {
+		return 1
+	}
+	return variance(lagReturns) / (float64(lag) * v1)
+}
+
+func variance(values []float64) float64 {
+	var mean float64
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+
+	var sum float64
+	for _, v := range values {
+		sum += (v - mean) * (v - mean)
+	}
+	return sum / float64(len(values))
+}
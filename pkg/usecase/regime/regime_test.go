@@ -0,0 +1,122 @@
+package regime_test
+
+import (
+	"math"
+	"testing"
+	"trading-bot/pkg/usecase/regime"
+)
+
+func testConfig() *regime.Config {
+	return &regime.Config{
+		ADXPeriod:                   14,
+		ADXTrendThreshold:           25,
+		ADXRangeThreshold:           20,
+		BBandsPeriod:                20,
+		BBandsWidthLookback:         100,
+		BBandsWidthTrendPercentile:  0.8,
+		BBandsWidthRangePercentile:  0.5,
+		VarianceRatioLag:            5,
+		VarianceRatioPeriod:         100,
+		VarianceRatioTrendThreshold: 1.2,
+		VarianceRatioRangeThreshold: 0.8,
+		Votes:                       2,
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := map[string]struct {
+		rates []float64
+		want  regime.Regime
+	}{
+		"trend": {
+			// 横ばいの後に一方向へ加速しながら上昇
+			rates: makeRates(200, func(i int) float64 {
+				if i < 120 {
+					return 1000 + 2*math.Sin(float64(i))
+				}
+				return 1000 + 0.05*math.Pow(float64(i-120), 2) + math.Sin(float64(i))
+			}),
+			want: regime.Trend,
+		},
+		"range": {
+			// 一定幅で上下を繰り返す
+			rates: makeRates(200, func(i int) float64 {
+				return 1000 + 20*math.Sin(float64(i)*2*math.Pi/7)
+			}),
+			want: regime.Range,
+		},
+		"not enough rates": {
+			rates: makeRates(50, func(i int) float64 { return 1000 + float64(i) }),
+			want:  regime.Unknown,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := regime.Classify(testConfig(), tt.rates)
+			if got.Regime != tt.want {
+				t.Errorf("Classify() = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetector(t *testing.T) {
+	tests := map[string]struct {
+		confirmCount int
+		inputs       []regime.Regime
+		want         regime.Regime
+		wantSwitches int
+	}{
+		"switch after confirm count": {
+			confirmCount: 3,
+			inputs:       []regime.Regime{regime.Trend, regime.Trend, regime.Trend},
+			want:         regime.Trend,
+			wantSwitches: 1,
+		},
+		"not switch before confirm count": {
+			confirmCount: 3,
+			inputs:       []regime.Regime{regime.Trend, regime.Trend},
+			want:         regime.Unknown,
+			wantSwitches: 0,
+		},
+		"flapping does not switch": {
+			confirmCount: 2,
+			inputs:       []regime.Regime{regime.Range, regime.Range, regime.Trend, regime.Range, regime.Trend, regime.Unknown, regime.Trend},
+			want:         regime.Range,
+			wantSwitches: 1,
+		},
+		"unknown keeps current": {
+			confirmCount: 1,
+			inputs:       []regime.Regime{regime.Range, regime.Unknown, regime.Unknown},
+			want:         regime.Range,
+			wantSwitches: 1,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			d := regime.NewDetector(tt.confirmCount)
+			switches := 0
+			for _, r := range tt.inputs {
+				if d.Update(r) {
+					switches++
+				}
+			}
+			if d.Current() != tt.want {
+				t.Errorf("Current() = %v; want %v", d.Current(), tt.want)
+			}
+			if switches != tt.wantSwitches {
+				t.Errorf("switches = %d; want %d", switches, tt.wantSwitches)
+			}
+		})
+	}
+}
+
+func makeRates(n int, f func(i int) float64) []float64 {
+	rates := make([]float64, n)
+	for i := range rates {
+		rates[i] = f(i)
+	}
+	return rates
+}
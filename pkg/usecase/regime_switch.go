@@ -0,0 +1,364 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"trading-bot/pkg/domain"
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/usecase/regime"
+	"trading-bot/pkg/usecase/trade"
+
+	"github.com/BurntSushi/toml"
+)
+
+// RegimeSwitchConfig 相場状態に応じた戦略切り替え用設定
+type RegimeSwitchConfig struct {
+	// トレンド相場で使う戦略
+	TrendStrategy StrategyType `toml:"trend_strategy"`
+	// トレンド相場で使う戦略の設定ファイル（未指定なら ./configs/bot-<strategy>.toml）
+	TrendConfigFile string `toml:"trend_config_file"`
+	// レンジ相場で使う戦略
+	RangeStrategy StrategyType `toml:"range_strategy"`
+	// レンジ相場で使う戦略の設定ファイル（未指定なら ./configs/bot-<strategy>.toml）
+	RangeConfigFile string `toml:"range_config_file"`
+
+	// 同じ判定が何回続いたら切り替えるか
+	ConfirmCount int `toml:"confirm_count"`
+	// 相場状態の判定
+	Classifier regime.Config `toml:"classifier"`
+}
+
+func (c *RegimeSwitchConfig) valid() error {
+	for _, t := range []StrategyType{c.TrendStrategy, c.RangeStrategy} {
+		if t == "" || t == None {
+			return fmt.Errorf("strategy is empty, %v", t)
+		}
+		if t == RegimeSwitch {
+			return fmt.Errorf("strategy cannot be nested, %v", t)
+		}
+	}
+	if c.ConfirmCount <= 0 {
+		return fmt.Errorf("ConfirmCount is empty, %v", c.ConfirmCount)
+	}
+	if err := c.Classifier.Valid(); err != nil {
+		return fmt.Errorf("Classifier is invalid, %w", err)
+	}
+	return nil
+}
+
+// childConfigPath 子戦略の設定ファイルのパス
+func (c *RegimeSwitchConfig) childConfigPath(r regime.Regime) string {
+	if r == regime.Trend {
+		if c.TrendConfigFile != "" {
+			return c.TrendConfigFile
+		}
+		return StrategyConfigPath(c.TrendStrategy)
+	}
+	if c.RangeConfigFile != "" {
+		return c.RangeConfigFile
+	}
+	return StrategyConfigPath(c.RangeStrategy)
+}
+
+// NewRegimeSwitchConfig 設定ファイルから生成
+func NewRegimeSwitchConfig(f string) (*RegimeSwitchConfig, error) {
+	var conf RegimeSwitchConfig
+	if _, err := toml.DecodeFile(f, &conf); err != nil {
+		return nil, err
+	}
+	if err := conf.valid(); err != nil {
+		return nil, fmt.Errorf("[%s] validation error: %w", f, err)
+	}
+	return &conf, nil
+}
+
+// regimes 子戦略を呼び出す順序
+var regimes = []regime.Regime{regime.Range, regime.Trend}
+
+// defaultRegime 判定前に使う子戦略
+const defaultRegime = regime.Range
+
+// RegimeSwitchStrategy 相場状態（トレンド/レンジ）を判定して子戦略を切り替える
+// 新規の買いは現在の相場状態の子戦略だけが行い、建てたポジションの決済はそのポジションを建てた子戦略が行う
+type RegimeSwitchStrategy struct {
+	logger domain.Logger
+	facade *trade.Facade
+
+	config   *RegimeSwitchConfig
+	children map[regime.Regime]Strategy
+	detector *regime.Detector
+	// 直近の判定結果
+	last *regime.Result
+	// ポジションを建てた子戦略
+	owners map[uint64]regime.Regime
+}
+
+// NewRegimeSwitchStrategy 生成
+func NewRegimeSwitchStrategy(facade *trade.Facade, logger domain.Logger, config *RegimeSwitchConfig) (*RegimeSwitchStrategy, error) {
+	children := map[regime.Regime]Strategy{}
+	for r, t := range map[regime.Regime]StrategyType{regime.Trend: config.TrendStrategy, regime.Range: config.RangeStrategy} {
+		s, err := MakeStrategyWithConfig(t, config.childConfigPath(r), facade, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to make %s strategy, %w", r, err)
+		}
+		children[r] = s
+	}
+
+	return &RegimeSwitchStrategy{
+		logger:   logger,
+		facade:   facade,
+		config:   config,
+		children: children,
+		detector: regime.NewDetector(config.ConfirmCount),
+		last:     &regime.Result{Regime: regime.Unknown},
+		owners:   map[uint64]regime.Regime{},
+	}, nil
+}
+
+// Regime 現在の相場状態
+func (s *RegimeSwitchStrategy) Regime() regime.Regime {
+	return s.detector.Current()
+}
+
+func (s *RegimeSwitchStrategy) Buy(pair model.CurrencyPair, positions []model.Position) error {
+	rates, err := s.facade.GetRates(&pair)
+	if err != nil {
+		return err
+	}
+
+	before := s.detector.Current()
+	s.last = regime.Classify(&s.config.Classifier, rates)
+	if s.detector.Update(s.last.Regime) {
+		s.logger.Info("[regime] switched %s => %s, %s", before, s.detector.Current(), s.last.String())
+	} else {
+		candidate, count := s.detector.Candidate()
+		s.logger.Debug("[regime] current:%s candidate:%s(%d/%d), %s", s.detector.Current(), candidate, count, s.config.ConfirmCount, s.last.String())
+	}
+
+	current := s.detector.Current()
+	if current == regime.Unknown {
+		s.logger.Debug("[buy] => skip buy (regime is unknown)")
+		return nil
+	}
+
+	if err := s.children[current].Buy(pair, s.ownedPositions(current, positions)); err != nil {
+		return err
+	}
+
+	// 新しく建てたポジションを現在の子戦略に紐付ける
+	pp, err := s.facade.GetOpenPositions()
+	if err != nil {
+		return err
+	}
+	s.assign(pp)
+	return nil
+}
+
+func (s *RegimeSwitchStrategy) Sell(pair model.CurrencyPair, positions []model.Position) error {
+	s.assign(positions)
+
+	// 決済済みのポジションは紐付けを解除
+	opened := map[uint64]bool{}
+	for _, p := range positions {
+		opened[p.ID] = true
+	}
+	for id := range s.owners {
+		if !opened[id] {
+			delete(s.owners, id)
+		}
+	}
+
+	for _, r := range regimes {
+		if err := s.children[r].Sell(pair, s.ownedPositions(r, positions)); err != nil {
+			return fmt.Errorf("[%s] %w", r, err)
+		}
+	}
+	return nil
+}
+
+// assign 紐付けのないポジションを現在の子戦略に紐付ける（判定前は既定の子戦略、決済されないポジションを残さない）
+func (s *RegimeSwitchStrategy) assign(positions []model.Position) {
+	current := s.detector.Current()
+	if current == regime.Unknown {
+		current = defaultRegime
+	}
+	for _, p := range positions {
+		if _, ok := s.owners[p.ID]; !ok {
+			s.owners[p.ID] = current
+		}
+	}
+}
+
+// ownedPositions 子戦略が建てたポジション
+func (s *RegimeSwitchStrategy) ownedPositions(r regime.Regime, positions []model.Position) []model.Position {
+	owned := []model.Position{}
+	for _, p := range positions {
+		if s.owners[p.ID] == r {
+			owned = append(owned, p)
+		}
+	}
+	return owned
+}
+
+func (s *RegimeSwitchStrategy) BuyTradeCallback(pair model.CurrencyPair, rate float64) error {
+	for _, r := range regimes {
+		if err := s.children[r].BuyTradeCallback(pair, rate); err != nil {
+			return fmt.Errorf("[%s] %w", r, err)
+		}
+	}
+	return nil
+}
+
+func (s *RegimeSwitchStrategy) SellTradeCallback(pair model.CurrencyPair, rate float64) error {
+	for _, r := range regimes {
+		if err := s.children[r].SellTradeCallback(pair, rate); err != nil {
+			return fmt.Errorf("[%s] %w", r, err)
+		}
+	}
+	return nil
+}
+
+// Wait 現在の相場状態の子戦略に合わせて待機（判定前は既定の子戦略）
+func (s *RegimeSwitchStrategy) Wait(ctx context.Context) error {
+	current := s.detector.Current()
+	if current == regime.Unknown {
+		current = defaultRegime
+	}
+	return s.children[current].Wait(ctx)
+}
+
+// SaveState 状態を書き出す（子戦略の状態は "<相場状態>." を付けた項目名で書き出す）
+func (s *RegimeSwitchStrategy) SaveState(st *model.StrategyState) error {
+	candidate, count := s.detector.Candidate()
+	st.Values["regime"] = string(s.detector.Current())
+	st.Values["candidate"] = string(candidate)
+	st.SetFloat("candidate_count", float64(count))
+	if err := st.SetJSON("owners", s.owners); err != nil {
+		return err
+	}
+
+	for _, r := range regimes {
+		child, ok := s.children[r].(StatefulStrategy)
+		if !ok {
+			continue
+		}
+		cs := model.NewStrategyState(st.BotName, st.Strategy, st.Pair)
+		if err := child.SaveState(cs); err != nil {
+			return err
+		}
+		for k, v := range cs.Values {
+			st.Values[string(r)+"."+k] = v
+		}
+	}
+	return nil
+}
+
+// LoadState 状態を復元する
+func (s *RegimeSwitchStrategy) LoadState(st *model.StrategyState) error {
+	count, err := st.Float("candidate_count", 0)
+	if err != nil {
+		return err
+	}
+	current, candidate := regime.Regime(st.Values["regime"]), regime.Regime(st.Values["candidate"])
+	if current == "" {
+		current = regime.Unknown
+	}
+	if candidate == "" {
+		candidate = regime.Unknown
+	}
+	s.detector.Restore(current, candidate, int(count))
+
+	owners := map[uint64]regime.Regime{}
+	if _, err := st.JSON("owners", &owners); err != nil {
+		return err
+	}
+	s.owners = owners
+
+	for _, r := range regimes {
+		child, ok := s.children[r].(StatefulStrategy)
+		if !ok {
+			continue
+		}
+		cs := model.NewStrategyState(st.BotName, st.Strategy, st.Pair)
+		prefix := string(r) + "."
+		for k, v := range st.Values {
+			if strings.HasPrefix(k, prefix) {
+				cs.Values[strings.TrimPrefix(k, prefix)] = v
+			}
+		}
+		if len(cs.Values) == 0 {
+			continue
+		}
+		if err := child.LoadState(cs); err != nil {
+			return fmt.Errorf("[%s] %w", r, err)
+		}
+	}
+	return nil
+}
+
+// ReloadConfig 設定を再読み込み（子戦略の種別は変更不可、子戦略の設定も再読み込みする）
+// 全ての子戦略の設定を検証してから適用する（一部の子戦略だけ適用された状態にしない）
+func (s *RegimeSwitchStrategy) ReloadConfig(path string) (interface{}, interface{}, error) {
+	config, err := NewRegimeSwitchConfig(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if config.TrendStrategy != s.config.TrendStrategy || config.RangeStrategy != s.config.RangeStrategy {
+		return nil, nil, fmt.Errorf("strategy cannot be changed, trend:%s -> %s, range:%s -> %s",
+			s.config.TrendStrategy, config.TrendStrategy, s.config.RangeStrategy, config.RangeStrategy)
+	}
+
+	applies := map[regime.Regime]func() (interface{}, interface{}){}
+	for _, r := range regimes {
+		if _, ok := s.children[r].(ReloadableStrategy); !ok {
+			continue
+		}
+		child, ok := s.children[r].(PreparableStrategy)
+		if !ok {
+			return nil, nil, fmt.Errorf("[%s] strategy cannot be reloaded with other strategies, %T", r, s.children[r])
+		}
+		apply, err := child.PrepareReload(config.childConfigPath(r))
+		if err != nil {
+			return nil, nil, fmt.Errorf("[%s] %w", r, err)
+		}
+		applies[r] = apply
+	}
+
+	for _, r := range regimes {
+		apply, ok := applies[r]
+		if !ok {
+			continue
+		}
+		before, after := apply()
+		if diffs := DiffConfig(before, after); len(diffs) > 0 {
+			s.logger.Info("[reload] reloaded %s strategy (%s), %s", r, config.childConfigPath(r), strings.Join(diffs, ", "))
+		}
+	}
+
+	before := s.config
+	s.config = config
+	current, candidate := s.detector.Current(), regime.Unknown
+	s.detector = regime.NewDetector(config.ConfirmCount)
+	s.detector.Restore(current, candidate, 0)
+	return before, config, nil
+}
+
+// Statuses ダッシュボード用の状態
+func (s *RegimeSwitchStrategy) Statuses(botName string, pair model.CurrencyPair) []model.BotStatus {
+	p := pair.String()
+	owned := map[regime.Regime]int{}
+	for _, r := range s.owners {
+		owned[r]++
+	}
+
+	return []model.BotStatus{
+		{BotName: botName, Pair: p, Type: "regime", Value: s.detector.Current().Value(), Memo: "相場状態（0:判定不能 1:レンジ 2:トレンド）"},
+		{BotName: botName, Pair: p, Type: "regime_detected", Value: s.last.Regime.Value(), Memo: "直近の判定結果（0:判定不能 1:レンジ 2:トレンド）"},
+		{BotName: botName, Pair: p, Type: "regime_adx", Value: s.last.ADX, Memo: "ADX"},
+		{BotName: botName, Pair: p, Type: "regime_bbands_width_percentile", Value: s.last.BBandsWidthPercentile, Memo: "ボリンジャーバンド幅のパーセンタイル"},
+		{BotName: botName, Pair: p, Type: "regime_variance_ratio", Value: s.last.VarianceRatio, Memo: "分散比"},
+		{BotName: botName, Pair: p, Type: "regime_range_position_count", Value: float64(owned[regime.Range]), Memo: "レンジ用の戦略の未決済ポジション数"},
+		{BotName: botName, Pair: p, Type: "regime_trend_position_count", Value: float64(owned[regime.Trend]), Memo: "トレンド用の戦略の未決済ポジション数"},
+	}
+}
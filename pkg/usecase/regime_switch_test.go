@@ -0,0 +1,182 @@
+package usecase_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"trading-bot/pkg/domain"
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/infrastructure/memory"
+	"trading-bot/pkg/usecase"
+	"trading-bot/pkg/usecase/trade"
+)
+
+// fakeChild 設定ファイルの内容を設定として持ち、決済を依頼されたポジションを記録する子戦略
+type fakeChild struct {
+	config string
+	sold   []uint64
+}
+
+func (s *fakeChild) Buy(pair model.CurrencyPair, positions []model.Position) error { return nil }
+
+func (s *fakeChild) Sell(pair model.CurrencyPair, positions []model.Position) error {
+	for _, p := range positions {
+		s.sold = append(s.sold, p.ID)
+	}
+	return nil
+}
+
+func (s *fakeChild) BuyTradeCallback(pair model.CurrencyPair, rate float64) error  { return nil }
+func (s *fakeChild) SellTradeCallback(pair model.CurrencyPair, rate float64) error { return nil }
+func (s *fakeChild) Wait(ctx context.Context) error                                { return nil }
+
+func (s *fakeChild) ReloadConfig(path string) (interface{}, interface{}, error) {
+	apply, err := s.PrepareReload(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	before, after := apply()
+	return before, after, nil
+}
+
+func (s *fakeChild) PrepareReload(path string) (func() (interface{}, interface{}), error) {
+	config, err := readFakeConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return func() (interface{}, interface{}) {
+		before := s.config
+		s.config = config
+		return before, config
+	}, nil
+}
+
+func readFakeConfig(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	config := strings.TrimSpace(string(b))
+	if config == "invalid" {
+		return "", fmt.Errorf("config is invalid")
+	}
+	return config, nil
+}
+
+var fakeChildren = map[string]*fakeChild{}
+
+func init() {
+	for _, t := range []usecase.StrategyType{"fake-trend", "fake-range"} {
+		t := t
+		usecase.RegisterStrategy(t, func(p string, facade *trade.Facade, logger domain.Logger) (usecase.Strategy, error) {
+			config, err := readFakeConfig(p)
+			if err != nil {
+				return nil, err
+			}
+			s := &fakeChild{config: config, sold: []uint64{}}
+			fakeChildren[string(t)] = s
+			return s, nil
+		})
+	}
+}
+
+const regimeSwitchConfig = `
+trend_strategy = "fake-trend"
+trend_config_file = "%s"
+range_strategy = "fake-range"
+range_config_file = "%s"
+confirm_count = 1
+
+[classifier]
+adx_period = 14
+adx_trend_threshold = 25.0
+adx_range_threshold = 20.0
+bbands_period = 20
+bbands_width_lookback = 100
+bbands_width_trend_percentile = 0.8
+bbands_width_range_percentile = 0.5
+variance_ratio_lag = 5
+variance_ratio_period = 100
+variance_ratio_trend_threshold = 1.2
+variance_ratio_range_threshold = 0.8
+votes = 2
+`
+
+func newRegimeSwitch(t *testing.T) (*usecase.RegimeSwitchStrategy, func(trend, rng string) string) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		f := filepath.Join(dir, name)
+		if err := os.WriteFile(f, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+	// 子戦略の設定ファイルを書き換えて戦略の設定ファイルのパスを返す
+	writeConfigs := func(trend, rng string) string {
+		return write("regime.toml", fmt.Sprintf(regimeSwitchConfig, write("trend.toml", trend), write("range.toml", rng)))
+	}
+
+	config, err := usecase.NewRegimeSwitchConfig(writeConfigs("trend-1", "range-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exCli, err := memory.NewExchangeMock(strings.NewReader("日付, 販売所買い価格, 販売所売り価格\n2021-02-23T19:27:01Z,1000.0,1000.0"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rds := memory.NewDummyRDS(nil)
+	facade := trade.NewFacade(exCli, rds, rds, rds, rds, rds, rds, nil)
+	s, err := usecase.NewRegimeSwitchStrategy(facade, &memory.Logger{Level: memory.Error}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, writeConfigs
+}
+
+func TestRegimeSwitchStrategy_ReloadConfig(t *testing.T) {
+	tests := map[string]struct {
+		trend, rng string
+		wantErr    bool
+		// 再読み込み後の子戦略の設定
+		wantTrend, wantRange string
+	}{
+		"applied":       {trend: "trend-2", rng: "range-2", wantTrend: "trend-2", wantRange: "range-2"},
+		"invalid trend": {trend: "invalid", rng: "range-2", wantErr: true, wantTrend: "trend-1", wantRange: "range-1"},
+		"invalid range": {trend: "trend-2", rng: "invalid", wantErr: true, wantTrend: "trend-1", wantRange: "range-1"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			s, writeConfigs := newRegimeSwitch(t)
+			_, _, err := s.ReloadConfig(writeConfigs(tt.trend, tt.rng))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReloadConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			// 一方の子戦略の設定が不正なら、もう一方も適用しない
+			if got := fakeChildren["fake-trend"].config; got != tt.wantTrend {
+				t.Errorf("trend config = %v, want %v", got, tt.wantTrend)
+			}
+			if got := fakeChildren["fake-range"].config; got != tt.wantRange {
+				t.Errorf("range config = %v, want %v", got, tt.wantRange)
+			}
+		})
+	}
+}
+
+func TestRegimeSwitchStrategy_SellBeforeDetection(t *testing.T) {
+	s, _ := newRegimeSwitch(t)
+	positions := []model.Position{{ID: 1, OpenerOrder: &model.Order{ID: 1}}, {ID: 2, OpenerOrder: &model.Order{ID: 2}}}
+
+	// 判定前でも紐付けのないポジションは既定の子戦略が決済する
+	if err := s.Sell(model.BtcJpy, positions); err != nil {
+		t.Fatal(err)
+	}
+	if got := fakeChildren["fake-range"].sold; len(got) != 2 {
+		t.Errorf("range sold = %v, want 2 positions", got)
+	}
+	if got := fakeChildren["fake-trend"].sold; len(got) != 0 {
+		t.Errorf("trend sold = %v, want none", got)
+	}
+}
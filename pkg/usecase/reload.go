@@ -0,0 +1,105 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"time"
+)
+
+// ReloadableStrategy 設定の再読み込みに対応した戦略
+type ReloadableStrategy interface {
+	// ReloadConfig 設定ファイルを読み込んで検証し、問題なければ適用して適用前後の設定を返す
+	// 検証に失敗した場合は適用せずにエラーを返す
+	ReloadConfig(path string) (before, after interface{}, err error)
+}
+
+// PreparableStrategy 設定の検証と適用を分けて再読み込みできる戦略（複数の子戦略をまとめて再読み込みするときに使う）
+type PreparableStrategy interface {
+	// PrepareReload 設定ファイルを読み込んで検証し、適用する関数を返す（適用するまで設定は変わらない）
+	PrepareReload(path string) (apply func() (before, after interface{}), err error)
+}
+
+// DiffConfig 設定の変更箇所を取得（"項目名: 変更前 -> 変更後" の形式）
+func DiffConfig(before, after interface{}) []string {
+	diffs := []string{}
+	diffValue("", reflect.ValueOf(before), reflect.ValueOf(after), &diffs)
+	return diffs
+}
+
+func diffValue(name string, before, after reflect.Value, diffs *[]string) {
+	for before.Kind() == reflect.Ptr || before.Kind() == reflect.Interface {
+		if before.IsNil() || after.IsNil() {
+			if before.IsNil() != after.IsNil() {
+				*diffs = append(*diffs, fmt.Sprintf("%s: %v -> %v", name, before, after))
+			}
+			return
+		}
+		before, after = before.Elem(), after.Elem()
+	}
+
+	if before.Kind() != reflect.Struct {
+		if !reflect.DeepEqual(before.Interface(), after.Interface()) {
+			*diffs = append(*diffs, fmt.Sprintf("%s: %v -> %v", name, before.Interface(), after.Interface()))
+		}
+		return
+	}
+
+	t := before.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			// 非公開の項目は対象外
+			continue
+		}
+		n := f.Name
+		if name != "" {
+			n = name + "." + f.Name
+		}
+		diffValue(n, before.Field(i), after.Field(i), diffs)
+	}
+}
+
+// ConfigWatcher 設定ファイルの更新を監視
+type ConfigWatcher struct {
+	path     string
+	interval time.Duration
+	modTime  time.Time
+}
+
+// NewConfigWatcher 生成
+func NewConfigWatcher(path string, interval time.Duration) (*ConfigWatcher, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return &ConfigWatcher{
+		path:     path,
+		interval: interval,
+		modTime:  info.ModTime(),
+	}, nil
+}
+
+// Watch 更新日時が変わるたびにonChangeを呼び出す（ctxが終了するまで戻らない）
+func (w *ConfigWatcher) Watch(ctx context.Context, onChange func()) error {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			info, err := os.Stat(w.path)
+			if err != nil {
+				// 書き換え中で一時的に存在しない場合もあるため次回に再確認
+				continue
+			}
+			if info.ModTime().Equal(w.modTime) {
+				continue
+			}
+			w.modTime = info.ModTime()
+			onChange()
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package usecase_test
+
+import (
+	"reflect"
+	"testing"
+	"trading-bot/pkg/usecase"
+)
+
+func TestDiffConfig(t *testing.T) {
+	type sub struct {
+		Value float64
+	}
+	type config struct {
+		Interval int
+		Name     string
+		Sub      sub
+		Ptr      *sub
+		private  int
+	}
+
+	tests := map[string]struct {
+		before *config
+		after  *config
+		want   []string
+	}{
+		"no changes": {
+			before: &config{Interval: 1, Name: "a", Ptr: &sub{Value: 1}},
+			after:  &config{Interval: 1, Name: "a", Ptr: &sub{Value: 1}},
+			want:   []string{},
+		},
+		"changed fields": {
+			before: &config{Interval: 1, Name: "a", Sub: sub{Value: 0.5}},
+			after:  &config{Interval: 2, Name: "a", Sub: sub{Value: 1.5}},
+			want:   []string{"Interval: 1 -> 2", "Sub.Value: 0.5 -> 1.5"},
+		},
+		"nested pointer": {
+			before: &config{Ptr: &sub{Value: 1}},
+			after:  &config{Ptr: &sub{Value: 2}},
+			want:   []string{"Ptr.Value: 1 -> 2"},
+		},
+		"private fields are ignored": {
+			before: &config{private: 1},
+			after:  &config{private: 2},
+			want:   []string{},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := usecase.DiffConfig(tt.before, tt.after)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffConfig() = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
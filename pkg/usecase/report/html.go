@@ -0,0 +1,144 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	// chartWidth 総資産の推移のグラフの幅
+	chartWidth = 960
+	// chartHeight 総資産の推移のグラフの高さ
+	chartHeight = 320
+	// chartMaxPoints グラフに描く点の上限（超える場合は間引く）
+	chartMaxPoints = 1000
+)
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"jpy":     func(v float64) string { return fmt.Sprintf("%.0f", v) },
+	"rate":    func(v float64) string { return fmt.Sprintf("%.3f", v) },
+	"ratio":   func(v float64) string { return fmt.Sprintf("%.2f%%", v*100) },
+	"number":  func(v float64) string { return fmt.Sprintf("%.3f", v) },
+	"time":    func(t time.Time) string { return t.Format("2006-01-02 15:04:05") },
+	"seconds": func(v float64) string { return (time.Duration(v) * time.Second).String() },
+}).Parse(`<!DOCTYPE html>
+<html lang="ja">
+<head>
+<meta charset="utf-8">
+<title>Backtest report - {{.Report.Strategy}} {{.Report.Pair}}</title>
+<style>
+body { font-family: sans-serif; margin: 24px; color: #222; }
+table { border-collapse: collapse; margin-bottom: 24px; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: right; }
+th { background: #f4f4f4; }
+td.text { text-align: left; }
+.win { color: #1a7f37; }
+.loss { color: #cf222e; }
+.legend span { margin-right: 16px; }
+</style>
+</head>
+<body>
+<h1>Backtest report</h1>
+<p>{{.Report.Strategy}} / {{.Report.Pair}} / {{time .Report.Start}} - {{time .Report.End}}</p>
+
+<h2>Equity</h2>
+<svg width="{{.Width}}" height="{{.Height}}" viewBox="0 0 {{.Width}} {{.Height}}" style="border:1px solid #ccc">
+<polyline fill="none" stroke="#999" stroke-width="1" points="{{.BuyAndHold}}"/>
+<polyline fill="none" stroke="#0969da" stroke-width="2" points="{{.Equity}}"/>
+<text x="4" y="14" font-size="12">{{jpy .Max}}</text>
+<text x="4" y="{{.Height}}" dy="-4" font-size="12">{{jpy .Min}}</text>
+</svg>
+<p class="legend"><span style="color:#0969da">&#9632; strategy</span><span style="color:#999">&#9632; buy and hold</span></p>
+
+<h2>Metrics</h2>
+{{with .Report.Metrics}}
+<table>
+<tr><th>initial equity</th><td>{{jpy .InitialEquity}}</td><th>final equity</th><td>{{jpy .FinalEquity}}</td></tr>
+<tr><th>profit</th><td>{{jpy .Profit}} ({{ratio .Return}})</td><th>buy and hold</th><td>{{jpy .BuyAndHoldProfit}} ({{ratio .BuyAndHoldReturn}})</td></tr>
+<tr><th>trades</th><td>{{.TradeCount}} ({{.WinCount}} win / {{.LossCount}} loss)</td><th>win rate</th><td>{{ratio .WinRate}}</td></tr>
+<tr><th>average win</th><td>{{jpy .AverageWin}}</td><th>average loss</th><td>{{jpy .AverageLoss}}</td></tr>
+<tr><th>profit factor</th><td>{{number .ProfitFactor}}</td><th>max drawdown</th><td>{{jpy .MaxDrawdown}} ({{ratio .MaxDrawdownRatio}})</td></tr>
+<tr><th>sharpe</th><td>{{number .Sharpe}}</td><th>sortino</th><td>{{number .Sortino}}</td></tr>
+<tr><th>exposure</th><td>{{seconds .ExposureSeconds}} ({{ratio .ExposureRatio}})</td><th>fees</th><td>{{jpy .Fees}}</td></tr>
+</table>
+{{end}}
+
+<h2>Trades</h2>
+<table>
+<tr><th>position</th><th>entry</th><th>exit</th><th>entry rate</th><th>exit rate</th><th>amount</th><th>profit</th><th>fee</th><th>reason</th></tr>
+{{range .Report.Trades}}
+<tr>
+<td>{{.PositionID}}</td>
+<td class="text">{{time .EntryAt}}</td>
+<td class="text">{{if .Open}}(open){{else}}{{time .ExitAt}}{{end}}</td>
+<td>{{rate .EntryRate}}</td>
+<td>{{rate .ExitRate}}</td>
+<td>{{rate .Amount}}</td>
+<td class="{{if gt .Profit 0.0}}win{{else}}loss{{end}}">{{jpy .Profit}} ({{ratio .ProfitRatio}})</td>
+<td>{{jpy .Fee}}</td>
+<td class="text">{{.Reason}}</td>
+</tr>
+{{end}}
+</table>
+</body>
+</html>
+`))
+
+// WriteHTML 単体で閲覧できるHTML形式で書き出す
+func (r *Report) WriteHTML(w io.Writer) error {
+	points := r.EquityCurve
+	if len(points) > chartMaxPoints {
+		step := int(math.Ceil(float64(len(points)) / chartMaxPoints))
+		sampled := []EquityPoint{}
+		for i := 0; i < len(points); i += step {
+			sampled = append(sampled, points[i])
+		}
+		points = append(sampled, points[len(points)-1])
+	}
+
+	min, max := math.MaxFloat64, -math.MaxFloat64
+	for _, p := range points {
+		min = math.Min(min, math.Min(p.Equity, p.BuyAndHold))
+		max = math.Max(max, math.Max(p.Equity, p.BuyAndHold))
+	}
+	if len(points) == 0 {
+		min, max = 0, 0
+	}
+	polyline := func(value func(p EquityPoint) float64) string {
+		xy := []string{}
+		for i, p := range points {
+			x := 0.0
+			if len(points) > 1 {
+				x = float64(i) / float64(len(points)-1) * chartWidth
+			}
+			y := chartHeight / 2.0
+			if max > min {
+				y = (1 - (value(p)-min)/(max-min)) * chartHeight
+			}
+			xy = append(xy, fmt.Sprintf("%.1f,%.1f", x, y))
+		}
+		return strings.Join(xy, " ")
+	}
+
+	return htmlTemplate.Execute(w, struct {
+		Report     *Report
+		Width      int
+		Height     int
+		Min        float64
+		Max        float64
+		Equity     string
+		BuyAndHold string
+	}{
+		Report:     r,
+		Width:      chartWidth,
+		Height:     chartHeight,
+		Min:        min,
+		Max:        max,
+		Equity:     polyline(func(p EquityPoint) float64 { return p.Equity }),
+		BuyAndHold: polyline(func(p EquityPoint) float64 { return p.BuyAndHold }),
+	})
+}
@@ -0,0 +1,349 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// year 年率換算に使う1年の長さ
+const year = 365 * 24 * time.Hour
+
+// EquityPoint 総資産の推移の1点
+type EquityPoint struct {
+	Time time.Time `json:"time"`
+	// 総資産(JPY)
+	Equity float64 `json:"equity"`
+	// 通貨の売りレート
+	Rate float64 `json:"rate"`
+	// 同じ期間を買い持ちした場合の総資産(JPY)
+	BuyAndHold float64 `json:"buy_and_hold"`
+}
+
+// Trade 1ポジションの取引
+type Trade struct {
+	PositionID uint64    `json:"position_id"`
+	EntryAt    time.Time `json:"entry_at"`
+	// 決済日時（未決済なら期間の終了日時）
+	ExitAt    time.Time `json:"exit_at"`
+	EntryRate float64   `json:"entry_rate"`
+	// 決済レート（未決済なら期間の終了時のレート）
+	ExitRate float64 `json:"exit_rate"`
+	Amount   float64 `json:"amount"`
+	// 損益(JPY、手数料込み)
+	Profit float64 `json:"profit"`
+	// 損益率（購入額に対する割合）
+	ProfitRatio float64 `json:"profit_ratio"`
+	// 手数料(JPY)
+	Fee float64 `json:"fee"`
+	// 決済理由
+	Reason string `json:"reason"`
+	// 未決済か（勝率などの集計からは除く）
+	Open bool `json:"open"`
+}
+
+// Holding 保有期間
+func (t *Trade) Holding() time.Duration {
+	return t.ExitAt.Sub(t.EntryAt)
+}
+
+// Metrics 成績の指標
+type Metrics struct {
+	InitialEquity float64 `json:"initial_equity"`
+	FinalEquity   float64 `json:"final_equity"`
+	// 損益(JPY)
+	Profit float64 `json:"profit"`
+	// 損益率
+	Return float64 `json:"return"`
+	// 同じ期間を買い持ちした場合の損益(JPY)
+	BuyAndHoldProfit float64 `json:"buy_and_hold_profit"`
+	// 同じ期間を買い持ちした場合の損益率
+	BuyAndHoldReturn float64 `json:"buy_and_hold_return"`
+	// 決済済みの取引数
+	TradeCount  int     `json:"trade_count"`
+	WinCount    int     `json:"win_count"`
+	LossCount   int     `json:"loss_count"`
+	WinRate     float64 `json:"win_rate"`
+	AverageWin  float64 `json:"average_win"`
+	AverageLoss float64 `json:"average_loss"`
+	// 総利益/総損失（損失がなければ0）
+	ProfitFactor float64 `json:"profit_factor"`
+	// 最大ドローダウン(JPY)
+	MaxDrawdown float64 `json:"max_drawdown"`
+	// 最大ドローダウン（ピークに対する割合）
+	MaxDrawdownRatio float64 `json:"max_drawdown_ratio"`
+	// シャープレシオ（年率換算、無リスク金利は0）
+	Sharpe float64 `json:"sharpe"`
+	// ソルティノレシオ（年率換算、無リスク金利は0）
+	Sortino float64 `json:"sortino"`
+	// ポジションを保有していた時間
+	ExposureSeconds float64 `json:"exposure_seconds"`
+	// 期間に対するポジション保有時間の割合
+	ExposureRatio float64 `json:"exposure_ratio"`
+	// 手数料の合計(JPY)
+	Fees float64 `json:"fees"`
+}
+
+// Report バックテストの成績
+type Report struct {
+	Strategy    string        `json:"strategy"`
+	Pair        string        `json:"pair"`
+	Start       time.Time     `json:"start"`
+	End         time.Time     `json:"end"`
+	Metrics     Metrics       `json:"metrics"`
+	Trades      []Trade       `json:"trades"`
+	EquityCurve []EquityPoint `json:"equity_curve"`
+}
+
+// Recorder シミュレーション中の総資産の記録
+type Recorder struct {
+	points []EquityPoint
+}
+
+// NewRecorder 生成
+func NewRecorder() *Recorder {
+	return &Recorder{points: []EquityPoint{}}
+}
+
+// AddEquity 総資産を記録
+func (r *Recorder) AddEquity(t time.Time, equity, rate float64) {
+	buyAndHold := equity
+	if len(r.points) > 0 {
+		first := r.points[0]
+		buyAndHold = first.Equity
+		if first.Rate > 0 {
+			buyAndHold = first.Equity / first.Rate * rate
+		}
+	}
+	r.points = append(r.points, EquityPoint{Time: t, Equity: equity, Rate: rate, BuyAndHold: buyAndHold})
+}
+
+// Build 取引一覧と記録した総資産から成績を集計
+func (r *Recorder) Build(strategy, pair string, trades []Trade) *Report {
+	sort.SliceStable(trades, func(i, j int) bool {
+		return trades[i].EntryAt.Before(trades[j].EntryAt)
+	})
+	report := &Report{
+		Strategy:    strategy,
+		Pair:        pair,
+		Trades:      trades,
+		EquityCurve: r.points,
+	}
+	if len(r.points) == 0 {
+		return report
+	}
+	first, last := r.points[0], r.points[len(r.points)-1]
+	report.Start, report.End = first.Time, last.Time
+
+	m := &report.Metrics
+	m.InitialEquity = first.Equity
+	m.FinalEquity = last.Equity
+	m.Profit = last.Equity - first.Equity
+	m.BuyAndHoldProfit = last.BuyAndHold - first.Equity
+	if first.Equity > 0 {
+		m.Return = m.Profit / first.Equity
+		m.BuyAndHoldReturn = m.BuyAndHoldProfit / first.Equity
+	}
+
+	tradeMetrics(m, trades)
+	drawdown(m, r.points)
+	riskAdjusted(m, r.points)
+	exposure(m, trades, report.Start, report.End)
+	return report
+}
+
+// tradeMetrics 取引の勝敗と手数料を集計
+func tradeMetrics(m *Metrics, trades []Trade) {
+	grossWin, grossLoss := 0.0, 0.0
+	for _, t := range trades {
+		m.Fees += t.Fee
+		if t.Open {
+			continue
+		}
+		m.TradeCount++
+		if t.Profit > 0 {
+			m.WinCount++
+			grossWin += t.Profit
+		} else {
+			m.LossCount++
+			grossLoss += -t.Profit
+		}
+	}
+	if m.TradeCount > 0 {
+		m.WinRate = float64(m.WinCount) / float64(m.TradeCount)
+	}
+	if m.WinCount > 0 {
+		m.AverageWin = grossWin / float64(m.WinCount)
+	}
+	if m.LossCount > 0 {
+		m.AverageLoss = -grossLoss / float64(m.LossCount)
+	}
+	if grossLoss > 0 {
+		m.ProfitFactor = grossWin / grossLoss
+	}
+}
+
+// drawdown 総資産のピークからの最大下落を集計
+func drawdown(m *Metrics, points []EquityPoint) {
+	peak := 0.0
+	for _, p := range points {
+		if p.Equity > peak {
+			peak = p.Equity
+		}
+		dd := peak - p.Equity
+		if dd > m.MaxDrawdown {
+			m.MaxDrawdown = dd
+		}
+		if peak > 0 && dd/peak > m.MaxDrawdownRatio {
+			m.MaxDrawdownRatio = dd / peak
+		}
+	}
+}
+
+// riskAdjusted 記録ごとの損益率からシャープレシオとソルティノレシオを集計
+// 記録の平均間隔から1年あたりの記録数を求めて年率換算する
+func riskAdjusted(m *Metrics, points []EquityPoint) {
+	if len(points) < 3 {
+		return
+	}
+	returns := []float64{}
+	for i := 1; i < len(points); i++ {
+		if points[i-1].Equity <= 0 {
+			continue
+		}
+		returns = append(returns, points[i].Equity/points[i-1].Equity-1)
+	}
+	if len(returns) < 2 {
+		return
+	}
+
+	mean, variance, downside := 0.0, 0.0, 0.0
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+		if r < 0 {
+			downside += r * r
+		}
+	}
+	std := math.Sqrt(variance / float64(len(returns)-1))
+	downsideStd := math.Sqrt(downside / float64(len(returns)))
+
+	interval := points[len(points)-1].Time.Sub(points[0].Time) / time.Duration(len(points)-1)
+	if interval <= 0 {
+		return
+	}
+	annualize := math.Sqrt(float64(year) / float64(interval))
+	if std > 0 {
+		m.Sharpe = mean / std * annualize
+	}
+	if downsideStd > 0 {
+		m.Sortino = mean / downsideStd * annualize
+	}
+}
+
+// exposure ポジションを保有していた時間（重なる期間は1回だけ数える）
+func exposure(m *Metrics, trades []Trade, start, end time.Time) {
+	type span struct{ from, to time.Time }
+	spans := []span{}
+	for _, t := range trades {
+		if t.ExitAt.After(t.EntryAt) {
+			spans = append(spans, span{from: t.EntryAt, to: t.ExitAt})
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].from.Before(spans[j].from) })
+
+	var total time.Duration
+	var current *span
+	for i := range spans {
+		s := spans[i]
+		if current != nil && !s.from.After(current.to) {
+			if s.to.After(current.to) {
+				current.to = s.to
+			}
+			continue
+		}
+		if current != nil {
+			total += current.to.Sub(current.from)
+		}
+		current = &s
+	}
+	if current != nil {
+		total += current.to.Sub(current.from)
+	}
+
+	m.ExposureSeconds = total.Seconds()
+	if period := end.Sub(start); period > 0 {
+		m.ExposureRatio = float64(total) / float64(period)
+	}
+}
+
+// fitnessMetrics 評価値に使える指標（値が大きいほど良い、小さいほど良い指標は符号を反転）
+var fitnessMetrics = map[string]func(m *Metrics) float64{
+	"profit":             func(m *Metrics) float64 { return m.Profit },
+	"return":             func(m *Metrics) float64 { return m.Return },
+	"excess_return":      func(m *Metrics) float64 { return m.Return - m.BuyAndHoldReturn },
+	"win_rate":           func(m *Metrics) float64 { return m.WinRate },
+	"profit_factor":      profitFactorFitness,
+	"average_win":        func(m *Metrics) float64 { return m.AverageWin },
+	"average_loss":       func(m *Metrics) float64 { return m.AverageLoss },
+	"max_drawdown":       func(m *Metrics) float64 { return -m.MaxDrawdown },
+	"max_drawdown_ratio": func(m *Metrics) float64 { return -m.MaxDrawdownRatio },
+	"sharpe":             func(m *Metrics) float64 { return m.Sharpe },
+	"sortino":            func(m *Metrics) float64 { return m.Sortino },
+	"exposure_ratio":     func(m *Metrics) float64 { return -m.ExposureRatio },
+	"fees":               func(m *Metrics) float64 { return -m.Fees },
+}
+
+// minGrossLoss 評価値のプロフィットファクターで使う総損失の下限(JPY)
+const minGrossLoss = 1.0
+
+// profitFactorFitness 評価値のプロフィットファクター
+// 損失がなければ総利益を総損失の下限で割った値にする（損失のない試行を最低の評価にしない、成績の値は0のまま）
+func profitFactorFitness(m *Metrics) float64 {
+	if m.ProfitFactor > 0 {
+		return m.ProfitFactor
+	}
+	grossWin := m.AverageWin * float64(m.WinCount)
+	grossLoss := -m.AverageLoss * float64(m.LossCount)
+	return grossWin / math.Max(grossLoss, minGrossLoss)
+}
+
+// FitnessNames 評価値に使える指標名
+func FitnessNames() []string {
+	names := []string{}
+	for name := range fitnessMetrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidFitness 評価値に使える指標名か確認
+func ValidFitness(name string) error {
+	if _, ok := fitnessMetrics[name]; !ok {
+		return fmt.Errorf("unknown fitness metric %s, (%s)", name, strings.Join(FitnessNames(), ", "))
+	}
+	return nil
+}
+
+// Fitness 指標を評価値として取得（値が大きいほど良い）
+func (r *Report) Fitness(name string) (float64, error) {
+	if err := ValidFitness(name); err != nil {
+		return 0, err
+	}
+	return fitnessMetrics[name](&r.Metrics), nil
+}
+
+// WriteJSON JSON形式で書き出す
+func (r *Report) WriteJSON(w io.Writer) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(r)
+}
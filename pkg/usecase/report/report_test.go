@@ -0,0 +1,214 @@
+package report_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+	"trading-bot/pkg/usecase/report"
+)
+
+func TestRecorder_Build(t *testing.T) {
+	start := time.Date(2021, 2, 23, 0, 0, 0, 0, time.UTC)
+	at := func(hour int) time.Time {
+		return start.Add(time.Duration(hour) * time.Hour)
+	}
+
+	tests := map[string]struct {
+		equities []float64
+		rates    []float64
+		trades   []report.Trade
+		want     report.Metrics
+	}{
+		"win and loss": {
+			equities: []float64{1000, 1100, 1050, 1200},
+			rates:    []float64{100, 110, 100, 150},
+			trades: []report.Trade{
+				{EntryAt: at(0), ExitAt: at(1), Profit: 100, Fee: 1},
+				{EntryAt: at(1), ExitAt: at(2), Profit: -50, Fee: 1},
+				{EntryAt: at(2), ExitAt: at(3), Profit: 150, Fee: 1, Open: true},
+			},
+			want: report.Metrics{
+				InitialEquity:    1000,
+				FinalEquity:      1200,
+				Profit:           200,
+				Return:           0.2,
+				BuyAndHoldProfit: 500,
+				BuyAndHoldReturn: 0.5,
+				TradeCount:       2,
+				WinCount:         1,
+				LossCount:        1,
+				WinRate:          0.5,
+				AverageWin:       100,
+				AverageLoss:      -50,
+				ProfitFactor:     2,
+				MaxDrawdown:      50,
+				MaxDrawdownRatio: 50.0 / 1100,
+				ExposureSeconds:  3 * 3600,
+				ExposureRatio:    1,
+				Fees:             3,
+			},
+		},
+		"overlapped exposure": {
+			equities: []float64{1000, 1000, 1000, 1000, 1000},
+			rates:    []float64{100, 100, 100, 100, 100},
+			trades: []report.Trade{
+				{EntryAt: at(0), ExitAt: at(2), Profit: 0},
+				{EntryAt: at(1), ExitAt: at(2), Profit: 0},
+			},
+			want: report.Metrics{
+				InitialEquity:   1000,
+				FinalEquity:     1000,
+				TradeCount:      2,
+				LossCount:       2,
+				ExposureSeconds: 2 * 3600,
+				ExposureRatio:   0.5,
+			},
+		},
+		"no trades": {
+			equities: []float64{1000, 900, 950},
+			rates:    []float64{100, 90, 95},
+			trades:   []report.Trade{},
+			want: report.Metrics{
+				InitialEquity:    1000,
+				FinalEquity:      950,
+				Profit:           -50,
+				Return:           -0.05,
+				BuyAndHoldProfit: -50,
+				BuyAndHoldReturn: -0.05,
+				MaxDrawdown:      100,
+				MaxDrawdownRatio: 0.1,
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := report.NewRecorder()
+			for i, e := range tt.equities {
+				r.AddEquity(at(i), e, tt.rates[i])
+			}
+			got := r.Build("test", "btc_jpy", tt.trades).Metrics
+			// 年率換算の指標は個別に確認する
+			got.Sharpe, got.Sortino = 0, 0
+			if !metricsEqual(got, tt.want) {
+				t.Errorf("Build() metrics = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecorder_Build_RiskAdjusted(t *testing.T) {
+	start := time.Date(2021, 2, 23, 0, 0, 0, 0, time.UTC)
+	r := report.NewRecorder()
+	for i, e := range []float64{1000, 1010, 1000, 1020, 1030} {
+		r.AddEquity(start.Add(time.Duration(i)*24*time.Hour), e, 100)
+	}
+	m := r.Build("test", "btc_jpy", []report.Trade{}).Metrics
+	if m.Sharpe <= 0 || m.Sortino <= m.Sharpe {
+		t.Errorf("sharpe = %v, sortino = %v, want 0 < sharpe < sortino", m.Sharpe, m.Sortino)
+	}
+
+	f, err := (&report.Report{Metrics: m}).Fitness("sharpe")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if f != m.Sharpe {
+		t.Errorf("Fitness(sharpe) = %v, want %v", f, m.Sharpe)
+	}
+	if f, _ := (&report.Report{Metrics: report.Metrics{MaxDrawdown: 10}}).Fitness("max_drawdown"); f != -10 {
+		t.Errorf("Fitness(max_drawdown) = %v, want -10", f)
+	}
+	if _, err := (&report.Report{}).Fitness("unknown"); err == nil {
+		t.Errorf("Fitness(unknown) error = nil, want error")
+	}
+}
+
+func TestReport_Fitness_ProfitFactor(t *testing.T) {
+	tests := map[string]struct {
+		profits []float64
+		// 成績のプロフィットファクター（JSONに書き出せる値）と評価値
+		want, wantFitness float64
+	}{
+		"with loss": {profits: []float64{100, -50}, want: 2, wantFitness: 2},
+		"no loss":   {profits: []float64{100, 50}, want: 0, wantFitness: 150},
+		"even":      {profits: []float64{100, 0}, want: 0, wantFitness: 100},
+		"no win":    {profits: []float64{-100}, want: 0, wantFitness: 0},
+		"no trade":  {profits: []float64{}, want: 0, wantFitness: 0},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			trades := []report.Trade{}
+			for i, p := range tt.profits {
+				trades = append(trades, report.Trade{PositionID: uint64(i + 1), Profit: p})
+			}
+			recorder := report.NewRecorder()
+			start := time.Date(2021, 2, 23, 0, 0, 0, 0, time.UTC)
+			recorder.AddEquity(start, 1000, 1000)
+			recorder.AddEquity(start.Add(time.Hour), 1000, 1000)
+			r := recorder.Build("test", "btc_jpy", trades)
+			if r.Metrics.ProfitFactor != tt.want {
+				t.Errorf("ProfitFactor = %v, want %v", r.Metrics.ProfitFactor, tt.want)
+			}
+			f, err := r.Fitness("profit_factor")
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			if f != tt.wantFitness {
+				t.Errorf("Fitness(profit_factor) = %v, want %v", f, tt.wantFitness)
+			}
+		})
+	}
+}
+
+func TestReport_Write(t *testing.T) {
+	start := time.Date(2021, 2, 23, 0, 0, 0, 0, time.UTC)
+	r := report.NewRecorder()
+	r.AddEquity(start, 1000, 100)
+	r.AddEquity(start.Add(time.Hour), 1100, 110)
+	rep := r.Build("test", "btc_jpy", []report.Trade{
+		{PositionID: 1, EntryAt: start, ExitAt: start.Add(time.Hour), Profit: 100, Reason: "take_profit: <reached>"},
+	})
+
+	var js bytes.Buffer
+	if err := rep.WriteJSON(&js); err != nil {
+		t.Fatal(err.Error())
+	}
+	var decoded report.Report
+	if err := json.Unmarshal(js.Bytes(), &decoded); err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(decoded.Trades) != 1 || len(decoded.EquityCurve) != 2 || decoded.Metrics.Profit != 100 {
+		t.Errorf("decoded report = %+v", decoded)
+	}
+
+	var html bytes.Buffer
+	if err := rep.WriteHTML(&html); err != nil {
+		t.Fatal(err.Error())
+	}
+	for _, want := range []string{"<svg", "<polyline", "take_profit: &lt;reached&gt;"} {
+		if !strings.Contains(html.String(), want) {
+			t.Errorf("html does not contain %s", want)
+		}
+	}
+}
+
+func metricsEqual(a, b report.Metrics) bool {
+	av, _ := json.Marshal(a)
+	bv, _ := json.Marshal(b)
+	var am, bm map[string]float64
+	if err := json.Unmarshal(av, &am); err != nil {
+		return false
+	}
+	if err := json.Unmarshal(bv, &bm); err != nil {
+		return false
+	}
+	for k, v := range am {
+		if math.Abs(v-bm[k]) > 1e-9 {
+			return false
+		}
+	}
+	return true
+}
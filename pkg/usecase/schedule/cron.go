@@ -0,0 +1,137 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron cron形式（分 時 日 月 曜日）のスケジュール
+type Cron struct {
+	minutes  []bool
+	hours    []bool
+	days     []bool
+	months   []bool
+	weekdays []bool
+
+	// 日・曜日の指定有無（両方指定された場合はどちらかに一致すれば実行）
+	dayRestricted     bool
+	weekdayRestricted bool
+}
+
+// ParseCron cron形式の文字列を解析
+// 各項目は * / 数値 / 範囲(1-5) / 列挙(1,3,5) / 間隔(*/15, 0-30/5) に対応
+func ParseCron(spec string) (*Cron, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron spec must have 5 fields, spec = %s", spec)
+	}
+
+	c := &Cron{}
+	var err error
+	if c.minutes, err = parseField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("minute field is invalid, %w", err)
+	}
+	if c.hours, err = parseField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("hour field is invalid, %w", err)
+	}
+	if c.days, err = parseField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("day field is invalid, %w", err)
+	}
+	if c.months, err = parseField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("month field is invalid, %w", err)
+	}
+	// 日曜日は0と7のどちらでも指定可能
+	if c.weekdays, err = parseField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("weekday field is invalid, %w", err)
+	}
+	c.weekdays[0] = c.weekdays[0] || c.weekdays[7]
+	c.dayRestricted = fields[2] != "*"
+	c.weekdayRestricted = fields[4] != "*"
+
+	return c, nil
+}
+
+func parseField(field string, min, max int) ([]bool, error) {
+	values := make([]bool, max+1)
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			s, err := strconv.Atoi(part[i+1:])
+			if err != nil || s <= 0 {
+				return nil, fmt.Errorf("step is invalid, %s", part)
+			}
+			step = s
+			part = part[:i]
+		}
+
+		start, end := min, max
+		if part != "*" {
+			if i := strings.Index(part, "-"); i >= 0 {
+				var err error
+				if start, err = strconv.Atoi(part[:i]); err != nil {
+					return nil, fmt.Errorf("range is invalid, %s", part)
+				}
+				if end, err = strconv.Atoi(part[i+1:]); err != nil {
+					return nil, fmt.Errorf("range is invalid, %s", part)
+				}
+			} else {
+				v, err := strconv.Atoi(part)
+				if err != nil {
+					return nil, fmt.Errorf("value is invalid, %s", part)
+				}
+				start, end = v, v
+			}
+		}
+		if start < min || end > max || start > end {
+			return nil, fmt.Errorf("value is out of range [%d-%d], %s", min, max, part)
+		}
+
+		for v := start; v <= end; v += step {
+			values[v] = true
+		}
+	}
+	return values, nil
+}
+
+// Match 指定日時（分単位）が実行対象か判定
+func (c *Cron) Match(t time.Time) bool {
+	return c.minutes[t.Minute()] && c.hours[t.Hour()] && c.matchDay(t)
+}
+
+func (c *Cron) matchDay(t time.Time) bool {
+	if !c.months[int(t.Month())] {
+		return false
+	}
+	day := c.days[t.Day()]
+	weekday := c.weekdays[int(t.Weekday())]
+	if c.dayRestricted && c.weekdayRestricted {
+		return day || weekday
+	}
+	return day && weekday
+}
+
+// Next 指定日時より後で最初の実行日時を取得（5年以内に見つからなければゼロ値）
+func (c *Cron) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if !c.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !c.hours[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if !c.minutes[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
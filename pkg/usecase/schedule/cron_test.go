@@ -0,0 +1,85 @@
+package schedule_test
+
+import (
+	"testing"
+	"time"
+	"trading-bot/pkg/usecase/schedule"
+)
+
+func TestCron_Next(t *testing.T) {
+	// 2021-03-01は月曜日
+	base := time.Date(2021, 3, 1, 10, 30, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		spec string
+		want time.Time
+	}{
+		"every minute": {
+			spec: "* * * * *",
+			want: time.Date(2021, 3, 1, 10, 31, 0, 0, time.UTC),
+		},
+		"every 15 minutes": {
+			spec: "*/15 * * * *",
+			want: time.Date(2021, 3, 1, 10, 45, 0, 0, time.UTC),
+		},
+		"daily at 9:00": {
+			spec: "0 9 * * *",
+			want: time.Date(2021, 3, 2, 9, 0, 0, 0, time.UTC),
+		},
+		"weekdays at 9:00 and 21:00": {
+			spec: "0 9,21 * * 1-5",
+			want: time.Date(2021, 3, 1, 21, 0, 0, 0, time.UTC),
+		},
+		"sunday as 7": {
+			spec: "0 0 * * 7",
+			want: time.Date(2021, 3, 7, 0, 0, 0, 0, time.UTC),
+		},
+		"monthly on the 15th": {
+			spec: "0 0 15 * *",
+			want: time.Date(2021, 3, 15, 0, 0, 0, 0, time.UTC),
+		},
+		"day or weekday": {
+			spec: "0 0 15 * 3",
+			want: time.Date(2021, 3, 3, 0, 0, 0, 0, time.UTC),
+		},
+		"next year": {
+			spec: "0 0 1 1 *",
+			want: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		"leap day": {
+			spec: "0 0 29 2 *",
+			want: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			c, err := schedule.ParseCron(tt.spec)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			if got := c.Next(base); !got.Equal(tt.want) {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+			if !c.Match(tt.want) {
+				t.Errorf("Match(%v) = false, want true", tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCron_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"too few fields": "* * * *",
+		"out of range":   "60 * * * *",
+		"reverse range":  "* 5-1 * * *",
+		"invalid step":   "*/0 * * * *",
+		"not a number":   "a * * * *",
+	}
+	for name, spec := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := schedule.ParseCron(spec); err == nil {
+				t.Errorf("ParseCron(%q) error is nil", spec)
+			}
+		})
+	}
+}
@@ -0,0 +1,352 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// datetimeLayout 設定ファイルの日時の書式（タイムゾーンは設定のtimezone）
+const datetimeLayout = "2006-01-02 15:04"
+
+// Config 取引時間帯の設定
+type Config struct {
+	// タイムゾーン（未指定ならLocal）
+	Timezone string `toml:"timezone"`
+	// 経済指標等の予定を記載したカレンダーファイル
+	CalendarFile string `toml:"calendar_file"`
+	// 取引する時間帯（未指定なら常時）
+	Windows []WindowConfig `toml:"windows"`
+	// 新規注文を止める期間
+	Blackouts []BlackoutConfig `toml:"blackouts"`
+	// 時間帯ごとの戦略の設定ファイル（プロファイル名 => 設定ファイル）
+	Profiles map[string]string `toml:"profiles"`
+}
+
+// WindowConfig 毎週の取引時間帯
+type WindowConfig struct {
+	// 曜日（0:日曜〜6:土曜、未指定なら毎日）
+	Weekdays []int `toml:"weekdays"`
+	// 開始時刻（例: 09:00）
+	Start string `toml:"start"`
+	// 終了時刻（開始時刻より前なら翌日の時刻）
+	End string `toml:"end"`
+	// 適用するプロファイル（未指定なら通常の設定）
+	Profile string `toml:"profile"`
+}
+
+// BlackoutConfig 新規注文を止める期間（cronとminutes、またはstartとendで指定）
+type BlackoutConfig struct {
+	// 開始日時（cron形式、例: 毎週水曜15時なら 0 15 * * 3）
+	Cron string `toml:"cron"`
+	// 期間（分）
+	Minutes int `toml:"minutes"`
+	// 開始日時（例: 2021-03-01 15:00）
+	Start string `toml:"start"`
+	// 終了日時（例: 2021-03-01 17:00）
+	End string `toml:"end"`
+	// 理由
+	Reason string `toml:"reason"`
+}
+
+// CalendarConfig 経済指標等の予定
+type CalendarConfig struct {
+	// 予定の何分前から止めるか（予定ごとの指定がなければ使用）
+	BeforeMinutes int `toml:"before_minutes"`
+	// 予定の何分後まで止めるか（予定ごとの指定がなければ使用）
+	AfterMinutes int `toml:"after_minutes"`
+	// 予定
+	Events []CalendarEventConfig `toml:"events"`
+}
+
+// CalendarEventConfig 予定
+type CalendarEventConfig struct {
+	// 名前（例: FOMC）
+	Name string `toml:"name"`
+	// 日時（例: 2021-03-17 18:00）
+	At string `toml:"at"`
+	// 予定の何分前から止めるか
+	BeforeMinutes int `toml:"before_minutes"`
+	// 予定の何分後まで止めるか
+	AfterMinutes int `toml:"after_minutes"`
+}
+
+// NewConfig 設定ファイルから生成
+func NewConfig(f string) (*Config, error) {
+	var conf Config
+	if _, err := toml.DecodeFile(f, &conf); err != nil {
+		return nil, err
+	}
+	return &conf, nil
+}
+
+// Session 指定日時の取引時間帯の状態
+type Session struct {
+	// 取引時間帯内か
+	InWindow bool
+	// 新規注文を止めている理由（止めていなければ空）
+	Blackout string
+	// 適用するプロファイル
+	Profile string
+}
+
+// CanEntry 新規注文できるか
+func (s *Session) CanEntry() bool {
+	return s.InWindow && s.Blackout == ""
+}
+
+// String 文字列に変換
+func (s *Session) String() string {
+	state := "open"
+	if !s.InWindow {
+		state = "out of window"
+	} else if s.Blackout != "" {
+		state = fmt.Sprintf("blackout(%s)", s.Blackout)
+	}
+	if s.Profile == "" {
+		return state
+	}
+	return fmt.Sprintf("%s profile:%s", state, s.Profile)
+}
+
+type window struct {
+	weekdays []bool
+	// 0時からの経過時間
+	start, end time.Duration
+	profile    string
+}
+
+func (w *window) match(t time.Time) bool {
+	elapsed := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	weekday := int(t.Weekday())
+	if w.start <= w.end {
+		return w.weekdays[weekday] && w.start <= elapsed && elapsed < w.end
+	}
+	// 日をまたぐ場合は開始した曜日で判定
+	if elapsed >= w.start {
+		return w.weekdays[weekday]
+	}
+	return elapsed < w.end && w.weekdays[(weekday+6)%7]
+}
+
+type blackout struct {
+	cron     *Cron
+	duration time.Duration
+	start    time.Time
+	end      time.Time
+	reason   string
+}
+
+func (b *blackout) match(t time.Time) bool {
+	if b.cron == nil {
+		return !t.Before(b.start) && t.Before(b.end)
+	}
+	// 直近の開始日時から期間内か
+	next := b.cron.Next(t.Add(-b.duration))
+	return !next.IsZero() && !next.After(t)
+}
+
+// Schedule 取引時間帯
+type Schedule struct {
+	loc       *time.Location
+	windows   []window
+	blackouts []blackout
+	profiles  map[string]string
+}
+
+// NewSchedule 生成
+func NewSchedule(c *Config) (*Schedule, error) {
+	loc := time.Local
+	if c.Timezone != "" {
+		l, err := time.LoadLocation(c.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("Timezone is invalid, %w", err)
+		}
+		loc = l
+	}
+
+	s := &Schedule{
+		loc:       loc,
+		windows:   []window{},
+		blackouts: []blackout{},
+		profiles:  map[string]string{},
+	}
+	for k, v := range c.Profiles {
+		s.profiles[k] = v
+	}
+
+	for i, w := range c.Windows {
+		parsed, err := s.parseWindow(&w)
+		if err != nil {
+			return nil, fmt.Errorf("Windows[%d] is invalid, %w", i, err)
+		}
+		s.windows = append(s.windows, *parsed)
+	}
+	for i, b := range c.Blackouts {
+		parsed, err := s.parseBlackout(&b)
+		if err != nil {
+			return nil, fmt.Errorf("Blackouts[%d] is invalid, %w", i, err)
+		}
+		s.blackouts = append(s.blackouts, *parsed)
+	}
+
+	if c.CalendarFile != "" {
+		var calendar CalendarConfig
+		if _, err := toml.DecodeFile(c.CalendarFile, &calendar); err != nil {
+			return nil, fmt.Errorf("CalendarFile is invalid, %w", err)
+		}
+		if err := s.addCalendar(&calendar); err != nil {
+			return nil, fmt.Errorf("[%s] %w", c.CalendarFile, err)
+		}
+	}
+	return s, nil
+}
+
+// NewScheduleWithFile 設定ファイルから生成
+func NewScheduleWithFile(f string) (*Schedule, error) {
+	c, err := NewConfig(f)
+	if err != nil {
+		return nil, err
+	}
+	s, err := NewSchedule(c)
+	if err != nil {
+		return nil, fmt.Errorf("[%s] validation error: %w", f, err)
+	}
+	return s, nil
+}
+
+func (s *Schedule) parseWindow(c *WindowConfig) (*window, error) {
+	w := &window{weekdays: make([]bool, 7), profile: c.Profile}
+	if len(c.Weekdays) == 0 {
+		for i := range w.weekdays {
+			w.weekdays[i] = true
+		}
+	}
+	for _, d := range c.Weekdays {
+		if d < 0 || d > 6 {
+			return nil, fmt.Errorf("Weekdays is out of range [0-6], %v", c.Weekdays)
+		}
+		w.weekdays[d] = true
+	}
+
+	var err error
+	if w.start, err = parseClock(c.Start); err != nil {
+		return nil, fmt.Errorf("Start is invalid, %w", err)
+	}
+	if w.end, err = parseClock(c.End); err != nil {
+		return nil, fmt.Errorf("End is invalid, %w", err)
+	}
+	if w.start == w.end {
+		return nil, fmt.Errorf("Start and End are same, %s", c.Start)
+	}
+	if c.Profile != "" {
+		if _, ok := s.profiles[c.Profile]; !ok {
+			return nil, fmt.Errorf("Profile is not found in profiles, %s", c.Profile)
+		}
+	}
+	return w, nil
+}
+
+func (s *Schedule) parseBlackout(c *BlackoutConfig) (*blackout, error) {
+	b := &blackout{reason: c.Reason}
+	if b.reason == "" {
+		b.reason = "blackout"
+	}
+	if c.Cron != "" {
+		cron, err := ParseCron(c.Cron)
+		if err != nil {
+			return nil, err
+		}
+		if c.Minutes <= 0 {
+			return nil, fmt.Errorf("Minutes is empty, %v", c.Minutes)
+		}
+		b.cron = cron
+		b.duration = time.Duration(c.Minutes) * time.Minute
+		return b, nil
+	}
+
+	var err error
+	if b.start, err = time.ParseInLocation(datetimeLayout, c.Start, s.loc); err != nil {
+		return nil, fmt.Errorf("Start is invalid, %w", err)
+	}
+	if b.end, err = time.ParseInLocation(datetimeLayout, c.End, s.loc); err != nil {
+		return nil, fmt.Errorf("End is invalid, %w", err)
+	}
+	if !b.start.Before(b.end) {
+		return nil, fmt.Errorf("End must be after Start, %s - %s", c.Start, c.End)
+	}
+	return b, nil
+}
+
+func (s *Schedule) addCalendar(c *CalendarConfig) error {
+	for i, e := range c.Events {
+		at, err := time.ParseInLocation(datetimeLayout, e.At, s.loc)
+		if err != nil {
+			return fmt.Errorf("Events[%d] At is invalid, %w", i, err)
+		}
+		before, after := e.BeforeMinutes, e.AfterMinutes
+		if before == 0 {
+			before = c.BeforeMinutes
+		}
+		if after == 0 {
+			after = c.AfterMinutes
+		}
+		if before <= 0 && after <= 0 {
+			return fmt.Errorf("Events[%d] before_minutes and after_minutes are empty", i)
+		}
+		s.blackouts = append(s.blackouts, blackout{
+			start:  at.Add(-time.Duration(before) * time.Minute),
+			end:    at.Add(time.Duration(after) * time.Minute),
+			reason: e.Name,
+		})
+	}
+	return nil
+}
+
+// At 指定日時の取引時間帯の状態を取得
+func (s *Schedule) At(t time.Time) *Session {
+	t = t.In(s.loc)
+	session := &Session{InWindow: len(s.windows) == 0}
+	for _, w := range s.windows {
+		if w.match(t) {
+			session.InWindow = true
+			session.Profile = w.profile
+			break
+		}
+	}
+	for _, b := range s.blackouts {
+		if b.match(t) {
+			session.Blackout = b.reason
+			break
+		}
+	}
+	return session
+}
+
+// ProfileConfigPath プロファイルの設定ファイルを取得（プロファイル未指定なら空）
+func (s *Schedule) ProfileConfigPath(profile string) string {
+	return s.profiles[profile]
+}
+
+// parseClock 時刻（hh:mm）を0時からの経過時間に変換（24:00も可）
+func parseClock(s string) (time.Duration, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("clock must be hh:mm, %s", s)
+	}
+	h, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, fmt.Errorf("hour is invalid, %s", s)
+	}
+	m, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, fmt.Errorf("minute is invalid, %s", s)
+	}
+	if h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("clock is out of range, %s", s)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
@@ -0,0 +1,143 @@
+package schedule_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"trading-bot/pkg/usecase/schedule"
+)
+
+func TestSchedule_At(t *testing.T) {
+	config := &schedule.Config{
+		Timezone: "UTC",
+		Windows: []schedule.WindowConfig{
+			{Weekdays: []int{1, 2, 3, 4, 5}, Start: "08:00", End: "22:00"},
+			{Weekdays: []int{1, 2, 3, 4, 5}, Start: "22:00", End: "02:00", Profile: "night"},
+		},
+		Blackouts: []schedule.BlackoutConfig{
+			{Cron: "0 15 * * 3", Minutes: 90, Reason: "maintenance"},
+			{Start: "2021-03-04 10:00", End: "2021-03-04 12:00", Reason: "migration"},
+		},
+		Profiles: map[string]string{"night": "./bot-night.toml"},
+	}
+	s, err := schedule.NewSchedule(config)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// 2021-03-01は月曜日
+	tests := map[string]struct {
+		at   time.Time
+		want schedule.Session
+	}{
+		"in window": {
+			at:   time.Date(2021, 3, 1, 8, 0, 0, 0, time.UTC),
+			want: schedule.Session{InWindow: true},
+		},
+		"before window": {
+			at:   time.Date(2021, 3, 1, 7, 59, 0, 0, time.UTC),
+			want: schedule.Session{InWindow: false},
+		},
+		"night profile": {
+			at:   time.Date(2021, 3, 1, 23, 0, 0, 0, time.UTC),
+			want: schedule.Session{InWindow: true, Profile: "night"},
+		},
+		"night window over midnight": {
+			at:   time.Date(2021, 3, 2, 1, 59, 0, 0, time.UTC),
+			want: schedule.Session{InWindow: true, Profile: "night"},
+		},
+		"friday night continues to saturday": {
+			at:   time.Date(2021, 3, 6, 1, 0, 0, 0, time.UTC),
+			want: schedule.Session{InWindow: true, Profile: "night"},
+		},
+		"sunday night is out of window": {
+			at:   time.Date(2021, 3, 1, 1, 0, 0, 0, time.UTC),
+			want: schedule.Session{InWindow: false},
+		},
+		"recurring blackout": {
+			at:   time.Date(2021, 3, 3, 16, 29, 0, 0, time.UTC),
+			want: schedule.Session{InWindow: true, Blackout: "maintenance"},
+		},
+		"after recurring blackout": {
+			at:   time.Date(2021, 3, 3, 16, 30, 0, 0, time.UTC),
+			want: schedule.Session{InWindow: true},
+		},
+		"fixed blackout": {
+			at:   time.Date(2021, 3, 4, 11, 0, 0, 0, time.UTC),
+			want: schedule.Session{InWindow: true, Blackout: "migration"},
+		},
+		"other timezone": {
+			at:   time.Date(2021, 3, 1, 17, 0, 0, 0, time.FixedZone("JST", 9*60*60)),
+			want: schedule.Session{InWindow: true},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := s.At(tt.at)
+			if *got != tt.want {
+				t.Errorf("At() = %+v, want %+v", *got, tt.want)
+			}
+			if got.CanEntry() != (tt.want.InWindow && tt.want.Blackout == "") {
+				t.Errorf("CanEntry() = %v", got.CanEntry())
+			}
+		})
+	}
+}
+
+func TestNewScheduleWithFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "schedule")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	calendar := `
+before_minutes = 30
+after_minutes = 60
+
+[[events]]
+name = "FOMC"
+at = "2021-03-18 03:00"
+
+[[events]]
+name = "CPI"
+at = "2021-03-10 22:30"
+after_minutes = 10
+`
+	config := fmt.Sprintf(`
+timezone = "Asia/Tokyo"
+calendar_file = "%s"
+`, filepath.Join(dir, "calendar.toml"))
+	if err := ioutil.WriteFile(filepath.Join(dir, "calendar.toml"), []byte(calendar), 0644); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "schedule.toml"), []byte(config), 0644); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	s, err := schedule.NewScheduleWithFile(filepath.Join(dir, "schedule.toml"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tests := map[string]struct {
+		at   time.Time
+		want string
+	}{
+		"before event":           {at: time.Date(2021, 3, 17, 17, 30, 0, 0, time.UTC), want: "FOMC"},
+		"after event":            {at: time.Date(2021, 3, 17, 18, 59, 0, 0, time.UTC), want: "FOMC"},
+		"end of event":           {at: time.Date(2021, 3, 17, 19, 0, 0, 0, time.UTC), want: ""},
+		"event after_minutes":    {at: time.Date(2021, 3, 10, 13, 39, 0, 0, time.UTC), want: "CPI"},
+		"end of event (minutes)": {at: time.Date(2021, 3, 10, 13, 40, 0, 0, time.UTC), want: ""},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := s.At(tt.at); got.Blackout != tt.want {
+				t.Errorf("Blackout = %v, want %v", got.Blackout, tt.want)
+			}
+		})
+	}
+}
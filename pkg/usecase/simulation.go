@@ -3,9 +3,13 @@ package usecase
 import (
 	"context"
 	"fmt"
+	"time"
 	"trading-bot/pkg/domain"
+	"trading-bot/pkg/domain/model"
 	"trading-bot/pkg/domain/repository"
+	"trading-bot/pkg/infrastructure/coincheck"
 	"trading-bot/pkg/infrastructure/memory"
+	"trading-bot/pkg/usecase/report"
 )
 
 // Simulator シミュレーター
@@ -19,17 +23,36 @@ type Simulator struct {
 
 // Run シミュレーション実施
 func (s *Simulator) Run(ctx context.Context) (float64, error) {
+	if _, err := s.RunWithReport(ctx); err != nil {
+		return 0, err
+	}
+	return s.TradeRepo.GetProfit()
+}
+
+// RunWithReport シミュレーションを実施して成績を集計
+func (s *Simulator) RunWithReport(ctx context.Context) (*report.Report, error) {
 	if err := s.TradeRepo.TruncateAll(); err != nil {
-		return 0, fmt.Errorf("failed to truncate all, %v", err)
+		return nil, fmt.Errorf("failed to truncate all, %v", err)
 	}
 
+	recorder := report.NewRecorder()
 	for {
 		if err := s.Fetcher.Fetch(); err != nil {
-			return 0, err
+			return nil, err
 		}
 
 		if err := s.Bot.Trade(ctx); err != nil {
-			return 0, err
+			return nil, err
+		}
+
+		equity, err := s.equity()
+		if err != nil {
+			return nil, err
+		}
+		recorder.AddEquity(s.ExchangeMock.Now(), equity, s.ExchangeMock.Rate.OrderSellRate)
+
+		if err := s.replayTrades(); err != nil {
+			return nil, err
 		}
 
 		if !s.ExchangeMock.NextStep() {
@@ -37,5 +60,144 @@ func (s *Simulator) Run(ctx context.Context) (float64, error) {
 		}
 	}
 
-	return s.TradeRepo.GetProfit()
+	trades, err := s.trades()
+	if err != nil {
+		return nil, err
+	}
+	return recorder.Build(string(s.Bot.Config.Strategy), s.Bot.pair.String(), trades), nil
+}
+
+// replayTrades 次のレートまでの取引を日時順に取引履歴としてボットに通知
+func (s *Simulator) replayTrades() error {
+	for {
+		t, ok := s.ExchangeMock.NextTrade()
+		if !ok {
+			return nil
+		}
+		if err := s.Bot.ReceiveTrade(&coincheck.TradeHistory{
+			ID:     t.ID,
+			Pair:   t.Pair.String(),
+			Rate:   t.Rate,
+			Amount: t.Amount,
+			Side:   t.Side,
+			Time:   t.CreatedAt,
+		}); err != nil {
+			return err
+		}
+	}
+}
+
+// equity 総資産(JPY、通貨は売りレートで換算)
+func (s *Simulator) equity() (float64, error) {
+	jpy, err := s.ExchangeMock.GetBalance(model.JPY)
+	if err != nil {
+		return 0, err
+	}
+	currency, err := s.ExchangeMock.GetBalance(s.Bot.pair.Key)
+	if err != nil {
+		return 0, err
+	}
+	return jpy.Amount + currency.Amount*s.ExchangeMock.Rate.OrderSellRate, nil
+}
+
+// trades 約定したポジションを取引一覧にする（未決済のポジションは終了時のレートで評価）
+func (s *Simulator) trades() ([]report.Trade, error) {
+	closed, err := s.TradeRepo.GetClosedPositions()
+	if err != nil {
+		return nil, err
+	}
+	open, err := s.TradeRepo.GetOpenPositions()
+	if err != nil {
+		return nil, err
+	}
+
+	trades := []report.Trade{}
+	for _, p := range append(closed, open...) {
+		t, err := s.trade(&p)
+		if err != nil {
+			return nil, err
+		}
+		if t != nil {
+			trades = append(trades, *t)
+		}
+	}
+	return trades, nil
+}
+
+// trade ポジションの取引内容（買い注文が約定していなければnil）
+func (s *Simulator) trade(p *model.Position) (*report.Trade, error) {
+	opener, err := s.TradeRepo.GetContracts(p.OpenerOrder.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(opener) == 0 {
+		return nil, nil
+	}
+
+	rate := s.ExchangeMock.Rate.OrderSellRate
+	t := &report.Trade{
+		PositionID: p.ID,
+		EntryAt:    s.closedAt(p.OpenerOrder),
+		ExitAt:     s.ExchangeMock.Now(),
+		ExitRate:   rate,
+		Open:       true,
+	}
+	entryJPY := 0.0
+	for _, c := range opener {
+		t.Amount += c.IncreaseAmount
+		entryJPY += -c.DecreaseAmount
+		t.Fee += s.feeJPY(&c)
+	}
+	if t.Amount > 0 {
+		t.EntryRate = entryJPY / t.Amount
+	}
+	exitJPY := t.Amount * rate
+
+	if p.CloserOrder != nil && p.CloserOrder.Status == model.Closed {
+		closer, err := s.TradeRepo.GetContracts(p.CloserOrder.ID)
+		if err != nil {
+			return nil, err
+		}
+		amount := 0.0
+		exitJPY = 0
+		for _, c := range closer {
+			exitJPY += c.IncreaseAmount
+			amount += -c.DecreaseAmount
+			t.Fee += s.feeJPY(&c)
+		}
+		if amount > 0 {
+			t.ExitRate = exitJPY / amount
+		}
+		t.ExitAt = s.closedAt(p.CloserOrder)
+		t.Open = false
+	}
+	t.Profit = exitJPY - entryJPY - t.Fee
+	if entryJPY > 0 {
+		t.ProfitRatio = t.Profit / entryJPY
+	}
+
+	state, err := s.TradeRepo.GetPositionState(p.ID)
+	if err != nil {
+		return nil, err
+	}
+	if state != nil {
+		t.Reason = state.ExitReason
+	}
+	return t, nil
+}
+
+// closedAt 注文の約定日時（不明なら注文日時）
+func (s *Simulator) closedAt(o *model.Order) time.Time {
+	if t, ok := s.ExchangeMock.ClosedAt(o.ID); ok {
+		return t
+	}
+	return o.OrderedAt
+}
+
+// feeJPY 約定の手数料(JPY、通貨の手数料は約定レートで換算)
+func (s *Simulator) feeJPY(c *model.Contract) float64 {
+	if c.FeeCurrency == s.Bot.pair.Key {
+		return c.Fee * c.Rate
+	}
+	return c.Fee
 }
@@ -0,0 +1,231 @@
+package usecase_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/infrastructure/memory"
+	"trading-bot/pkg/usecase"
+	"trading-bot/pkg/usecase/trade"
+)
+
+func TestSimulator_Clock(t *testing.T) {
+	rates := []string{
+		"日付, 販売所買い価格, 販売所売り価格",
+		"2021-02-23T19:27:00Z,1000.0,1000.0",
+		"2021-02-23T19:28:00Z,1000.0,1000.0",
+		"2021-02-23T19:40:00Z,1000.0,1000.0",
+	}
+	exCli, err := memory.NewExchangeMock(strings.NewReader(strings.Join(rates, "\n")), 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	rds := memory.NewDummyRDS(nil)
+	facade := trade.NewFacade(exCli, rds, rds, rds, rds, rds, rds, nil)
+	facade.SetClock(exCli)
+	logger := &memory.Logger{Level: memory.Error}
+	strategy := &buyCountStrategy{}
+	bot := usecase.NewBot(logger, facade, strategy, &usecase.BotConfig{Currency: model.BTC, PositionCountMax: 1})
+	// CSVの日時で判定するので2021年のレートでも古くない（3行目は12分空くので止まる）
+	bot.RateFreshness = &usecase.RateFreshnessConfig{MaxAge: 5 * time.Minute, MaxGap: 5 * time.Minute}
+	fetcher := usecase.NewFetcher(exCli, model.BtcJpy, rds)
+	fetcher.SetClock(exCli)
+
+	simulator := &usecase.Simulator{
+		Bot:          bot,
+		Fetcher:      fetcher,
+		ExchangeMock: exCli,
+		TradeRepo:    rds,
+		Logger:       logger,
+	}
+	if _, err := simulator.Run(context.Background()); err != nil {
+		t.Fatal(err.Error())
+	}
+	if strategy.buys != 2 {
+		t.Errorf("buys = %d, want 2", strategy.buys)
+	}
+
+	f, err := rds.GetRateFreshness(&model.BtcJpy, nil, 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	want := time.Date(2021, 2, 23, 19, 40, 0, 0, time.UTC)
+	if !f.LastRecordedAt.Equal(want) {
+		t.Errorf("last recorded at = %v, want %v", f.LastRecordedAt, want)
+	}
+}
+
+// roundTripStrategy 最初に成行で買い、約定したら指値で売る
+type roundTripStrategy struct {
+	facade   *trade.Facade
+	sellRate float64
+	bought   bool
+}
+
+func (s *roundTripStrategy) Buy(pair model.CurrencyPair, positions []model.Position) error {
+	if s.bought {
+		return nil
+	}
+	s.bought = true
+	_, err := s.facade.SendMarketBuyOrder(&pair, 1000, nil)
+	return err
+}
+
+func (s *roundTripStrategy) Sell(pair model.CurrencyPair, positions []model.Position) error {
+	for _, p := range positions {
+		p := p
+		if p.CloserOrder != nil {
+			continue
+		}
+		cc, err := s.facade.GetContracts(p.OpenerOrder.ID)
+		if err != nil {
+			return err
+		}
+		if len(cc) == 0 {
+			continue
+		}
+		if _, err := s.facade.SendSellOrder(&pair, cc[0].IncreaseAmount, s.sellRate, &p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *roundTripStrategy) BuyTradeCallback(pair model.CurrencyPair, rate float64) error {
+	return nil
+}
+
+func (s *roundTripStrategy) SellTradeCallback(pair model.CurrencyPair, rate float64) error {
+	return nil
+}
+
+func (s *roundTripStrategy) Wait(ctx context.Context) error {
+	return nil
+}
+
+func TestSimulator_RunWithReport(t *testing.T) {
+	rates := []string{
+		"日付, 販売所買い価格, 販売所売り価格",
+		"2021-02-23T19:00:00Z,1000.0,1000.0",
+		"2021-02-23T20:00:00Z,1100.0,1100.0",
+		"2021-02-23T21:00:00Z,1200.0,1200.0",
+		"2021-02-23T22:00:00Z,1000.0,1000.0",
+	}
+	exCli, err := memory.NewExchangeMock(strings.NewReader(strings.Join(rates, "\n")), 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	rds := memory.NewDummyRDS(nil)
+	facade := trade.NewFacade(exCli, rds, rds, rds, rds, rds, rds, nil)
+	facade.SetClock(exCli)
+	logger := &memory.Logger{Level: memory.Error}
+	strategy := &roundTripStrategy{facade: facade, sellRate: 1200}
+	bot := usecase.NewBot(logger, facade, strategy, &usecase.BotConfig{Strategy: "round_trip", Currency: model.BTC, PositionCountMax: 1})
+	fetcher := usecase.NewFetcher(exCli, model.BtcJpy, rds)
+	fetcher.SetClock(exCli)
+
+	simulator := &usecase.Simulator{
+		Bot:          bot,
+		Fetcher:      fetcher,
+		ExchangeMock: exCli,
+		TradeRepo:    rds,
+		Logger:       logger,
+	}
+	r, err := simulator.RunWithReport(context.Background())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if r.Strategy != "round_trip" || r.Pair != "btc_jpy" || len(r.EquityCurve) != 4 {
+		t.Errorf("report = %s %s, %d points", r.Strategy, r.Pair, len(r.EquityCurve))
+	}
+	if len(r.Trades) != 1 {
+		t.Fatalf("trades = %+v, want 1 trade", r.Trades)
+	}
+	got := r.Trades[0]
+	if got.Open || got.EntryRate != 1000 || got.ExitRate != 1200 || got.Profit != 200 {
+		t.Errorf("trade = %+v, want closed 1000 => 1200 with profit 200", got)
+	}
+	wantEntry := time.Date(2021, 2, 23, 19, 0, 0, 0, time.UTC)
+	if !got.EntryAt.Equal(wantEntry) || !got.ExitAt.Equal(wantEntry.Add(2*time.Hour)) {
+		t.Errorf("trade entry/exit = %v / %v", got.EntryAt, got.ExitAt)
+	}
+	if r.Metrics.Profit != 200 || r.Metrics.WinRate != 1 || r.Metrics.ExposureRatio <= 0 {
+		t.Errorf("metrics = %+v", r.Metrics)
+	}
+}
+
+// callbackStrategy 取引履歴の通知を記録する
+type callbackStrategy struct {
+	clock    interface{ Now() time.Time }
+	callback []string
+}
+
+func (s *callbackStrategy) Buy(pair model.CurrencyPair, positions []model.Position) error {
+	s.callback = append(s.callback, fmt.Sprintf("trade %s", s.clock.Now().Format("15:04:05")))
+	return nil
+}
+
+func (s *callbackStrategy) Sell(pair model.CurrencyPair, positions []model.Position) error {
+	return nil
+}
+
+func (s *callbackStrategy) BuyTradeCallback(pair model.CurrencyPair, rate float64) error {
+	s.callback = append(s.callback, fmt.Sprintf("buy %g %s", rate, s.clock.Now().Format("15:04:05")))
+	return nil
+}
+
+func (s *callbackStrategy) SellTradeCallback(pair model.CurrencyPair, rate float64) error {
+	s.callback = append(s.callback, fmt.Sprintf("sell %g %s", rate, s.clock.Now().Format("15:04:05")))
+	return nil
+}
+
+func (s *callbackStrategy) Wait(ctx context.Context) error {
+	return nil
+}
+
+func TestSimulator_ReplayTrades(t *testing.T) {
+	rates := []string{
+		"日付, 販売所買い価格, 販売所売り価格",
+		"2021-02-23T19:00:00Z,1000.0,1000.0",
+		"2021-02-23T19:01:00Z,1000.0,1000.0",
+	}
+	exCli, err := memory.NewExchangeMock(strings.NewReader(strings.Join(rates, "\n")), 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	at := time.Date(2021, 2, 23, 19, 0, 0, 0, time.UTC)
+	exCli.SetTrades([]model.Trade{
+		{ID: 2, Pair: model.BtcJpy, Rate: 990, Amount: 1, Side: model.SellSide, CreatedAt: at.Add(40 * time.Second)},
+		{ID: 1, Pair: model.BtcJpy, Rate: 1010, Amount: 1, Side: model.BuySide, CreatedAt: at.Add(20 * time.Second)},
+	})
+	rds := memory.NewDummyRDS(nil)
+	facade := trade.NewFacade(exCli, rds, rds, rds, rds, rds, rds, nil)
+	facade.SetClock(exCli)
+	logger := &memory.Logger{Level: memory.Error}
+	strategy := &callbackStrategy{clock: exCli}
+	bot := usecase.NewBot(logger, facade, strategy, &usecase.BotConfig{Currency: model.BTC, PositionCountMax: 1})
+	fetcher := usecase.NewFetcher(exCli, model.BtcJpy, rds)
+	fetcher.SetClock(exCli)
+
+	simulator := &usecase.Simulator{
+		Bot:          bot,
+		Fetcher:      fetcher,
+		ExchangeMock: exCli,
+		TradeRepo:    rds,
+		Logger:       logger,
+	}
+	if _, err := simulator.RunWithReport(context.Background()); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// 取引はTradeの後に日時順で通知する
+	want := []string{"trade 19:00:00", "buy 1010 19:00:20", "sell 990 19:00:40", "trade 19:01:00"}
+	if !reflect.DeepEqual(strategy.callback, want) {
+		t.Errorf("callback = %v, want %v", strategy.callback, want)
+	}
+}
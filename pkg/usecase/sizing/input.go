@@ -0,0 +1,37 @@
+package sizing
+
+import (
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/usecase/trade"
+)
+
+// NewInput 注文サイズ算出用の情報を取得
+// stopPer は損切予定レートの買レートに対する割合（0なら不明）
+// 決済済みトレードの実績は、実績を使う算出のときだけ取得する
+func NewInput(facade *trade.Facade, sizer Sizer, pair *model.CurrencyPair, rates []float64, stopPer float64) (*Input, error) {
+	balance, err := facade.GetJpyBalance()
+	if err != nil {
+		return nil, err
+	}
+	equity, err := facade.GetEquityJPY(pair)
+	if err != nil {
+		return nil, err
+	}
+	rate, err := facade.GetBuyRate(pair)
+	if err != nil {
+		return nil, err
+	}
+	in := &Input{
+		BalanceJPY: balance.Amount,
+		EquityJPY:  equity,
+		Rate:       rate,
+		Rates:      rates,
+		StopRate:   rate * stopPer,
+	}
+	if UsesResults(sizer) {
+		if in.Results, err = facade.GetTradeResults(); err != nil {
+			return nil, err
+		}
+	}
+	return in, nil
+}
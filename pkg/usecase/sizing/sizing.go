@@ -0,0 +1,292 @@
+package sizing
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/markcheno/go-talib"
+)
+
+// Model サイズ算出モデル
+type Model string
+
+const (
+	// FixedJPYModel 固定金額
+	FixedJPYModel Model = "fixed_jpy"
+	// FixedFractionalModel 残高の一定割合
+	FixedFractionalModel Model = "fixed_fractional"
+	// VolatilityModel ボラティリティ（ATR）に応じて調整
+	VolatilityModel Model = "volatility"
+	// FixedRiskModel 損切幅から1トレードの損失を一定にする
+	FixedRiskModel Model = "fixed_risk"
+	// KellyModel 実績の勝率・損益比からケリー基準で算出
+	KellyModel Model = "kelly"
+)
+
+// Config サイズ算出用設定
+type Config struct {
+	// 算出モデル
+	Model Model `toml:"model"`
+
+	// 1注文の金額(JPY) [fixed_jpy]
+	AmountJPY float64 `toml:"amount_jpy"`
+	// 残高に対する注文金額の割合 [fixed_fractional, kellyの実績不足時]
+	FundsRatio float64 `toml:"funds_ratio"`
+	// 1トレードで許容する損失（総資産に対する割合） [volatility, fixed_risk]
+	RiskPer float64 `toml:"risk_per"`
+	// ATRの算出期間 [volatility]
+	ATRPeriod int `toml:"atr_period"`
+	// 損切幅（ATRの何倍か） [volatility]
+	ATRMultiplier float64 `toml:"atr_multiplier"`
+	// ケリー基準に掛ける割合 [kelly]
+	KellyFraction float64 `toml:"kelly_fraction"`
+	// ケリー基準の算出に必要な決済済みトレード数 [kelly]
+	KellyMinTrades int `toml:"kelly_min_trades"`
+
+	// 残高に対する注文金額の上限（0なら残高まで）
+	MaxFundsRatio float64 `toml:"max_funds_ratio"`
+}
+
+// Input サイズ算出用の情報
+type Input struct {
+	// 利用可能な残高(JPY)
+	BalanceJPY float64
+	// 総資産(JPY換算)
+	EquityJPY float64
+	// 現在の買レート
+	Rate float64
+	// レート履歴
+	Rates []float64
+	// 損切予定レート（0なら不明）
+	StopRate float64
+	// 決済済みトレードの損益率（実績を使う算出のときのみ）
+	Results []float64
+}
+
+// Sizer 注文サイズの算出
+type Sizer interface {
+	// Name モデル名
+	Name() string
+	// Size 注文金額(JPY)を算出し、根拠を返す
+	Size(in *Input) (float64, string)
+}
+
+// New 設定から生成
+func New(c *Config) (Sizer, error) {
+	var s Sizer
+	switch c.Model {
+	case FixedJPYModel:
+		if c.AmountJPY <= 0 {
+			return nil, fmt.Errorf("AmountJPY is empty, %v", c.AmountJPY)
+		}
+		s = &FixedJPY{AmountJPY: c.AmountJPY}
+	case FixedFractionalModel, "":
+		if c.FundsRatio <= 0 {
+			return nil, fmt.Errorf("FundsRatio is empty, %v", c.FundsRatio)
+		}
+		s = &FixedFractional{FundsRatio: c.FundsRatio}
+	case VolatilityModel:
+		if c.RiskPer <= 0 || c.ATRPeriod <= 0 || c.ATRMultiplier <= 0 {
+			return nil, fmt.Errorf("RiskPer, ATRPeriod and ATRMultiplier are required, %v, %v, %v", c.RiskPer, c.ATRPeriod, c.ATRMultiplier)
+		}
+		s = &Volatility{RiskPer: c.RiskPer, ATRPeriod: c.ATRPeriod, ATRMultiplier: c.ATRMultiplier}
+	case FixedRiskModel:
+		if c.RiskPer <= 0 {
+			return nil, fmt.Errorf("RiskPer is empty, %v", c.RiskPer)
+		}
+		s = &FixedRisk{RiskPer: c.RiskPer}
+	case KellyModel:
+		if c.KellyFraction <= 0 {
+			return nil, fmt.Errorf("KellyFraction is empty, %v", c.KellyFraction)
+		}
+		s = &Kelly{
+			Fraction:   c.KellyFraction,
+			MinTrades:  c.KellyMinTrades,
+			FundsRatio: c.FundsRatio,
+		}
+	default:
+		return nil, fmt.Errorf("sizing model is unknown; model = %s", c.Model)
+	}
+
+	return &capped{Sizer: s, maxFundsRatio: c.MaxFundsRatio}, nil
+}
+
+// capped 残高を上限とする
+type capped struct {
+	Sizer
+	maxFundsRatio float64
+}
+
+// Size 注文金額(JPY)を算出
+func (s *capped) Size(in *Input) (float64, string) {
+	amount, reason := s.Sizer.Size(in)
+	max := in.BalanceJPY
+	if s.maxFundsRatio > 0 {
+		max = in.BalanceJPY * s.maxFundsRatio
+	}
+	if amount > max {
+		return max, fmt.Sprintf("%s, capped by max:%.3f", reason, max)
+	}
+	if amount < 0 {
+		return 0, reason
+	}
+	return amount, reason
+}
+
+// UsesResults 決済済みトレードの実績を使うか
+func (s *capped) UsesResults() bool {
+	return UsesResults(s.Sizer)
+}
+
+// ResultSizer 決済済みトレードの実績を使う算出
+type ResultSizer interface {
+	// UsesResults 実績を使うか（Input.Resultsを取得するか）
+	UsesResults() bool
+}
+
+// UsesResults 決済済みトレードの実績を使う算出か
+func UsesResults(s Sizer) bool {
+	r, ok := s.(ResultSizer)
+	return ok && r.UsesResults()
+}
+
+// FixedJPY 固定金額
+type FixedJPY struct {
+	AmountJPY float64
+}
+
+// Name モデル名
+func (s *FixedJPY) Name() string {
+	return string(FixedJPYModel)
+}
+
+// Size 注文金額(JPY)を算出
+func (s *FixedJPY) Size(in *Input) (float64, string) {
+	return s.AmountJPY, fmt.Sprintf("fixed jpy:%.3f", s.AmountJPY)
+}
+
+// FixedFractional 残高の一定割合
+type FixedFractional struct {
+	FundsRatio float64
+}
+
+// Name モデル名
+func (s *FixedFractional) Name() string {
+	return string(FixedFractionalModel)
+}
+
+// Size 注文金額(JPY)を算出
+func (s *FixedFractional) Size(in *Input) (float64, string) {
+	amount := in.BalanceJPY * s.FundsRatio
+	return amount, fmt.Sprintf("balance:%.3f * %.3f", in.BalanceJPY, s.FundsRatio)
+}
+
+// Volatility 損切幅をATRから求め、1トレードの損失を総資産の一定割合にする
+type Volatility struct {
+	RiskPer       float64
+	ATRPeriod     int
+	ATRMultiplier float64
+}
+
+// Name モデル名
+func (s *Volatility) Name() string {
+	return string(VolatilityModel)
+}
+
+// Size 注文金額(JPY)を算出
+func (s *Volatility) Size(in *Input) (float64, string) {
+	if len(in.Rates) <= s.ATRPeriod {
+		return 0, fmt.Sprintf("rate count:%d <= required:%d", len(in.Rates), s.ATRPeriod)
+	}
+
+	// 終値しかないため高値・安値にも同じ値を使う
+	atrs := talib.Atr(in.Rates, in.Rates, in.Rates, s.ATRPeriod)
+	atr := atrs[len(atrs)-1]
+	stopWidth := atr * s.ATRMultiplier
+	if stopWidth <= 0 {
+		return 0, fmt.Sprintf("atr is zero (atr:%.3f)", atr)
+	}
+
+	amount := in.EquityJPY * s.RiskPer * in.Rate / stopWidth
+	return amount, fmt.Sprintf("equity:%.3f * risk:%.3f * rate:%.3f / (atr:%.3f * %.3f)", in.EquityJPY, s.RiskPer, in.Rate, atr, s.ATRMultiplier)
+}
+
+// FixedRisk 損切予定レートまでの幅から1トレードの損失を総資産の一定割合にする
+type FixedRisk struct {
+	RiskPer float64
+}
+
+// Name モデル名
+func (s *FixedRisk) Name() string {
+	return string(FixedRiskModel)
+}
+
+// Size 注文金額(JPY)を算出
+func (s *FixedRisk) Size(in *Input) (float64, string) {
+	if in.StopRate <= 0 || in.StopRate >= in.Rate {
+		return 0, fmt.Sprintf("stop rate is invalid (stop:%.3f, rate:%.3f)", in.StopRate, in.Rate)
+	}
+
+	stopRatio := (in.Rate - in.StopRate) / in.Rate
+	amount := in.EquityJPY * s.RiskPer / stopRatio
+	return amount, fmt.Sprintf("equity:%.3f * risk:%.3f / stop ratio:%.5f", in.EquityJPY, s.RiskPer, stopRatio)
+}
+
+// Kelly 実績の勝率・損益比からケリー基準で算出
+type Kelly struct {
+	Fraction   float64
+	MinTrades  int
+	FundsRatio float64
+}
+
+// Name モデル名
+func (s *Kelly) Name() string {
+	return string(KellyModel)
+}
+
+// UsesResults 決済済みトレードの実績を使うか
+func (s *Kelly) UsesResults() bool {
+	return true
+}
+
+// Size 注文金額(JPY)を算出
+func (s *Kelly) Size(in *Input) (float64, string) {
+	if len(in.Results) < s.MinTrades || len(in.Results) == 0 {
+		amount := in.BalanceJPY * s.FundsRatio
+		return amount, fmt.Sprintf("not enough trades (%d < %d), balance:%.3f * %.3f", len(in.Results), s.MinTrades, in.BalanceJPY, s.FundsRatio)
+	}
+
+	f, winRate, payoff := KellyRatio(in.Results)
+	amount := in.BalanceJPY * math.Max(f, 0) * s.Fraction
+	return amount, fmt.Sprintf("balance:%.3f * kelly:%.3f * %.3f (win rate:%.3f, payoff:%.3f)", in.BalanceJPY, f, s.Fraction, winRate, payoff)
+}
+
+// KellyRatio 損益率の実績からケリー基準の割合を算出
+func KellyRatio(results []float64) (f, winRate, payoff float64) {
+	wins, losses := 0, 0
+	winTotal, lossTotal := 0.0, 0.0
+	for _, r := range results {
+		if r > 0 {
+			wins++
+			winTotal += r
+		} else if r < 0 {
+			losses++
+			lossTotal += -r
+		}
+	}
+	if wins+losses == 0 {
+		return 0, 0, 0
+	}
+
+	winRate = float64(wins) / float64(wins+losses)
+	if wins == 0 {
+		return 0, winRate, 0
+	}
+	if losses == 0 {
+		return 1, winRate, math.Inf(1)
+	}
+
+	payoff = (winTotal / float64(wins)) / (lossTotal / float64(losses))
+	f = winRate - (1-winRate)/payoff
+	return f, winRate, payoff
+}
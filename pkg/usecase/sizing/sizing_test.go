@@ -0,0 +1,167 @@
+package sizing_test
+
+import (
+	"strings"
+	"testing"
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/infrastructure/memory"
+	"trading-bot/pkg/usecase/sizing"
+	"trading-bot/pkg/usecase/trade"
+)
+
+var EPSILON float64 = 0.00000001
+
+func floatEquals(a, b float64) bool {
+	return (a-b) < EPSILON && (b-a) < EPSILON
+}
+
+func TestSizer(t *testing.T) {
+	in := sizing.Input{
+		BalanceJPY: 100000,
+		EquityJPY:  200000,
+		Rate:       1000,
+		Rates:      []float64{1000, 1010, 1000, 1010, 1000},
+		StopRate:   900,
+		Results:    []float64{0.1, 0.1, -0.05, 0.1, -0.05},
+	}
+
+	tests := map[string]struct {
+		config sizing.Config
+		want   float64
+	}{
+		"fixed jpy": {
+			config: sizing.Config{Model: sizing.FixedJPYModel, AmountJPY: 5000},
+			want:   5000,
+		},
+		"fixed jpy capped by balance": {
+			config: sizing.Config{Model: sizing.FixedJPYModel, AmountJPY: 500000},
+			want:   100000,
+		},
+		"fixed fractional is default": {
+			config: sizing.Config{FundsRatio: 0.3},
+			want:   30000,
+		},
+		"volatility": {
+			// atr:10, stop width:20 => 200000 * 0.01 * 1000 / 20
+			config: sizing.Config{Model: sizing.VolatilityModel, RiskPer: 0.01, ATRPeriod: 2, ATRMultiplier: 2},
+			want:   100000,
+		},
+		"volatility with max funds ratio": {
+			config: sizing.Config{Model: sizing.VolatilityModel, RiskPer: 0.01, ATRPeriod: 2, ATRMultiplier: 2, MaxFundsRatio: 0.5},
+			want:   50000,
+		},
+		"fixed risk": {
+			// stop ratio:0.1 => 200000 * 0.02 / 0.1
+			config: sizing.Config{Model: sizing.FixedRiskModel, RiskPer: 0.02},
+			want:   40000,
+		},
+		"kelly": {
+			// win rate:0.6, payoff:2 => f = 0.6 - 0.4/2 = 0.4
+			config: sizing.Config{Model: sizing.KellyModel, KellyFraction: 0.5, KellyMinTrades: 5},
+			want:   20000,
+		},
+		"kelly without enough trades": {
+			config: sizing.Config{Model: sizing.KellyModel, KellyFraction: 0.5, KellyMinTrades: 10, FundsRatio: 0.1},
+			want:   10000,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			s, err := sizing.New(&tt.config)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			got, reason := s.Size(&in)
+			if !floatEquals(got, tt.want) {
+				t.Errorf("Size() = %v, want %v (%s)", got, tt.want, reason)
+			}
+		})
+	}
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	tests := map[string]sizing.Config{
+		"unknown model":         {Model: "unknown"},
+		"fixed jpy without amt": {Model: sizing.FixedJPYModel},
+		"fixed risk without":    {Model: sizing.FixedRiskModel},
+		"kelly without frac":    {Model: sizing.KellyModel},
+	}
+	for name, c := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := sizing.New(&c); err == nil {
+				t.Errorf("New() error is nil")
+			}
+		})
+	}
+}
+
+func TestKellyRatio(t *testing.T) {
+	f, winRate, payoff := sizing.KellyRatio([]float64{0.1, -0.1})
+	if !floatEquals(f, 0) || !floatEquals(winRate, 0.5) || !floatEquals(payoff, 1) {
+		t.Errorf("KellyRatio() = %v, %v, %v; want 0, 0.5, 1", f, winRate, payoff)
+	}
+
+	f, _, _ = sizing.KellyRatio([]float64{-0.1, -0.2})
+	if !floatEquals(f, 0) {
+		t.Errorf("KellyRatio() f = %v; want 0", f)
+	}
+}
+
+func TestNewInput(t *testing.T) {
+	rates := []string{
+		"日付, 販売所買い価格, 販売所売り価格",
+		"2021-02-23T19:27:01Z,1000.0,1000.0",
+		"2021-02-23T19:27:02Z,1100.0,1100.0",
+	}
+	exCli, err := memory.NewExchangeMock(strings.NewReader(strings.Join(rates, "\n")), 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	exCli.SetBalance(model.JPY, 100000)
+	rds := memory.NewDummyRDS(nil)
+	facade := trade.NewFacade(exCli, rds, rds, rds, rds, rds, rds, nil)
+
+	// 1000で買って1100で売った決済済みのポジション
+	p, err := facade.SendMarketBuyOrder(&model.BtcJpy, 1000, nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := facade.SyncOrders(&model.BtcJpy); err != nil {
+		t.Fatal(err.Error())
+	}
+	exCli.NextStep()
+	if _, err := facade.SendMarketSellOrder(&model.BtcJpy, 1, p); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := facade.SyncOrders(&model.BtcJpy); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tests := map[string]struct {
+		config      sizing.Config
+		wantResults int
+	}{
+		"kelly":      {config: sizing.Config{Model: sizing.KellyModel, KellyFraction: 0.5}, wantResults: 1},
+		"fixed jpy":  {config: sizing.Config{Model: sizing.FixedJPYModel, AmountJPY: 1000}, wantResults: 0},
+		"fixed risk": {config: sizing.Config{Model: sizing.FixedRiskModel, RiskPer: 0.01}, wantResults: 0},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			s, err := sizing.New(&tt.config)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			in, err := sizing.NewInput(facade, s, &model.BtcJpy, []float64{1000, 1100}, 0.9)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			// 実績を使う算出のときだけ取得する
+			if len(in.Results) != tt.wantResults {
+				t.Errorf("Results = %v, want %d results", in.Results, tt.wantResults)
+			}
+			if in.Rate != 1100 || in.StopRate != 990 {
+				t.Errorf("Rate = %v, StopRate = %v, want 1100, 990", in.Rate, in.StopRate)
+			}
+		})
+	}
+}
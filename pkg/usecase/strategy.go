@@ -3,6 +3,7 @@ package usecase
 import (
 	"context"
 	"fmt"
+	"sort"
 	"trading-bot/pkg/domain"
 	"trading-bot/pkg/domain/model"
 	"trading-bot/pkg/usecase/strategy"
@@ -27,6 +28,21 @@ type Strategy interface {
 	Wait(ctx context.Context) error
 }
 
+// StatefulStrategy 再起動をまたいで状態を引き継ぐ戦略
+type StatefulStrategy interface {
+	// SaveState 状態を書き出す
+	SaveState(s *model.StrategyState) error
+
+	// LoadState 状態を復元する
+	LoadState(s *model.StrategyState) error
+}
+
+// StatusStrategy ダッシュボード用の状態を公開する戦略
+type StatusStrategy interface {
+	// Statuses ボットの状態として記録する値
+	Statuses(botName string, pair model.CurrencyPair) []model.BotStatus
+}
+
 // StrategyType 戦略種別
 type StrategyType string
 
@@ -40,39 +56,101 @@ const (
 	Range StrategyType = "range"
 	// Inago イナゴトレード
 	Inago StrategyType = "inago"
+	// Grid グリッドトレード
+	Grid StrategyType = "grid"
+	// DCA 定期積立
+	DCA StrategyType = "dca"
+	// External 外部プロセスに売買判断を委ねる
+	External StrategyType = "external"
+	// RegimeSwitch 相場状態に応じて戦略を切り替える
+	RegimeSwitch StrategyType = "regime"
 )
 
+// StrategyConfigPath 戦略の設定ファイルのパス
+func StrategyConfigPath(t StrategyType) string {
+	return fmt.Sprintf("./configs/bot-%s.toml", t)
+}
+
 // MakeStrategy 戦略を生成
 func MakeStrategy(t StrategyType, facade *trade.Facade, logger domain.Logger) (Strategy, error) {
-	p := fmt.Sprintf("./configs/bot-%s.toml", t)
-	switch t {
-	case None:
-		return nil, nil
-	// case WatchOnly:
-	// 	return strategy.NewWatchOnlyStrategy(facade, logger, p)
-	// case FollowUptrend:
-	// 	return strategy.NewFollowUptrendStrategy(facade, logger, p)
-	// case Scalping:
-	// 	config, err := strategy.NewScalpingConfig(p)
-	// 	if err != nil {
-	// 		return nil, err
-	// 	}
-	// 	return strategy.NewScalpingStrategy(facade, logger, config)
-	// case Uptrend:
-	// 	return &strategy.Uptrend{Facade: facade, Logger: logger}, nil
-	case Range:
+	return MakeStrategyWithConfig(t, StrategyConfigPath(t), facade, logger)
+}
+
+// StrategyFactory 設定ファイルから戦略を生成する関数
+type StrategyFactory func(p string, facade *trade.Facade, logger domain.Logger) (Strategy, error)
+
+var strategyFactories = map[StrategyType]StrategyFactory{}
+
+// RegisterStrategy 戦略を登録（MakeStrategyWithConfigやパラメータ最適化で使える）
+func RegisterStrategy(t StrategyType, f StrategyFactory) {
+	strategyFactories[t] = f
+}
+
+// RegisteredStrategies 登録済みの戦略種別（名前順）
+func RegisteredStrategies() []StrategyType {
+	types := []StrategyType{}
+	for t := range strategyFactories {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+func init() {
+	// Scalping: strategy.NewScalpingConfig / strategy.NewScalpingStrategy
+	// Uptrend: &strategy.Uptrend{Facade: facade, Logger: logger}
+	RegisterStrategy(Range, func(p string, facade *trade.Facade, logger domain.Logger) (Strategy, error) {
 		config, err := strategy.NewRangeConfig(p)
 		if err != nil {
 			return nil, err
 		}
 		return strategy.NewRangeStrategy(facade, logger, config)
-	case Inago:
+	})
+	RegisterStrategy(Inago, func(p string, facade *trade.Facade, logger domain.Logger) (Strategy, error) {
 		config, err := strategy.NewInagoConfig(p)
 		if err != nil {
 			return nil, err
 		}
 		return strategy.NewInagoStrategy(facade, logger, config)
-	default:
+	})
+	RegisterStrategy(Grid, func(p string, facade *trade.Facade, logger domain.Logger) (Strategy, error) {
+		config, err := strategy.NewGridConfig(p)
+		if err != nil {
+			return nil, err
+		}
+		return strategy.NewGridStrategy(facade, logger, config)
+	})
+	RegisterStrategy(DCA, func(p string, facade *trade.Facade, logger domain.Logger) (Strategy, error) {
+		config, err := strategy.NewDCAConfig(p)
+		if err != nil {
+			return nil, err
+		}
+		return strategy.NewDCAStrategy(facade, logger, config)
+	})
+	RegisterStrategy(External, func(p string, facade *trade.Facade, logger domain.Logger) (Strategy, error) {
+		config, err := strategy.NewExternalConfig(p)
+		if err != nil {
+			return nil, err
+		}
+		return strategy.NewExternalStrategy(facade, logger, config)
+	})
+	RegisterStrategy(RegimeSwitch, func(p string, facade *trade.Facade, logger domain.Logger) (Strategy, error) {
+		config, err := NewRegimeSwitchConfig(p)
+		if err != nil {
+			return nil, err
+		}
+		return NewRegimeSwitchStrategy(facade, logger, config)
+	})
+}
+
+// MakeStrategyWithConfig 設定ファイルを指定して戦略を生成
+func MakeStrategyWithConfig(t StrategyType, p string, facade *trade.Facade, logger domain.Logger) (Strategy, error) {
+	if t == None {
+		return nil, nil
+	}
+	f, ok := strategyFactories[t]
+	if !ok {
 		return nil, fmt.Errorf("strategy name is unknown; name = %s", t)
 	}
+	return f(p, facade, logger)
 }
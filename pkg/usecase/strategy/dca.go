@@ -0,0 +1,239 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"trading-bot/pkg/domain"
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/usecase/schedule"
+	"trading-bot/pkg/usecase/trade"
+
+	"github.com/BurntSushi/toml"
+	"github.com/markcheno/go-talib"
+)
+
+type DCAConfig struct {
+	Interval int `toml:"interval_seconds"`
+
+	// 買い付けスケジュール（cron形式: 分 時 日 月 曜日）
+	Schedule string `toml:"schedule"`
+	// スケジュールのタイムゾーン（未指定ならローカル）
+	Timezone string `toml:"timezone"`
+	// 1回の買い付け金額(JPY)
+	AmountJPY float64 `toml:"amount_jpy"`
+
+	// 移動平均の期間（0なら金額を調整しない）
+	MAPeriod int `toml:"ma_period"`
+	// レートが移動平均を下回っている時に買い付け金額に掛ける倍率
+	BelowMAMultiplier float64 `toml:"below_ma_multiplier"`
+
+	// 上限レート（これを超えていたら買い付けを見送る、0なら無制限）
+	MaxRate float64 `toml:"max_rate"`
+}
+
+func (c *DCAConfig) valid() error {
+	if c.Interval == 0 {
+		return fmt.Errorf("Interval is empty, %v", c.Interval)
+	}
+	if _, err := schedule.ParseCron(c.Schedule); err != nil {
+		return fmt.Errorf("Schedule is invalid, %w", err)
+	}
+	if _, err := c.location(); err != nil {
+		return fmt.Errorf("Timezone is invalid, %w", err)
+	}
+	if c.AmountJPY <= 0 {
+		return fmt.Errorf("AmountJPY is empty, %v", c.AmountJPY)
+	}
+	if c.MAPeriod > 0 && c.BelowMAMultiplier <= 0 {
+		return fmt.Errorf("BelowMAMultiplier is empty, %v", c.BelowMAMultiplier)
+	}
+	return nil
+}
+
+func (c *DCAConfig) location() (*time.Location, error) {
+	if c.Timezone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(c.Timezone)
+}
+
+func NewDCAConfig(f string) (*DCAConfig, error) {
+	var conf DCAConfig
+	if _, err := toml.DecodeFile(f, &conf); err != nil {
+		return nil, err
+	}
+	if err := conf.valid(); err != nil {
+		return nil, fmt.Errorf("[%s] validation error: %w", f, err)
+	}
+	return &conf, nil
+}
+
+// DCAStrategy 定期積立
+// スケジュールに従って一定金額を買い付け、買い付けごとに1ポジションとして記録する（売却はしない）
+type DCAStrategy struct {
+	logger domain.Logger
+	facade *trade.Facade
+
+	config   *DCAConfig
+	cron     *schedule.Cron
+	location *time.Location
+
+	// 前回の買い付け日時（未設定なら保有ポジションまたは起動日時から判断）
+	lastBuyAt *time.Time
+}
+
+func NewDCAStrategy(facade *trade.Facade, logger domain.Logger, config *DCAConfig) (*DCAStrategy, error) {
+	cron, err := schedule.ParseCron(config.Schedule)
+	if err != nil {
+		return nil, err
+	}
+	location, err := config.location()
+	if err != nil {
+		return nil, err
+	}
+	return &DCAStrategy{
+		logger:   logger,
+		facade:   facade,
+		config:   config,
+		cron:     cron,
+		location: location,
+	}, nil
+}
+
+func (s *DCAStrategy) Buy(p model.CurrencyPair, positions []model.Position) error {
+	now := s.facade.Now().In(s.location)
+	if s.lastBuyAt == nil {
+		var last time.Time
+		for _, pos := range positions {
+			if pos.OpenerOrder.Pair == p && pos.OpenerOrder.OrderedAt.After(last) {
+				last = pos.OpenerOrder.OrderedAt
+			}
+		}
+		if last.IsZero() {
+			last = now
+		}
+		s.lastBuyAt = &last
+	}
+
+	next := s.cron.Next(s.lastBuyAt.In(s.location))
+	if next.IsZero() || now.Before(next) {
+		s.logger.Debug("[buy] => skip buy (now:%v < next:%v)", now, next)
+		return nil
+	}
+
+	rates, err := s.facade.GetRates(&p)
+	if err != nil {
+		return err
+	}
+	rate, err := s.facade.GetBuyRate(&p)
+	if err != nil {
+		return err
+	}
+
+	if s.config.MaxRate > 0 && rate > s.config.MaxRate {
+		s.logger.Debug("[buy] => skip buy (rate:%.3f > max:%.3f)", rate, s.config.MaxRate)
+		s.lastBuyAt = &now
+		return nil
+	}
+
+	amount := s.amount(rate, rates)
+	balance, err := s.facade.GetJpyBalance()
+	if err != nil {
+		return err
+	}
+	if amount > balance.Amount {
+		s.logger.Debug("[buy] => skip buy (amount:%.3f > balance:%.3f)", amount, balance.Amount)
+		s.lastBuyAt = &now
+		return nil
+	}
+
+	s.logger.Debug("[buy] sending buy order ... (amount:%.3f)", amount)
+	pos, err := s.facade.SendMarketBuyOrder(&p, amount, nil)
+	if err != nil {
+		return err
+	}
+	s.logger.Debug("[buy] completed to send buy order [%v]", pos.OpenerOrder)
+
+	s.lastBuyAt = &now
+	return nil
+}
+
+// amount 買い付け金額を算出
+func (s *DCAStrategy) amount(rate float64, rates []float64) float64 {
+	if s.config.MAPeriod <= 0 {
+		return s.config.AmountJPY
+	}
+	if len(rates) < s.config.MAPeriod {
+		s.logger.Debug("[buy] not scaled (rate count:%d < required:%d)", len(rates), s.config.MAPeriod)
+		return s.config.AmountJPY
+	}
+
+	mas := talib.Sma(rates, s.config.MAPeriod)
+	ma := mas[len(mas)-1]
+	if rate >= ma {
+		s.logger.Debug("[buy] not scaled (rate:%.3f >= ma:%.3f)", rate, ma)
+		return s.config.AmountJPY
+	}
+
+	s.logger.Debug("[buy] scaled amount (rate:%.3f < ma:%.3f, x%.3f)", rate, ma, s.config.BelowMAMultiplier)
+	return s.config.AmountJPY * s.config.BelowMAMultiplier
+}
+
+func (s *DCAStrategy) BuyTradeCallback(p model.CurrencyPair, rate float64) error {
+	return nil
+}
+
+func (s *DCAStrategy) Sell(pair model.CurrencyPair, positions []model.Position) error {
+	return nil
+}
+
+func (s *DCAStrategy) SellTradeCallback(pair model.CurrencyPair, rate float64) error {
+	return nil
+}
+
+func (s *DCAStrategy) Wait(ctx context.Context) error {
+	s.logger.Debug("waiting ... (%v)\n", s.config.Interval)
+	return s.facade.Wait(ctx, time.Duration(s.config.Interval)*time.Second)
+}
+
+// SaveState 状態を書き出す
+func (s *DCAStrategy) SaveState(st *model.StrategyState) error {
+	st.SetTime("last_buy_at", s.lastBuyAt)
+	return nil
+}
+
+// LoadState 状態を復元する
+func (s *DCAStrategy) LoadState(st *model.StrategyState) (err error) {
+	s.lastBuyAt, err = st.Time("last_buy_at")
+	return err
+}
+
+// ReloadConfig 設定を再読み込み
+func (s *DCAStrategy) ReloadConfig(path string) (interface{}, interface{}, error) {
+	return reload(s, path)
+}
+
+// PrepareReload 設定を検証して適用する関数を返す
+func (s *DCAStrategy) PrepareReload(path string) (func() (interface{}, interface{}), error) {
+	config, err := NewDCAConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	cron, err := schedule.ParseCron(config.Schedule)
+	if err != nil {
+		return nil, err
+	}
+	location, err := config.location()
+	if err != nil {
+		return nil, err
+	}
+
+	return func() (interface{}, interface{}) {
+		before := s.config
+		s.config = config
+		s.cron = cron
+		s.location = location
+		return before, config
+	}, nil
+}
@@ -0,0 +1,113 @@
+package strategy_test
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/infrastructure/memory"
+	"trading-bot/pkg/usecase"
+	"trading-bot/pkg/usecase/strategy"
+	"trading-bot/pkg/usecase/trade"
+)
+
+func TestDCAStrategy_Simulation(t *testing.T) {
+	// 1分ごとに11個のレート（00:00〜00:10、00:05から移動平均を下回る）
+	start := time.Date(2021, 2, 23, 0, 0, 0, 0, time.UTC)
+	rates := []string{"日付, 販売所買い価格, 販売所売り価格"}
+	for i := 0; i <= 10; i++ {
+		rate := 1000.0
+		if i >= 5 {
+			rate = 500
+		}
+		rates = append(rates, fmt.Sprintf("%s,%.1f,%.1f", start.Add(time.Duration(i)*time.Minute).Format(time.RFC3339), rate, rate))
+	}
+
+	tests := map[string]struct {
+		config           strategy.DCAConfig
+		positionCountMax int
+		// 買い付けた金額（ポジションごと）
+		want []float64
+	}{
+		// 最初のレートでは買わず、2分ごとに買う
+		"every 2 minutes": {
+			config:           strategy.DCAConfig{Schedule: "*/2 * * * *"},
+			positionCountMax: 10,
+			want:             []float64{100, 100, 100, 100, 100},
+		},
+		"every 5 minutes": {
+			config:           strategy.DCAConfig{Schedule: "*/5 * * * *"},
+			positionCountMax: 10,
+			want:             []float64{100, 100},
+		},
+		// 売却しないので上限に達すると以降は買わない
+		"position count max": {
+			config:           strategy.DCAConfig{Schedule: "*/2 * * * *"},
+			positionCountMax: 3,
+			want:             []float64{100, 100, 100},
+		},
+		"max rate": {
+			config:           strategy.DCAConfig{Schedule: "*/2 * * * *", MaxRate: 800},
+			positionCountMax: 10,
+			want:             []float64{100, 100, 100},
+		},
+		"below ma": {
+			config:           strategy.DCAConfig{Schedule: "*/2 * * * *", MAPeriod: 3, BelowMAMultiplier: 2},
+			positionCountMax: 10,
+			want:             []float64{100, 100, 200, 100, 100},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			exCli, err := memory.NewExchangeMock(strings.NewReader(strings.Join(rates, "\n")), 0)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			exCli.SetBalance(model.JPY, 10000)
+			rds := memory.NewDummyRDS(nil)
+			facade := trade.NewFacade(exCli, rds, rds, rds, rds, rds, rds, nil)
+			facade.SetClock(exCli)
+			logger := &memory.Logger{Level: memory.Error}
+
+			config := tt.config
+			config.Interval = 60
+			config.Timezone = "UTC"
+			config.AmountJPY = 100
+			s, err := strategy.NewDCAStrategy(facade, logger, &config)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			fetcher := usecase.NewFetcher(exCli, model.BtcJpy, rds)
+			fetcher.SetClock(exCli)
+			simulator := usecase.Simulator{
+				Bot: usecase.NewBot(logger, facade, s, &usecase.BotConfig{
+					Currency:         model.BTC,
+					PositionCountMax: tt.positionCountMax,
+				}),
+				Fetcher:      fetcher,
+				ExchangeMock: exCli,
+				TradeRepo:    rds,
+				Logger:       logger,
+			}
+			if _, err := simulator.Run(context.Background()); err != nil {
+				t.Fatal(err.Error())
+			}
+
+			positions, err := facade.GetOpenPositions()
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			sort.Slice(positions, func(i, j int) bool { return positions[i].OpenerOrder.ID < positions[j].OpenerOrder.ID })
+			got := []float64{}
+			for _, p := range positions {
+				got = append(got, p.OpenerOrder.Amount)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("bought amounts = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,533 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+	"trading-bot/pkg/domain"
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/usecase/trade"
+
+	"github.com/BurntSushi/toml"
+)
+
+type ExternalConfig struct {
+	Interval int `toml:"interval_seconds"`
+
+	// 起動するコマンド
+	Command string `toml:"command"`
+	// コマンドの引数
+	Args []string `toml:"args"`
+	// 作業ディレクトリ（未指定なら現在のディレクトリ）
+	Dir string `toml:"dir"`
+	// 追加する環境変数（KEY=VALUE形式）
+	Env []string `toml:"env"`
+
+	// 応答の待ち時間（秒、超えたらプロセスを停止して次回に再起動する）
+	TimeoutSeconds int `toml:"timeout_seconds"`
+	// 再起動の最短間隔（秒）
+	RestartIntervalSeconds int `toml:"restart_interval_seconds"`
+	// 送信するレート履歴の件数（0なら全件）
+	RateCount int `toml:"rate_count"`
+	// 取引履歴を通知するか（応答は不要）
+	ForwardTrades bool `toml:"forward_trades"`
+
+	// 1回の買い注文の上限(JPY、0なら残高まで)
+	MaxOrderJPY float64 `toml:"max_order_jpy"`
+}
+
+func (c *ExternalConfig) valid() error {
+	if c.Interval == 0 {
+		return fmt.Errorf("Interval is empty, %v", c.Interval)
+	}
+	if c.Command == "" {
+		return fmt.Errorf("Command is empty")
+	}
+	if c.TimeoutSeconds <= 0 {
+		return fmt.Errorf("TimeoutSeconds is empty, %v", c.TimeoutSeconds)
+	}
+	if c.RestartIntervalSeconds < 0 {
+		return fmt.Errorf("RestartIntervalSeconds is negative, %v", c.RestartIntervalSeconds)
+	}
+	if c.RateCount < 0 {
+		return fmt.Errorf("RateCount is negative, %v", c.RateCount)
+	}
+	if c.MaxOrderJPY < 0 {
+		return fmt.Errorf("MaxOrderJPY is negative, %v", c.MaxOrderJPY)
+	}
+	return nil
+}
+
+func NewExternalConfig(f string) (*ExternalConfig, error) {
+	var conf ExternalConfig
+	if _, err := toml.DecodeFile(f, &conf); err != nil {
+		return nil, err
+	}
+	if err := conf.valid(); err != nil {
+		return nil, fmt.Errorf("[%s] validation error: %w", f, err)
+	}
+	return &conf, nil
+}
+
+// ExternalPhase 外部戦略へ問い合わせる契機
+type ExternalPhase string
+
+const (
+	// ExternalBuyPhase 定期実行時の買い判断（buyのみ受け付ける）
+	ExternalBuyPhase ExternalPhase = "buy"
+	// ExternalSellPhase 定期実行時の売り判断（sell/closeのみ受け付ける）
+	ExternalSellPhase ExternalPhase = "sell"
+	// ExternalTradePhase 取引検知の通知（応答は不要）
+	ExternalTradePhase ExternalPhase = "trade"
+)
+
+// ExternalAction 外部戦略からの注文意図の種別
+type ExternalAction string
+
+const (
+	// ExternalBuy 新規の買い（amount_jpyなら成行、amountとrateなら指値）
+	ExternalBuy ExternalAction = "buy"
+	// ExternalSell ポジションの売り（rate未指定なら成行、amount未指定なら全量）
+	ExternalSell ExternalAction = "sell"
+	// ExternalClose ポジションを成行で全量決済（未約定の決済注文は取り消す）
+	ExternalClose ExternalAction = "close"
+)
+
+// ExternalIntent 外部戦略からの注文意図
+type ExternalIntent struct {
+	Action     ExternalAction `json:"action"`
+	PositionID uint64         `json:"position_id,omitempty"`
+	AmountJPY  float64        `json:"amount_jpy,omitempty"`
+	Amount     float64        `json:"amount,omitempty"`
+	Rate       float64        `json:"rate,omitempty"`
+	Reason     string         `json:"reason,omitempty"`
+}
+
+// externalRequest 外部戦略へ送るメッセージ
+type externalRequest struct {
+	ID        uint64             `json:"id"`
+	Phase     ExternalPhase      `json:"phase"`
+	Time      time.Time          `json:"time"`
+	Pair      string             `json:"pair"`
+	Market    *externalMarket    `json:"market,omitempty"`
+	Balance   *externalBalance   `json:"balance,omitempty"`
+	Positions []externalPosition `json:"positions,omitempty"`
+	Trade     *externalTrade     `json:"trade,omitempty"`
+}
+
+type externalMarket struct {
+	BuyRate  float64   `json:"buy_rate"`
+	SellRate float64   `json:"sell_rate"`
+	Rates    []float64 `json:"rates"`
+}
+
+type externalBalance struct {
+	JPY  float64 `json:"jpy"`
+	Coin float64 `json:"coin"`
+}
+
+type externalPosition struct {
+	ID       uint64    `json:"id"`
+	Status   string    `json:"status"`
+	Amount   float64   `json:"amount"`
+	Rate     float64   `json:"rate"`
+	OpenedAt time.Time `json:"opened_at"`
+	// 未約定の決済注文があるか
+	Closing bool `json:"closing"`
+}
+
+type externalTrade struct {
+	Side string  `json:"side"`
+	Rate float64 `json:"rate"`
+}
+
+func externalStatus(s model.OrderStatus) string {
+	switch s {
+	case model.Open:
+		return "open"
+	case model.Closed:
+		return "closed"
+	case model.Canceled:
+		return "canceled"
+	}
+	return "unknown"
+}
+
+// externalResponse 外部戦略からの応答
+type externalResponse struct {
+	ID      uint64           `json:"id"`
+	Intents []ExternalIntent `json:"intents"`
+}
+
+// ExternalStrategy 外部プロセスに売買判断を委ねる
+// 定期実行のたびに相場・残高・ポジションを標準入力へJSONで1行送り、標準出力から注文意図を1行受け取る
+// 注文意図は検証した上でFacade経由で発注する（Facadeの注文チェックも通常通り適用される）
+type ExternalStrategy struct {
+	logger domain.Logger
+	facade *trade.Facade
+
+	config *ExternalConfig
+
+	// 定期実行と取引検知の送信が混ざらないようにする
+	mu        sync.Mutex
+	process   *externalProcess
+	startedAt time.Time
+	requestID uint64
+}
+
+func NewExternalStrategy(facade *trade.Facade, logger domain.Logger, config *ExternalConfig) (*ExternalStrategy, error) {
+	return &ExternalStrategy{
+		logger: logger,
+		facade: facade,
+		config: config,
+	}, nil
+}
+
+// ensureProcess 停止していればサブプロセスを起動
+func (s *ExternalStrategy) ensureProcess() (*externalProcess, error) {
+	if s.process != nil && s.process.running() {
+		return s.process, nil
+	}
+
+	if !s.startedAt.IsZero() {
+		next := s.startedAt.Add(time.Duration(s.config.RestartIntervalSeconds) * time.Second)
+		if time.Now().Before(next) {
+			return nil, fmt.Errorf("waiting for restart until %v", next.Format(time.RFC3339))
+		}
+		s.logger.Info("[external] restarting process ...")
+	}
+
+	s.startedAt = time.Now()
+	p, err := startExternalProcess(s.logger, s.config)
+	if err != nil {
+		return nil, err
+	}
+	s.process = p
+	return p, nil
+}
+
+// request 問い合わせて注文意図を受け取る
+func (s *ExternalStrategy) request(req *externalRequest) ([]ExternalIntent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, err := s.ensureProcess()
+	if err != nil {
+		return nil, err
+	}
+
+	s.requestID++
+	req.ID = s.requestID
+	req.Time = s.facade.Now()
+	timeout := time.Duration(s.config.TimeoutSeconds) * time.Second
+	if err := p.send(req, timeout); err != nil {
+		p.stop()
+		return nil, fmt.Errorf("failed to send request, %w", err)
+	}
+
+	var res externalResponse
+	if err := p.receive(req.ID, timeout, &res); err != nil {
+		// 応答しないプロセスは止めて次回に再起動する
+		p.stop()
+		return nil, err
+	}
+	return res.Intents, nil
+}
+
+// makeRequest 相場・残高・ポジションを含む問い合わせを生成
+func (s *ExternalStrategy) makeRequest(phase ExternalPhase, pair *model.CurrencyPair, positions []model.Position) (*externalRequest, error) {
+	rates, err := s.facade.GetRates(pair)
+	if err != nil {
+		return nil, err
+	}
+	if s.config.RateCount > 0 && len(rates) > s.config.RateCount {
+		rates = rates[len(rates)-s.config.RateCount:]
+	}
+	buyRate, err := s.facade.GetBuyRate(pair)
+	if err != nil {
+		return nil, err
+	}
+	sellRate, err := s.facade.GetSellRate(pair)
+	if err != nil {
+		return nil, err
+	}
+	jpy, err := s.facade.GetJpyBalance()
+	if err != nil {
+		return nil, err
+	}
+	coin, err := s.facade.GetBalance(pair.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	pp := []externalPosition{}
+	for _, p := range positions {
+		amount, rate, err := s.contracted(&p)
+		if err != nil {
+			return nil, err
+		}
+		pp = append(pp, externalPosition{
+			ID:       p.ID,
+			Status:   externalStatus(p.OpenerOrder.Status),
+			Amount:   amount,
+			Rate:     rate,
+			OpenedAt: p.OpenerOrder.OrderedAt,
+			Closing:  p.CloserOrder != nil && p.CloserOrder.Status == model.Open,
+		})
+	}
+
+	return &externalRequest{
+		Phase:     phase,
+		Pair:      pair.String(),
+		Market:    &externalMarket{BuyRate: buyRate, SellRate: sellRate, Rates: rates},
+		Balance:   &externalBalance{JPY: jpy.Amount, Coin: coin.Amount},
+		Positions: pp,
+	}, nil
+}
+
+// contracted 買い注文の約定量と平均約定レート
+func (s *ExternalStrategy) contracted(p *model.Position) (float64, float64, error) {
+	contracts, err := s.facade.GetContracts(p.OpenerOrder.ID)
+	if err != nil {
+		return 0, 0, err
+	}
+	var amount, jpy float64
+	for _, c := range contracts {
+		amount += c.IncreaseAmount
+		jpy += -c.DecreaseAmount
+	}
+	if amount == 0 {
+		return 0, 0, nil
+	}
+	return amount, jpy / amount, nil
+}
+
+func (s *ExternalStrategy) Buy(pair model.CurrencyPair, positions []model.Position) error {
+	return s.decide(ExternalBuyPhase, &pair, positions)
+}
+
+func (s *ExternalStrategy) Sell(pair model.CurrencyPair, positions []model.Position) error {
+	return s.decide(ExternalSellPhase, &pair, positions)
+}
+
+// decide 外部戦略に問い合わせて注文意図を実行
+func (s *ExternalStrategy) decide(phase ExternalPhase, pair *model.CurrencyPair, positions []model.Position) error {
+	req, err := s.makeRequest(phase, pair, positions)
+	if err != nil {
+		return err
+	}
+	intents, err := s.request(req)
+	if err != nil {
+		return fmt.Errorf("[external][%s] %w", phase, err)
+	}
+	if len(intents) == 0 {
+		s.logger.Debug("[%s] => no intents", phase)
+		return nil
+	}
+
+	for _, intent := range intents {
+		if err := s.validate(phase, &intent, positions); err != nil {
+			s.logger.Error("[%s] => rejected intent %+v, %v", phase, intent, err)
+			continue
+		}
+		if err := s.execute(pair, &intent, positions); err != nil {
+			s.logger.Error("[%s] => failed to execute intent %+v, %v", phase, intent, err)
+		}
+	}
+	return nil
+}
+
+// validate 注文意図を検証
+func (s *ExternalStrategy) validate(phase ExternalPhase, intent *ExternalIntent, positions []model.Position) error {
+	switch intent.Action {
+	case ExternalBuy:
+		if phase != ExternalBuyPhase {
+			return fmt.Errorf("action %s is not allowed in %s phase", intent.Action, phase)
+		}
+		jpy := intent.AmountJPY
+		if intent.Rate != 0 || intent.Amount != 0 {
+			if intent.Rate <= 0 || intent.Amount <= 0 {
+				return fmt.Errorf("both amount and rate are required for limit buy")
+			}
+			jpy = intent.Amount * intent.Rate
+		} else if jpy <= 0 {
+			return fmt.Errorf("amount_jpy is empty, %v", intent.AmountJPY)
+		}
+		if s.config.MaxOrderJPY > 0 && jpy > s.config.MaxOrderJPY {
+			return fmt.Errorf("order %.3f JPY exceeds max %.3f JPY", jpy, s.config.MaxOrderJPY)
+		}
+		balance, err := s.facade.GetJpyBalance()
+		if err != nil {
+			return err
+		}
+		if jpy > balance.Amount {
+			return fmt.Errorf("order %.3f JPY exceeds balance %.3f JPY", jpy, balance.Amount)
+		}
+		return nil
+	case ExternalSell, ExternalClose:
+		if phase != ExternalSellPhase {
+			return fmt.Errorf("action %s is not allowed in %s phase", intent.Action, phase)
+		}
+		p := findPosition(intent.PositionID, positions)
+		if p == nil {
+			return fmt.Errorf("position %d is not found", intent.PositionID)
+		}
+		if intent.Amount < 0 || intent.Rate < 0 {
+			return fmt.Errorf("amount and rate must not be negative")
+		}
+		if intent.Action == ExternalSell && p.CloserOrder != nil && p.CloserOrder.Status == model.Open {
+			return fmt.Errorf("position %d already has sell order", p.ID)
+		}
+		amount, _, err := s.contracted(p)
+		if err != nil {
+			return err
+		}
+		if amount <= 0 {
+			return fmt.Errorf("position %d is not contracted", p.ID)
+		}
+		if intent.Amount > amount {
+			return fmt.Errorf("amount %.8f exceeds position amount %.8f", intent.Amount, amount)
+		}
+		return nil
+	default:
+		return fmt.Errorf("action is unknown, %v", intent.Action)
+	}
+}
+
+// execute 注文意図を実行
+func (s *ExternalStrategy) execute(pair *model.CurrencyPair, intent *ExternalIntent, positions []model.Position) error {
+	switch intent.Action {
+	case ExternalBuy:
+		var pos *model.Position
+		var err error
+		if intent.Rate > 0 {
+			s.logger.Debug("[buy] sending buy order ... (amount:%.8f rate:%.3f)(%s)", intent.Amount, intent.Rate, intent.Reason)
+			pos, err = s.facade.SendBuyOrder(pair, intent.Amount, intent.Rate, nil)
+		} else {
+			s.logger.Debug("[buy] sending buy order ... (amount:%.3f JPY)(%s)", intent.AmountJPY, intent.Reason)
+			pos, err = s.facade.SendMarketBuyOrder(pair, intent.AmountJPY, nil)
+		}
+		if err != nil {
+			return err
+		}
+		s.logger.Debug("[buy] completed to send buy order [%v]", pos.OpenerOrder)
+		return nil
+	case ExternalSell, ExternalClose:
+		p := findPosition(intent.PositionID, positions)
+		amount, _, err := s.contracted(p)
+		if err != nil {
+			return err
+		}
+		if intent.Action == ExternalSell && intent.Amount > 0 {
+			amount = intent.Amount
+		}
+
+		if p.CloserOrder != nil && p.CloserOrder.Status == model.Open {
+			s.logger.Debug("[pos:%d][sell] canceling sell order ... [%v]", p.ID, p.CloserOrder)
+			if p, err = s.facade.CancelSettleOrder(p); err != nil {
+				return err
+			}
+		}
+
+		var pos *model.Position
+		if intent.Action == ExternalSell && intent.Rate > 0 {
+			s.logger.Debug("[pos:%d][sell] sending sell order ... (amount:%.8f rate:%.3f)(%s)", p.ID, amount, intent.Rate, intent.Reason)
+			pos, err = s.facade.SendSellOrder(pair, amount, intent.Rate, p)
+		} else {
+			s.logger.Debug("[pos:%d][sell] sending sell order ... (amount:%.8f)(%s)", p.ID, amount, intent.Reason)
+			pos, err = s.facade.SendMarketSellOrder(pair, amount, p)
+		}
+		if err != nil {
+			return err
+		}
+		s.logger.Debug("[pos:%d][sell] completed to send sell order [%v]", pos.ID, pos.CloserOrder)
+		return nil
+	}
+	return nil
+}
+
+func findPosition(id uint64, positions []model.Position) *model.Position {
+	for i := range positions {
+		if positions[i].ID == id {
+			return &positions[i]
+		}
+	}
+	return nil
+}
+
+func (s *ExternalStrategy) BuyTradeCallback(pair model.CurrencyPair, rate float64) error {
+	return s.forwardTrade(&pair, "buy", rate)
+}
+
+func (s *ExternalStrategy) SellTradeCallback(pair model.CurrencyPair, rate float64) error {
+	return s.forwardTrade(&pair, "sell", rate)
+}
+
+// forwardTrade 取引履歴を通知（稼働中のプロセスにのみ送る）
+func (s *ExternalStrategy) forwardTrade(pair *model.CurrencyPair, side string, rate float64) error {
+	if !s.config.ForwardTrades {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.process == nil || !s.process.running() {
+		return nil
+	}
+	s.requestID++
+	err := s.process.send(&externalRequest{
+		ID:    s.requestID,
+		Phase: ExternalTradePhase,
+		Time:  s.facade.Now(),
+		Pair:  pair.String(),
+		Trade: &externalTrade{Side: side, Rate: rate},
+	}, time.Duration(s.config.TimeoutSeconds)*time.Second)
+	if err != nil {
+		// 入力を読まないプロセスは止めて次回に再起動する
+		s.process.stop()
+		return fmt.Errorf("failed to forward trade, %w", err)
+	}
+	return nil
+}
+
+func (s *ExternalStrategy) Wait(ctx context.Context) error {
+	s.logger.Debug("waiting ... (%v)\n", s.config.Interval)
+	if err := s.facade.Wait(ctx, time.Duration(s.config.Interval)*time.Second); err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		// 終了時はサブプロセスも止める
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.process != nil {
+			s.process.stop()
+		}
+	}
+	return nil
+}
+
+// ReloadConfig 設定を再読み込み（コマンドが変わった場合は次回に起動し直す）
+func (s *ExternalStrategy) ReloadConfig(path string) (interface{}, interface{}, error) {
+	return reload(s, path)
+}
+
+// PrepareReload 設定を検証して適用する関数を返す
+func (s *ExternalStrategy) PrepareReload(path string) (func() (interface{}, interface{}), error) {
+	config, err := NewExternalConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() (interface{}, interface{}) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		before := s.config
+		s.config = config
+		if s.process != nil && (before.Command != config.Command || fmt.Sprint(before.Args, before.Dir, before.Env) != fmt.Sprint(config.Args, config.Dir, config.Env)) {
+			s.process.stop()
+			s.process = nil
+			s.startedAt = time.Time{}
+		}
+		return before, config
+	}, nil
+}
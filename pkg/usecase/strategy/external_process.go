@@ -0,0 +1,181 @@
+package strategy
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+	"trading-bot/pkg/domain"
+)
+
+// externalLineMax 1行の最大サイズ
+const externalLineMax = 1024 * 1024
+
+// externalProcess 外部戦略のサブプロセス（標準入出力でJSONを1行ずつやりとりする）
+type externalProcess struct {
+	logger domain.Logger
+
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	lines chan []byte
+	done  chan struct{}
+}
+
+// startExternalProcess サブプロセスを起動
+func startExternalProcess(logger domain.Logger, c *ExternalConfig) (*externalProcess, error) {
+	cmd := exec.Command(c.Command, c.Args...)
+	cmd.Dir = c.Dir
+	cmd.Env = append(os.Environ(), c.Env...)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	p := &externalProcess{
+		logger: logger,
+		cmd:    cmd,
+		stdin:  stdin,
+		lines:  make(chan []byte, 16),
+		done:   make(chan struct{}),
+	}
+
+	stderrDone := make(chan struct{})
+	go func() {
+		// 標準エラー出力はログに流す
+		defer close(stderrDone)
+		scanner := bufio.NewScanner(stderr)
+		scanner.Buffer(make([]byte, 0, 4096), externalLineMax)
+		for scanner.Scan() {
+			logger.Info("[external][pid:%d] %s", cmd.Process.Pid, scanner.Text())
+		}
+	}()
+	go func() {
+		defer close(p.done)
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, 0, 4096), externalLineMax)
+		for scanner.Scan() {
+			line := make([]byte, len(scanner.Bytes()))
+			copy(line, scanner.Bytes())
+			select {
+			case p.lines <- line:
+			default:
+				logger.Error("[external][pid:%d] discard output (not read), %s", cmd.Process.Pid, string(line))
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			logger.Error("[external][pid:%d] failed to read stdout, %v", cmd.Process.Pid, err)
+			// 読み取れなくなったプロセスは止めて再起動させる
+			cmd.Process.Kill()
+		}
+		close(p.lines)
+		<-stderrDone
+		if err := cmd.Wait(); err != nil {
+			logger.Error("[external][pid:%d] exited, %v", cmd.Process.Pid, err)
+		} else {
+			logger.Info("[external][pid:%d] exited", cmd.Process.Pid)
+		}
+	}()
+
+	logger.Info("[external][pid:%d] started %s %v", cmd.Process.Pid, c.Command, c.Args)
+	return p, nil
+}
+
+// running 稼働中か
+func (p *externalProcess) running() bool {
+	select {
+	case <-p.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// send メッセージを1行送信（入力を読まずに書き込めないまま時間切れになったプロセスは止める）
+func (p *externalProcess) send(v interface{}, timeout time.Duration) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	written := make(chan error, 1)
+	go func() {
+		_, err := p.stdin.Write(append(b, '\n'))
+		written <- err
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-written:
+		return err
+	case <-timer.C:
+		p.logger.Error("[external][pid:%d] write timeout, kill process", p.cmd.Process.Pid)
+		if err := p.cmd.Process.Kill(); err != nil {
+			p.logger.Error("[external][pid:%d] failed to kill, %v", p.cmd.Process.Pid, err)
+		}
+		return fmt.Errorf("write timeout (timeout:%v)", timeout)
+	}
+}
+
+// receive 指定IDへの応答を受信（別IDの応答は読み捨てる）
+func (p *externalProcess) receive(id uint64, timeout time.Duration, v *externalResponse) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case line, ok := <-p.lines:
+			if !ok {
+				return fmt.Errorf("process exited before responding (id:%d)", id)
+			}
+			var res externalResponse
+			if err := json.Unmarshal(line, &res); err != nil {
+				p.logger.Error("[external][pid:%d] invalid response, %v, %s", p.cmd.Process.Pid, err, string(line))
+				continue
+			}
+			if res.ID != id {
+				p.logger.Debug("[external][pid:%d] discard response (id:%d != %d)", p.cmd.Process.Pid, res.ID, id)
+				continue
+			}
+			*v = res
+			return nil
+		case <-timer.C:
+			return fmt.Errorf("response timeout (id:%d, timeout:%v)", id, timeout)
+		}
+	}
+}
+
+// stop サブプロセスを停止
+func (p *externalProcess) stop() {
+	if !p.running() {
+		return
+	}
+	p.stdin.Close()
+	select {
+	case <-p.done:
+		return
+	case <-time.After(time.Second):
+	}
+	if err := p.cmd.Process.Kill(); err != nil {
+		p.logger.Error("[external][pid:%d] failed to kill, %v", p.cmd.Process.Pid, err)
+	}
+	select {
+	case <-p.done:
+	case <-time.After(3 * time.Second):
+		// 孫プロセスが出力を掴んだままの場合は終了を待たない
+		p.logger.Error("[external][pid:%d] output is still open after kill", p.cmd.Process.Pid)
+	}
+}
@@ -0,0 +1,182 @@
+package strategy_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/infrastructure/memory"
+	"trading-bot/pkg/usecase"
+	"trading-bot/pkg/usecase/strategy"
+	"trading-bot/pkg/usecase/trade"
+)
+
+// externalScript 1000で買い、1100で決済する外部戦略（買い判断時の売り注文は拒否される）
+const externalScript = `
+while read -r line; do
+  id=$(printf '%s' "$line" | sed -n 's/^{"id":\([0-9]*\).*/\1/p')
+  pid=$(printf '%s' "$line" | sed -n 's/.*"positions":\[{"id":\([0-9]*\).*/\1/p')
+  case "$line" in
+    *'"phase":"buy"'*'"buy_rate":1000,'*)
+      if [ -z "$pid" ]; then
+        echo "{\"id\":$id,\"intents\":[{\"action\":\"buy\",\"amount_jpy\":1000},{\"action\":\"sell\",\"position_id\":1}]}"
+      else
+        echo "{\"id\":$id,\"intents\":[]}"
+      fi;;
+    *'"phase":"sell"'*'"sell_rate":1100,'*)
+      echo "{\"id\":$id,\"intents\":[{\"action\":\"close\",\"position_id\":$pid,\"reason\":\"take profit\"}]}";;
+    *'"phase":"trade"'*) ;;
+    *)
+      echo "{\"id\":$id,\"intents\":[]}";;
+  esac
+done
+`
+
+func TestExternalStrategy_Simulation(t *testing.T) {
+	rates := []string{
+		"日付, 販売所買い価格, 販売所売り価格",
+		"2021-02-23T19:27:01Z,1000.0,1000.0",
+		"2021-02-23T19:27:02Z,1100.0,1100.0",
+		"2021-02-23T19:27:03Z,1000.0,1000.0",
+	}
+	exCli, err := memory.NewExchangeMock(strings.NewReader(strings.Join(rates, "\n")), 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	rds := memory.NewDummyRDS(nil)
+	facade := trade.NewFacade(exCli, rds, rds, rds, rds, rds, rds, nil)
+	logger := &memory.Logger{Level: memory.Error}
+
+	s, err := strategy.NewExternalStrategy(facade, logger, &strategy.ExternalConfig{
+		Interval:       1,
+		Command:        "sh",
+		Args:           []string{"-c", externalScript},
+		TimeoutSeconds: 5,
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		s.Wait(ctx)
+	}()
+
+	simulator := usecase.Simulator{
+		Bot: usecase.NewBot(logger, facade, s, &usecase.BotConfig{
+			Currency:         model.BTC,
+			PositionCountMax: 1,
+		}),
+		Fetcher:      usecase.NewFetcher(exCli, model.BtcJpy, rds),
+		ExchangeMock: exCli,
+		TradeRepo:    rds,
+		Logger:       logger,
+	}
+
+	profit, err := simulator.Run(context.Background())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	// 1000JPY分を1000で買って1100で売る
+	if profit != 100 {
+		t.Errorf("profit = %v, want 100", profit)
+	}
+
+	positions, err := facade.GetOpenPositions()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	// 再び1000で買ったポジション
+	if len(positions) != 1 {
+		t.Errorf("open positions count = %d, want 1; %v", len(positions), positions)
+	}
+}
+
+func TestExternalStrategy_Timeout(t *testing.T) {
+	rates := []string{
+		"日付, 販売所買い価格, 販売所売り価格",
+		"2021-02-23T19:27:01Z,1000.0,1000.0",
+	}
+	exCli, err := memory.NewExchangeMock(strings.NewReader(strings.Join(rates, "\n")), 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	rds := memory.NewDummyRDS(nil)
+	facade := trade.NewFacade(exCli, rds, rds, rds, rds, rds, rds, nil)
+	logger := &memory.Logger{Level: memory.Error}
+
+	s, err := strategy.NewExternalStrategy(facade, logger, &strategy.ExternalConfig{
+		Interval:       1,
+		Command:        "sh",
+		Args:           []string{"-c", "exec sleep 10"},
+		TimeoutSeconds: 1,
+		// 再起動を待たせる
+		RestartIntervalSeconds: 60,
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := s.Buy(model.BtcJpy, []model.Position{}); err == nil || !strings.Contains(err.Error(), "timeout") {
+		t.Errorf("Buy() error = %v, want timeout", err)
+	}
+	if err := s.Buy(model.BtcJpy, []model.Position{}); err == nil || !strings.Contains(err.Error(), "waiting for restart") {
+		t.Errorf("Buy() error = %v, want waiting for restart", err)
+	}
+}
+
+func TestExternalStrategy_WriteTimeout(t *testing.T) {
+	rates := []string{
+		"日付, 販売所買い価格, 販売所売り価格",
+		"2021-02-23T19:27:01Z,1000.0,1000.0",
+	}
+	exCli, err := memory.NewExchangeMock(strings.NewReader(strings.Join(rates, "\n")), 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	rds := memory.NewDummyRDS(nil)
+	facade := trade.NewFacade(exCli, rds, rds, rds, rds, rds, rds, nil)
+	logger := &memory.Logger{Level: memory.Error}
+
+	// 最初の問い合わせにだけ応答し、その後は入力を読まない
+	script := `read -r line
+id=$(printf '%s' "$line" | sed -n 's/^{"id":\([0-9]*\).*/\1/p')
+echo "{\"id\":$id,\"intents\":[]}"
+exec sleep 10`
+	s, err := strategy.NewExternalStrategy(facade, logger, &strategy.ExternalConfig{
+		Interval:       1,
+		Command:        "sh",
+		Args:           []string{"-c", script},
+		TimeoutSeconds: 1,
+		ForwardTrades:  true,
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		s.Wait(ctx)
+	}()
+
+	if err := s.Buy(model.BtcJpy, []model.Position{}); err != nil {
+		t.Fatal(err.Error())
+	}
+	// パイプが詰まるまで送ると時間切れでプロセスを止める
+	start := time.Now()
+	var sendErr error
+	for i := 0; i < 10000 && sendErr == nil; i++ {
+		sendErr = s.BuyTradeCallback(model.BtcJpy, 1000)
+	}
+	if sendErr == nil || !strings.Contains(sendErr.Error(), "timeout") {
+		t.Fatalf("BuyTradeCallback() error = %v, want timeout", sendErr)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("elapsed = %v, want about the timeout", elapsed)
+	}
+	// 止めたプロセスには送らない
+	if err := s.BuyTradeCallback(model.BtcJpy, 1000); err != nil {
+		t.Errorf("BuyTradeCallback() error = %v, want nil", err)
+	}
+}
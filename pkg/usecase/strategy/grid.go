@@ -0,0 +1,312 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+	"trading-bot/pkg/domain"
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/usecase/trade"
+
+	"github.com/BurntSushi/toml"
+)
+
+// GridSpacing グリッドの間隔種別
+type GridSpacing string
+
+const (
+	// ArithmeticSpacing 等差（一定のJPY幅）
+	ArithmeticSpacing GridSpacing = "arithmetic"
+	// GeometricSpacing 等比（一定の比率）
+	GeometricSpacing GridSpacing = "geometric"
+)
+
+type GridConfig struct {
+	Interval int `toml:"interval_seconds"`
+
+	// 基準レートの下に並べる買い注文の段数
+	Levels int `toml:"levels"`
+	// 間隔種別（arithmetic / geometric）
+	Spacing GridSpacing `toml:"spacing"`
+	// 1段の間隔（arithmeticならJPY幅、geometricなら比率 例:0.01）
+	Step float64 `toml:"step"`
+	// 1段あたりの注文量
+	AmountPerLevel float64 `toml:"amount_per_level"`
+}
+
+func (c *GridConfig) valid() error {
+	if c.Interval == 0 {
+		return fmt.Errorf("Interval is empty, %v", c.Interval)
+	}
+	if c.Levels <= 0 {
+		return fmt.Errorf("Levels is empty, %v", c.Levels)
+	}
+	switch c.Spacing {
+	case ArithmeticSpacing:
+	case GeometricSpacing:
+		if c.Step >= 1 {
+			return fmt.Errorf("Step must be less than 1 for geometric spacing, %v", c.Step)
+		}
+	default:
+		return fmt.Errorf("Spacing is unknown, %v", c.Spacing)
+	}
+	if c.Step <= 0 {
+		return fmt.Errorf("Step is empty, %v", c.Step)
+	}
+	if c.AmountPerLevel <= 0 {
+		return fmt.Errorf("AmountPerLevel is empty, %v", c.AmountPerLevel)
+	}
+	return nil
+}
+
+func NewGridConfig(f string) (*GridConfig, error) {
+	var conf GridConfig
+	if _, err := toml.DecodeFile(f, &conf); err != nil {
+		return nil, err
+	}
+	if err := conf.valid(); err != nil {
+		return nil, fmt.Errorf("[%s] validation error: %w", f, err)
+	}
+	return &conf, nil
+}
+
+// GridStrategy グリッドトレード
+// 基準レートの下に指値の買い注文を並べ、約定した買い注文ごとに1段上へ指値の売り注文を出す
+type GridStrategy struct {
+	logger domain.Logger
+	facade *trade.Facade
+
+	config *GridConfig
+
+	// 基準レート（未設定なら次回の買い判断時に設定）
+	reference *float64
+}
+
+func NewGridStrategy(facade *trade.Facade, logger domain.Logger, config *GridConfig) (*GridStrategy, error) {
+	return &GridStrategy{
+		logger: logger,
+		facade: facade,
+		config: config,
+	}, nil
+}
+
+// level 基準レートからn段離れたレート
+func (s *GridStrategy) level(n int) float64 {
+	if s.config.Spacing == GeometricSpacing {
+		return *s.reference * math.Pow(1+s.config.Step, float64(n))
+	}
+	return *s.reference + s.config.Step*float64(n)
+}
+
+// nextLevel 指定レートの1段上のレート
+func (s *GridStrategy) nextLevel(rate float64) float64 {
+	if s.config.Spacing == GeometricSpacing {
+		return rate * (1 + s.config.Step)
+	}
+	return rate + s.config.Step
+}
+
+// sameLevel 同じ段のレートか判定
+func (s *GridStrategy) sameLevel(a, b float64) bool {
+	tolerance := s.config.Step / 2
+	if s.config.Spacing == GeometricSpacing {
+		tolerance = math.Min(a, b) * s.config.Step / 2
+	}
+	return math.Abs(a-b) < tolerance
+}
+
+func (s *GridStrategy) Buy(p model.CurrencyPair, positions []model.Position) error {
+	rate, err := s.facade.GetSellRate(&p)
+	if err != nil {
+		return err
+	}
+
+	if s.reference == nil {
+		s.logger.Debug("[buy] => rebuild grid (reference is empty, rate:%.3f)", rate)
+		if err := s.rebuild(rate, positions); err != nil {
+			return err
+		}
+	} else if lower, upper := s.level(-s.config.Levels), s.level(s.config.Levels); rate < lower || rate > upper {
+		s.logger.Debug("[buy] => rebuild grid (rate:%.3f out of range[%.3f, %.3f])", rate, lower, upper)
+		if err := s.rebuild(rate, positions); err != nil {
+			return err
+		}
+	}
+
+	for i := 1; i <= s.config.Levels; i++ {
+		buyRate := domain.Round(s.level(-i))
+		if buyRate <= 0 || buyRate >= rate {
+			continue
+		}
+		if s.hasLevel(buyRate, positions) {
+			continue
+		}
+
+		s.logger.Debug("[buy] sending buy order ... (level:-%d rate:%.3f amount:%.3f)", i, buyRate, s.config.AmountPerLevel)
+		pos, err := s.facade.SendBuyOrder(&p, s.config.AmountPerLevel, buyRate, nil)
+		if err != nil {
+			return err
+		}
+		s.logger.Debug("[buy] completed to send buy order [%v]", pos.OpenerOrder)
+	}
+
+	return nil
+}
+
+// hasLevel 指定レートの段に買い注文またはポジションがあるか判定
+func (s *GridStrategy) hasLevel(rate float64, positions []model.Position) bool {
+	for _, pos := range positions {
+		if pos.OpenerOrder.Rate == nil {
+			continue
+		}
+		if s.sameLevel(*pos.OpenerOrder.Rate, rate) {
+			return true
+		}
+	}
+	return false
+}
+
+// rebuild 基準レートを設定し直し、未約定の買い注文を取り消す
+func (s *GridStrategy) rebuild(rate float64, positions []model.Position) error {
+	for _, pos := range positions {
+		if pos.OpenerOrder.Status != model.Open || pos.CloserOrder != nil {
+			continue
+		}
+		amount, err := s.contractedAmount(&pos)
+		if err != nil {
+			return err
+		}
+		if amount > 0 {
+			// 一部約定済みの注文は約定を待つ
+			continue
+		}
+
+		s.logger.Debug("[pos:%d][buy] canceling buy order ... [%v]", pos.ID, pos.OpenerOrder)
+		if err := s.facade.CancelNewOrder(&pos); err != nil {
+			return err
+		}
+	}
+
+	s.reference = &rate
+	return nil
+}
+
+func (s *GridStrategy) BuyTradeCallback(p model.CurrencyPair, rate float64) error {
+	return nil
+}
+
+func (s *GridStrategy) Sell(pair model.CurrencyPair, positions []model.Position) error {
+	for _, p := range positions {
+		if p.OpenerOrder.Status != model.Closed || p.CloserOrder != nil {
+			continue
+		}
+
+		amount, err := s.contractedAmount(&p)
+		if err != nil {
+			return err
+		}
+		if amount <= 0 {
+			continue
+		}
+
+		var buyRate float64
+		if p.OpenerOrder.Rate != nil {
+			buyRate = *p.OpenerOrder.Rate
+		} else {
+			buyRate, err = s.contractedRate(&p)
+			if err != nil {
+				return err
+			}
+		}
+		sellRate := domain.Round(s.nextLevel(buyRate))
+
+		s.logger.Debug("[pos:%d][sell] sending sell order ... (buy rate:%.3f sell rate:%.3f amount:%.3f)", p.ID, buyRate, sellRate, amount)
+		pos, err := s.facade.SendSellOrder(&pair, amount, sellRate, &p)
+		if err != nil {
+			return err
+		}
+		s.logger.Debug("[pos:%d][sell] completed to send sell order [%v]", pos.ID, pos.CloserOrder)
+	}
+
+	return nil
+}
+
+// contractedAmount 買い注文の約定量
+func (s *GridStrategy) contractedAmount(p *model.Position) (float64, error) {
+	contracts, err := s.facade.GetContracts(p.OpenerOrder.ID)
+	if err != nil {
+		return 0, err
+	}
+	var amount float64
+	for _, c := range contracts {
+		amount += c.IncreaseAmount
+	}
+	return amount, nil
+}
+
+// contractedRate 買い注文の平均約定レート
+func (s *GridStrategy) contractedRate(p *model.Position) (float64, error) {
+	contracts, err := s.facade.GetContracts(p.OpenerOrder.ID)
+	if err != nil {
+		return 0, err
+	}
+	var amount, jpy float64
+	for _, c := range contracts {
+		amount += c.IncreaseAmount
+		jpy += -c.DecreaseAmount
+	}
+	if amount == 0 {
+		return 0, nil
+	}
+	return jpy / amount, nil
+}
+
+func (s *GridStrategy) SellTradeCallback(pair model.CurrencyPair, rate float64) error {
+	return nil
+}
+
+func (s *GridStrategy) Wait(ctx context.Context) error {
+	s.logger.Debug("waiting ... (%v)\n", s.config.Interval)
+	return s.facade.Wait(ctx, time.Duration(s.config.Interval)*time.Second)
+}
+
+// SaveState 状態を書き出す
+func (s *GridStrategy) SaveState(st *model.StrategyState) error {
+	if s.reference != nil {
+		st.SetFloat("reference", *s.reference)
+	}
+	return nil
+}
+
+// LoadState 状態を復元する
+func (s *GridStrategy) LoadState(st *model.StrategyState) error {
+	if _, ok := st.Values["reference"]; !ok {
+		return nil
+	}
+	reference, err := st.Float("reference", 0)
+	if err != nil {
+		return err
+	}
+	s.reference = &reference
+	return nil
+}
+
+// ReloadConfig 設定を再読み込み（基準レートは次回の範囲外判定で作り直す）
+func (s *GridStrategy) ReloadConfig(path string) (interface{}, interface{}, error) {
+	return reload(s, path)
+}
+
+// PrepareReload 設定を検証して適用する関数を返す
+func (s *GridStrategy) PrepareReload(path string) (func() (interface{}, interface{}), error) {
+	config, err := NewGridConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() (interface{}, interface{}) {
+		before := s.config
+		s.config = config
+		return before, config
+	}, nil
+}
@@ -0,0 +1,69 @@
+package strategy_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/infrastructure/memory"
+	"trading-bot/pkg/usecase"
+	"trading-bot/pkg/usecase/strategy"
+	"trading-bot/pkg/usecase/trade"
+)
+
+func TestGridStrategy_Simulation(t *testing.T) {
+	rates := []string{
+		"日付, 販売所買い価格, 販売所売り価格",
+		"2021-02-23T19:27:01Z,1000.0,1000.0",
+		"2021-02-23T19:27:02Z,985.0,985.0",
+		"2021-02-23T19:27:03Z,995.0,995.0",
+		"2021-02-23T19:27:04Z,1001.0,1001.0",
+		"2021-02-23T19:27:05Z,1001.0,1001.0",
+	}
+	exCli, err := memory.NewExchangeMock(strings.NewReader(strings.Join(rates, "\n")), 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	rds := memory.NewDummyRDS(nil)
+	facade := trade.NewFacade(exCli, rds, rds, rds, rds, rds, rds, nil)
+	logger := &memory.Logger{Level: memory.Error}
+
+	s, err := strategy.NewGridStrategy(facade, logger, &strategy.GridConfig{
+		Interval:       1,
+		Levels:         2,
+		Spacing:        strategy.ArithmeticSpacing,
+		Step:           10,
+		AmountPerLevel: 1,
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	simulator := usecase.Simulator{
+		Bot: usecase.NewBot(logger, facade, s, &usecase.BotConfig{
+			Currency:         model.BTC,
+			PositionCountMax: 4,
+		}),
+		Fetcher:      usecase.NewFetcher(exCli, model.BtcJpy, rds),
+		ExchangeMock: exCli,
+		TradeRepo:    rds,
+		Logger:       logger,
+	}
+
+	profit, err := simulator.Run(context.Background())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	// 990で買って1000で売る
+	if profit != 10 {
+		t.Errorf("profit = %v, want 10", profit)
+	}
+
+	positions, err := facade.GetOpenPositions()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	// 980と、売却後に置き直した990の買い注文
+	if len(positions) != 2 {
+		t.Errorf("open positions count = %d, want 2; %v", len(positions), positions)
+	}
+}
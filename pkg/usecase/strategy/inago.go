@@ -7,6 +7,8 @@ import (
 	"time"
 	"trading-bot/pkg/domain"
 	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/usecase/exit"
+	"trading-bot/pkg/usecase/sizing"
 	"trading-bot/pkg/usecase/trade"
 
 	"github.com/BurntSushi/toml"
@@ -38,6 +40,11 @@ type InagoConfig struct {
 	// レートがどの程度下がったらナンピンするか
 	AveragingDownRatePer float64 `toml:"averaging_down_rate_per"`
 
+	// 決済ポリシー（未指定の利確・損切は上記の設定値を使う）
+	Exit exit.Config `toml:"exit"`
+	// 注文サイズ（未指定ならfunds_ratioを使う）
+	Sizing sizing.Config `toml:"sizing"`
+
 	//LongTermSize           int     `toml:"long_term_size"`
 	//ShortTermSize          int     `toml:"short_term_size"`
 	//CrossCheckWidth        int     `toml:"cross_check_width"`
@@ -47,9 +54,6 @@ func (c *InagoConfig) valid() error {
 	if c.Interval == 0 {
 		return fmt.Errorf("Interval is empty, %v", c.Interval)
 	}
-	if c.FundsRatio == 0 {
-		return fmt.Errorf("FundsRatio is empty, %v", c.FundsRatio)
-	}
 	if c.LossCutLowerLimitPer == 0 {
 		return fmt.Errorf("LossCutLowerLimitPer is empty, %v", c.LossCutLowerLimitPer)
 	}
@@ -77,9 +81,34 @@ func (c *InagoConfig) valid() error {
 	if c.AveragingDownRatePer == 0 {
 		return fmt.Errorf("AveragingDownRatePer is empty, %v", c.AveragingDownRatePer)
 	}
+	// FundsRatioはfixed_fractional（sizing未指定を含む）のときのみ必須で、sizing.Newで確認する
+	if _, err := sizing.New(c.SizingConfig()); err != nil {
+		return fmt.Errorf("Sizing is invalid, %w", err)
+	}
 	return nil
 }
 
+// SizingConfig 注文サイズ用設定を取得
+func (c *InagoConfig) SizingConfig() *sizing.Config {
+	conf := c.Sizing
+	if conf.FundsRatio == 0 {
+		conf.FundsRatio = c.FundsRatio
+	}
+	return &conf
+}
+
+// ExitConfig 決済ポリシー用設定を取得
+func (c *InagoConfig) ExitConfig() *exit.Config {
+	conf := c.Exit
+	if conf.TakeProfitPer == 0 {
+		conf.TakeProfitPer = c.FixProfitUpperLimitPer
+	}
+	if conf.StopLossPer == 0 {
+		conf.StopLossPer = c.LossCutLowerLimitPer
+	}
+	return &conf
+}
+
 func NewInagoConfig(f string) (*InagoConfig, error) {
 	var conf InagoConfig
 	if _, err := toml.DecodeFile(f, &conf); err != nil {
@@ -95,7 +124,9 @@ type InagoStrategy struct {
 	logger domain.Logger
 	facade *trade.Facade
 
-	config *InagoConfig
+	config    *InagoConfig
+	exitChain *exit.Chain
+	sizer     sizing.Sizer
 
 	sellStandby bool
 
@@ -104,10 +135,16 @@ type InagoStrategy struct {
 }
 
 func NewInagoStrategy(facade *trade.Facade, logger domain.Logger, config *InagoConfig) (*InagoStrategy, error) {
+	sizer, err := sizing.New(config.SizingConfig())
+	if err != nil {
+		return nil, err
+	}
 	return &InagoStrategy{
 		logger:      logger,
 		facade:      facade,
 		config:      config,
+		exitChain:   exit.NewChainFromConfig(facade, logger, config.ExitConfig()),
+		sizer:       sizer,
 		sellStandby: false,
 	}, nil
 }
@@ -133,11 +170,11 @@ func (s *InagoStrategy) Buy(pair model.CurrencyPair, positions []model.Position)
 		return nil
 	}
 	s.logger.Debug("[buy] => should buy (supportLineCrossed:%v, canAveragingDown:%v, canOrder:%v)", supportLineCrossed, canAveragingDown, canOrder)
-	return s.buy(&pair)
+	return s.buy(&pair, rr)
 }
 
 func (s *InagoStrategy) canOrder(positions []model.Position) bool {
-	border := time.Now().Add(-1 * time.Duration(s.config.BuyIntervalSeconds) * time.Second)
+	border := s.facade.Now().Add(-1 * time.Duration(s.config.BuyIntervalSeconds) * time.Second)
 	for _, pos := range positions {
 		if pos.OpenerOrder.OrderedAt.After(border) {
 			s.logger.Debug("[buy] => cannot be ordered(already ordred within %dsec)(%v)", s.config.BuyIntervalSeconds, pos.OpenerOrder.OrderedAt)
@@ -267,12 +304,17 @@ func (s *InagoStrategy) isGoldenCross(pair model.CurrencyPair, rates []float64,
 	return true
 }
 
-func (s *InagoStrategy) buy(p *model.CurrencyPair) error {
-	balance, err := s.facade.GetJpyBalance()
+func (s *InagoStrategy) buy(p *model.CurrencyPair, rates []float64) error {
+	in, err := sizing.NewInput(s.facade, s.sizer, p, rates, s.config.ExitConfig().StopLossPer)
 	if err != nil {
 		return err
 	}
-	amount := balance.Amount * s.config.FundsRatio
+	amount, reason := s.sizer.Size(in)
+	if amount <= 0 {
+		s.logger.Debug("[buy] => skip buy (%s amount:%.3f)(%s)", s.sizer.Name(), amount, reason)
+		return nil
+	}
+	s.logger.Debug("[buy] %s amount:%.3f (%s)", s.sizer.Name(), amount, reason)
 
 	s.logger.Debug("======================================")
 	s.logger.Debug("[buy] sending buy order ...")
@@ -295,7 +337,9 @@ func (s *InagoStrategy) buy(p *model.CurrencyPair) error {
 		}
 
 		// 約定待ちのため1秒待機
-		time.Sleep(1 * time.Second)
+		if err := s.facade.Wait(context.Background(), 1*time.Second); err != nil {
+			return err
+		}
 	}
 
 	return err
@@ -393,29 +437,21 @@ func (s *InagoStrategy) Sell(pair model.CurrencyPair, positions []model.Position
 		s.logger.Debug("[sell] => skip sell (rates len:%d < required:%d)", len(rates), s.config.SellROCPeriod)
 		return nil
 	}
-	rocs := talib.Roc(rates, s.config.SellROCPeriod)
-	roc := rocs[len(rocs)-1]
+	result, err := s.exitChain.CheckGroup(positions, sellRate, rates)
+	if err != nil {
+		return err
+	}
 
 	sellJPY := sellRate * currencyAmount
-	fixLimit := buyJPY * s.calcFixLimitRate(roc)
-	losscutLimit := buyJPY * s.calcLosscutLimitRate(roc)
-
-	skip := false
-	if sellJPY >= fixLimit {
-		s.logger.Debug("[sell] => fix profit (sell:%.3f >= fix:%.3f) (sellRate:%.3f, buyJPY:%.3f, buyRateAVG:%.3f)", sellJPY, fixLimit, sellRate, buyJPY, buyJPY/currencyAmount)
-	} else if sellJPY <= losscutLimit {
-		s.logger.Debug("[sell] => losscut (sell:%.3f <= losscut:%.3f) (sellRate:%.3f, buyJPY:%.3f, buyRateAVG:%.3f)", sellJPY, losscutLimit, sellRate, buyJPY, buyJPY/currencyAmount)
-	} else {
-		s.logger.Debug("[sell] => skip sell (losscut:%.3f < sell:%.3f < fix:%.3f) (sellRate:%.3f, buyJPY:%.3f, buyRateAVG:%.3f)", losscutLimit, sellJPY, fixLimit, sellRate, buyJPY, buyJPY/currencyAmount)
-		skip = true
-	}
-	if skip {
+	if result == nil {
+		s.logger.Debug("[sell] => skip sell (sell:%.3f) (sellRate:%.3f, buyJPY:%.3f, buyRateAVG:%.3f)", sellJPY, sellRate, buyJPY, buyJPY/currencyAmount)
 		if !s.sellStandby && sellJPY > buyJPY {
 			s.logger.Debug("[sell] => standby (sellJPY:%.3f > buyJPY:%.3f)", sellJPY, buyJPY)
 			s.sellStandby = true
 		}
 		return nil
 	}
+	s.logger.Debug("[sell] => %s (%s) (sellRate:%.3f, buyJPY:%.3f, buyRateAVG:%.3f)", result.Policy, result.Reason, sellRate, buyJPY, buyJPY/currencyAmount)
 
 	s.logger.Debug("======================================")
 	for _, p := range positions {
@@ -433,14 +469,6 @@ func (s *InagoStrategy) Sell(pair model.CurrencyPair, positions []model.Position
 	return err
 }
 
-func (s *InagoStrategy) calcFixLimitRate(roc float64) float64 {
-	return s.config.FixProfitUpperLimitPer
-}
-
-func (s *InagoStrategy) calcLosscutLimitRate(roc float64) float64 {
-	return s.config.LossCutLowerLimitPer
-}
-
 func (s *InagoStrategy) Wait(ctx context.Context) error {
 	s.logger.Debug("wait ... (%d sec)", s.config.Interval)
 
@@ -466,3 +494,50 @@ func (s *InagoStrategy) Wait(ctx context.Context) error {
 		}
 	}
 }
+
+// SaveState 状態を書き出す
+func (s *InagoStrategy) SaveState(st *model.StrategyState) error {
+	st.SetBool("sell_standby", s.sellStandby)
+	st.SetFloat("long_ema", s.longEma)
+	st.SetFloat("short_ema", s.shortEma)
+	return nil
+}
+
+// LoadState 状態を復元する
+func (s *InagoStrategy) LoadState(st *model.StrategyState) (err error) {
+	if s.sellStandby, err = st.Bool("sell_standby", false); err != nil {
+		return err
+	}
+	if s.longEma, err = st.Float("long_ema", 0); err != nil {
+		return err
+	}
+	if s.shortEma, err = st.Float("short_ema", 0); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ReloadConfig 設定を再読み込み
+func (s *InagoStrategy) ReloadConfig(path string) (interface{}, interface{}, error) {
+	return reload(s, path)
+}
+
+// PrepareReload 設定を検証して適用する関数を返す
+func (s *InagoStrategy) PrepareReload(path string) (func() (interface{}, interface{}), error) {
+	config, err := NewInagoConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	sizer, err := sizing.New(config.SizingConfig())
+	if err != nil {
+		return nil, err
+	}
+
+	return func() (interface{}, interface{}) {
+		before := s.config
+		s.config = config
+		s.exitChain = exit.NewChainFromConfig(s.facade, s.logger, config.ExitConfig())
+		s.sizer = sizer
+		return before, config
+	}, nil
+}
@@ -2,9 +2,12 @@ package strategy
 
 import (
 	"context"
+	"fmt"
 	"time"
 	"trading-bot/pkg/domain"
 	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/usecase/exit"
+	"trading-bot/pkg/usecase/sizing"
 	"trading-bot/pkg/usecase/trade"
 
 	"github.com/BurntSushi/toml"
@@ -20,6 +23,61 @@ type RangeConfig struct {
 	BBandsNBDevUp          float64 `toml:"bbands_nb_dev_up"`
 	BBandsNBDevDown        float64 `toml:"bbands_nb_dev_down"`
 	BBandsMaxWidthRate     float64 `toml:"bbands_max_width_rate"`
+
+	// 決済ポリシー（未指定の利確・損切は上記の設定値を使う）
+	Exit exit.Config `toml:"exit"`
+	// 注文サイズ（未指定ならfunds_ratioを使う）
+	Sizing sizing.Config `toml:"sizing"`
+}
+
+// SizingConfig 注文サイズ用設定を取得
+func (c *RangeConfig) SizingConfig() *sizing.Config {
+	conf := c.Sizing
+	if conf.FundsRatio == 0 {
+		conf.FundsRatio = c.FundsRatio
+	}
+	return &conf
+}
+
+// ExitConfig 決済ポリシー用設定を取得
+func (c *RangeConfig) ExitConfig() *exit.Config {
+	conf := c.Exit
+	if conf.TakeProfitPer == 0 {
+		conf.TakeProfitPer = c.FixProfitUpperLimitPer
+	}
+	if conf.StopLossPer == 0 {
+		conf.StopLossPer = c.LossCutLowerLimitPer
+	}
+	return &conf
+}
+
+func (c *RangeConfig) valid() error {
+	if c.Interval == 0 {
+		return fmt.Errorf("Interval is empty, %v", c.Interval)
+	}
+	if c.TermSize == 0 {
+		return fmt.Errorf("TermSize is empty, %v", c.TermSize)
+	}
+	if c.LossCutLowerLimitPer == 0 {
+		return fmt.Errorf("LossCutLowerLimitPer is empty, %v", c.LossCutLowerLimitPer)
+	}
+	if c.FixProfitUpperLimitPer == 0 {
+		return fmt.Errorf("FixProfitUpperLimitPer is empty, %v", c.FixProfitUpperLimitPer)
+	}
+	if c.BBandsNBDevUp == 0 {
+		return fmt.Errorf("BBandsNBDevUp is empty, %v", c.BBandsNBDevUp)
+	}
+	if c.BBandsNBDevDown == 0 {
+		return fmt.Errorf("BBandsNBDevDown is empty, %v", c.BBandsNBDevDown)
+	}
+	if c.BBandsMaxWidthRate == 0 {
+		return fmt.Errorf("BBandsMaxWidthRate is empty, %v", c.BBandsMaxWidthRate)
+	}
+	// FundsRatioはfixed_fractional（sizing未指定を含む）のときのみ必須で、sizing.Newで確認する
+	if _, err := sizing.New(c.SizingConfig()); err != nil {
+		return fmt.Errorf("Sizing is invalid, %w", err)
+	}
+	return nil
 }
 
 func NewRangeConfig(f string) (*RangeConfig, error) {
@@ -27,6 +85,9 @@ func NewRangeConfig(f string) (*RangeConfig, error) {
 	if _, err := toml.DecodeFile(f, &conf); err != nil {
 		return nil, err
 	}
+	if err := conf.valid(); err != nil {
+		return nil, fmt.Errorf("[%s] validation error: %w", f, err)
+	}
 	return &conf, nil
 }
 
@@ -34,14 +95,22 @@ type RangeStrategy struct {
 	logger domain.Logger
 	facade *trade.Facade
 
-	config *RangeConfig
+	config    *RangeConfig
+	exitChain *exit.Chain
+	sizer     sizing.Sizer
 }
 
 func NewRangeStrategy(facade *trade.Facade, logger domain.Logger, config *RangeConfig) (*RangeStrategy, error) {
+	sizer, err := sizing.New(config.SizingConfig())
+	if err != nil {
+		return nil, err
+	}
 	return &RangeStrategy{
-		logger: logger,
-		facade: facade,
-		config: config,
+		logger:    logger,
+		facade:    facade,
+		config:    config,
+		exitChain: exit.NewChainFromConfig(facade, logger, config.ExitConfig()),
+		sizer:     sizer,
 	}, nil
 }
 
@@ -81,19 +150,24 @@ func (s *RangeStrategy) Buy(p model.CurrencyPair, positions []model.Position) er
 	}
 	s.logger.Debug("[buy] => should buy (rate:%.3f <= bband lower:%.3f)", rate, bbLower)
 
-	return s.buy(&p)
+	return s.buy(&p, rates)
 }
 
 func (s *RangeStrategy) BuyTradeCallback(p model.CurrencyPair, rate float64) error {
 	return nil
 }
 
-func (s *RangeStrategy) buy(p *model.CurrencyPair) error {
-	balance, err := s.facade.GetJpyBalance()
+func (s *RangeStrategy) buy(p *model.CurrencyPair, rates []float64) error {
+	in, err := sizing.NewInput(s.facade, s.sizer, p, rates, s.config.ExitConfig().StopLossPer)
 	if err != nil {
 		return err
 	}
-	amount := balance.Amount * s.config.FundsRatio
+	amount, reason := s.sizer.Size(in)
+	if amount <= 0 {
+		s.logger.Debug("[buy] => skip buy (%s amount:%.3f)(%s)", s.sizer.Name(), amount, reason)
+		return nil
+	}
+	s.logger.Debug("[buy] %s amount:%.3f (%s)", s.sizer.Name(), amount, reason)
 
 	s.logger.Debug("[buy] sending buy order ...")
 	pos, err := s.facade.SendMarketBuyOrder(p, amount, nil)
@@ -124,16 +198,13 @@ func (s *RangeStrategy) Sell(pair model.CurrencyPair, positions []model.Position
 	if err != nil {
 		return err
 	}
+	rate := rates[len(rates)-1]
 	for _, p := range positions {
-		shouldFixProfit, err := s.shouldFixProfit(&pair, rates, &p)
-		if err != nil {
-			return err
-		}
-		shouldLossCut, err := s.shouldLossCut(&pair, rates, &p)
+		result, err := s.exitChain.Check(&p, rate, rates)
 		if err != nil {
 			return err
 		}
-		if shouldSell || shouldFixProfit || shouldLossCut {
+		if shouldSell || result != nil {
 			if err := s.sell(&pair, &p); err != nil {
 				return err
 			}
@@ -166,86 +237,6 @@ func (s *RangeStrategy) shouldSell(pair *model.CurrencyPair, rates []float64, po
 	return true, nil
 }
 
-// shouldFixProfit 利確すべきか判定
-func (s *RangeStrategy) shouldFixProfit(pair *model.CurrencyPair, rates []float64, pos *model.Position) (bool, error) {
-	contracts, err := s.facade.GetContracts(pos.OpenerOrder.ID)
-	if err != nil {
-		return false, err
-	}
-	var buyJPY float64
-	var amount float64
-	for _, c := range contracts {
-		buyJPY += float64(-c.DecreaseAmount)
-		amount += float64(c.IncreaseAmount)
-	}
-	upperLimit := buyJPY * s.config.FixProfitUpperLimitPer
-	sellJPY := rates[len(rates)-1] * amount
-
-	// 上限以下なら利確しない
-	if sellJPY <= upperLimit {
-		s.logger.Debug(
-			"[pos:%d][fixProfit] => skip fix profit (sell[jpy:%.3f] <= upper limit[jpy:%.3f] = buy[jpy:%.3f] * %.3f)",
-			pos.ID,
-			sellJPY,
-			upperLimit,
-			buyJPY,
-			s.config.FixProfitUpperLimitPer,
-		)
-
-		return false, nil
-	}
-
-	s.logger.Debug(
-		"[pos:%d][losscut] => should fix profit (sell[jpy:%.3f] > upper limit[jpy:%.3f] = buy[jpy:%.3f] * %.3f)",
-		pos.ID,
-		sellJPY,
-		upperLimit,
-		buyJPY,
-		s.config.FixProfitUpperLimitPer,
-	)
-	return true, nil
-}
-
-// ShouldLossCut ロスカットすべきか判定
-func (s *RangeStrategy) shouldLossCut(pair *model.CurrencyPair, rates []float64, pos *model.Position) (bool, error) {
-	contracts, err := s.facade.GetContracts(pos.OpenerOrder.ID)
-	if err != nil {
-		return false, err
-	}
-	var buyJPY float64
-	var amount float64
-	for _, c := range contracts {
-		buyJPY += float64(-c.DecreaseAmount)
-		amount += float64(c.IncreaseAmount)
-	}
-	lowerLimit := buyJPY * s.config.LossCutLowerLimitPer
-	sellJPY := rates[len(rates)-1] * amount
-
-	// 下限以上ならロスカットしない
-	if sellJPY >= lowerLimit {
-		s.logger.Debug(
-			"[pos:%d][losscut] => skip loss cut (sell[jpy:%.3f] >= lower limit[jpy:%.3f] = buy[jpy:%.3f] * %.3f)",
-			pos.ID,
-			sellJPY,
-			lowerLimit,
-			buyJPY,
-			s.config.LossCutLowerLimitPer,
-		)
-
-		return false, nil
-	}
-
-	s.logger.Debug(
-		"[pos:%d][losscut] => should loss cut (sell[jpy:%.3f] < lower limit[jpy:%.3f] = buy[jpy:%.3f] * %.3f)",
-		pos.ID,
-		sellJPY,
-		lowerLimit,
-		buyJPY,
-		s.config.LossCutLowerLimitPer,
-	)
-	return true, nil
-}
-
 func (s *RangeStrategy) sell(pair *model.CurrencyPair, p *model.Position) error {
 	contracts, err := s.facade.GetContracts(p.OpenerOrder.ID)
 	if err != nil {
@@ -274,3 +265,28 @@ func (s *RangeStrategy) Wait(ctx context.Context) error {
 	s.logger.Debug("waiting ... (%v)\n", s.config.Interval)
 	return s.facade.Wait(ctx, time.Duration(s.config.Interval))
 }
+
+// ReloadConfig 設定を再読み込み
+func (s *RangeStrategy) ReloadConfig(path string) (interface{}, interface{}, error) {
+	return reload(s, path)
+}
+
+// PrepareReload 設定を検証して適用する関数を返す
+func (s *RangeStrategy) PrepareReload(path string) (func() (interface{}, interface{}), error) {
+	config, err := NewRangeConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	sizer, err := sizing.New(config.SizingConfig())
+	if err != nil {
+		return nil, err
+	}
+
+	return func() (interface{}, interface{}) {
+		before := s.config
+		s.config = config
+		s.exitChain = exit.NewChainFromConfig(s.facade, s.logger, config.ExitConfig())
+		s.sizer = sizer
+		return before, config
+	}, nil
+}
@@ -0,0 +1,46 @@
+package strategy_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"trading-bot/pkg/usecase/strategy"
+)
+
+func TestNewRangeConfig(t *testing.T) {
+	base := []string{
+		"interval_seconds = 10",
+		"term_size = 100",
+		"loss_cut_lower_limit_per = 0.927",
+		"fix_profit_upper_limit_per = 1.593",
+		"bbands_nb_dev_up = 2.0",
+		"bbands_nb_dev_down = 2.0",
+		"bbands_max_width_rate = 0.01",
+	}
+	tests := map[string]struct {
+		conf    []string
+		wantErr bool
+	}{
+		"funds ratio":                    {conf: []string{"funds_ratio = 0.3"}},
+		"no funds ratio":                 {conf: []string{}, wantErr: true},
+		"fixed_fractional":               {conf: []string{"[sizing]", "model = \"fixed_fractional\"", "funds_ratio = 0.3"}},
+		"fixed_fractional without ratio": {conf: []string{"[sizing]", "model = \"fixed_fractional\""}, wantErr: true},
+		"fixed_jpy":                      {conf: []string{"[sizing]", "model = \"fixed_jpy\"", "amount_jpy = 1000.0"}},
+		"fixed_risk":                     {conf: []string{"[sizing]", "model = \"fixed_risk\"", "risk_per = 0.01"}},
+		"fixed_risk without risk":        {conf: []string{"[sizing]", "model = \"fixed_risk\""}, wantErr: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "bot-range.toml")
+			conf := append(append([]string{}, base...), tt.conf...)
+			if err := ioutil.WriteFile(path, []byte(strings.Join(conf, "\n")), 0600); err != nil {
+				t.Fatal(err)
+			}
+			_, err := strategy.NewRangeConfig(path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewRangeConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package strategy
+
+// preparable 設定の検証と適用を分けて再読み込みできる戦略
+type preparable interface {
+	PrepareReload(path string) (func() (interface{}, interface{}), error)
+}
+
+// reload 設定を検証して、問題なければ適用する
+func reload(s preparable, path string) (interface{}, interface{}, error) {
+	apply, err := s.PrepareReload(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	before, after := apply()
+	return before, after, nil
+}
@@ -3,6 +3,7 @@ package strategy
 import (
 	"trading-bot/pkg/domain"
 	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/usecase/exit"
 	"trading-bot/pkg/usecase/trade"
 
 	"github.com/BurntSushi/toml"
@@ -34,7 +35,9 @@ type Scalping struct {
 	logger domain.Logger
 	facade *trade.Facade
 
-	config *ScalpingConfig
+	config         *ScalpingConfig
+	fixProfitChain *exit.Chain
+	lossCutChain   *exit.Chain
 }
 
 // NewScalpingStrategy 戦略を生成
@@ -43,6 +46,10 @@ func NewScalpingStrategy(facade *trade.Facade, logger domain.Logger, config *Sca
 		logger: logger,
 		facade: facade,
 		config: config,
+		fixProfitChain: exit.NewChain(facade, logger,
+			&exit.TakeProfit{Per: config.FixProfitUpperLimitPer}),
+		lossCutChain: exit.NewChain(facade, logger,
+			&exit.StopLoss{Per: config.LossCutLowerLimitPer}),
 	}
 
 	return s, nil
@@ -183,42 +190,11 @@ func (s *Scalping) shouldFixProfit(pair *model.CurrencyPair, rates []float64, po
 		return false, nil
 	}
 
-	contracts, err := s.facade.GetContracts(pos.OpenerOrder.ID)
+	result, err := s.fixProfitChain.Check(pos, rates[len(rates)-1], rates)
 	if err != nil {
 		return false, err
 	}
-	var buyJPY float64
-	var amount float64
-	for _, c := range contracts {
-		buyJPY += float64(-c.DecreaseAmount)
-		amount += float64(c.IncreaseAmount)
-	}
-	upperLimit := buyJPY * s.config.FixProfitUpperLimitPer
-	sellJPY := rates[len(rates)-1] * amount
-
-	// 上限以下なら利確しない
-	if sellJPY <= upperLimit {
-		s.logger.Debug(
-			"[pos:%d][fixProfit] => skip fix profit (sell[jpy:%.3f] <= upper limit[jpy:%.3f] = buy[jpy:%.3f] * %.3f)",
-			pos.ID,
-			sellJPY,
-			upperLimit,
-			buyJPY,
-			s.config.FixProfitUpperLimitPer,
-		)
-
-		return false, nil
-	}
-
-	s.logger.Debug(
-		"[pos:%d][losscut] => should fix profit (sell[jpy:%.3f] > upper limit[jpy:%.3f] = buy[jpy:%.3f] * %.3f)",
-		pos.ID,
-		sellJPY,
-		upperLimit,
-		buyJPY,
-		s.config.FixProfitUpperLimitPer,
-	)
-	return true, nil
+	return result != nil, nil
 }
 
 // ShouldLossCut ロスカットすべきか判定
@@ -240,42 +216,11 @@ func (s *Scalping) shouldLossCut(pair *model.CurrencyPair, rates []float64, pos
 		return false, nil
 	}
 
-	contracts, err := s.facade.GetContracts(pos.OpenerOrder.ID)
+	result, err := s.lossCutChain.Check(pos, rates[len(rates)-1], rates)
 	if err != nil {
 		return false, err
 	}
-	var buyJPY float64
-	var amount float64
-	for _, c := range contracts {
-		buyJPY += float64(-c.DecreaseAmount)
-		amount += float64(c.IncreaseAmount)
-	}
-	lowerLimit := buyJPY * s.config.LossCutLowerLimitPer
-	sellJPY := rates[len(rates)-1] * amount
-
-	// 下限以上ならロスカットしない
-	if sellJPY >= lowerLimit {
-		s.logger.Debug(
-			"[pos:%d][losscut] => skip loss cut (sell[jpy:%.3f] >= lower limit[jpy:%.3f] = buy[jpy:%.3f] * %.3f)",
-			pos.ID,
-			sellJPY,
-			lowerLimit,
-			buyJPY,
-			s.config.LossCutLowerLimitPer,
-		)
-
-		return false, nil
-	}
-
-	s.logger.Debug(
-		"[pos:%d][losscut] => should loss cut (sell[jpy:%.3f] < lower limit[jpy:%.3f] = buy[jpy:%.3f] * %.3f)",
-		pos.ID,
-		sellJPY,
-		lowerLimit,
-		buyJPY,
-		s.config.LossCutLowerLimitPer,
-	)
-	return true, nil
+	return result != nil, nil
 }
 
 func (s *Scalping) sell(pair *model.CurrencyPair, p *model.Position) error {
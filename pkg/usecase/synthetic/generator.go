@@ -0,0 +1,208 @@
+package synthetic
+
+import (
+	"math"
+	"math/rand"
+	"time"
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/infrastructure/memory"
+)
+
+const (
+	// year ドリフトとボラティリティの年率換算に使う1年の長さ
+	year = 365 * 24 * time.Hour
+	// minVolatility 取引量の計算でボラティリティが0のときに使う値（年率）
+	minVolatility = 0.01
+	// tickNoise 取引のレートのばらつき（レートの間の標準偏差に対する割合）
+	tickNoise = 0.3
+)
+
+// Market 生成したレートと取引
+type Market struct {
+	Scenario *Scenario
+	// レート（ExchangeMockのレートと同じ形式、取引量は前のレートからの間の取引量）
+	Rates []memory.Rate
+	// 取引（日時順、レートの間の取引の数量の合計はそのレートの取引量と同じ）
+	Ticks []model.Trade
+}
+
+// Generate シナリオからレートと取引を生成（同じシナリオなら同じデータ）
+func Generate(s *Scenario, pair model.CurrencyPair) (*Market, error) {
+	if err := s.valid(); err != nil {
+		return nil, err
+	}
+	start, _ := s.start()
+	interval := time.Duration(s.IntervalSeconds) * time.Second
+	dt := float64(interval) / float64(year)
+	rnd := rand.New(rand.NewSource(s.Seed))
+
+	m := &Market{
+		Scenario: s,
+		Rates:    []memory.Rate{rate(s, start, s.InitialRate, 0, 0)},
+		Ticks:    []model.Trade{},
+	}
+	p := newProcess(s, dt)
+	prev := s.InitialRate
+	for i := 1; i < s.Steps; i++ {
+		sigma := p.next(i, rnd)
+		price := round(math.Exp(p.logPrice()), 1)
+		from, to := start.Add(time.Duration(i-1)*interval), start.Add(time.Duration(i)*interval)
+
+		// 変動が大きいほど取引量を増やし、上げたときは買い、下げたときは売りを多くする
+		typical := math.Max(sigma, minVolatility) * math.Sqrt(dt)
+		z := math.Log(price/prev) / typical
+		volume := s.BaseVolume * math.Exp(0.25*rnd.NormFloat64()) * (1 + s.VolumeSensitivity*math.Min(math.Abs(z), 10)) / (1 + s.VolumeSensitivity*0.8)
+		buyShare := 0.5 + 0.4*math.Tanh(z)
+		buyVolume, sellVolume := round(volume*buyShare, 8), round(volume*(1-buyShare), 8)
+
+		if s.TicksPerStep > 0 && volume > 0 {
+			ticks := m.ticks(s, pair, rnd, from, interval, prev, price, typical, volume, buyShare)
+			buyVolume, sellVolume = 0, 0
+			for _, t := range ticks {
+				if t.Side == model.BuySide {
+					buyVolume += t.Amount
+				} else {
+					sellVolume += t.Amount
+				}
+			}
+			m.Ticks = append(m.Ticks, ticks...)
+		}
+		m.Rates = append(m.Rates, rate(s, to, price, buyVolume, sellVolume))
+		prev = price
+	}
+	return m, nil
+}
+
+// ticks レートの間の取引（日時はレートの間に収め、レートは前後のレートの間を補間してばらつかせる）
+func (m *Market) ticks(s *Scenario, pair model.CurrencyPair, rnd *rand.Rand, from time.Time, interval time.Duration, prev, price, typical, volume, buyShare float64) []model.Trade {
+	n := poisson(rnd, s.TicksPerStep)
+	if n == 0 {
+		n = 1
+	}
+	weights, total := make([]float64, n), 0.0
+	for k := range weights {
+		weights[k] = rnd.ExpFloat64()
+		total += weights[k]
+	}
+	ticks := []model.Trade{}
+	for k := 0; k < n; k++ {
+		frac := (float64(k) + 0.25 + 0.5*rnd.Float64()) / float64(n)
+		logRate := math.Log(prev) + (math.Log(price)-math.Log(prev))*frac + tickNoise*typical*rnd.NormFloat64()
+		side := model.SellSide
+		if rnd.Float64() < buyShare {
+			side = model.BuySide
+		}
+		amount := round(volume*weights[k]/total, 8)
+		if amount <= 0 {
+			continue
+		}
+		ticks = append(ticks, model.Trade{
+			ID:        uint64(len(m.Ticks) + len(ticks) + 1),
+			Pair:      pair,
+			Rate:      round(math.Exp(logRate), 1),
+			Amount:    amount,
+			Side:      side,
+			CreatedAt: from.Add(time.Duration(frac * float64(interval))),
+		})
+	}
+	return ticks
+}
+
+func rate(s *Scenario, t time.Time, sell, buyVolume, sellVolume float64) memory.Rate {
+	buy := round(sell*(1+s.Spread), 1)
+	store := round(sell*(1+s.Spread/2), 1)
+	return memory.NewRateWithVolumes(t, store, buy, sell, buyVolume, sellVolume)
+}
+
+// process 対数価格の生成過程
+type process struct {
+	s  *Scenario
+	dt float64
+	// 対数価格（戻る急変を除く）と戻る急変の分
+	base   float64
+	offset float64
+	// 平均回帰する対数価格（ou）
+	mean float64
+	// 現在のレジーム（regime）
+	regime int
+}
+
+func newProcess(s *Scenario, dt float64) *process {
+	p := &process{s: s, dt: dt, base: math.Log(s.InitialRate)}
+	p.mean = p.base
+	if s.LongTermRate > 0 {
+		p.mean = math.Log(s.LongTermRate)
+	}
+	return p
+}
+
+func (p *process) logPrice() float64 {
+	return p.base + p.offset
+}
+
+// next 1ステップ進める（そのステップのボラティリティを返す）
+func (p *process) next(step int, rnd *rand.Rand) float64 {
+	s, dt := p.s, p.dt
+	sigma := s.Volatility
+	switch s.Model {
+	case GBM:
+		p.base += gbm(s.Drift, sigma, dt, rnd)
+	case Jump:
+		p.base += gbm(s.Drift, sigma, dt, rnd)
+		for k := poisson(rnd, s.JumpIntensity*dt); k > 0; k-- {
+			p.base += s.JumpMean + s.JumpStd*rnd.NormFloat64()
+		}
+	case Regime:
+		if len(s.Regimes) > 1 && rnd.Float64() < s.SwitchProbability {
+			// 今と違うレジームに等確率で切り替える
+			p.regime = (p.regime + 1 + rnd.Intn(len(s.Regimes)-1)) % len(s.Regimes)
+		}
+		r := s.Regimes[p.regime]
+		sigma = r.Volatility
+		p.base += gbm(r.Drift, sigma, dt, rnd)
+	case OU:
+		p.base += s.MeanReversion*(p.mean-p.base)*dt + sigma*math.Sqrt(dt)*rnd.NormFloat64()
+	}
+
+	// 戻らない急変は価格を動かし、戻る急変はRecoveryStepsで線形に戻す
+	p.offset = 0
+	for _, shock := range s.Shocks {
+		jump := math.Log(1 + shock.Return)
+		if shock.RecoverySteps <= 0 {
+			if step == shock.Step {
+				p.base += jump
+			}
+			continue
+		}
+		if step >= shock.Step && step < shock.Step+shock.RecoverySteps {
+			p.offset += jump * (1 - float64(step-shock.Step)/float64(shock.RecoverySteps))
+		}
+	}
+	return sigma
+}
+
+// gbm 幾何ブラウン運動の対数変化率
+func gbm(drift, sigma, dt float64, rnd *rand.Rand) float64 {
+	return (drift-sigma*sigma/2)*dt + sigma*math.Sqrt(dt)*rnd.NormFloat64()
+}
+
+// poisson ポアソン分布の乱数（平均が小さい前提）
+func poisson(rnd *rand.Rand, lambda float64) int {
+	if lambda <= 0 {
+		return 0
+	}
+	l, k, p := math.Exp(-lambda), 0, 1.0
+	for {
+		p *= rnd.Float64()
+		if p <= l {
+			return k
+		}
+		k++
+	}
+}
+
+// round 小数点以下digits桁に丸める
+func round(v float64, digits int) float64 {
+	p := math.Pow10(digits)
+	return math.Round(v*p) / p
+}
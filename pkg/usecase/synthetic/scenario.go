@@ -0,0 +1,249 @@
+package synthetic
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ModelType 価格の生成モデル
+type ModelType string
+
+const (
+	// GBM 幾何ブラウン運動
+	GBM ModelType = "gbm"
+	// Jump ジャンプ拡散（幾何ブラウン運動にポアソン過程のジャンプを加える）
+	Jump ModelType = "jump"
+	// Regime レジームスイッチ（ステップごとの確率でドリフトとボラティリティの組を切り替える）
+	Regime ModelType = "regime"
+	// OU 対数価格の平均回帰（オルンシュタイン＝ウーレンベック過程）
+	OU ModelType = "ou"
+)
+
+// defaultStart 開始日時の既定値
+var defaultStart = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// RegimeParams レジームごとのドリフトとボラティリティ（年率）
+type RegimeParams struct {
+	Name       string  `toml:"name"`
+	Drift      float64 `toml:"drift"`
+	Volatility float64 `toml:"volatility"`
+}
+
+// Shock 台本で決めた急変
+type Shock struct {
+	// 急変するステップ
+	Step int `toml:"step"`
+	// 変化率（-0.3なら30%下落）
+	Return float64 `toml:"return"`
+	// 元の価格に戻るまでのステップ数（0なら戻らない）
+	RecoverySteps int `toml:"recovery_steps"`
+}
+
+// Expect 回帰テストで確認する成績の条件（0なら確認しない）
+type Expect struct {
+	// 最大ドローダウン（ピークに対する割合）の上限
+	MaxDrawdownRatio float64 `toml:"max_drawdown_ratio" json:"max_drawdown_ratio"`
+	// 損益率の下限（-0.1なら10%の損失まで）
+	MinReturn float64 `toml:"min_return" json:"min_return"`
+}
+
+// Scenario 生成するシナリオ
+type Scenario struct {
+	Name        string `toml:"name"`
+	Description string `toml:"description"`
+	// 乱数の種（同じなら同じデータになる）
+	Seed int64 `toml:"seed"`
+	// 開始日時（RFC3339、空なら2021-01-01T00:00:00Z）
+	Start string `toml:"start"`
+	// レートの間隔（秒）とレートの数
+	IntervalSeconds int `toml:"interval_seconds"`
+	Steps           int `toml:"steps"`
+	// 最初の売りレートと買いレートの売りレートに対する開き
+	InitialRate float64 `toml:"initial_rate"`
+	Spread      float64 `toml:"spread"`
+
+	Model ModelType `toml:"model"`
+	// ドリフトとボラティリティ（年率、gbm, jump, ou）
+	Drift      float64 `toml:"drift"`
+	Volatility float64 `toml:"volatility"`
+	// ジャンプの頻度（年あたりの回数）と大きさ（対数変化率の平均と標準偏差、jump）
+	JumpIntensity float64 `toml:"jump_intensity"`
+	JumpMean      float64 `toml:"jump_mean"`
+	JumpStd       float64 `toml:"jump_std"`
+	// 平均回帰の速さ（年率）と回帰する価格（0なら最初のレート、ou）
+	MeanReversion float64 `toml:"mean_reversion"`
+	LongTermRate  float64 `toml:"long_term_rate"`
+	// レジームとステップごとに切り替わる確率（regime）
+	Regimes           []RegimeParams `toml:"regimes"`
+	SwitchProbability float64        `toml:"switch_probability"`
+
+	Shocks []Shock `toml:"shocks"`
+
+	// レートの間の平均取引量と変動率の大きさに対する取引量の感応度
+	BaseVolume        float64 `toml:"base_volume"`
+	VolumeSensitivity float64 `toml:"volume_sensitivity"`
+	// レートの間の平均取引数（0なら取引を生成しない）
+	TicksPerStep float64 `toml:"ticks_per_step"`
+
+	Expect Expect `toml:"expect"`
+}
+
+func (s *Scenario) valid() error {
+	if s.Name == "" {
+		return fmt.Errorf("name is empty, %v", s.Name)
+	}
+	if s.IntervalSeconds <= 0 {
+		return fmt.Errorf("interval_seconds must be positive, (scenario:%s, interval:%d)", s.Name, s.IntervalSeconds)
+	}
+	if s.Steps < 2 {
+		return fmt.Errorf("steps must be at least 2, (scenario:%s, steps:%d)", s.Name, s.Steps)
+	}
+	if s.InitialRate <= 0 {
+		return fmt.Errorf("initial_rate must be positive, (scenario:%s, rate:%v)", s.Name, s.InitialRate)
+	}
+	if s.Spread < 0 || s.Volatility < 0 || s.BaseVolume < 0 || s.TicksPerStep < 0 {
+		return fmt.Errorf("spread, volatility, base_volume and ticks_per_step must not be negative, (scenario:%s)", s.Name)
+	}
+	if _, err := s.start(); err != nil {
+		return err
+	}
+	switch s.Model {
+	case GBM, Jump, OU:
+	case Regime:
+		if len(s.Regimes) == 0 {
+			return fmt.Errorf("regimes is empty, (scenario:%s)", s.Name)
+		}
+	default:
+		return fmt.Errorf("model is unknown, (scenario:%s, model:%s)", s.Name, s.Model)
+	}
+	for _, shock := range s.Shocks {
+		if shock.Return <= -1 {
+			return fmt.Errorf("shock return must be greater than -1, (scenario:%s, return:%v)", s.Name, shock.Return)
+		}
+	}
+	return nil
+}
+
+func (s *Scenario) start() (time.Time, error) {
+	if s.Start == "" {
+		return defaultStart, nil
+	}
+	t, err := time.Parse(time.RFC3339, s.Start)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("start is invalid, (scenario:%s, start:%s)", s.Name, s.Start)
+	}
+	return t, nil
+}
+
+// Violations 成績が条件を満たさない項目（満たしていれば空）
+func (s *Scenario) Violations(maxDrawdownRatio, ret float64) []string {
+	violations := []string{}
+	if s.Expect.MaxDrawdownRatio > 0 && maxDrawdownRatio > s.Expect.MaxDrawdownRatio {
+		violations = append(violations, fmt.Sprintf("max drawdown ratio %.4f > %.4f", maxDrawdownRatio, s.Expect.MaxDrawdownRatio))
+	}
+	if s.Expect.MinReturn != 0 && ret < s.Expect.MinReturn {
+		violations = append(violations, fmt.Sprintf("return %.4f < %.4f", ret, s.Expect.MinReturn))
+	}
+	return violations
+}
+
+// LoadScenarios シナリオの設定ファイル（[[scenario]]の配列）を読み込む
+func LoadScenarios(f string) ([]Scenario, error) {
+	var conf struct {
+		Scenario []Scenario `toml:"scenario"`
+	}
+	if _, err := toml.DecodeFile(f, &conf); err != nil {
+		return nil, err
+	}
+	if len(conf.Scenario) == 0 {
+		return nil, fmt.Errorf("scenario is empty, %v", f)
+	}
+	for i := range conf.Scenario {
+		if err := conf.Scenario[i].valid(); err != nil {
+			return nil, err
+		}
+	}
+	return conf.Scenario, nil
+}
+
+// Library 組み込みのシナリオ（名前順）
+// 1分足で1日分、最初のレートは500万円で、取引量と取引も生成する
+func Library() []Scenario {
+	base := Scenario{
+		IntervalSeconds:   60,
+		Steps:             24 * 60,
+		InitialRate:       5000000,
+		Spread:            0.0002,
+		Model:             GBM,
+		Volatility:        0.6,
+		BaseVolume:        2,
+		VolumeSensitivity: 1,
+		TicksPerStep:      4,
+	}
+	scenarios := []Scenario{}
+	add := func(name, description string, seed int64, update func(s *Scenario)) {
+		s := base
+		s.Name, s.Description, s.Seed = name, description, seed
+		update(&s)
+		scenarios = append(scenarios, s)
+	}
+	add("crash", "gradual decline followed by a 30% crash that never recovers", 1, func(s *Scenario) {
+		s.Drift = -10
+		s.Shocks = []Shock{{Step: 12 * 60, Return: -0.3}}
+		s.Expect = Expect{MaxDrawdownRatio: 0.5}
+	})
+	add("flash_spike", "15% spike that fully reverts within 10 minutes", 2, func(s *Scenario) {
+		s.Shocks = []Shock{{Step: 8 * 60, Return: 0.15, RecoverySteps: 10}}
+		s.Expect = Expect{MaxDrawdownRatio: 0.3}
+	})
+	add("flash_crash", "20% drop that fully reverts within 15 minutes", 3, func(s *Scenario) {
+		s.Shocks = []Shock{{Step: 8 * 60, Return: -0.2, RecoverySteps: 15}}
+		s.Expect = Expect{MaxDrawdownRatio: 0.3}
+	})
+	add("dead_range", "low-volatility mean-reverting range with thin volume", 4, func(s *Scenario) {
+		s.Model = OU
+		s.Volatility = 0.1
+		s.MeanReversion = 200
+		s.BaseVolume = 0.2
+		s.TicksPerStep = 1
+		s.Expect = Expect{MaxDrawdownRatio: 0.1}
+	})
+	add("bull_trend", "steady uptrend", 5, func(s *Scenario) {
+		s.Drift = 20
+		s.Volatility = 0.4
+		s.Expect = Expect{MaxDrawdownRatio: 0.2}
+	})
+	add("jump_diffusion", "volatile market with frequent jumps in both directions", 6, func(s *Scenario) {
+		s.Model = Jump
+		s.JumpIntensity = 365 * 6
+		s.JumpStd = 0.02
+		s.Expect = Expect{MaxDrawdownRatio: 0.4}
+	})
+	add("regime_switch", "switches between calm, trending and turbulent regimes", 7, func(s *Scenario) {
+		s.Model = Regime
+		s.Regimes = []RegimeParams{
+			{Name: "calm", Volatility: 0.2},
+			{Name: "trend", Drift: 40, Volatility: 0.5},
+			{Name: "turbulent", Drift: -40, Volatility: 2},
+		}
+		s.SwitchProbability = 0.01
+		s.Expect = Expect{MaxDrawdownRatio: 0.4}
+	})
+	sort.Slice(scenarios, func(i, j int) bool { return scenarios[i].Name < scenarios[j].Name })
+	return scenarios
+}
+
+// Find 名前でシナリオを探す
+func Find(scenarios []Scenario, name string) (*Scenario, error) {
+	names := []string{}
+	for i := range scenarios {
+		if scenarios[i].Name == name {
+			return &scenarios[i], nil
+		}
+		names = append(names, scenarios[i].Name)
+	}
+	return nil, fmt.Errorf("scenario is not found, (name:%s, scenarios:%v)", name, names)
+}
@@ -0,0 +1,257 @@
+package synthetic_test
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/infrastructure/memory"
+	"trading-bot/pkg/usecase/synthetic"
+)
+
+var pair = model.CurrencyPair{Key: model.BTC, Settlement: model.JPY}
+
+func scenario(update func(s *synthetic.Scenario)) *synthetic.Scenario {
+	s := &synthetic.Scenario{
+		Name:              "test",
+		Seed:              1,
+		IntervalSeconds:   60,
+		Steps:             200,
+		InitialRate:       1000000,
+		Spread:            0.001,
+		Model:             synthetic.GBM,
+		Volatility:        0.5,
+		BaseVolume:        1,
+		VolumeSensitivity: 1,
+		TicksPerStep:      3,
+	}
+	if update != nil {
+		update(s)
+	}
+	return s
+}
+
+func TestGenerate(t *testing.T) {
+	tests := map[string]struct {
+		update func(s *synthetic.Scenario)
+	}{
+		"gbm": {},
+		"jump": {update: func(s *synthetic.Scenario) {
+			s.Model = synthetic.Jump
+			s.JumpIntensity = 365 * 24 * 10
+			s.JumpStd = 0.01
+		}},
+		"regime": {update: func(s *synthetic.Scenario) {
+			s.Model = synthetic.Regime
+			s.Regimes = []synthetic.RegimeParams{{Volatility: 0.1}, {Drift: 10, Volatility: 1}}
+			s.SwitchProbability = 0.1
+		}},
+		"ou": {update: func(s *synthetic.Scenario) {
+			s.Model = synthetic.OU
+			s.MeanReversion = 100
+		}},
+		"without ticks": {update: func(s *synthetic.Scenario) {
+			s.TicksPerStep = 0
+		}},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := scenario(tt.update)
+			m, err := synthetic.Generate(s, pair)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(m.Rates) != s.Steps {
+				t.Fatalf("rates = %d, want %d", len(m.Rates), s.Steps)
+			}
+			if (len(m.Ticks) > 0) != (s.TicksPerStep > 0) {
+				t.Fatalf("ticks = %d, ticks per step = %v", len(m.Ticks), s.TicksPerStep)
+			}
+
+			// 取引はレートの間に収まり、その数量の合計がレートの取引量になる
+			k := 0
+			for i, r := range m.Rates {
+				if want := m.Rates[0].Time.Add(time.Duration(i) * time.Minute); !r.Time.Equal(want) {
+					t.Fatalf("time[%d] = %v, want %v", i, r.Time, want)
+				}
+				if r.OrderSellRate <= 0 || r.OrderBuyRate < r.OrderSellRate {
+					t.Fatalf("rate[%d] = buy %v, sell %v", i, r.OrderBuyRate, r.OrderSellRate)
+				}
+				if i == 0 || s.TicksPerStep == 0 {
+					continue
+				}
+				buy, sell := 0.0, 0.0
+				for ; k < len(m.Ticks) && m.Ticks[k].CreatedAt.Before(r.Time); k++ {
+					if !m.Ticks[k].CreatedAt.After(m.Rates[i-1].Time) {
+						t.Fatalf("tick %d at %v is not after %v", k, m.Ticks[k].CreatedAt, m.Rates[i-1].Time)
+					}
+					if m.Ticks[k].Side == model.BuySide {
+						buy += m.Ticks[k].Amount
+					} else {
+						sell += m.Ticks[k].Amount
+					}
+				}
+				if math.Abs(buy-r.BuyVolume) > 1e-9 || math.Abs(sell-r.SellVolume) > 1e-9 {
+					t.Fatalf("volume[%d] = %v / %v, ticks %v / %v", i, r.BuyVolume, r.SellVolume, buy, sell)
+				}
+			}
+			if k != len(m.Ticks) {
+				t.Fatalf("ticks after the last rate: %d", len(m.Ticks)-k)
+			}
+
+			again, _ := synthetic.Generate(s, pair)
+			if !reflect.DeepEqual(m, again) {
+				t.Errorf("Generate() with the same seed is different")
+			}
+		})
+	}
+}
+
+func TestGenerate_Shock(t *testing.T) {
+	tests := map[string]struct {
+		shock synthetic.Shock
+		// 急変の直後と最後の売りレートの最初に対する比率
+		after, last float64
+	}{
+		"permanent":  {shock: synthetic.Shock{Step: 100, Return: -0.3}, after: 0.7, last: 0.7},
+		"recovering": {shock: synthetic.Shock{Step: 100, Return: 0.2, RecoverySteps: 10}, after: 1.2, last: 1},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// ボラティリティを0にして急変の分だけ動かす
+			s := scenario(func(s *synthetic.Scenario) {
+				s.Volatility = 0
+				s.Shocks = []synthetic.Shock{tt.shock}
+			})
+			m, err := synthetic.Generate(s, pair)
+			if err != nil {
+				t.Fatal(err)
+			}
+			before, after, last := m.Rates[99].OrderSellRate, m.Rates[100].OrderSellRate, m.Rates[len(m.Rates)-1].OrderSellRate
+			if before != s.InitialRate || math.Abs(after/s.InitialRate-tt.after) > 1e-6 || math.Abs(last/s.InitialRate-tt.last) > 1e-6 {
+				t.Errorf("rates = %v, %v, %v", before, after, last)
+			}
+		})
+	}
+}
+
+func TestGenerate_Invalid(t *testing.T) {
+	tests := map[string]func(s *synthetic.Scenario){
+		"no name":       func(s *synthetic.Scenario) { s.Name = "" },
+		"one step":      func(s *synthetic.Scenario) { s.Steps = 1 },
+		"no rate":       func(s *synthetic.Scenario) { s.InitialRate = 0 },
+		"unknown model": func(s *synthetic.Scenario) { s.Model = "walk" },
+		"no regimes":    func(s *synthetic.Scenario) { s.Model = synthetic.Regime },
+		"invalid start": func(s *synthetic.Scenario) { s.Start = "yesterday" },
+		"total loss":    func(s *synthetic.Scenario) { s.Shocks = []synthetic.Shock{{Step: 1, Return: -1}} },
+	}
+	for name, update := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := synthetic.Generate(scenario(update), pair); err == nil {
+				t.Errorf("Generate() must fail")
+			}
+		})
+	}
+}
+
+func TestLibrary(t *testing.T) {
+	for _, s := range synthetic.Library() {
+		s := s
+		m, err := synthetic.Generate(&s, pair)
+		if err != nil {
+			t.Fatalf("%s: %v", s.Name, err)
+		}
+
+		// CSVに書き出して読み込んだものを再生できる
+		rates, ticks := &bytes.Buffer{}, &bytes.Buffer{}
+		if err := memory.WriteRates(rates, pair, m.Rates); err != nil {
+			t.Fatal(err)
+		}
+		if err := memory.WriteTicks(ticks, pair, m.Ticks); err != nil {
+			t.Fatal(err)
+		}
+		readTicks, err := memory.ReadTicks(ticks)
+		if err != nil {
+			t.Fatal(err)
+		}
+		exCli, err := memory.NewExchangeMock(rates, 0)
+		if err != nil {
+			t.Fatalf("%s: %v", s.Name, err)
+		}
+		exCli.SetTrades(readTicks)
+		replayed := 0
+		for {
+			for {
+				if _, ok := exCli.NextTrade(); !ok {
+					break
+				}
+				replayed++
+			}
+			if !exCli.NextStep() {
+				break
+			}
+		}
+		if replayed != len(m.Ticks) {
+			t.Errorf("%s: replayed = %d, want %d", s.Name, replayed, len(m.Ticks))
+		}
+	}
+}
+
+func TestLoadScenarios(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "scenarios.toml")
+	conf := `
+[[scenario]]
+name = "custom"
+seed = 3
+interval_seconds = 300
+steps = 10
+initial_rate = 3000000.0
+model = "regime"
+switch_probability = 0.1
+regimes = [{ name = "calm", volatility = 0.1 }, { name = "storm", drift = -1.0, volatility = 2.0 }]
+shocks = [{ step = 5, return = -0.1, recovery_steps = 2 }]
+expect = { max_drawdown_ratio = 0.2 }
+`
+	if err := os.WriteFile(f, []byte(conf), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	scenarios, err := synthetic.LoadScenarios(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := synthetic.Find(scenarios, "custom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Regimes) != 2 || s.Regimes[1].Drift != -1 || len(s.Shocks) != 1 || s.Shocks[0].RecoverySteps != 2 || s.Expect.MaxDrawdownRatio != 0.2 {
+		t.Errorf("LoadScenarios() = %+v", s)
+	}
+	if _, err := synthetic.Find(scenarios, "crash"); err == nil {
+		t.Errorf("Find() with an unknown name must fail")
+	}
+}
+
+func TestScenario_Violations(t *testing.T) {
+	s := scenario(func(s *synthetic.Scenario) {
+		s.Expect = synthetic.Expect{MaxDrawdownRatio: 0.2, MinReturn: -0.1}
+	})
+	tests := map[string]struct {
+		maxDrawdownRatio, ret float64
+		want                  int
+	}{
+		"pass":          {maxDrawdownRatio: 0.1, ret: -0.05, want: 0},
+		"drawdown":      {maxDrawdownRatio: 0.3, ret: 0, want: 1},
+		"drawdown loss": {maxDrawdownRatio: 0.3, ret: -0.2, want: 2},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := s.Violations(tt.maxDrawdownRatio, tt.ret); len(got) != tt.want {
+				t.Errorf("Violations() = %v, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package trade
+
+import (
+	"fmt"
+	"sync"
+	"trading-bot/pkg/domain/exchange"
+	"trading-bot/pkg/domain/model"
+)
+
+// OrderChecker 注文前の確認
+type OrderChecker interface {
+	// CheckOrder 注文可能か確認し、不可ならエラーを返す
+	CheckOrder(o *model.NewOrder, p *model.Position) error
+}
+
+// OrderSentHandler 確認を通過した注文の送信後の処理（必要なOrderCheckerのみ実装する）
+type OrderSentHandler interface {
+	// OrderSent 送信の結果を受け取る（後の確認で拒否された場合や送信に失敗した場合はエラー）
+	OrderSent(o *model.NewOrder, p *model.Position, err error)
+}
+
+// Budget 資金枠（JPY固定額または総資産に対する割合のどちらか）
+type Budget struct {
+	AmountJPY   float64
+	EquityRatio float64
+}
+
+// Allocator 複数のボットで共有する資金の割り当て
+type Allocator struct {
+	sync.Mutex
+
+	exClient    exchange.Client
+	pairs       []model.CurrencyPair
+	allocations []*Allocation
+}
+
+// NewAllocator 生成
+func NewAllocator(exCli exchange.Client) *Allocator {
+	return &Allocator{
+		exClient:    exCli,
+		pairs:       []model.CurrencyPair{},
+		allocations: []*Allocation{},
+	}
+}
+
+// Allocate 資金枠を割り当て
+func (a *Allocator) Allocate(name string, pair model.CurrencyPair, budget Budget, f *Facade) (*Allocation, error) {
+	if budget.AmountJPY <= 0 && budget.EquityRatio <= 0 {
+		return nil, fmt.Errorf("budget is empty, name = %s", name)
+	}
+	if budget.AmountJPY > 0 && budget.EquityRatio > 0 {
+		return nil, fmt.Errorf("budget must be either amount or ratio, name = %s", name)
+	}
+
+	total := budget.EquityRatio
+	for _, al := range a.allocations {
+		total += al.budget.EquityRatio
+	}
+	if total > 1 {
+		return nil, fmt.Errorf("total equity ratio exceeds 1, name = %s, total = %.3f", name, total)
+	}
+
+	al := &Allocation{
+		allocator: a,
+		name:      name,
+		budget:    budget,
+		facade:    f,
+	}
+	a.allocations = append(a.allocations, al)
+
+	found := false
+	for _, p := range a.pairs {
+		if p == pair {
+			found = true
+			break
+		}
+	}
+	if !found {
+		a.pairs = append(a.pairs, pair)
+	}
+
+	f.locker = a
+	f.allocation = al
+	f.AddOrderChecker(al)
+	return al, nil
+}
+
+// Allocations 割り当て済みの資金枠
+func (a *Allocator) Allocations() []*Allocation {
+	return a.allocations
+}
+
+// GetEquityJPY 対象通貨ペアを含めた総資産（JPY換算）を取得
+func (a *Allocator) GetEquityJPY() (float64, error) {
+	return equityJPY(a.exClient, a.pairs)
+}
+
+// equityJPY 日本円と指定通貨ペアの残高の合計（JPY換算）を取得
+func equityJPY(exCli exchange.Client, pairs []model.CurrencyPair) (float64, error) {
+	jpy, err := exCli.GetBalance(model.JPY)
+	if err != nil {
+		return 0, err
+	}
+	equity := jpy.Total()
+	for _, p := range pairs {
+		currency, err := exCli.GetBalance(p.Key)
+		if err != nil {
+			return 0, err
+		}
+		if currency.Total() == 0 {
+			continue
+		}
+		rate, err := exCli.GetOrderRate(&p, model.SellSide)
+		if err != nil {
+			return 0, err
+		}
+		equity += currency.Total() * rate.Rate
+	}
+	return equity, nil
+}
+
+// Allocation ボット単位の資金枠
+type Allocation struct {
+	allocator *Allocator
+	name      string
+	budget    Budget
+	facade    *Facade
+}
+
+// Name ボット名
+func (a *Allocation) Name() string {
+	return a.name
+}
+
+// LimitJPY 資金枠(JPY)を取得
+func (a *Allocation) LimitJPY() (float64, error) {
+	if a.budget.AmountJPY > 0 {
+		return a.budget.AmountJPY, nil
+	}
+	equity, err := a.allocator.GetEquityJPY()
+	if err != nil {
+		return 0, err
+	}
+	return equity * a.budget.EquityRatio, nil
+}
+
+// UsedJPY 未決済ポジションと未約定の買い注文で使用中の金額(JPY)を取得
+func (a *Allocation) UsedJPY() (float64, error) {
+	pp, err := a.facade.GetOpenPositions()
+	if err != nil {
+		return 0, err
+	}
+
+	used := 0.0
+	for _, p := range pp {
+		contracts, err := a.facade.GetContracts(p.OpenerOrder.ID)
+		if err != nil {
+			return 0, err
+		}
+		contracted := 0.0
+		for _, c := range contracts {
+			contracted += -c.DecreaseAmount
+		}
+
+		if p.OpenerOrder.Status == model.Open {
+			// 未約定分は注文金額で見積もる
+			used += maxFloat(contracted, orderJPY(p.OpenerOrder))
+		} else {
+			used += contracted
+		}
+	}
+	return used, nil
+}
+
+// AvailableJPY 資金枠の残り(JPY)を取得
+func (a *Allocation) AvailableJPY() (float64, error) {
+	limit, err := a.LimitJPY()
+	if err != nil {
+		return 0, err
+	}
+	used, err := a.UsedJPY()
+	if err != nil {
+		return 0, err
+	}
+	if limit < used {
+		return 0, nil
+	}
+	return limit - used, nil
+}
+
+// CheckOrder 資金枠を超える買い注文を拒否
+func (a *Allocation) CheckOrder(o *model.NewOrder, p *model.Position) error {
+	if p != nil || (o.Type != model.Buy && o.Type != model.MarketBuy) {
+		return nil
+	}
+
+	var amount float64
+	if o.Type == model.MarketBuy {
+		amount = *o.MarketBuyAmount
+	} else {
+		amount = *o.Amount * *o.Rate
+	}
+
+	available, err := a.AvailableJPY()
+	if err != nil {
+		return err
+	}
+	if amount > available {
+		return fmt.Errorf("order amount exceeds budget, name = %s, amount = %.3f, available = %.3f", a.name, amount, available)
+	}
+	return nil
+}
+
+func orderJPY(o *model.Order) float64 {
+	if o.Type == model.MarketBuy || o.Rate == nil {
+		return o.Amount
+	}
+	return o.Amount * *o.Rate
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,77 @@
+package trade_test
+
+import (
+	"strings"
+	"testing"
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/infrastructure/memory"
+	"trading-bot/pkg/usecase/trade"
+)
+
+func TestAllocator(t *testing.T) {
+	rates := []string{
+		"日付, 販売所買い価格, 販売所売り価格",
+		"2021-02-23T19:27:01Z,100.0,100.0",
+	}
+	exCli, err := memory.NewExchangeMock(strings.NewReader(strings.Join(rates, "\n")), 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	exCli.SetBalance(model.JPY, 100000)
+
+	// ボットごとにポジションを分ける
+	rds1, rds2 := memory.NewDummyRDS(nil), memory.NewDummyRDS(nil)
+	f1 := trade.NewFacade(exCli, rds1, rds1, rds1, rds1, rds1, rds1, nil)
+	f2 := trade.NewFacade(exCli, rds2, rds2, rds2, rds2, rds2, rds2, nil)
+
+	allocator := trade.NewAllocator(exCli)
+	a1, err := allocator.Allocate("fixed", model.BtcJpy, trade.Budget{AmountJPY: 10000}, f1)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if _, err := allocator.Allocate("ratio", model.BtcJpy, trade.Budget{EquityRatio: 0.5}, f2); err != nil {
+		t.Fatal(err.Error())
+	}
+	if _, err := allocator.Allocate("over", model.BtcJpy, trade.Budget{EquityRatio: 0.6}, f2); err == nil {
+		t.Errorf("Allocate() error is nil; total ratio exceeds 1")
+	}
+
+	b, err := f1.GetJpyBalance()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if b.Amount != 10000 {
+		t.Errorf("GetJpyBalance() = %v, want 10000", b.Amount)
+	}
+
+	if _, err := f1.SendMarketBuyOrder(&model.BtcJpy, 6000, nil); err != nil {
+		t.Fatal(err.Error())
+	}
+	cc, err := exCli.GetContracts()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := rds1.UpsertContracts(cc); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	available, err := a1.AvailableJPY()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if available != 4000 {
+		t.Errorf("AvailableJPY() = %v, want 4000", available)
+	}
+	if _, err := f1.SendMarketBuyOrder(&model.BtcJpy, 5000, nil); err == nil {
+		t.Errorf("SendMarketBuyOrder() error is nil; order exceeds budget")
+	}
+
+	// 総資産 100000 の半分
+	b, err = f2.GetJpyBalance()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if b.Amount != 50000 {
+		t.Errorf("GetJpyBalance() = %v, want 50000", b.Amount)
+	}
+}
@@ -0,0 +1,210 @@
+package trade
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+	"trading-bot/pkg/domain"
+	"trading-bot/pkg/domain/exchange"
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/domain/repository"
+)
+
+// GuardConfig 誤発注防止の設定（0や空の項目は確認しない）
+type GuardConfig struct {
+	// 1注文の金額上限(JPY)
+	MaxOrderJPY float64
+	// 指値と現在のレートの乖離率の上限（例: 0.05なら±5%）
+	MaxRateDeviation float64
+	// 注文を禁止する通貨ペア（例: btc_jpy）
+	DisabledPairs []string
+	// 期間内の注文数の上限
+	MaxOrders int
+	// 注文数を数える期間
+	Window time.Duration
+}
+
+// Enabled 確認する項目があるか
+func (c *GuardConfig) Enabled() bool {
+	return c.MaxOrderJPY > 0 || c.MaxRateDeviation > 0 || len(c.DisabledPairs) > 0 || (c.MaxOrders > 0 && c.Window > 0)
+}
+
+// OrderGuard 全注文の送信前の確認（誤発注防止）
+// 拒否した注文はイベントとして記録する
+type OrderGuard struct {
+	sync.Mutex
+
+	logger    domain.Logger
+	exClient  exchange.Client
+	eventRepo repository.EventRepository
+	config    GuardConfig
+	// 期間内に送信した注文の日時
+	sentAt []time.Time
+	// 確認を通過し、送信の結果を待っている注文数
+	pending int
+}
+
+// NewOrderGuard 生成
+func NewOrderGuard(logger domain.Logger, exCli exchange.Client, eventRepo repository.EventRepository, config GuardConfig) *OrderGuard {
+	return &OrderGuard{
+		logger:    logger,
+		exClient:  exCli,
+		eventRepo: eventRepo,
+		config:    config,
+		sentAt:    []time.Time{},
+	}
+}
+
+// Attach Facadeの注文を確認対象にする
+func (g *OrderGuard) Attach(botName string, f *Facade) {
+	f.AddOrderChecker(&guardChecker{guard: g, botName: botName, facade: f})
+}
+
+// guardChecker ボットごとの確認（拒否理由の記録にボット名を含める）
+type guardChecker struct {
+	guard   *OrderGuard
+	botName string
+	facade  *Facade
+}
+
+// CheckOrder 注文可能か確認（決済注文は損切りや全決済を止めないように一部の確認を省く）
+func (c *guardChecker) CheckOrder(o *model.NewOrder, p *model.Position) error {
+	return c.guard.check(c.botName, o, p != nil, c.facade.Now())
+}
+
+// OrderSent 送信した注文のみ注文数に数える
+func (c *guardChecker) OrderSent(o *model.NewOrder, p *model.Position, err error) {
+	c.guard.sent(err == nil, c.facade.Now())
+}
+
+func (g *OrderGuard) check(botName string, o *model.NewOrder, closing bool, now time.Time) error {
+	g.Lock()
+	defer g.Unlock()
+
+	reason, err := g.reject(o, closing, now)
+	if err != nil {
+		return err
+	}
+	if reason == "" {
+		// 送信の結果が出るまでは注文数に含めておく
+		g.pending++
+		return nil
+	}
+
+	g.logger.Error("[guard][%s] rejected order, %s, %s", botName, reason, orderSummary(o))
+	event := &model.Event{
+		Pair:       o.Pair.String(),
+		Type:       model.RejectedEvent,
+		Memo:       fmt.Sprintf("[guard] rejected (bot:%s) %s\n%s", botName, reason, orderSummary(o)),
+		RecordedAt: now,
+	}
+	if err := g.eventRepo.RecordEvent(event); err != nil {
+		g.logger.Error("[guard][%s] failed to record event, %v", botName, err)
+	}
+	return fmt.Errorf("order is rejected by guard, %s", reason)
+}
+
+// sent 確認を通過した注文の送信結果を反映する
+func (g *OrderGuard) sent(ok bool, now time.Time) {
+	g.Lock()
+	defer g.Unlock()
+
+	if g.pending > 0 {
+		g.pending--
+	}
+	if ok {
+		g.sentAt = append(g.sentAt, now)
+	}
+}
+
+// reject 拒否する理由を返す（問題なければ空）
+// 決済注文は通貨ペアの禁止、注文数の上限、金額の上限を確認しない（レートの乖離のみ確認する）
+func (g *OrderGuard) reject(o *model.NewOrder, closing bool, now time.Time) (string, error) {
+	if !closing {
+		for _, pair := range g.config.DisabledPairs {
+			if pair == o.Pair.String() {
+				return fmt.Sprintf("pair %s is disabled", pair), nil
+			}
+		}
+	}
+
+	if g.config.MaxOrders > 0 && g.config.Window > 0 {
+		border := now.Add(-g.config.Window)
+		sentAt := []time.Time{}
+		for _, t := range g.sentAt {
+			if t.After(border) {
+				sentAt = append(sentAt, t)
+			}
+		}
+		g.sentAt = sentAt
+		if count := len(g.sentAt) + g.pending; !closing && count >= g.config.MaxOrders {
+			return fmt.Sprintf("too many orders (%d orders in %v)", count, g.config.Window), nil
+		}
+	}
+
+	maxOrderJPY := g.config.MaxOrderJPY
+	if closing {
+		maxOrderJPY = 0
+	}
+	if maxOrderJPY <= 0 && g.config.MaxRateDeviation <= 0 {
+		return "", nil
+	}
+
+	side := model.BuySide
+	if o.Type == model.Sell || o.Type == model.MarketSell {
+		side = model.SellSide
+	}
+	market, err := g.exClient.GetOrderRate(&o.Pair, side)
+	if err != nil {
+		return "", err
+	}
+
+	if g.config.MaxRateDeviation > 0 && o.Rate != nil && market.Rate > 0 {
+		deviation := math.Abs(*o.Rate-market.Rate) / market.Rate
+		if deviation > g.config.MaxRateDeviation {
+			return fmt.Sprintf("rate %.3f deviates %.2f%% from market %.3f (max %.2f%%)",
+				*o.Rate, deviation*100, market.Rate, g.config.MaxRateDeviation*100), nil
+		}
+	}
+
+	if maxOrderJPY > 0 {
+		notional := orderNotionalJPY(o, market.Rate)
+		if notional > maxOrderJPY {
+			return fmt.Sprintf("notional %.3f JPY exceeds max %.3f JPY", notional, maxOrderJPY), nil
+		}
+	}
+	return "", nil
+}
+
+// orderNotionalJPY 注文金額(JPY)（成行売りは現在のレートで換算）
+func orderNotionalJPY(o *model.NewOrder, marketRate float64) float64 {
+	if o.Type == model.MarketBuy {
+		if o.MarketBuyAmount == nil {
+			return 0
+		}
+		return *o.MarketBuyAmount
+	}
+	if o.Amount == nil {
+		return 0
+	}
+	if o.Rate != nil {
+		return *o.Amount * *o.Rate
+	}
+	return *o.Amount * marketRate
+}
+
+// orderSummary 記録用の注文内容
+func orderSummary(o *model.NewOrder) string {
+	s := fmt.Sprintf("type:%s pair:%s", o.Type, o.Pair.String())
+	if o.Amount != nil {
+		s += fmt.Sprintf(" amount:%f", *o.Amount)
+	}
+	if o.MarketBuyAmount != nil {
+		s += fmt.Sprintf(" market_buy_amount:%f", *o.MarketBuyAmount)
+	}
+	if o.Rate != nil {
+		s += fmt.Sprintf(" rate:%f", *o.Rate)
+	}
+	return s
+}
@@ -0,0 +1,158 @@
+package trade_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/infrastructure/memory"
+	"trading-bot/pkg/usecase/trade"
+)
+
+func TestOrderGuard(t *testing.T) {
+	type order struct {
+		side   model.OrderSide
+		amount float64
+		rate   float64
+	}
+	tests := map[string]struct {
+		config  trade.GuardConfig
+		orders  []order
+		wantErr []bool
+	}{
+		"notional within cap": {
+			config:  trade.GuardConfig{MaxOrderJPY: 10000},
+			orders:  []order{{side: model.BuySide, amount: 10000}},
+			wantErr: []bool{false},
+		},
+		"market buy over cap": {
+			config:  trade.GuardConfig{MaxOrderJPY: 10000},
+			orders:  []order{{side: model.BuySide, amount: 10001}},
+			wantErr: []bool{true},
+		},
+		"limit sell over cap": {
+			config:  trade.GuardConfig{MaxOrderJPY: 10000},
+			orders:  []order{{side: model.SellSide, amount: 11, rate: 1000}},
+			wantErr: []bool{true},
+		},
+		"market sell over cap": {
+			config:  trade.GuardConfig{MaxOrderJPY: 10000},
+			orders:  []order{{side: model.SellSide, amount: 11}},
+			wantErr: []bool{true},
+		},
+		"rate deviation": {
+			config: trade.GuardConfig{MaxRateDeviation: 0.05},
+			orders: []order{
+				{side: model.SellSide, amount: 1, rate: 950},
+				{side: model.SellSide, amount: 1, rate: 949},
+				{side: model.BuySide, amount: 1, rate: 1051},
+			},
+			wantErr: []bool{false, true, true},
+		},
+		"disabled pair": {
+			config:  trade.GuardConfig{DisabledPairs: []string{"btc_jpy"}},
+			orders:  []order{{side: model.BuySide, amount: 1000}},
+			wantErr: []bool{true},
+		},
+		"too many orders": {
+			config: trade.GuardConfig{MaxOrders: 2, Window: time.Minute},
+			orders: []order{
+				{side: model.BuySide, amount: 1000},
+				{side: model.BuySide, amount: 1000},
+				{side: model.BuySide, amount: 1000},
+			},
+			wantErr: []bool{false, false, true},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			rates := []string{
+				"日付, 販売所買い価格, 販売所売り価格",
+				"2021-02-23T19:27:01Z,1000.0,1000.0",
+			}
+			exCli, err := memory.NewExchangeMock(strings.NewReader(strings.Join(rates, "\n")), 0)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			rds := memory.NewDummyRDS(nil)
+			f := trade.NewFacade(exCli, rds, rds, rds, rds, rds, rds, nil)
+			logger := &memory.Logger{Level: memory.Error}
+			trade.NewOrderGuard(logger, exCli, rds, tt.config).Attach("test", f)
+
+			rejected := 0
+			for i, o := range tt.orders {
+				var err error
+				switch {
+				case o.side == model.BuySide && o.rate == 0:
+					_, err = f.SendMarketBuyOrder(&model.BtcJpy, o.amount, nil)
+				case o.side == model.BuySide:
+					_, err = f.SendBuyOrder(&model.BtcJpy, o.amount, o.rate, nil)
+				case o.rate == 0:
+					_, err = f.SendMarketSellOrder(&model.BtcJpy, o.amount, nil)
+				default:
+					_, err = f.SendSellOrder(&model.BtcJpy, o.amount, o.rate, nil)
+				}
+				if (err != nil) != tt.wantErr[i] {
+					t.Errorf("orders[%d] error = %v, wantErr %v", i, err, tt.wantErr[i])
+				}
+				if err != nil {
+					rejected++
+				}
+			}
+
+			events := rds.Events()
+			if len(events) != rejected {
+				t.Fatalf("events count = %d, want %d", len(events), rejected)
+			}
+			for _, e := range events {
+				if e.Type != model.RejectedEvent || !strings.Contains(e.Memo, "bot:test") {
+					t.Errorf("event = %+v, want rejected event of bot test", e)
+				}
+			}
+		})
+	}
+}
+
+// rejectChecker 指定した数の注文を拒否する確認
+type rejectChecker struct {
+	count int
+}
+
+func (c *rejectChecker) CheckOrder(o *model.NewOrder, p *model.Position) error {
+	if c.count == 0 {
+		return nil
+	}
+	c.count--
+	return errors.New("rejected by other checker")
+}
+
+func TestOrderGuard_NotSent(t *testing.T) {
+	rates := []string{
+		"日付, 販売所買い価格, 販売所売り価格",
+		"2021-02-23T19:27:01Z,1000.0,1000.0",
+	}
+	exCli, err := memory.NewExchangeMock(strings.NewReader(strings.Join(rates, "\n")), 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	rds := memory.NewDummyRDS(nil)
+	f := trade.NewFacade(exCli, rds, rds, rds, rds, rds, rds, nil)
+	logger := &memory.Logger{Level: memory.Error}
+	trade.NewOrderGuard(logger, exCli, rds, trade.GuardConfig{MaxOrders: 2, Window: time.Minute}).Attach("test", f)
+	// 確認を通過した後に拒否された注文は注文数に数えない
+	f.AddOrderChecker(&rejectChecker{count: 3})
+
+	wantErr := []bool{true, true, true, false, false, true}
+	for i, want := range wantErr {
+		_, err := f.SendBuyOrder(&model.BtcJpy, 1, 1000, nil)
+		if (err != nil) != want {
+			t.Errorf("orders[%d] error = %v, wantErr %v", i, err, want)
+		}
+	}
+	// 注文数の上限で拒否したのは最後の注文のみ
+	if events := rds.Events(); len(events) != 1 {
+		t.Errorf("events count = %d, want 1", len(events))
+	}
+}
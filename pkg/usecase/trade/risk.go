@@ -0,0 +1,435 @@
+package trade
+
+import (
+	"fmt"
+	"sync"
+	"time"
+	"trading-bot/pkg/domain"
+	"trading-bot/pkg/domain/exchange"
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/domain/repository"
+)
+
+const (
+	// RiskBotName リスク管理の状態を記録する際のボット名
+	RiskBotName = "risk"
+	// riskStateStrategy リスク管理の状態の保存キー
+	riskStateStrategy = "risk"
+	// riskStatePair リスク管理の状態の保存キー（通貨ペアを問わない）
+	riskStatePair = "all"
+)
+
+// RiskConfig リスク管理の設定
+type RiskConfig struct {
+	// 1日の損失上限(JPY、0なら確認しない)
+	DailyLossLimitJPY float64
+	// 総資産のピークからの下落率の上限（0〜1、0なら確認しない）
+	MaxDrawdownRatio float64
+	// 上限に達したら全ポジションを決済するか
+	Flatten bool
+}
+
+// Enabled 確認する項目があるか
+func (c *RiskConfig) Enabled() bool {
+	return c.DailyLossLimitJPY > 0 || c.MaxDrawdownRatio > 0
+}
+
+// RiskState リスク管理の状態
+type RiskState struct {
+	// 集計日（yyyy-mm-dd）
+	Day string
+	// 集計日の開始時の総資産(JPY)
+	DayStartEquity float64
+	// 総資産のピーク(JPY)
+	PeakEquity float64
+	// 停止中か（手動で解除するまで新規注文しない）
+	Halted bool
+	// 停止理由
+	Reason string
+	// 停止日時
+	HaltedAt time.Time
+
+	// 以下は保存しない集計値
+	// 総資産(JPY)
+	Equity float64
+	// 当日の損益(JPY)
+	DailyPnL float64
+	// 当日に決済したポジションの損益(JPY)
+	RealizedPnL float64
+	// 未決済ポジションの含み損益(JPY)
+	UnrealizedPnL float64
+	// ピークからの下落率
+	Drawdown float64
+}
+
+// Flattener 全決済（戦略の処理を止めてから決済し、決済したポジション数を返す）
+type Flattener func() (closed int, err error)
+
+// RiskManager 全ボット共通のリスク管理
+// 1日の損失やピークからの下落が上限に達したら新規注文を止める（決済は止めない）
+type RiskManager struct {
+	sync.Mutex
+
+	logger    domain.Logger
+	exClient  exchange.Client
+	stateRepo repository.StrategyStateRepository
+	config    RiskConfig
+	pairs     []model.CurrencyPair
+	facades   []*Facade
+	// Facadeごとの全決済（未設定ならFacadeで直接決済する）
+	flatteners map[*Facade]Flattener
+	state      RiskState
+
+	// 通知先（未設定なら通知しない）
+	Notifier domain.Notifier
+}
+
+// NewRiskManager 生成
+func NewRiskManager(logger domain.Logger, exCli exchange.Client, stateRepo repository.StrategyStateRepository, config RiskConfig) *RiskManager {
+	return &RiskManager{
+		logger:     logger,
+		exClient:   exCli,
+		stateRepo:  stateRepo,
+		config:     config,
+		pairs:      []model.CurrencyPair{},
+		facades:    []*Facade{},
+		flatteners: map[*Facade]Flattener{},
+	}
+}
+
+// Attach Facadeの注文を管理対象にする
+func (r *RiskManager) Attach(pair model.CurrencyPair, f *Facade) {
+	r.Lock()
+	defer r.Unlock()
+
+	found := false
+	for _, p := range r.pairs {
+		if p == pair {
+			found = true
+			break
+		}
+	}
+	if !found {
+		r.pairs = append(r.pairs, pair)
+	}
+	r.facades = append(r.facades, f)
+	f.AddOrderChecker(r)
+}
+
+// SetFlattener Facadeのポジションの全決済を、戦略と同時に決済しないようにボットの全決済で行う
+func (r *RiskManager) SetFlattener(f *Facade, flatten Flattener) {
+	r.Lock()
+	defer r.Unlock()
+	r.flatteners[f] = flatten
+}
+
+// CheckOrder 停止中なら新規注文を拒否
+func (r *RiskManager) CheckOrder(o *model.NewOrder, p *model.Position) error {
+	if p != nil {
+		// 決済は止めない
+		return nil
+	}
+	r.Lock()
+	defer r.Unlock()
+	if r.state.Halted {
+		return fmt.Errorf("new order is halted by risk manager (%s), reset is required", r.state.Reason)
+	}
+	return nil
+}
+
+// State 現在の状態を取得
+func (r *RiskManager) State() RiskState {
+	r.Lock()
+	defer r.Unlock()
+	return r.state
+}
+
+// Update 損益を集計して上限を確認（保存済みの状態を読み込んでから更新する）
+func (r *RiskManager) Update(now time.Time) (*RiskState, error) {
+	state, halted, err := r.update(now)
+	if err != nil {
+		return nil, err
+	}
+	if halted {
+		// 決済注文は注文前の確認でロックを取るため、ロックの外で行う
+		r.onHalted(state)
+	}
+	return state, nil
+}
+
+// update 損益を集計して保存し、今回停止したかを返す
+func (r *RiskManager) update(now time.Time) (*RiskState, bool, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if err := r.load(); err != nil {
+		return nil, false, err
+	}
+
+	equity, err := equityJPY(r.exClient, r.pairs)
+	if err != nil {
+		return nil, false, err
+	}
+	realized, unrealized, err := r.pnl(now)
+	if err != nil {
+		return nil, false, err
+	}
+
+	day := now.Format("2006-01-02")
+	if r.state.Day != day {
+		if r.state.Day != "" {
+			r.logger.Info("[risk] day changed %s => %s, day start equity: %.3f", r.state.Day, day, equity)
+		}
+		r.state.Day = day
+		r.state.DayStartEquity = equity
+	}
+	if equity > r.state.PeakEquity {
+		r.state.PeakEquity = equity
+	}
+
+	r.state.Equity = equity
+	r.state.DailyPnL = equity - r.state.DayStartEquity
+	r.state.RealizedPnL = realized
+	r.state.UnrealizedPnL = unrealized
+	r.state.Drawdown = 0
+	if r.state.PeakEquity > 0 {
+		r.state.Drawdown = (r.state.PeakEquity - equity) / r.state.PeakEquity
+	}
+
+	halted := false
+	if !r.state.Halted {
+		if reason := r.breach(); reason != "" {
+			r.state.Halted = true
+			r.state.Reason = reason
+			r.state.HaltedAt = now
+			halted = true
+			r.logger.Error("[risk] halted new orders, %s", reason)
+		}
+	}
+
+	if err := r.save(); err != nil {
+		return nil, false, err
+	}
+	state := r.state
+	return &state, halted, nil
+}
+
+// breach 上限に達していれば理由を返す
+func (r *RiskManager) breach() string {
+	if r.config.DailyLossLimitJPY > 0 && -r.state.DailyPnL >= r.config.DailyLossLimitJPY {
+		return fmt.Sprintf("daily loss %.3f JPY reached limit %.3f JPY", -r.state.DailyPnL, r.config.DailyLossLimitJPY)
+	}
+	if r.config.MaxDrawdownRatio > 0 && r.state.Drawdown >= r.config.MaxDrawdownRatio {
+		return fmt.Sprintf("drawdown %.2f%% reached limit %.2f%% (peak %.3f JPY, equity %.3f JPY)",
+			r.state.Drawdown*100, r.config.MaxDrawdownRatio*100, r.state.PeakEquity, r.state.Equity)
+	}
+	return ""
+}
+
+// onHalted 停止時の通知と決済
+func (r *RiskManager) onHalted(state *RiskState) {
+	message := fmt.Sprintf("[risk] 上限に達したため新規注文を停止しました（解除は trading-bot risk-reset）\n%s\n当日損益: %.3f JPY（決済済み %.3f / 含み %.3f）",
+		state.Reason, state.DailyPnL, state.RealizedPnL, state.UnrealizedPnL)
+	if r.config.Flatten {
+		closed, err := r.flatten()
+		if err != nil {
+			r.logger.Error("[risk] failed to flatten, %v", err)
+			message += fmt.Sprintf("\n全ポジションの決済に失敗しました（%d件決済済み）: %v", closed, err)
+		} else {
+			message += fmt.Sprintf("\n全ポジションを決済しました（%d件）", closed)
+		}
+	}
+	r.notify(message)
+}
+
+// flatten 全ポジションを決済
+func (r *RiskManager) flatten() (int, error) {
+	r.Lock()
+	facades := append([]*Facade{}, r.facades...)
+	flatteners := map[*Facade]Flattener{}
+	for f, fn := range r.flatteners {
+		flatteners[f] = fn
+	}
+	r.Unlock()
+
+	closed := 0
+	for _, f := range facades {
+		if flatten, ok := flatteners[f]; ok {
+			n, err := flatten()
+			closed += n
+			if err != nil {
+				return closed, err
+			}
+			continue
+		}
+		pp, err := f.GetOpenPositions()
+		if err != nil {
+			return closed, err
+		}
+		for _, p := range pp {
+			p := p
+			order, err := f.ClosePosition(&p)
+			if err != nil {
+				return closed, err
+			}
+			if order != nil {
+				r.logger.Info("[risk] flatten position %d => %s", p.ID, order.String())
+				closed++
+			}
+		}
+	}
+	return closed, nil
+}
+
+// pnl 当日に決済したポジションの損益と、未決済ポジションの含み損益を集計
+func (r *RiskManager) pnl(now time.Time) (realized float64, unrealized float64, err error) {
+	y, m, d := now.Date()
+	dayStart := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+
+	for _, f := range r.facades {
+		closed, err := f.GetClosedPositions()
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, p := range closed {
+			if p.CloserOrder == nil || p.CloserOrder.OrderedAt.Before(dayStart) {
+				continue
+			}
+			cost, _, err := r.openerCost(f, &p)
+			if err != nil {
+				return 0, 0, err
+			}
+			closer, err := f.GetContracts(p.CloserOrder.ID)
+			if err != nil {
+				return 0, 0, err
+			}
+			for _, c := range closer {
+				realized += c.IncreaseAmount
+			}
+			realized -= cost
+		}
+
+		open, err := f.GetOpenPositions()
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, p := range open {
+			if p.OpenerOrder.Status != model.Closed {
+				continue
+			}
+			cost, amount, err := r.openerCost(f, &p)
+			if err != nil {
+				return 0, 0, err
+			}
+			rate, err := r.exClient.GetOrderRate(&p.OpenerOrder.Pair, model.SellSide)
+			if err != nil {
+				return 0, 0, err
+			}
+			unrealized += amount*rate.Rate - cost
+		}
+	}
+	return realized, unrealized, nil
+}
+
+// openerCost 買い注文の約定金額(JPY)と数量
+func (r *RiskManager) openerCost(f *Facade, p *model.Position) (cost float64, amount float64, err error) {
+	contracts, err := f.GetContracts(p.OpenerOrder.ID)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, c := range contracts {
+		cost += -c.DecreaseAmount
+		amount += c.IncreaseAmount
+	}
+	return cost, amount, nil
+}
+
+// Reset 停止を解除（当日の開始時点とピークは次回の集計時の総資産になる）
+func (r *RiskManager) Reset() error {
+	r.Lock()
+	defer r.Unlock()
+
+	if err := r.load(); err != nil {
+		return err
+	}
+	reason := r.state.Reason
+	r.state = RiskState{}
+	if err := r.save(); err != nil {
+		return err
+	}
+	r.logger.Info("[risk] reset (reason was: %s)", reason)
+	if reason != "" {
+		r.notify(fmt.Sprintf("[risk] 停止を解除しました\n停止理由: %s", reason))
+	}
+	return nil
+}
+
+// Statuses 公開する状態
+func (r *RiskManager) Statuses() []model.BotStatus {
+	s := r.State()
+	halted := 0.0
+	if s.Halted {
+		halted = 1
+	}
+	return []model.BotStatus{
+		{BotName: RiskBotName, Pair: riskStatePair, Type: "equity_jpy", Value: s.Equity, Memo: "総資産"},
+		{BotName: RiskBotName, Pair: riskStatePair, Type: "daily_pnl_jpy", Value: s.DailyPnL, Memo: "当日損益"},
+		{BotName: RiskBotName, Pair: riskStatePair, Type: "realized_pnl_jpy", Value: s.RealizedPnL, Memo: "当日の決済損益"},
+		{BotName: RiskBotName, Pair: riskStatePair, Type: "unrealized_pnl_jpy", Value: s.UnrealizedPnL, Memo: "含み損益"},
+		{BotName: RiskBotName, Pair: riskStatePair, Type: "drawdown", Value: s.Drawdown, Memo: "ピークからの下落率"},
+		{BotName: RiskBotName, Pair: riskStatePair, Type: "halted", Value: halted, Memo: s.Reason},
+	}
+}
+
+// load 保存済みの状態を読み込む（別プロセスでの解除を反映するため毎回読む）
+func (r *RiskManager) load() error {
+	s, err := r.stateRepo.GetStrategyState(RiskBotName, riskStateStrategy, riskStatePair)
+	if err != nil {
+		return err
+	}
+	if len(s.Values) == 0 {
+		return nil
+	}
+
+	state := RiskState{Day: s.Values["day"], Reason: s.Values["reason"]}
+	if state.DayStartEquity, err = s.Float("day_start_equity", 0); err != nil {
+		return fmt.Errorf("day_start_equity is invalid, %w", err)
+	}
+	if state.PeakEquity, err = s.Float("peak_equity", 0); err != nil {
+		return fmt.Errorf("peak_equity is invalid, %w", err)
+	}
+	if state.Halted, err = s.Bool("halted", false); err != nil {
+		return fmt.Errorf("halted is invalid, %w", err)
+	}
+	haltedAt, err := s.Time("halted_at")
+	if err != nil {
+		return fmt.Errorf("halted_at is invalid, %w", err)
+	}
+	if haltedAt != nil {
+		state.HaltedAt = *haltedAt
+	}
+	r.state = state
+	return nil
+}
+
+func (r *RiskManager) save() error {
+	s := model.NewStrategyState(RiskBotName, riskStateStrategy, riskStatePair)
+	s.Values["day"] = r.state.Day
+	s.Values["reason"] = r.state.Reason
+	s.SetFloat("day_start_equity", r.state.DayStartEquity)
+	s.SetFloat("peak_equity", r.state.PeakEquity)
+	s.SetBool("halted", r.state.Halted)
+	if r.state.Halted {
+		s.SetTime("halted_at", &r.state.HaltedAt)
+	}
+	return r.stateRepo.UpsertStrategyState(s)
+}
+
+func (r *RiskManager) notify(message string) {
+	if r.Notifier == nil {
+		return
+	}
+	if err := r.Notifier.Notify(message); err != nil {
+		r.logger.Error("[risk] failed to notify, %v", err)
+	}
+}
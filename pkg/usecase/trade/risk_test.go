@@ -0,0 +1,149 @@
+package trade_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"trading-bot/pkg/domain/model"
+	"trading-bot/pkg/infrastructure/memory"
+	"trading-bot/pkg/usecase/trade"
+)
+
+func TestRiskManager(t *testing.T) {
+	rates := []string{
+		"日付, 販売所買い価格, 販売所売り価格",
+		"2021-02-23T19:27:01Z,1000.0,1000.0",
+		"2021-02-23T19:27:02Z,900.0,900.0",
+	}
+	exCli, err := memory.NewExchangeMock(strings.NewReader(strings.Join(rates, "\n")), 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	exCli.SetBalance(model.JPY, 100000)
+	rds := memory.NewDummyRDS(nil)
+	f := trade.NewFacade(exCli, rds, rds, rds, rds, rds, rds, nil)
+	logger := &memory.Logger{Level: memory.Error}
+
+	config := trade.RiskConfig{DailyLossLimitJPY: 3000, Flatten: true}
+	risk := trade.NewRiskManager(logger, exCli, rds, config)
+	risk.Attach(model.BtcJpy, f)
+
+	// 約定をDBに反映
+	applyContracts := func(order *model.Order) {
+		cc, err := exCli.GetContracts()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if err := rds.UpsertContracts(cc); err != nil {
+			t.Fatal(err.Error())
+		}
+		if err := rds.UpdateStatus(order.ID, model.Closed); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	now := time.Date(2021, 2, 23, 10, 0, 0, 0, time.UTC)
+	if _, err := risk.Update(now); err != nil {
+		t.Fatal(err.Error())
+	}
+	p, err := f.SendMarketBuyOrder(&model.BtcJpy, 50000, nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	applyContracts(p.OpenerOrder)
+
+	// 1000 => 900 で 5000JPY の含み損
+	exCli.NextStep()
+	state, err := risk.Update(now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if state.DailyPnL != -5000 || state.UnrealizedPnL != -5000 {
+		t.Errorf("DailyPnL = %v, UnrealizedPnL = %v, want -5000", state.DailyPnL, state.UnrealizedPnL)
+	}
+	if !state.Halted {
+		t.Fatalf("Halted = false, want true")
+	}
+
+	// 全ポジションを決済済み
+	pp, err := f.GetOpenPositions()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(pp) != 1 || pp[0].CloserOrder == nil {
+		t.Fatalf("open positions = %v, want 1 position with closer order", pp)
+	}
+	applyContracts(pp[0].CloserOrder)
+	state, err = risk.Update(now.Add(2 * time.Hour))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if state.RealizedPnL != -5000 || state.UnrealizedPnL != 0 {
+		t.Errorf("RealizedPnL = %v, UnrealizedPnL = %v, want -5000, 0", state.RealizedPnL, state.UnrealizedPnL)
+	}
+
+	// 停止中は新規注文できない（再起動しても停止したまま）
+	restarted := trade.NewRiskManager(logger, exCli, rds, config)
+	f2 := trade.NewFacade(exCli, rds, rds, rds, rds, rds, rds, nil)
+	restarted.Attach(model.BtcJpy, f2)
+	if _, err := restarted.Update(now.AddDate(0, 0, 1)); err != nil {
+		t.Fatal(err.Error())
+	}
+	if _, err := f2.SendMarketBuyOrder(&model.BtcJpy, 1000, nil); err == nil {
+		t.Errorf("SendMarketBuyOrder() error is nil; halted")
+	}
+
+	// 解除後は新規注文できる
+	if err := restarted.Reset(); err != nil {
+		t.Fatal(err.Error())
+	}
+	state, err = restarted.Update(now.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if state.Halted || state.DailyPnL != 0 || state.Drawdown != 0 {
+		t.Errorf("state = %+v, want reset", state)
+	}
+	if _, err := f2.SendMarketBuyOrder(&model.BtcJpy, 1000, nil); err != nil {
+		t.Errorf("SendMarketBuyOrder() error = %v", err)
+	}
+}
+
+func TestRiskManager_Drawdown(t *testing.T) {
+	rates := []string{
+		"日付, 販売所買い価格, 販売所売り価格",
+		"2021-02-23T19:27:01Z,1000.0,1000.0",
+	}
+	exCli, err := memory.NewExchangeMock(strings.NewReader(strings.Join(rates, "\n")), 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	rds := memory.NewDummyRDS(nil)
+	logger := &memory.Logger{Level: memory.Error}
+	risk := trade.NewRiskManager(logger, exCli, rds, trade.RiskConfig{MaxDrawdownRatio: 0.1})
+	risk.Attach(model.BtcJpy, trade.NewFacade(exCli, rds, rds, rds, rds, rds, rds, nil))
+
+	tests := []struct {
+		day        int
+		jpy        float64
+		wantHalted bool
+	}{
+		{day: 1, jpy: 100000, wantHalted: false},
+		// 日をまたいでもピークは維持する
+		{day: 2, jpy: 120000, wantHalted: false},
+		{day: 3, jpy: 109000, wantHalted: false},
+		{day: 4, jpy: 108000, wantHalted: true},
+		// 回復しても解除しない
+		{day: 5, jpy: 130000, wantHalted: true},
+	}
+	for _, tt := range tests {
+		exCli.SetBalance(model.JPY, tt.jpy)
+		state, err := risk.Update(time.Date(2021, 3, tt.day, 0, 0, 0, 0, time.UTC))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if state.Halted != tt.wantHalted {
+			t.Errorf("day %d: Halted = %v, want %v; %+v", tt.day, state.Halted, tt.wantHalted, state)
+		}
+	}
+}
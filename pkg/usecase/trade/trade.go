@@ -2,10 +2,15 @@ package trade
 
 import (
 	"context"
+	"errors"
+	"sync"
 	"time"
+	"trading-bot/pkg/domain"
 	"trading-bot/pkg/domain/exchange"
 	"trading-bot/pkg/domain/model"
 	"trading-bot/pkg/domain/repository"
+
+	"gorm.io/gorm"
 )
 
 // Facade トレード操作をまとめたもの
@@ -15,7 +20,18 @@ type Facade struct {
 	orderRepo    repository.OrderRepository
 	contractRepo repository.ContractRepository
 	positionRepo repository.PositionRepository
+	posStateRepo repository.PositionStateRepository
+	stStateRepo  repository.StrategyStateRepository
 	rateDuration *time.Duration
+
+	// 注文前の確認
+	checkers []OrderChecker
+	// 確認から注文までの排他制御（複数ボットで資金を共有する場合）
+	locker sync.Locker
+	// 資金枠（未設定なら残高全体を使う）
+	allocation *Allocation
+	// 時計（シミュレーションでは履歴データの日時）
+	clock domain.Clock
 }
 
 // NewFacade 生成
@@ -25,6 +41,8 @@ func NewFacade(
 	orderRepo repository.OrderRepository,
 	contractRepo repository.ContractRepository,
 	positionRepo repository.PositionRepository,
+	posStateRepo repository.PositionStateRepository,
+	stStateRepo repository.StrategyStateRepository,
 	rateDuration *time.Duration,
 ) *Facade {
 	return &Facade{
@@ -33,10 +51,29 @@ func NewFacade(
 		orderRepo:    orderRepo,
 		contractRepo: contractRepo,
 		positionRepo: positionRepo,
+		posStateRepo: posStateRepo,
+		stStateRepo:  stStateRepo,
 		rateDuration: rateDuration,
+		checkers:     []OrderChecker{},
+		clock:        domain.SystemClock{},
 	}
 }
 
+// SetClock 時計を設定
+func (f *Facade) SetClock(c domain.Clock) {
+	f.clock = c
+}
+
+// Now 現在日時
+func (f *Facade) Now() time.Time {
+	return f.clock.Now()
+}
+
+// AddOrderChecker 注文前の確認を追加
+func (f *Facade) AddOrderChecker(c OrderChecker) {
+	f.checkers = append(f.checkers, c)
+}
+
 // getOrderRate レートを取得
 //func (f *Facade) getOrderRate(pair *model.CurrencyPair, side model.OrderSide) (float64, error) {
 //	if rate := f.rateRepo.GetCurrentRate(&pair.Key, side); rate != nil {
@@ -61,6 +98,11 @@ func (f *Facade) GetRates(p *model.CurrencyPair) ([]float64, error) {
 	return f.rateRepo.GetRates(p, f.rateDuration)
 }
 
+// GetRateFreshness GetRatesで取得するレートの鮮度を取得
+func (f *Facade) GetRateFreshness(p *model.CurrencyPair, gap time.Duration) (*model.RateFreshness, error) {
+	return f.rateRepo.GetRateFreshness(p, f.rateDuration, gap)
+}
+
 // GetBuyRate 買レートを取得
 func (f *Facade) GetBuyRate(pair *model.CurrencyPair) (float64, error) {
 	r, err := f.exClient.GetOrderRate(pair, model.BuySide)
@@ -106,6 +148,64 @@ func (f *Facade) GetOpenPositions() ([]model.Position, error) {
 	return f.positionRepo.GetOpenPositions()
 }
 
+// GetClosedPositions 決済済みのポジションを取得
+func (f *Facade) GetClosedPositions() ([]model.Position, error) {
+	return f.positionRepo.GetClosedPositions()
+}
+
+// GetTradeResults 決済済みポジションの損益率を取得
+func (f *Facade) GetTradeResults() ([]float64, error) {
+	pp, err := f.positionRepo.GetClosedPositions()
+	if err != nil {
+		return nil, err
+	}
+
+	results := []float64{}
+	for _, p := range pp {
+		opener, err := f.contractRepo.GetContracts(p.OpenerOrder.ID)
+		if err != nil {
+			return nil, err
+		}
+		closer, err := f.contractRepo.GetContracts(p.CloserOrder.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		buyJPY, sellJPY := 0.0, 0.0
+		for _, c := range opener {
+			buyJPY += -c.DecreaseAmount
+		}
+		for _, c := range closer {
+			sellJPY += c.IncreaseAmount
+		}
+		if buyJPY == 0 || sellJPY == 0 {
+			continue
+		}
+		results = append(results, (sellJPY-buyJPY)/buyJPY)
+	}
+	return results, nil
+}
+
+// GetPositionState ポジションの状態を取得
+func (f *Facade) GetPositionState(positionID uint64) (*model.PositionState, error) {
+	return f.posStateRepo.GetPositionState(positionID)
+}
+
+// UpsertPositionState ポジションの状態を更新
+func (f *Facade) UpsertPositionState(s *model.PositionState) error {
+	return f.posStateRepo.UpsertPositionState(s)
+}
+
+// GetStrategyState 戦略の状態を取得
+func (f *Facade) GetStrategyState(botName, strategy string, pair *model.CurrencyPair) (*model.StrategyState, error) {
+	return f.stStateRepo.GetStrategyState(botName, strategy, pair.String())
+}
+
+// UpsertStrategyState 戦略の状態を保存
+func (f *Facade) UpsertStrategyState(s *model.StrategyState) error {
+	return f.stStateRepo.UpsertStrategyState(s)
+}
+
 // GetContracts 約定情報を取得
 func (f *Facade) GetContracts(orderID uint64) ([]model.Contract, error) {
 	return f.contractRepo.GetContracts(orderID)
@@ -125,6 +225,16 @@ func (f *Facade) SendMarketBuyOrder(pair *model.CurrencyPair, amount float64, p
 	}, p)
 }
 
+// SendBuyOrder 買い注文
+func (f *Facade) SendBuyOrder(pair *model.CurrencyPair, amount float64, rate float64, p *model.Position) (*model.Position, error) {
+	return f.postOrder(&model.NewOrder{
+		Type:   model.Buy,
+		Pair:   *pair,
+		Amount: &amount,
+		Rate:   &rate,
+	}, p)
+}
+
 // SendMarketSellOrder 成行売り注文
 func (f *Facade) SendMarketSellOrder(pair *model.CurrencyPair, amount float64, p *model.Position) (*model.Position, error) {
 	return f.postOrder(&model.NewOrder{
@@ -149,7 +259,19 @@ func (f *Facade) SendSellOrder(pair *model.CurrencyPair, amount float64, rate fl
 
 // postOrder 注文
 func (f *Facade) postOrder(o *model.NewOrder, p *model.Position) (*model.Position, error) {
+	if f.locker != nil {
+		f.locker.Lock()
+		defer f.locker.Unlock()
+	}
+	for i, c := range f.checkers {
+		if err := c.CheckOrder(o, p); err != nil {
+			orderSent(f.checkers[:i], o, p, err)
+			return nil, err
+		}
+	}
+
 	order, err := f.exClient.PostOrder(o)
+	orderSent(f.checkers, o, p, err)
 	if err != nil {
 		return nil, err
 	}
@@ -160,6 +282,24 @@ func (f *Facade) postOrder(o *model.NewOrder, p *model.Position) (*model.Positio
 	return f.positionRepo.AddSettleOrder(p.ID, order)
 }
 
+// orderSent 確認を通過した注文の送信結果を通知
+func orderSent(checkers []OrderChecker, o *model.NewOrder, p *model.Position, err error) {
+	for _, c := range checkers {
+		if h, ok := c.(OrderSentHandler); ok {
+			h.OrderSent(o, p, err)
+		}
+	}
+}
+
+// CancelNewOrder 新規注文キャンセル
+func (f *Facade) CancelNewOrder(p *model.Position) error {
+	if err := f.exClient.DeleteOrder(p.OpenerOrder.ID); err != nil {
+		return err
+	}
+
+	return f.orderRepo.UpdateStatus(p.OpenerOrder.ID, model.Canceled)
+}
+
 // CancelSettleOrder 注文キャンセル
 func (f *Facade) CancelSettleOrder(p *model.Position) (*model.Position, error) {
 	if err := f.exClient.DeleteOrder(p.CloserOrder.ID); err != nil {
@@ -169,14 +309,159 @@ func (f *Facade) CancelSettleOrder(p *model.Position) (*model.Position, error) {
 	return f.positionRepo.CancelSettleOrder(p.ID)
 }
 
+// CancelOpenOrders 通貨ペアの未約定の注文を取引所で取り消し、DBに反映する
+// 対象は自身のポジションの注文とDBにない注文（取引所で直接出した注文）で、他のボットの注文は取り消さない
+// 決済注文を取り消したポジションは決済前の状態に戻す
+func (f *Facade) CancelOpenOrders(pair *model.CurrencyPair) ([]model.Order, error) {
+	orders, err := f.exClient.GetOpenOrders(pair)
+	if err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 {
+		return []model.Order{}, nil
+	}
+	positions, err := f.positionRepo.GetOpenPositions()
+	if err != nil {
+		return nil, err
+	}
+
+	canceled := []model.Order{}
+	for _, o := range orders {
+		var opened, settled *model.Position
+		for i := range positions {
+			p := &positions[i]
+			if p.OpenerOrder.ID == o.ID {
+				opened = p
+			}
+			if p.CloserOrder != nil && p.CloserOrder.ID == o.ID {
+				settled = p
+			}
+		}
+		if opened == nil && settled == nil {
+			registered, err := f.orderRepo.GetOrder(o.ID)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				// DBの障害で自身の注文を取り消さないように中断する
+				return canceled, err
+			}
+			if err == nil && registered != nil {
+				// 他のボットの注文
+				continue
+			}
+		}
+
+		if err := f.exClient.DeleteOrder(o.ID); err != nil {
+			return canceled, err
+		}
+		o.Status = model.Canceled
+		canceled = append(canceled, o)
+
+		if settled != nil {
+			if _, err := f.positionRepo.CancelSettleOrder(settled.ID); err != nil {
+				return canceled, err
+			}
+			continue
+		}
+		if err := f.orderRepo.UpdateStatus(o.ID, model.Canceled); err != nil {
+			return canceled, err
+		}
+	}
+	return canceled, nil
+}
+
+// SyncOrders 未約定の注文の約定情報と状態を取引所に合わせる
+func (f *Facade) SyncOrders(pair *model.CurrencyPair) error {
+	return SyncOrders(f.exClient, pair, f.orderRepo, f.contractRepo)
+}
+
+// ClosePosition ポジションを成行で決済（未約定の買い注文は取り消し、決済注文が未約定なら取り消してから決済）
+// 送信した決済注文、または取り消した買い注文を返す（対象外のポジションならnil）
+func (f *Facade) ClosePosition(p *model.Position) (*model.Order, error) {
+	switch p.OpenerOrder.Status {
+	case model.Open:
+		if err := f.CancelNewOrder(p); err != nil {
+			return nil, err
+		}
+		canceled := *p.OpenerOrder
+		canceled.Status = model.Canceled
+		return &canceled, nil
+	case model.Closed:
+	default:
+		return nil, nil
+	}
+
+	contracts, err := f.contractRepo.GetContracts(p.OpenerOrder.ID)
+	if err != nil {
+		return nil, err
+	}
+	var amount float64
+	for _, c := range contracts {
+		amount += c.IncreaseAmount
+	}
+	if amount <= 0 {
+		return nil, nil
+	}
+
+	if p.CloserOrder != nil && p.CloserOrder.Status == model.Open {
+		if p, err = f.CancelSettleOrder(p); err != nil {
+			return nil, err
+		}
+	}
+	pos, err := f.SendMarketSellOrder(&p.OpenerOrder.Pair, amount, p)
+	if err != nil {
+		return nil, err
+	}
+	return pos.CloserOrder, nil
+}
+
 // // GetRateHistorySizeMax レート履歴の最大容量を取得
 // func (f *Facade) GetRateHistorySizeMax() int {
 // 	return f.rateRepo.GetHistorySizeMax()
 // }
 
-// GetJpyBalance 日本円の残高を取得
+// GetJpyBalance 日本円の残高を取得（資金枠がある場合は枠の残りが上限）
 func (f *Facade) GetJpyBalance() (*model.Balance, error) {
-	return f.exClient.GetBalance(model.JPY)
+	b, err := f.exClient.GetBalance(model.JPY)
+	if err != nil {
+		return nil, err
+	}
+	if f.allocation == nil {
+		return b, nil
+	}
+
+	available, err := f.allocation.AvailableJPY()
+	if err != nil {
+		return nil, err
+	}
+	if b.Amount > available {
+		b.Amount = available
+	}
+	return b, nil
+}
+
+// GetBalance 残高を取得
+func (f *Facade) GetBalance(currency model.CurrencyType) (*model.Balance, error) {
+	return f.exClient.GetBalance(currency)
+}
+
+// GetEquityJPY 総資産（JPY換算）を取得（資金枠がある場合は枠の金額）
+func (f *Facade) GetEquityJPY(pair *model.CurrencyPair) (float64, error) {
+	if f.allocation != nil {
+		return f.allocation.LimitJPY()
+	}
+
+	jpy, err := f.exClient.GetBalance(model.JPY)
+	if err != nil {
+		return 0, err
+	}
+	currency, err := f.exClient.GetBalance(pair.Key)
+	if err != nil {
+		return 0, err
+	}
+	rate, err := f.GetSellRate(pair)
+	if err != nil {
+		return 0, err
+	}
+	return jpy.Total() + currency.Total()*rate, nil
 }
 
 // GetVolumes 取引量を取得
@@ -184,14 +469,65 @@ func (f *Facade) GetVolumes(p *model.CurrencyPair, side model.OrderSide, d time.
 	return f.exClient.GetVolumes(p, side, d)
 }
 
+// Wait 待機（シミュレーションでは待機しない）
 func (f *Facade) Wait(ctx context.Context, interval time.Duration) error {
-	timeout, cancel := context.WithTimeout(ctx, interval)
-	defer cancel()
+	return f.clock.Sleep(ctx, interval)
+}
 
-	<-timeout.Done()
+// SyncOrders 未約定の注文の約定情報と状態を取引所に合わせる
+func SyncOrders(exCli exchange.Client, pair *model.CurrencyPair, orderRepo repository.OrderRepository, contractRepo repository.ContractRepository) error {
+	orders, err := orderRepo.GetOpenOrders()
+	if err != nil {
+		return err
+	}
+	// 取引所の未約定の注文は通貨ペアごとに取得するので、他の通貨ペアの注文は対象にしない
+	registeredOrders := []model.Order{}
+	for _, o := range orders {
+		if o.Pair == *pair {
+			registeredOrders = append(registeredOrders, o)
+		}
+	}
+	if len(registeredOrders) == 0 {
+		return nil
+	}
 
-	if timeout.Err() != context.Canceled && ctx.Err() != context.DeadlineExceeded {
-		return ctx.Err()
+	// 約定情報を更新
+	cc, err := exCli.GetContracts()
+	if err != nil {
+		return err
+	}
+	targets := []model.Contract{}
+	for _, c := range cc {
+		for _, o := range registeredOrders {
+			if c.OrderID == o.ID {
+				targets = append(targets, c)
+				break
+			}
+		}
+	}
+	if err := contractRepo.UpsertContracts(targets); err != nil {
+		return err
+	}
+
+	// 取引所で未約定でなくなった注文は約定済みにする
+	openOrders, err := exCli.GetOpenOrders(pair)
+	if err != nil {
+		return err
+	}
+	for _, o := range registeredOrders {
+		opened := false
+		for _, openOrder := range openOrders {
+			if openOrder.ID == o.ID {
+				opened = true
+				break
+			}
+		}
+		if opened {
+			continue
+		}
+		if err := orderRepo.UpdateStatus(o.ID, model.Closed); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -0,0 +1,177 @@
+package walkforward
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"time"
+	"trading-bot/pkg/infrastructure/memory"
+	"trading-bot/pkg/usecase/report"
+)
+
+// Window 最適化期間（in-sample）と検証期間（out-of-sample）の組（各期間は開始を含み終了を含まない）
+type Window struct {
+	InSampleStart    time.Time `json:"in_sample_start"`
+	InSampleEnd      time.Time `json:"in_sample_end"`
+	OutOfSampleStart time.Time `json:"out_of_sample_start"`
+	OutOfSampleEnd   time.Time `json:"out_of_sample_end"`
+}
+
+// Split 期間を検証期間の長さずつずらした組に分割（最後の検証期間は終了日時で切る）
+func Split(start, end time.Time, inSample, outOfSample time.Duration) ([]Window, error) {
+	if inSample <= 0 || outOfSample <= 0 {
+		return nil, fmt.Errorf("in-sample and out-of-sample must be positive, (in:%v, out:%v)", inSample, outOfSample)
+	}
+	windows := []Window{}
+	for from := start; ; from = from.Add(outOfSample) {
+		w := Window{
+			InSampleStart:    from,
+			InSampleEnd:      from.Add(inSample),
+			OutOfSampleStart: from.Add(inSample),
+			OutOfSampleEnd:   from.Add(inSample + outOfSample),
+		}
+		if !w.OutOfSampleStart.Before(end) {
+			break
+		}
+		if w.OutOfSampleEnd.After(end) {
+			w.OutOfSampleEnd = end
+		}
+		windows = append(windows, w)
+	}
+	if len(windows) == 0 {
+		return nil, fmt.Errorf("period is shorter than in-sample, (period:%v, in:%v)", end.Sub(start), inSample)
+	}
+	return windows, nil
+}
+
+// Slice 期間内のレート（開始を含み終了を含まない）
+func Slice(rates []memory.Rate, from, to time.Time) []memory.Rate {
+	begin := sort.Search(len(rates), func(i int) bool { return !rates[i].Time.Before(from) })
+	end := sort.Search(len(rates), func(i int) bool { return !rates[i].Time.Before(to) })
+	return rates[begin:end]
+}
+
+// WindowResult 1組の最適化と検証の結果
+type WindowResult struct {
+	Window
+	// 最適化で選んだパラメータ
+	Params map[string]float64 `json:"params"`
+	// 最適化期間での評価値
+	InSampleFitness float64 `json:"in_sample_fitness"`
+	// 検証期間での評価値
+	OutOfSampleFitness float64 `json:"out_of_sample_fitness"`
+	// 検証期間での成績
+	OutOfSample report.Metrics `json:"out_of_sample"`
+}
+
+// ParamStability 組ごとに選んだパラメータのばらつき
+type ParamStability struct {
+	Name string  `json:"name"`
+	Mean float64 `json:"mean"`
+	Std  float64 `json:"std"`
+	Min  float64 `json:"min"`
+	Max  float64 `json:"max"`
+	// 変動係数（標準偏差/平均の絶対値、平均が0なら0）
+	CV float64 `json:"cv"`
+}
+
+// Result ウォークフォワードの結果
+type Result struct {
+	Windows []WindowResult `json:"windows"`
+	// 検証期間の成績をつなげたもの
+	Stitched *report.Report `json:"stitched"`
+	// パラメータのばらつき
+	Stability []ParamStability `json:"stability"`
+}
+
+// Stitch 検証期間の成績を期間順につなげる
+// 各期間は前の期間の終了時の総資産から始めたものとして総資産と取引の損益を換算する
+func Stitch(reports []*report.Report) *report.Report {
+	recorder := report.NewRecorder()
+	trades := []report.Trade{}
+	strategy, pair := "", ""
+	equity := 0.0
+	for _, r := range reports {
+		if len(r.EquityCurve) == 0 {
+			continue
+		}
+		if strategy == "" {
+			strategy, pair = r.Strategy, r.Pair
+			equity = r.Metrics.InitialEquity
+		}
+		scale := 1.0
+		if r.Metrics.InitialEquity > 0 {
+			scale = equity / r.Metrics.InitialEquity
+		}
+		for _, p := range r.EquityCurve {
+			recorder.AddEquity(p.Time, p.Equity*scale, p.Rate)
+		}
+		for _, t := range r.Trades {
+			t.Amount *= scale
+			t.Profit *= scale
+			t.Fee *= scale
+			trades = append(trades, t)
+		}
+		equity = r.Metrics.FinalEquity * scale
+	}
+	return recorder.Build(strategy, pair, trades)
+}
+
+// Params 設定の数値の項目を名前と値の組にする（パラメータのばらつきの集計用）
+func Params(config interface{}) map[string]float64 {
+	params := map[string]float64{}
+	v := reflect.Indirect(reflect.ValueOf(config))
+	if v.Kind() != reflect.Struct {
+		return params
+	}
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		switch fv := v.Field(i); fv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			params[f.Name] = float64(fv.Int())
+		case reflect.Float32, reflect.Float64:
+			params[f.Name] = fv.Float()
+		}
+	}
+	return params
+}
+
+// Stability 組ごとに選んだパラメータのばらつきを集計（名前順）
+func Stability(params []map[string]float64) []ParamStability {
+	values := map[string][]float64{}
+	for _, p := range params {
+		for name, v := range p {
+			values[name] = append(values[name], v)
+		}
+	}
+	names := []string{}
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	stability := []ParamStability{}
+	for _, name := range names {
+		vv := values[name]
+		s := ParamStability{Name: name, Min: math.MaxFloat64, Max: -math.MaxFloat64}
+		for _, v := range vv {
+			s.Mean += v
+			s.Min = math.Min(s.Min, v)
+			s.Max = math.Max(s.Max, v)
+		}
+		s.Mean /= float64(len(vv))
+		for _, v := range vv {
+			s.Std += (v - s.Mean) * (v - s.Mean)
+		}
+		s.Std = math.Sqrt(s.Std / float64(len(vv)))
+		if s.Mean != 0 {
+			s.CV = s.Std / math.Abs(s.Mean)
+		}
+		stability = append(stability, s)
+	}
+	return stability
+}
@@ -0,0 +1,137 @@
+package walkforward_test
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+	"trading-bot/pkg/infrastructure/memory"
+	"trading-bot/pkg/usecase/report"
+	"trading-bot/pkg/usecase/walkforward"
+)
+
+var start = time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC)
+
+func day(n int) time.Time {
+	return start.AddDate(0, 0, n)
+}
+
+func TestSplit(t *testing.T) {
+	tests := map[string]struct {
+		end         time.Time
+		inSample    time.Duration
+		outOfSample time.Duration
+		want        []walkforward.Window
+		wantErr     bool
+	}{
+		"rolling windows": {
+			end:         day(10),
+			inSample:    4 * 24 * time.Hour,
+			outOfSample: 3 * 24 * time.Hour,
+			want: []walkforward.Window{
+				{InSampleStart: day(0), InSampleEnd: day(4), OutOfSampleStart: day(4), OutOfSampleEnd: day(7)},
+				{InSampleStart: day(3), InSampleEnd: day(7), OutOfSampleStart: day(7), OutOfSampleEnd: day(10)},
+			},
+		},
+		"last out-of-sample is cut": {
+			end:         day(9),
+			inSample:    4 * 24 * time.Hour,
+			outOfSample: 3 * 24 * time.Hour,
+			want: []walkforward.Window{
+				{InSampleStart: day(0), InSampleEnd: day(4), OutOfSampleStart: day(4), OutOfSampleEnd: day(7)},
+				{InSampleStart: day(3), InSampleEnd: day(7), OutOfSampleStart: day(7), OutOfSampleEnd: day(9)},
+			},
+		},
+		"period is too short": {
+			end:         day(4),
+			inSample:    4 * 24 * time.Hour,
+			outOfSample: 3 * 24 * time.Hour,
+			wantErr:     true,
+		},
+		"invalid duration": {
+			end:         day(10),
+			inSample:    0,
+			outOfSample: 3 * 24 * time.Hour,
+			wantErr:     true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := walkforward.Split(start, tt.end, tt.inSample, tt.outOfSample)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Split() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Split() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlice(t *testing.T) {
+	rates := []memory.Rate{}
+	for i := 0; i < 5; i++ {
+		rates = append(rates, memory.Rate{Time: day(i), OrderSellRate: float64(i)})
+	}
+	got := walkforward.Slice(rates, day(1), day(3))
+	if len(got) != 2 || got[0].OrderSellRate != 1 || got[1].OrderSellRate != 2 {
+		t.Errorf("Slice() = %+v, want rates of day 1 and 2", got)
+	}
+}
+
+func TestStitch(t *testing.T) {
+	build := func(from int, equities []float64, profit float64) *report.Report {
+		r := report.NewRecorder()
+		for i, e := range equities {
+			r.AddEquity(day(from+i), e, 100)
+		}
+		return r.Build("range", "btc_jpy", []report.Trade{{EntryAt: day(from), ExitAt: day(from + 1), Profit: profit}})
+	}
+	got := walkforward.Stitch([]*report.Report{
+		build(0, []float64{1000, 1100}, 100),
+		// 2つ目は1100から始めたものとして1.1倍する
+		build(2, []float64{1000, 900}, -100),
+	})
+
+	wantEquities := []float64{1000, 1100, 1100, 990}
+	if len(got.EquityCurve) != len(wantEquities) {
+		t.Fatalf("equity curve = %+v", got.EquityCurve)
+	}
+	for i, want := range wantEquities {
+		if math.Abs(got.EquityCurve[i].Equity-want) > 1e-9 {
+			t.Errorf("equity[%d] = %v, want %v", i, got.EquityCurve[i].Equity, want)
+		}
+	}
+	if len(got.Trades) != 2 || math.Abs(got.Trades[1].Profit-(-110)) > 1e-9 {
+		t.Errorf("trades = %+v, want second profit -110", got.Trades)
+	}
+	if math.Abs(got.Metrics.Profit-(-10)) > 1e-9 || got.Metrics.TradeCount != 2 {
+		t.Errorf("metrics = %+v", got.Metrics)
+	}
+}
+
+func TestStability(t *testing.T) {
+	type config struct {
+		TermSize int
+		Ratio    float64
+		Name     string
+		private  int
+	}
+	params := []map[string]float64{
+		walkforward.Params(&config{TermSize: 10, Ratio: 0.5, Name: "a", private: 1}),
+		walkforward.Params(&config{TermSize: 30, Ratio: 0.5}),
+	}
+	if !reflect.DeepEqual(params[0], map[string]float64{"TermSize": 10, "Ratio": 0.5}) {
+		t.Fatalf("Params() = %v", params[0])
+	}
+
+	got := walkforward.Stability(params)
+	want := []walkforward.ParamStability{
+		{Name: "Ratio", Mean: 0.5, Std: 0, Min: 0.5, Max: 0.5, CV: 0},
+		{Name: "TermSize", Mean: 20, Std: 10, Min: 10, Max: 30, CV: 0.5},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Stability() = %+v, want %+v", got, want)
+	}
+}
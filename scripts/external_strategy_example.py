@@ -0,0 +1,61 @@
+#!/usr/bin/env python3
+# 外部戦略のサンプル（strategy = "external" で起動される）
+#
+# 入力（標準入力、1行1メッセージ）
+#   {"id":1,"phase":"buy"|"sell","time":"...","pair":"btc_jpy",
+#    "market":{"buy_rate":0,"sell_rate":0,"rates":[...]},
+#    "balance":{"jpy":0,"coin":0},
+#    "positions":[{"id":1,"status":"open|closed|canceled","amount":0,"rate":0,"opened_at":"...","closing":false}]}
+#   {"id":2,"phase":"trade","trade":{"side":"buy|sell","rate":0}}  ※応答不要
+#
+# 出力（標準出力、1行1メッセージ、idは入力と同じ値）
+#   {"id":1,"intents":[
+#     {"action":"buy","amount_jpy":1000,"reason":"..."},                 買い（成行）
+#     {"action":"buy","amount":0.001,"rate":4000000,"reason":"..."},     買い（指値）
+#     {"action":"sell","position_id":1,"rate":4100000,"reason":"..."},   売り（rate省略で成行、amount省略で全量）
+#     {"action":"close","position_id":1,"reason":"..."}                  成行で全量決済
+#   ]}
+#   buyフェーズではbuyのみ、sellフェーズではsell/closeのみ受け付ける
+#   ログは標準エラー出力へ
+import json
+import sys
+
+SMA_PERIOD = 20
+TAKE_PROFIT = 1.01
+
+
+def decide(msg):
+    market = msg["market"]
+    rates = market["rates"]
+    positions = msg.get("positions", [])
+
+    if msg["phase"] == "buy":
+        if positions or len(rates) < SMA_PERIOD:
+            return []
+        sma = sum(rates[-SMA_PERIOD:]) / SMA_PERIOD
+        if market["buy_rate"] < sma * 0.99:
+            return [{"action": "buy", "amount_jpy": 1000, "reason": "below sma"}]
+        return []
+
+    intents = []
+    for p in positions:
+        if p["status"] == "closed" and not p["closing"] and market["sell_rate"] >= p["rate"] * TAKE_PROFIT:
+            intents.append({"action": "close", "position_id": p["id"], "reason": "take profit"})
+    return intents
+
+
+def main():
+    for line in sys.stdin:
+        msg = json.loads(line)
+        if msg["phase"] == "trade":
+            continue
+        try:
+            intents = decide(msg)
+        except Exception as e:
+            print("failed to decide: %s" % e, file=sys.stderr)
+            intents = []
+        print(json.dumps({"id": msg["id"], "intents": intents}), flush=True)
+
+
+if __name__ == "__main__":
+    main()
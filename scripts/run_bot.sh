@@ -16,4 +16,4 @@ export BOT_DB_NAME=trading-bot
 export BOT_DB_USER_NAME=bot
 export BOT_DB_PASSWORD=P@ssw0rd
 
-go run cmd/trading-bot/main.go $1
+go run ./cmd/trading-bot $@
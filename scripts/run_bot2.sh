@@ -5,5 +5,6 @@ export $(cat configs/db.env | grep -v "^#" | xargs)
 export $(cat configs/slack.env | grep -v "^#" | xargs)
 export $(cat configs/exchange.env | grep -v "^#" | xargs)
 export $(cat configs/bot2.env | grep -v "^#" | xargs)
+export ENV_FILE=configs/bot2.env
 
-go run cmd/trading-bot2/main.go $1
+go run ./cmd/trading-bot2 $1
@@ -24,4 +24,4 @@ export BOT_SLIPPAGE=0.001
 #export BOT_RATE_HISTORY_FILE=./data/simulator/historical_mona_jpy.csv
 export BOT_RATE_HISTORY_FILE=./data/simulator/historical_btc_jpy_1.csv
 
-go run cmd/ga-simulator/main.go
+go run ./cmd/ga-simulator
@@ -76,6 +76,8 @@ async function draw(pair, id) {
 
             var bought = false;
             var selled = false;
+            var alerted = false;
+            var rejected = false;
             botInfo.events.forEach(e => {
                 const eventDatetime = new Date(e.datetime)
                 var matched = false;
@@ -91,6 +93,12 @@ async function draw(pair, id) {
                 if (matched && e.type == 1) {
                     selled = true;
                 }
+                if (matched && e.type == 2) {
+                    alerted = true;
+                }
+                if (matched && e.type == 3) {
+                    rejected = true;
+                }
             });
 
             var point = null;
@@ -100,6 +108,10 @@ async function draw(pair, id) {
                 point = 'point {size:7;shape-type:diamond;fill-color:#3cb371;}'
             } else if (selled) {
                 point = 'point {size:7;shape-type:diamond;fill-color:#dc3545;}'
+            } else if (alerted) {
+                point = 'point {size:7;shape-type:star;fill-color:#8a2be2;}'
+            } else if (rejected) {
+                point = 'point {size:7;shape-type:triangle;fill-color:#808080;}'
             }
             var value = [
                 datetime,